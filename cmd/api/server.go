@@ -6,9 +6,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	mw "github.com/greatdaveo/Schoolly/internal/api/middlewares"
 	"github.com/greatdaveo/Schoolly/internal/api/router"
+	"github.com/greatdaveo/Schoolly/internal/jobs"
 	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
 	"github.com/greatdaveo/Schoolly/pkg/utils"
 	"github.com/joho/godotenv"
@@ -29,6 +32,28 @@ func main() {
 		return
 	}
 
+	// To permanently remove soft deleted rows once they are past the retention period
+	retention, err := time.ParseDuration(os.Getenv("PURGE_RETENTION"))
+	if err != nil {
+		retention = 30 * 24 * time.Hour
+	}
+	purgeInterval, err := time.ParseDuration(os.Getenv("PURGE_INTERVAL"))
+	if err != nil {
+		purgeInterval = 24 * time.Hour
+	}
+	jobs.StartPurgeJob(retention, purgeInterval)
+
+	// To email announcements once they are published
+	announcementInterval, err := time.ParseDuration(os.Getenv("ANNOUNCEMENT_EMAIL_INTERVAL"))
+	if err != nil {
+		announcementInterval = time.Minute
+	}
+	announcementAttempts, err := strconv.Atoi(os.Getenv("ANNOUNCEMENT_EMAIL_MAX_ATTEMPTS"))
+	if err != nil || announcementAttempts < 1 {
+		announcementAttempts = 5
+	}
+	jobs.StartAnnouncementEmailJob(announcementInterval, announcementAttempts)
+
 	// To load the cert file
 	cert := "cert.pem"
 	key := "key.pem"
@@ -53,8 +78,8 @@ func main() {
 	// secureMux := mw.Cors(rl.RateLimiterMiddleware(mw.ResponseTimeMiddleWare(mw.SecurityHeaders(mw.Compression(mw.Hpp(hppOptions)(mux))))))
 	// secureMux := utils.ApplyMiddlewares(mux, mw.Hpp(hppOptions), mw.Compression, mw.SecurityHeaders, mw.ResponseTimeMiddleWare, rl.RateLimiterMiddleware, mw.Cors)
 	router := router.MainRouter()
-	jwtMiddleware := mw.MiddlewaresExcludePaths(mw.JWTMiddleware, "/execs/login", "/execs/forgot-password", "/execs/reset-password/reset")
-	secureMux := jwtMiddleware(mw.SecurityHeaders(router))
+	jwtMiddleware := mw.MiddlewaresExcludePaths(mw.JWTMiddleware, "/execs/login", "/execs/forgot-password", "/execs/reset-password/reset", "/feeds/", "/admissions/apply")
+	secureMux := mw.RequestID(jwtMiddleware(mw.SecurityHeaders(router)))
 	// secureMux := (mw.SecurityHeaders(router))
 
 	// To create custom server
@@ -0,0 +1,213 @@
+// migrate_subjects is a one-off command that turns the free text subjects of teachers
+// into rows of the subjects catalog. Spelling and casing variants such as "maths ",
+// "Mathematics" and "Mathmatics" are folded into a single subject.
+//
+//	go run ./cmd/migrate_subjects -dry-run
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+	"github.com/joho/godotenv"
+)
+
+// Common short forms that edit distance alone would not match
+var aliases = map[string]string{
+	"Maths": "Mathematics",
+	"Math":  "Mathematics",
+	"Bio":   "Biology",
+	"Chem":  "Chemistry",
+	"Phys":  "Physics",
+	"Lit":   "Literature",
+	"Pe":    "Physical Education",
+	"It":    "Information Technology",
+	"Ict":   "Information Technology",
+}
+
+// The largest number of typos allowed when matching a name against the catalog
+const maxDistance = 2
+
+// To allow one typo for every four letters, so short names such as "Art" and "Law" are
+// only matched exactly and never folded into each other
+func allowedDistance(name string) int {
+	length := utf8.RuneCountInString(name)
+	if length <= 3 {
+		return 0
+	}
+	return min(length/4, maxDistance)
+}
+
+type subject struct {
+	id   int
+	code string
+	name string
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the changes without writing them")
+	flag.Parse()
+
+	godotenv.Load()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		log.Fatalln("❌ Error connecting to DB:", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatalln("❌ Error starting transaction:", err)
+	}
+	defer tx.Rollback()
+
+	catalog, err := loadCatalog(tx)
+	if err != nil {
+		log.Fatalln("❌ Error loading subjects:", err)
+	}
+
+	rows, err := tx.Query("SELECT DISTINCT subject FROM teachers WHERE subject <> '' UNION SELECT DISTINCT subject FROM teaching_assignments WHERE subject <> ''")
+	if err != nil {
+		log.Fatalln("❌ Error reading teacher subjects:", err)
+	}
+	var rawSubjects []string
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			log.Fatalln("❌ Error reading teacher subjects:", err)
+		}
+		rawSubjects = append(rawSubjects, raw)
+	}
+	rows.Close()
+
+	for _, raw := range rawSubjects {
+		match, created, err := matchSubject(tx, &catalog, raw, *dryRun)
+		if err != nil {
+			log.Fatalln("❌ Error matching subject:", err)
+		}
+
+		action := "matched"
+		if created {
+			action = "created"
+		}
+		fmt.Printf("%-30q -> %s (%s, id %d)\n", raw, match.name, action, match.id)
+
+		if *dryRun {
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE teachers SET subject = ?, subject_id = ? WHERE subject = ?", match.name, match.id, raw)
+		if err != nil {
+			log.Fatalln("❌ Error updating teachers:", err)
+		}
+
+		// Leads are keyed by the subject name, so the key follows the new name
+		_, err = tx.Exec(
+			"UPDATE teaching_assignments SET subject = ?, subject_id = ?, lead_key = IF(lead_key IS NULL, NULL, CONCAT(class_id, ':', subject, ':', term)) WHERE subject = ?",
+			match.name, match.id, raw,
+		)
+		if utils.IsDuplicateKey(err) {
+			log.Fatalf("❌ %q and %q both have a lead teacher in the same class and term, make one an assistant first", raw, match.name)
+		} else if err != nil {
+			log.Fatalln("❌ Error updating teaching_assignments:", err)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing was written")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatalln("❌ Error committing transaction:", err)
+	}
+	fmt.Printf("✅ Normalized %d subject values\n", len(rawSubjects))
+}
+
+func loadCatalog(tx *sql.Tx) ([]subject, error) {
+	rows, err := tx.Query("SELECT id, code, name FROM subjects WHERE deleted_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalog []subject
+	for rows.Next() {
+		var s subject
+		if err := rows.Scan(&s.id, &s.code, &s.name); err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, s)
+	}
+	return catalog, nil
+}
+
+// To find the catalog entry closest to a raw subject, creating one when nothing is close enough
+func matchSubject(tx *sql.Tx, catalog *[]subject, raw string, dryRun bool) (subject, bool, error) {
+	name := utils.NormalizeName(raw)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	best, bestDistance := -1, allowedDistance(name)+1
+	for i, s := range *catalog {
+		if strings.EqualFold(s.code, name) {
+			return s, false, nil
+		}
+		// Catalog names are compared in the same normalised, lower case form as the raw value,
+		// so casing and spacing never use up the typo allowance
+		catalogName := strings.ToLower(utils.NormalizeName(s.name))
+		if distance := utils.Levenshtein(catalogName, strings.ToLower(name)); distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	if best >= 0 {
+		return (*catalog)[best], false, nil
+	}
+
+	created := subject{code: subjectCode(*catalog, name), name: name}
+	if !dryRun {
+		res, err := tx.Exec("INSERT INTO subjects (code, name) VALUES (?, ?)", created.code, created.name)
+		if err != nil {
+			return subject{}, false, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return subject{}, false, err
+		}
+		created.id = int(id)
+	}
+
+	*catalog = append(*catalog, created)
+	return created, true, nil
+}
+
+// To build a unique code from the first letters of a name e.g. "Physical Education" -> "PHYS", "PHYS2"
+func subjectCode(catalog []subject, name string) string {
+	base := []rune(strings.ToUpper(strings.ReplaceAll(name, " ", "")))
+	if len(base) > 4 {
+		base = base[:4]
+	}
+
+	code := string(base)
+	for n := 2; ; n++ {
+		taken := false
+		for _, s := range catalog {
+			if strings.EqualFold(s.code, code) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return code
+		}
+		code = fmt.Sprintf("%s%d", string(base), n)
+	}
+}
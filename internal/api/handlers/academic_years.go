@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// DateLayout is how calendar dates are written in requests and stored in DATE columns
+const DateLayout = "2006-01-02"
+
+const academicYearColumns = "id, name, start_date, end_date, version"
+
+var academicYearFilterFields = map[string]string{
+	"name": "name",
+}
+
+var academicYearSortFields = map[string]bool{
+	"name":       true,
+	"start_date": true,
+	"end_date":   true,
+}
+
+func scanAcademicYear(row interface{ Scan(...interface{}) error }, year *models.AcademicYear) error {
+	return row.Scan(
+		&year.ID,
+		&year.Name,
+		&year.StartDate,
+		&year.EndDate,
+		&year.Version,
+	)
+}
+
+// To check that a start and end date are valid dates in the right order
+func validateDateRange(startDate, endDate string) error {
+	start, err := time.Parse(DateLayout, startDate)
+	if err != nil {
+		return errors.New("❌ start_date must be a date like 2025-09-01")
+	}
+	end, err := time.Parse(DateLayout, endDate)
+	if err != nil {
+		return errors.New("❌ end_date must be a date like 2026-07-31")
+	}
+	if end.Before(start) {
+		return errors.New("❌ end_date cannot be before start_date")
+	}
+	return nil
+}
+
+// To check an academic year before it is written. It returns the HTTP status to answer with when invalid.
+func validateAcademicYear(db Queryer, year models.AcademicYear) (int, error) {
+	if year.Name == "" {
+		return http.StatusBadRequest, errors.New("❌ name, start_date and end_date are required")
+	}
+	if err := validateDateRange(year.StartDate, year.EndDate); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	var overlapping string
+	err := db.QueryRow(
+		"SELECT name FROM academic_years WHERE start_date <= ? AND end_date >= ? AND id <> ? AND deleted_at IS NULL LIMIT 1",
+		year.EndDate, year.StartDate, year.ID,
+	).Scan(&overlapping)
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("❌ Dates overlap with academic year %s", overlapping)
+	} else if err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check for overlapping academic years")
+	}
+
+	return http.StatusOK, nil
+}
+
+// To get multiple academic years
+func GetAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + academicYearColumns + " FROM academic_years WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, academicYearFilterFields)
+	query = utils.AddSortingFor(r, query, academicYearSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	yearList := make([]models.AcademicYear, 0)
+	for rows.Next() {
+		var year models.AcademicYear
+		err := scanAcademicYear(rows, &year)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		yearList = append(yearList, year)
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.AcademicYear `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(yearList),
+		Data:   yearList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single academic year
+func GetOneAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var year models.AcademicYear
+	err = scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", id), &year)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Academic year not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(year.ID, year.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(year)
+}
+
+// To add academic years to the DB
+func AddAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	var newYears []models.AcademicYear
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newYears)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedYears := make([]models.AcademicYear, len(newYears))
+	for i, newYear := range newYears {
+		// Validating inside the transaction also catches overlaps within the same request
+		status, err := validateAcademicYear(tx, newYear)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newYear.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO academic_years (name, start_date, end_date, version) VALUES (?, ?, ?, ?)",
+			newYear.Name,
+			newYear.StartDate,
+			newYear.EndDate,
+			newYear.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newYear.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "academic_years", newYear.ID, nil, newYear)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedYears[i] = newYear
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.AcademicYear `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedYears),
+		Data:   addedYears,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of an academic year
+func EditAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingYear models.AcademicYear
+	err = scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", id), &existingYear)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Academic year not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingYear.ID, existingYear.Version) {
+		return
+	}
+
+	previousYear := existingYear
+	err = ApplyPatch(&existingYear, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := validateAcademicYear(db, existingYear)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Terms and holidays have to stay inside the year
+	var outside int
+	err = db.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM terms WHERE academic_year_id = ? AND deleted_at IS NULL AND (start_date < ? OR end_date > ?)) + (SELECT COUNT(*) FROM holidays WHERE academic_year_id = ? AND (start_date < ? OR end_date > ?))",
+		id, existingYear.StartDate, existingYear.EndDate, id, existingYear.StartDate, existingYear.EndDate,
+	).Scan(&outside)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check terms of academic year")
+		http.Error(w, "❌ Unable to check terms of academic year", http.StatusInternalServerError)
+		return
+	}
+	if outside > 0 {
+		http.Error(w, "❌ New dates would leave terms or holidays outside the academic year", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE academic_years SET name = ?, start_date = ?, end_date = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingYear.Name,
+		existingYear.StartDate,
+		existingYear.EndDate,
+		existingYear.ID,
+		previousYear.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating academic year")
+		http.Error(w, "❌ Error updating academic year", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingYear.Version = previousYear.Version + 1
+
+	// Classes keep a copy of the academic year name, so a rename has to follow them
+	if existingYear.Name != previousYear.Name {
+		_, err = tx.Exec("UPDATE classes SET academic_year = ? WHERE academic_year = ?", existingYear.Name, previousYear.Name)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error renaming academic year")
+			http.Error(w, "❌ Error renaming academic year", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "academic_years", existingYear.ID, previousYear, existingYear)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingYear.ID, existingYear.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingYear)
+}
+
+func DeleteOneAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedYear models.AcademicYear
+	err = scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", id), &deletedYear)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Academic year not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedYear.ID, deletedYear.Version) {
+		return
+	}
+
+	var terms int
+	err = db.QueryRow("SELECT COUNT(*) FROM terms WHERE academic_year_id = ? AND deleted_at IS NULL", id).Scan(&terms)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to count terms of academic year")
+		http.Error(w, "❌ Unable to count terms of academic year", http.StatusInternalServerError)
+		return
+	}
+	if terms > 0 {
+		http.Error(w, fmt.Sprintf("❌ Academic year still has %d terms, delete them first", terms), http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE academic_years SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedYear.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete academic year")
+		http.Error(w, "❌ Unable delete academic year", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "academic_years", id, deletedYear, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Academic year successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "academic_years", "Academic year")
+}
@@ -0,0 +1,907 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/storage"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const applicationColumns = `id, first_name, last_name, date_of_birth, email, grade_level, academic_year, previous_school, notes,
+	guardian_first_name, guardian_last_name, guardian_phone, guardian_email, relationship,
+	status, submitted_at, COALESCE(reviewed_by, 0), decision_note, COALESCE(offered_class_id, 0), COALESCE(offer_expires_on, ''),
+	COALESCE(responded_at, ''), COALESCE(student_id, 0), COALESCE(converted_at, ''), version`
+
+// The owner type of application documents in the attachments table
+const applicationAttachments = "application"
+
+// The feed name signed into the tokens applicants use to follow their application
+const applicationTokenFeed = "admission-application"
+
+var applicationFilterFields = map[string]string{
+	"status":        "status",
+	"grade_level":   "grade_level",
+	"academic_year": "academic_year",
+}
+
+var applicationSortFields = map[string]bool{
+	"submitted_at": true,
+	"last_name":    true,
+	"grade_level":  true,
+	"status":       true,
+}
+
+// The statuses an application can move to from each status. Accepted, declined and
+// rejected applications are finished, an accepted one is then turned into a student.
+var applicationTransitions = map[string]map[string]bool{
+	"submitted":    {"under_review": true, "rejected": true},
+	"under_review": {"offered": true, "rejected": true},
+	"offered":      {"accepted": true, "declined": true},
+}
+
+// The fields of an application that describe the applicant rather than the school's decision
+var applicantFields = map[string]bool{
+	"first_name":          true,
+	"last_name":           true,
+	"date_of_birth":       true,
+	"email":               true,
+	"grade_level":         true,
+	"academic_year":       true,
+	"previous_school":     true,
+	"notes":               true,
+	"guardian_first_name": true,
+	"guardian_last_name":  true,
+	"guardian_phone":      true,
+	"guardian_email":      true,
+	"relationship":        true,
+}
+
+func scanApplication(row interface{ Scan(...interface{}) error }, application *models.Application) error {
+	return row.Scan(
+		&application.ID,
+		&application.FirstName,
+		&application.LastName,
+		&application.DateOfBirth,
+		&application.Email,
+		&application.GradeLevel,
+		&application.AcademicYear,
+		&application.PreviousSchool,
+		&application.Notes,
+		&application.GuardianFirstName,
+		&application.GuardianLastName,
+		&application.GuardianPhone,
+		&application.GuardianEmail,
+		&application.Relationship,
+		&application.Status,
+		&application.SubmittedAt,
+		&application.ReviewedBy,
+		&application.DecisionNote,
+		&application.OfferedClassID,
+		&application.OfferExpiresOn,
+		&application.RespondedAt,
+		&application.StudentID,
+		&application.ConvertedAt,
+		&application.Version,
+	)
+}
+
+// To check an application's details and bring names, phone numbers and emails into one format
+func validateApplication(application *models.Application) error {
+	application.FirstName = strings.TrimSpace(application.FirstName)
+	application.LastName = strings.TrimSpace(application.LastName)
+	application.Email = strings.ToLower(strings.TrimSpace(application.Email))
+	application.AcademicYear = strings.TrimSpace(application.AcademicYear)
+	application.PreviousSchool = strings.TrimSpace(application.PreviousSchool)
+	application.Notes = strings.TrimSpace(application.Notes)
+	application.Relationship = strings.ToLower(strings.TrimSpace(application.Relationship))
+
+	if application.FirstName == "" || application.LastName == "" || application.DateOfBirth == "" || application.AcademicYear == "" {
+		return errors.New("❌ first_name, last_name, date_of_birth and academic_year are required")
+	}
+	if application.GradeLevel < 1 {
+		return errors.New("❌ grade_level must be a positive number")
+	}
+	dateOfBirth, err := time.Parse(DateLayout, application.DateOfBirth)
+	if err != nil {
+		return errors.New("❌ date_of_birth must look like 2015-04-23")
+	}
+	if !dateOfBirth.Before(time.Now()) {
+		return errors.New("❌ date_of_birth must be in the past")
+	}
+	if application.Email != "" {
+		if err := utils.ValidateEmail(application.Email); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(application.GuardianFirstName) == "" || strings.TrimSpace(application.GuardianLastName) == "" ||
+		application.GuardianPhone == "" || application.GuardianEmail == "" || application.Relationship == "" {
+		return errors.New("❌ guardian_first_name, guardian_last_name, guardian_phone, guardian_email and relationship are required")
+	}
+
+	// The guardian goes through the same checks as one added directly
+	guardian := models.Guardian{
+		FirstName: application.GuardianFirstName,
+		LastName:  application.GuardianLastName,
+		Phone:     application.GuardianPhone,
+		Email:     application.GuardianEmail,
+	}
+	if err := validateGuardian(&guardian); err != nil {
+		return err
+	}
+	application.GuardianFirstName, application.GuardianLastName = guardian.FirstName, guardian.LastName
+	application.GuardianPhone, application.GuardianEmail = guardian.Phone, guardian.Email
+	return nil
+}
+
+// To load one application that hasn't been deleted
+func loadApplication(db Queryer, id int) (models.Application, int, error) {
+	var application models.Application
+	err := scanApplication(db.QueryRow("SELECT "+applicationColumns+" FROM applications WHERE id = ? AND deleted_at IS NULL", id), &application)
+	if err == sql.ErrNoRows {
+		return application, http.StatusNotFound, errors.New("❌ Application not found")
+	} else if err != nil {
+		return application, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	return application, http.StatusOK, nil
+}
+
+// To save a decision on an application, refusing moves the pipeline doesn't allow.
+// It returns the HTTP status to answer with when the move fails.
+func moveApplication(tx *sql.Tx, r *http.Request, existing, updated *models.Application) (int, error) {
+	if updated.Status != existing.Status && !applicationTransitions[existing.Status][updated.Status] {
+		return http.StatusConflict, fmt.Errorf("❌ A %s application can't be %s", strings.ReplaceAll(existing.Status, "_", " "), strings.ReplaceAll(updated.Status, "_", " "))
+	}
+
+	result, err := tx.Exec(
+		"UPDATE applications SET status = ?, reviewed_by = ?, decision_note = ?, offered_class_id = ?, offer_expires_on = ?, responded_at = ?, student_id = ?, converted_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		updated.Status,
+		nullableID(updated.ReviewedBy),
+		updated.DecisionNote,
+		nullableID(updated.OfferedClassID),
+		nullableDate(updated.OfferExpiresOn),
+		nullableDate(updated.RespondedAt),
+		nullableID(updated.StudentID),
+		nullableDate(updated.ConvertedAt),
+		existing.ID,
+		existing.Version,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to update application")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		return http.StatusPreconditionFailed, errors.New("❌ Resource has been modified by someone else, reload and try again")
+	}
+	updated.Version = existing.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "applications", existing.ID, existing, updated)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// To record the applicant's answer to an offer. Offers can't be taken up once they have expired.
+func answerOffer(application *models.Application, accept bool) (int, error) {
+	if application.Status != "offered" {
+		return http.StatusConflict, errors.New("❌ Only offers can be accepted or declined")
+	}
+	if accept && application.OfferExpiresOn != "" && application.OfferExpiresOn < time.Now().Format(DateLayout) {
+		return http.StatusConflict, errors.New("❌ The offer expired on " + application.OfferExpiresOn)
+	}
+	application.Status = "declined"
+	if accept {
+		application.Status = "accepted"
+	}
+	application.RespondedAt = time.Now().Format(DateTimeLayout)
+	return http.StatusOK, nil
+}
+
+// To answer a decision request: load the application, let decide change it, and save the move
+func decideApplication(w http.ResponseWriter, r *http.Request, decide func(tx *sql.Tx, application *models.Application) (int, error)) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	existing, status, err := loadApplication(tx, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existing.ID, existing.Version) {
+		tx.Rollback()
+		return
+	}
+
+	updated := existing
+	updated.ReviewedBy = ActorID(r)
+	status, err = decide(tx, &updated)
+	if err == nil {
+		status, err = moveApplication(tx, r, &existing, &updated)
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updated.ID, updated.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// To read an optional {"note": "..."} body for a decision
+func decisionNote(r *http.Request) (string, error) {
+	var request struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength == 0 {
+		return "", nil
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return "", errors.New("❌ Invalid request body, only a note can be given")
+	}
+	return strings.TrimSpace(request.Note), nil
+}
+
+// To get applications, e.g. ?status=submitted for the ones waiting to be looked at
+func GetApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + applicationColumns + " FROM applications WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, applicationFilterFields)
+	if sorted := utils.AddSortingFor(r, query, applicationSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY submitted_at, id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	applicationList := make([]models.Application, 0)
+	for rows.Next() {
+		var application models.Application
+		err := scanApplication(rows, &application)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		applicationList = append(applicationList, application)
+	}
+
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.Application `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(applicationList),
+		Data:   applicationList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get one application with its documents
+func GetOneApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	application, status, err := loadApplication(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	documents, err := loadAttachments(db, applicationAttachments, []int{id})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	application.Documents = documents[id]
+
+	if CheckIfNoneMatch(w, r, ETag(application.ID, application.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(application)
+}
+
+// To correct the details of an application before an offer is made
+func EditApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	for field := range input {
+		if !applicantFields[field] {
+			http.Error(w, "❌ "+field+" can't be edited, decisions are made through the review endpoints", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingApplication, status, err := loadApplication(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingApplication.ID, existingApplication.Version) {
+		return
+	}
+
+	if existingApplication.Status != "submitted" && existingApplication.Status != "under_review" {
+		http.Error(w, "❌ Only applications that haven't been decided can be edited", http.StatusConflict)
+		return
+	}
+
+	updatedApplication := existingApplication
+	err = ApplyPatch(&updatedApplication, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = validateApplication(&updatedApplication)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		`UPDATE applications SET first_name = ?, last_name = ?, date_of_birth = ?, email = ?, grade_level = ?, academic_year = ?, previous_school = ?, notes = ?,
+		guardian_first_name = ?, guardian_last_name = ?, guardian_phone = ?, guardian_email = ?, relationship = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		updatedApplication.FirstName,
+		updatedApplication.LastName,
+		updatedApplication.DateOfBirth,
+		updatedApplication.Email,
+		updatedApplication.GradeLevel,
+		updatedApplication.AcademicYear,
+		updatedApplication.PreviousSchool,
+		updatedApplication.Notes,
+		updatedApplication.GuardianFirstName,
+		updatedApplication.GuardianLastName,
+		updatedApplication.GuardianPhone,
+		updatedApplication.GuardianEmail,
+		updatedApplication.Relationship,
+		id,
+		existingApplication.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to update application")
+		http.Error(w, "❌ Unable to update application", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	updatedApplication.Version = existingApplication.Version + 1
+	err = RecordAudit(tx, r, AuditUpdate, "applications", id, existingApplication, updatedApplication)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updatedApplication.ID, updatedApplication.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedApplication)
+}
+
+// To start looking at a submitted application
+func ReviewApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	decideApplication(w, r, func(tx *sql.Tx, application *models.Application) (int, error) {
+		application.Status = "under_review"
+		return http.StatusOK, nil
+	})
+}
+
+// To turn an application down, optionally with {"note": "..."}
+func RejectApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	note, err := decisionNote(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	decideApplication(w, r, func(tx *sql.Tx, application *models.Application) (int, error) {
+		application.Status = "rejected"
+		application.DecisionNote = note
+		return http.StatusOK, nil
+	})
+}
+
+// To offer a place in a class, e.g. {"class_id": 4, "expires_on": "2025-06-30", "note": "..."}.
+// Offers run for APPLICATION_OFFER_DAYS days unless expires_on says otherwise, and a class
+// with a capacity can't be offered beyond it counting offers still open.
+func OfferApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ClassID   int    `json:"class_id"`
+		ExpiresOn string `json:"expires_on"`
+		Note      string `json:"note"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil || request.ClassID == 0 {
+		http.Error(w, "❌ Invalid request body, class_id is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if request.ExpiresOn == "" {
+		offerDays := 14
+		if v, err := strconv.Atoi(os.Getenv("APPLICATION_OFFER_DAYS")); err == nil && v > 0 {
+			offerDays = v
+		}
+		request.ExpiresOn = time.Now().AddDate(0, 0, offerDays).Format(DateLayout)
+	} else if _, err := time.Parse(DateLayout, request.ExpiresOn); err != nil || request.ExpiresOn < time.Now().Format(DateLayout) {
+		http.Error(w, "❌ expires_on must be a date like 2025-06-30 that hasn't passed", http.StatusBadRequest)
+		return
+	}
+
+	decideApplication(w, r, func(tx *sql.Tx, application *models.Application) (int, error) {
+		var class models.Class
+		err := scanClass(tx.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ? AND deleted_at IS NULL FOR UPDATE", request.ClassID), &class)
+		if err == sql.ErrNoRows {
+			return http.StatusBadRequest, fmt.Errorf("❌ Class %d does not exist", request.ClassID)
+		} else if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		}
+
+		if class.Capacity > 0 {
+			var taken int
+			err = tx.QueryRow(
+				`SELECT (SELECT COUNT(*) FROM students WHERE class_id = ? AND deleted_at IS NULL)
+				+ (SELECT COUNT(*) FROM applications WHERE offered_class_id = ? AND student_id IS NULL AND deleted_at IS NULL
+					AND (status = 'accepted' OR (status = 'offered' AND (offer_expires_on IS NULL OR offer_expires_on >= CURDATE()))))`,
+				class.ID, class.ID,
+			).Scan(&taken)
+			if err != nil {
+				return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to count places in the class")
+			}
+			if taken >= class.Capacity {
+				return http.StatusConflict, fmt.Errorf("❌ %s is full with %d of %d places taken or offered", class.Name, taken, class.Capacity)
+			}
+		}
+
+		application.Status = "offered"
+		application.OfferedClassID = class.ID
+		application.OfferExpiresOn = request.ExpiresOn
+		application.DecisionNote = strings.TrimSpace(request.Note)
+		return http.StatusOK, nil
+	})
+}
+
+// To record an answer to an offer given on paper or by phone, {"accept": true} or {"accept": false}
+func RecordApplicationResponseHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Accept *bool `json:"accept"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil || request.Accept == nil {
+		http.Error(w, "❌ Invalid request body, accept is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	decideApplication(w, r, func(tx *sql.Tx, application *models.Application) (int, error) {
+		return answerOffer(application, *request.Accept)
+	})
+}
+
+// To turn an accepted application into a student in the offered class, or in {"class_id": ...}
+// when the placement changed. The guardian who applied is linked as the primary contact,
+// reusing a guardian already on file with the same email.
+func ConvertApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ClassID int    `json:"class_id"`
+		Email   string `json:"email"`
+	}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			http.Error(w, "❌ Invalid request body, only class_id and email can be given", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	var student models.Student
+	decideApplication(w, r, func(tx *sql.Tx, application *models.Application) (int, error) {
+		if application.Status != "accepted" {
+			return http.StatusConflict, errors.New("❌ Only accepted applications can be turned into students")
+		}
+		if application.StudentID != 0 {
+			return http.StatusConflict, fmt.Errorf("❌ Application %d is already student %d", application.ID, application.StudentID)
+		}
+
+		student = models.Student{
+			FirstName: application.FirstName,
+			LastName:  application.LastName,
+			Email:     application.Email,
+			ClassID:   application.OfferedClassID,
+			Status:    "active",
+			Version:   1,
+		}
+		if request.Email != "" {
+			student.Email = strings.ToLower(strings.TrimSpace(request.Email))
+			if err := utils.ValidateEmail(student.Email); err != nil {
+				return http.StatusBadRequest, err
+			}
+		}
+		if student.Email == "" {
+			return http.StatusBadRequest, errors.New("❌ The application has no email, give one for the student")
+		}
+		if request.ClassID != 0 {
+			student.ClassID = request.ClassID
+		}
+		var err error
+		student.ClassID, student.Class, err = ResolveClass(tx, student.ClassID, "")
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if student.ClassID == 0 {
+			return http.StatusBadRequest, errors.New("❌ class_id is required, the offered class no longer exists")
+		}
+
+		res, err := tx.Exec(
+			"INSERT INTO students (first_name, last_name, email, class, class_id, version) VALUES (?, ?, ?, ?, ?, ?)",
+			student.FirstName, student.LastName, student.Email, student.Class, student.ClassID, student.Version,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error creating student")
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		student.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "students", student.ID, nil, student)
+		if err == nil {
+			err = EnrollStudent(tx, student.ID, student.ClassID)
+		}
+		if err == nil {
+			err = recordEnrollmentEvent(tx, r, &models.EnrollmentEvent{
+				StudentID: student.ID,
+				Event:     "admitted",
+				ToStatus:  student.Status,
+				ToClassID: student.ClassID,
+				Reason:    fmt.Sprintf("Admitted from application %d", application.ID),
+			})
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		status, err := linkApplicantGuardian(tx, r, *application, student.ID)
+		if err != nil {
+			return status, err
+		}
+
+		application.StudentID = student.ID
+		application.ConvertedAt = time.Now().Format(DateTimeLayout)
+		return http.StatusOK, nil
+	})
+}
+
+// To link the guardian named on an application to the new student, adding them when
+// no guardian with their email is on file yet
+func linkApplicantGuardian(tx *sql.Tx, r *http.Request, application models.Application, studentId int) (int, error) {
+	var guardianId int
+	err := tx.QueryRow("SELECT id FROM guardians WHERE email = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", application.GuardianEmail).Scan(&guardianId)
+	if err == sql.ErrNoRows {
+		guardian := models.Guardian{
+			FirstName: application.GuardianFirstName,
+			LastName:  application.GuardianLastName,
+			Phone:     application.GuardianPhone,
+			Email:     application.GuardianEmail,
+			Version:   1,
+		}
+		res, err := tx.Exec(
+			"INSERT INTO guardians (first_name, last_name, phone, alt_phone, email, address, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			guardian.FirstName, guardian.LastName, guardian.Phone, guardian.AltPhone, guardian.Email, guardian.Address, guardian.Version,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error creating guardian")
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		guardian.ID = int(lastID)
+		guardianId = guardian.ID
+
+		err = RecordAudit(tx, r, AuditCreate, "guardians", guardian.ID, nil, guardian)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	} else if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve guardian")
+	}
+
+	link := models.GuardianLink{
+		StudentID:        studentId,
+		GuardianID:       guardianId,
+		Relationship:     application.Relationship,
+		PickupAuthorized: true,
+		PrimaryContact:   true,
+	}
+	_, err = tx.Exec(
+		"INSERT INTO student_guardians (student_id, guardian_id, relationship, pickup_authorized, primary_contact) VALUES (?, ?, ?, ?, ?)",
+		link.StudentID, link.GuardianID, link.Relationship, link.PickupAuthorized, link.PrimaryContact,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error linking guardian")
+	}
+	err = RecordAudit(tx, r, AuditCreate, "student_guardians", studentId, nil, link)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// To add documents to an application, as multipart/form-data with the files in "files"
+func AddApplicationDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return
+	}
+	addApplicationDocuments(w, r, id, false)
+}
+
+// To store uploaded documents against an application. Applicants can only add them
+// while the application is still open.
+func addApplicationDocuments(w http.ResponseWriter, r *http.Request, id int, applicant bool) {
+	err := parseUpload(w, r)
+	if errors.Is(err, storage.ErrTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	application, status, err := loadApplication(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if applicant && applicationTransitions[application.Status] == nil {
+		http.Error(w, "❌ Documents can't be added once the application is "+application.Status, http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	attachments, status, err := saveAttachments(tx, r, applicationAttachments, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		removeStoredFiles(attachments)
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Attachment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(attachments),
+		Data:   attachments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteOneApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	application, status, err := loadApplication(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, application.ID, application.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE applications SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, application.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete application")
+		http.Error(w, "❌ Unable delete application", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "applications", id, application, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Application successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "applications", "Application")
+}
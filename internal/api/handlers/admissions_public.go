@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/storage"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// The endpoints in this file are open to the public, so applicants never see the school's
+// notes: they get a receipt and follow their application with the token it carries.
+
+// To build the receipt an applicant sees for their application
+func applicationReceipt(db Queryer, application models.Application) (models.ApplicationReceipt, error) {
+	receipt := models.ApplicationReceipt{
+		ID:          application.ID,
+		FirstName:   application.FirstName,
+		LastName:    application.LastName,
+		Status:      application.Status,
+		SubmittedAt: application.SubmittedAt,
+	}
+
+	if application.Status == "offered" || application.Status == "accepted" {
+		receipt.OfferExpiresOn = application.OfferExpiresOn
+		err := db.QueryRow("SELECT name FROM classes WHERE id = ?", application.OfferedClassID).Scan(&receipt.OfferedClass)
+		if err != nil && err != sql.ErrNoRows {
+			return receipt, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		}
+	}
+
+	err := db.QueryRow("SELECT COUNT(*) FROM attachments WHERE owner_type = ? AND owner_id = ?", applicationAttachments, application.ID).Scan(&receipt.Documents)
+	if err != nil {
+		return receipt, utils.ErrorHandler(err, "❌ Unable to count documents")
+	}
+	return receipt, nil
+}
+
+// To read the id of an application and check the token handed out with its receipt
+func applicantApplicationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid application id", http.StatusBadRequest)
+		return 0, false
+	}
+	if !utils.VerifyFeedToken(applicationTokenFeed, id, r.URL.Query().Get("token")) {
+		http.Error(w, "❌ Invalid or missing token", http.StatusForbidden)
+		return 0, false
+	}
+	return id, true
+}
+
+// To apply for a place, as JSON or as multipart/form-data with the application as JSON in
+// "application" and documents such as birth certificates in "files"
+func SubmitApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var application models.Application
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := parseUpload(w, r)
+		if errors.Is(err, storage.ErrTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		err = json.Unmarshal([]byte(r.FormValue("application")), &application)
+		if err != nil {
+			http.Error(w, "❌ Invalid application, send it as JSON in the application field", http.StatusBadRequest)
+			return
+		}
+	} else {
+		err := json.NewDecoder(r.Body).Decode(&application)
+		if err != nil {
+			http.Error(w, "❌ Invalid request body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	err := validateApplication(&application)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Whatever the applicant sent, a new application starts at the beginning of the pipeline
+	res, err := tx.Exec(
+		`INSERT INTO applications (first_name, last_name, date_of_birth, email, grade_level, academic_year, previous_school, notes,
+		guardian_first_name, guardian_last_name, guardian_phone, guardian_email, relationship, status, decision_note, submitted_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'submitted', '', NOW(), 1)`,
+		application.FirstName,
+		application.LastName,
+		application.DateOfBirth,
+		application.Email,
+		application.GradeLevel,
+		application.AcademicYear,
+		application.PreviousSchool,
+		application.Notes,
+		application.GuardianFirstName,
+		application.GuardianLastName,
+		application.GuardianPhone,
+		application.GuardianEmail,
+		application.Relationship,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error saving application")
+		http.Error(w, "❌ Error saving application", http.StatusInternalServerError)
+		return
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+		return
+	}
+
+	saved, status, err := loadApplication(tx, int(lastID))
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditCreate, "applications", saved.ID, nil, saved)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	attachments, status, err := saveAttachments(tx, r, applicationAttachments, saved.ID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		removeStoredFiles(attachments)
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	receipt := models.ApplicationReceipt{
+		ID:          saved.ID,
+		Token:       utils.FeedToken(applicationTokenFeed, saved.ID),
+		FirstName:   saved.FirstName,
+		LastName:    saved.LastName,
+		Status:      saved.Status,
+		SubmittedAt: saved.SubmittedAt,
+		Documents:   len(attachments),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// To let an applicant see where their application is, with ?token= from their receipt
+func GetApplicationReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := applicantApplicationID(w, r)
+	if !ok {
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	application, status, err := loadApplication(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	receipt, err := applicationReceipt(db, application)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// To let an applicant send documents they didn't have when they applied
+func AddApplicantDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := applicantApplicationID(w, r)
+	if !ok {
+		return
+	}
+	addApplicationDocuments(w, r, id, true)
+}
+
+// To let an applicant accept or decline an offer, {"accept": true} or {"accept": false}
+func RespondToOfferHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := applicantApplicationID(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Accept *bool `json:"accept"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil || request.Accept == nil {
+		http.Error(w, "❌ Invalid request body, accept is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	existing, status, err := loadApplication(tx, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	updated := existing
+	status, err = answerOffer(&updated, *request.Accept)
+	if err == nil {
+		status, err = moveApplication(tx, r, &existing, &updated)
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	receipt, err := applicationReceipt(tx, updated)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
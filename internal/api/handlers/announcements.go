@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const announcementColumns = "id, title, body, audience, COALESCE(class_id, 0), role, author_id, publish_at, COALESCE(expires_at, ''), send_email, COALESCE(emailed_at, ''), version"
+
+// Announcements that have been published and have not expired
+const liveAnnouncementCondition = " AND publish_at <= NOW() AND (expires_at IS NULL OR expires_at > NOW())"
+
+var announcementAudiences = map[string]bool{
+	"school": true,
+	"class":  true,
+	"role":   true,
+}
+
+var announcementFilterFields = map[string]string{
+	"audience":  "audience",
+	"class_id":  "class_id",
+	"role":      "role",
+	"author_id": "author_id",
+}
+
+var announcementSortFields = map[string]bool{
+	"title":      true,
+	"publish_at": true,
+	"expires_at": true,
+}
+
+func scanAnnouncement(row interface{ Scan(...interface{}) error }, announcement *models.Announcement) error {
+	return row.Scan(
+		&announcement.ID,
+		&announcement.Title,
+		&announcement.Body,
+		&announcement.Audience,
+		&announcement.ClassID,
+		&announcement.Role,
+		&announcement.AuthorID,
+		&announcement.PublishAt,
+		&announcement.ExpiresAt,
+		&announcement.SendEmail,
+		&announcement.EmailedAt,
+		&announcement.Version,
+	)
+}
+
+// To read a moment given with or without a time. A date alone means the start of that day.
+func parseDateTime(value string) (time.Time, error) {
+	for _, layout := range []string{DateTimeLayout, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", DateLayout} {
+		if moment, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return moment, nil
+		}
+	}
+	return time.Time{}, errors.New("❌ Times must look like 2025-09-01 or 2025-09-01 08:00")
+}
+
+// To check an announcement before it is written and fill in its defaults.
+// It returns the HTTP status to answer with when invalid.
+func validateAnnouncement(db Queryer, announcement *models.Announcement) (int, error) {
+	announcement.Title = strings.TrimSpace(announcement.Title)
+	announcement.Body = strings.TrimSpace(announcement.Body)
+	if announcement.Title == "" || announcement.Body == "" {
+		return http.StatusBadRequest, errors.New("❌ title and body are required")
+	}
+
+	if announcement.Audience == "" {
+		announcement.Audience = "school"
+	}
+	if !announcementAudiences[announcement.Audience] {
+		return http.StatusBadRequest, errors.New("❌ audience must be school, class or role")
+	}
+
+	switch announcement.Audience {
+	case "school":
+		announcement.ClassID = 0
+		announcement.Role = ""
+	case "class":
+		announcement.Role = ""
+		if announcement.ClassID == 0 {
+			return http.StatusBadRequest, errors.New("❌ class_id is required for a class announcement")
+		}
+		var exists int
+		err := db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ? AND deleted_at IS NULL", announcement.ClassID).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Class %d does not exist", announcement.ClassID)
+		}
+	case "role":
+		announcement.ClassID = 0
+		announcement.Role = strings.TrimSpace(announcement.Role)
+		if announcement.Role == "" {
+			return http.StatusBadRequest, errors.New("❌ role is required for a role announcement")
+		}
+	}
+
+	publishAt := time.Now()
+	if announcement.PublishAt != "" {
+		var err error
+		publishAt, err = parseDateTime(announcement.PublishAt)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	announcement.PublishAt = publishAt.Format(DateTimeLayout)
+
+	if announcement.ExpiresAt != "" {
+		expiresAt, err := parseDateTime(announcement.ExpiresAt)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if !expiresAt.After(publishAt) {
+			return http.StatusBadRequest, errors.New("❌ expires_at must be after publish_at")
+		}
+		announcement.ExpiresAt = expiresAt.Format(DateTimeLayout)
+	}
+
+	return http.StatusOK, nil
+}
+
+// To bring the inbox copies of an announcement in line with it. Exec users are the only ones
+// with an inbox, so school announcements go to all of them, role announcements to those with
+// the role, and class announcements to none (they are read per class and emailed instead).
+func deliverAnnouncement(tx *sql.Tx, announcement models.Announcement) error {
+	recipients := "SELECT id FROM execs WHERE deleted_at IS NULL AND inactive_status = FALSE"
+	var recipientArgs []interface{}
+	switch announcement.Audience {
+	case "role":
+		recipients += " AND role = ?"
+		recipientArgs = append(recipientArgs, announcement.Role)
+	case "class":
+		recipients += " AND 1 = 0"
+	}
+
+	args := append([]interface{}{announcement.ID}, recipientArgs...)
+	_, err := tx.Exec("DELETE FROM notifications WHERE announcement_id = ? AND user_id NOT IN ("+recipients+")", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to update notifications")
+	}
+
+	expiresAt := sql.NullString{String: announcement.ExpiresAt, Valid: announcement.ExpiresAt != ""}
+	_, err = tx.Exec(
+		"UPDATE notifications SET title = ?, body = ?, visible_from = ?, expires_at = ? WHERE announcement_id = ?",
+		announcement.Title, announcement.Body, announcement.PublishAt, expiresAt, announcement.ID,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to update notifications")
+	}
+
+	args = append([]interface{}{announcement.Title, announcement.Body, announcement.ID, announcement.PublishAt, expiresAt}, recipientArgs...)
+	_, err = tx.Exec(
+		"INSERT IGNORE INTO notifications (user_id, kind, title, body, announcement_id, visible_from, expires_at) SELECT id, 'announcement', ?, ?, ?, ?, ? FROM ("+recipients+") recipients",
+		args...,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to deliver announcement")
+	}
+	return nil
+}
+
+// To write an announcement and deliver it to inboxes in one transaction
+func saveAnnouncement(db *sql.DB, r *http.Request, announcement *models.Announcement, previous *models.Announcement) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error starting transaction")
+	}
+
+	expiresAt := sql.NullString{String: announcement.ExpiresAt, Valid: announcement.ExpiresAt != ""}
+	if previous == nil {
+		announcement.Version = 1
+		announcement.AuthorID = ActorID(r)
+		res, err := tx.Exec(
+			"INSERT INTO announcements (title, body, audience, class_id, role, author_id, publish_at, expires_at, send_email, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			announcement.Title, announcement.Body, announcement.Audience, nullableID(announcement.ClassID), announcement.Role,
+			announcement.AuthorID, announcement.PublishAt, expiresAt, announcement.SendEmail, announcement.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error inserting data into database")
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		announcement.ID = int(lastID)
+	} else {
+		result, err := tx.Exec(
+			"UPDATE announcements SET title = ?, body = ?, audience = ?, class_id = ?, role = ?, publish_at = ?, expires_at = ?, send_email = ?, version = version + 1 WHERE id = ? AND version = ?",
+			announcement.Title, announcement.Body, announcement.Audience, nullableID(announcement.ClassID), announcement.Role,
+			announcement.PublishAt, expiresAt, announcement.SendEmail, announcement.ID, previous.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error updating announcement")
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err == nil && rowsAffected == 0 {
+			tx.Rollback()
+			return http.StatusPreconditionFailed, errors.New("❌ Resource has been modified by someone else, reload and try again")
+		}
+		announcement.Version = previous.Version + 1
+	}
+
+	err = deliverAnnouncement(tx, *announcement)
+	if err != nil {
+		tx.Rollback()
+		return http.StatusInternalServerError, err
+	}
+
+	action := AuditCreate
+	var before interface{}
+	if previous != nil {
+		action = AuditUpdate
+		before = *previous
+	}
+	err = RecordAudit(tx, r, action, "announcements", announcement.ID, before, *announcement)
+	if err != nil {
+		tx.Rollback()
+		return http.StatusInternalServerError, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error committing transaction")
+	}
+	return http.StatusOK, nil
+}
+
+// To list announcements matching a condition
+func listAnnouncements(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + announcementColumns + " FROM announcements WHERE 1=1" + condition
+	args := conditionArgs
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, announcementFilterFields)
+	if sorted := utils.AddSortingFor(r, query, announcementSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY publish_at DESC, id DESC"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	announcementList := make([]models.Announcement, 0)
+	for rows.Next() {
+		var announcement models.Announcement
+		err := scanAnnouncement(rows, &announcement)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		announcementList = append(announcementList, announcement)
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.Announcement `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(announcementList),
+		Data:   announcementList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get announcements, including scheduled and expired ones unless ?active=true
+func GetAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	condition := ""
+	if r.URL.Query().Get("active") == "true" {
+		condition = liveAnnouncementCondition
+	}
+	listAnnouncements(w, r, condition)
+}
+
+// To get the live announcements a class should see: its own and the school wide ones
+func GetAnnouncementsForAClass(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+	listAnnouncements(w, r, liveAnnouncementCondition+" AND (audience = 'school' OR (audience = 'class' AND class_id = ?))", classId)
+}
+
+func GetOneAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var announcement models.Announcement
+	err = scanAnnouncement(db.QueryRow("SELECT "+announcementColumns+" FROM announcements WHERE id = ? AND deleted_at IS NULL", id), &announcement)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Announcement not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(announcement.ID, announcement.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(announcement)
+}
+
+// To post announcements. Without publish_at they go out straight away.
+func AddAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	var newAnnouncements []models.Announcement
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newAnnouncements)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newAnnouncements {
+		newAnnouncements[i].EmailedAt = ""
+		status, err := validateAnnouncement(db, &newAnnouncements[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	for i := range newAnnouncements {
+		status, err := saveAnnouncement(db, r, &newAnnouncements[i], nil)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.Announcement `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(newAnnouncements),
+		Data:   newAnnouncements,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update an announcement. Inbox copies follow the change; an email already sent is not sent again.
+func EditAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingAnnouncement models.Announcement
+	err = scanAnnouncement(db.QueryRow("SELECT "+announcementColumns+" FROM announcements WHERE id = ? AND deleted_at IS NULL", id), &existingAnnouncement)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Announcement not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingAnnouncement.ID, existingAnnouncement.Version) {
+		return
+	}
+
+	previousAnnouncement := existingAnnouncement
+
+	err = ApplyPatch(&existingAnnouncement, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingAnnouncement.AuthorID = previousAnnouncement.AuthorID
+	existingAnnouncement.EmailedAt = previousAnnouncement.EmailedAt
+
+	status, err := validateAnnouncement(db, &existingAnnouncement)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	status, err = saveAnnouncement(db, r, &existingAnnouncement, &previousAnnouncement)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingAnnouncement.ID, existingAnnouncement.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingAnnouncement)
+}
+
+func DeleteOneAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid announcement id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedAnnouncement models.Announcement
+	err = scanAnnouncement(db.QueryRow("SELECT "+announcementColumns+" FROM announcements WHERE id = ? AND deleted_at IS NULL", id), &deletedAnnouncement)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Announcement not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedAnnouncement.ID, deletedAnnouncement.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE announcements SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedAnnouncement.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete announcement")
+		http.Error(w, "❌ Unable delete announcement", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "announcements", id, deletedAnnouncement, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Announcement successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "announcements", "Announcement")
+}
@@ -0,0 +1,676 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const (
+	AssessmentQuiz     = "quiz"
+	AssessmentExam     = "exam"
+	AssessmentHomework = "homework"
+)
+
+var assessmentTypes = map[string]bool{
+	AssessmentQuiz:     true,
+	AssessmentExam:     true,
+	AssessmentHomework: true,
+}
+
+const assessmentColumns = "id, subject_id, class_id, term_id, name, type, weight, max_score, COALESCE(due_date, ''), version"
+
+var assessmentFilterFields = map[string]string{
+	"subject_id": "subject_id",
+	"class_id":   "class_id",
+	"term_id":    "term_id",
+	"type":       "type",
+}
+
+var assessmentSortFields = map[string]bool{
+	"name":      true,
+	"type":      true,
+	"weight":    true,
+	"max_score": true,
+	"due_date":  true,
+}
+
+func scanAssessment(row interface{ Scan(...interface{}) error }, assessment *models.Assessment) error {
+	return row.Scan(
+		&assessment.ID,
+		&assessment.SubjectID,
+		&assessment.ClassID,
+		&assessment.TermID,
+		&assessment.Name,
+		&assessment.Type,
+		&assessment.Weight,
+		&assessment.MaxScore,
+		&assessment.DueDate,
+		&assessment.Version,
+	)
+}
+
+// nullableDate stores a blank date as NULL
+func nullableDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+// To check an assessment before it is written. It returns the HTTP status to answer with when invalid.
+func validateAssessment(db Queryer, assessment models.Assessment) (int, error) {
+	if assessment.SubjectID == 0 || assessment.ClassID == 0 || assessment.TermID == 0 || assessment.Name == "" {
+		return http.StatusBadRequest, errors.New("❌ subject_id, class_id, term_id, name, type and max_score are required")
+	}
+	if !assessmentTypes[assessment.Type] {
+		return http.StatusBadRequest, errors.New("❌ type must be quiz, exam or homework")
+	}
+	if assessment.MaxScore <= 0 {
+		return http.StatusBadRequest, errors.New("❌ max_score must be greater than zero")
+	}
+	if assessment.Weight < 0 {
+		return http.StatusBadRequest, errors.New("❌ weight cannot be negative")
+	}
+	if assessment.DueDate != "" {
+		if _, err := time.Parse(DateLayout, assessment.DueDate); err != nil {
+			return http.StatusBadRequest, errors.New("❌ due_date must be a date like 2025-09-01")
+		}
+	}
+
+	for table, id := range map[string]int{"subjects": assessment.SubjectID, "classes": assessment.ClassID, "terms": assessment.TermID} {
+		var exists int
+		err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ? AND deleted_at IS NULL", id).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check assessment references")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ %d does not exist in %s", id, table)
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// To get multiple assessments
+func GetAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + assessmentColumns + " FROM assessments WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, assessmentFilterFields)
+	query = utils.AddSortingFor(r, query, assessmentSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	assessmentList := make([]models.Assessment, 0)
+	for rows.Next() {
+		var assessment models.Assessment
+		err := scanAssessment(rows, &assessment)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		assessmentList = append(assessmentList, assessment)
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assessmentList),
+		Data:   assessmentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single assessment
+func GetOneAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assessment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var assessment models.Assessment
+	err = scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ? AND deleted_at IS NULL", id), &assessment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assessment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(assessment.ID, assessment.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessment)
+}
+
+// insertAssessment writes a new assessment inside the caller's transaction and audits it
+func insertAssessment(tx *sql.Tx, r *http.Request, assessment models.Assessment) (models.Assessment, error) {
+	assessment.Version = 1
+	res, err := tx.Exec(
+		"INSERT INTO assessments (subject_id, class_id, term_id, name, type, weight, max_score, due_date, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		assessment.SubjectID,
+		assessment.ClassID,
+		assessment.TermID,
+		assessment.Name,
+		assessment.Type,
+		assessment.Weight,
+		assessment.MaxScore,
+		nullableDate(assessment.DueDate),
+		assessment.Version,
+	)
+	if err != nil {
+		return assessment, utils.ErrorHandler(err, "❌ Error inserting data into database")
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return assessment, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+	}
+	assessment.ID = int(lastID)
+
+	return assessment, RecordAudit(tx, r, AuditCreate, "assessments", assessment.ID, nil, assessment)
+}
+
+// To add assessments to the DB
+func AddAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	var newAssessments []models.Assessment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newAssessments)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newAssessments {
+		// Weight defaults to 1 so unweighted assessments count equally
+		if newAssessments[i].Weight == 0 {
+			newAssessments[i].Weight = 1
+		}
+		status, err := validateAssessment(db, newAssessments[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedAssessments := make([]models.Assessment, len(newAssessments))
+	for i, newAssessment := range newAssessments {
+		addedAssessments[i], err = insertAssessment(tx, r, newAssessment)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedAssessments),
+		Data:   addedAssessments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of an assessment
+func EditAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assessment id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingAssessment models.Assessment
+	err = scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ? AND deleted_at IS NULL", id), &existingAssessment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assessment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingAssessment.ID, existingAssessment.Version) {
+		return
+	}
+
+	previousAssessment := existingAssessment
+	err = ApplyPatch(&existingAssessment, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := validateAssessment(db, existingAssessment)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Lowering the max score must not leave existing scores above it
+	if existingAssessment.MaxScore < previousAssessment.MaxScore {
+		var over int
+		err = db.QueryRow("SELECT COUNT(*) FROM scores WHERE assessment_id = ? AND score > ?", id, existingAssessment.MaxScore).Scan(&over)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to check scores")
+			http.Error(w, "❌ Unable to check scores", http.StatusInternalServerError)
+			return
+		}
+		if over > 0 {
+			http.Error(w, fmt.Sprintf("❌ %d scores are above the new max_score", over), http.StatusConflict)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE assessments SET subject_id = ?, class_id = ?, term_id = ?, name = ?, type = ?, weight = ?, max_score = ?, due_date = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingAssessment.SubjectID,
+		existingAssessment.ClassID,
+		existingAssessment.TermID,
+		existingAssessment.Name,
+		existingAssessment.Type,
+		existingAssessment.Weight,
+		existingAssessment.MaxScore,
+		nullableDate(existingAssessment.DueDate),
+		existingAssessment.ID,
+		previousAssessment.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating assessment")
+		http.Error(w, "❌ Error updating assessment", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingAssessment.Version = previousAssessment.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "assessments", existingAssessment.ID, previousAssessment, existingAssessment)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingAssessment.ID, existingAssessment.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingAssessment)
+}
+
+func DeleteOneAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assessment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedAssessment models.Assessment
+	err = scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ? AND deleted_at IS NULL", id), &deletedAssessment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assessment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedAssessment.ID, deletedAssessment.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE assessments SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedAssessment.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete assessment")
+		http.Error(w, "❌ Unable delete assessment", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "assessments", id, deletedAssessment, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Assessment successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "assessments", "Assessment")
+}
+
+// To get the scores entered for an assessment
+func GetScoresForAnAssessment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assessment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, assessment_id, student_id, score, comment, version FROM scores WHERE assessment_id = ? ORDER BY student_id", id)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scoreList := make([]models.Score, 0)
+	for rows.Next() {
+		var score models.Score
+		err := rows.Scan(&score.ID, &score.AssessmentID, &score.StudentID, &score.Score, &score.Comment, &score.Version)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		scoreList = append(scoreList, score)
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Score `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scoreList),
+		Data:   scoreList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// SaveScore records a student's score for an assessment, replacing any earlier score.
+// The student has to be in the assessment's class, now or through an enrollment for its term.
+func SaveScore(tx *sql.Tx, r *http.Request, assessment models.Assessment, score models.Score) (models.Score, int, error) {
+	if score.Score < 0 || score.Score > assessment.MaxScore {
+		return score, http.StatusBadRequest, fmt.Errorf("❌ Score for student %d must be between 0 and %g", score.StudentID, assessment.MaxScore)
+	}
+
+	var inClass int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL AND (class_id = ? OR id IN (SELECT student_id FROM enrollments WHERE class_id = ? AND term_id = ?))",
+		score.StudentID, assessment.ClassID, assessment.ClassID, assessment.TermID,
+	).Scan(&inClass)
+	if err != nil {
+		return score, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve student")
+	}
+	if inClass == 0 {
+		return score, http.StatusBadRequest, fmt.Errorf("❌ Student %d is not in class %d", score.StudentID, assessment.ClassID)
+	}
+
+	score.AssessmentID = assessment.ID
+
+	var existing models.Score
+	err = tx.QueryRow(
+		"SELECT id, assessment_id, student_id, score, comment, version FROM scores WHERE assessment_id = ? AND student_id = ? FOR UPDATE",
+		score.AssessmentID, score.StudentID,
+	).Scan(&existing.ID, &existing.AssessmentID, &existing.StudentID, &existing.Score, &existing.Comment, &existing.Version)
+
+	if err == sql.ErrNoRows {
+		score.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO scores (assessment_id, student_id, score, comment, version) VALUES (?, ?, ?, ?, ?)",
+			score.AssessmentID, score.StudentID, score.Score, score.Comment, score.Version,
+		)
+		if err != nil {
+			return score, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error inserting data into database")
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return score, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		score.ID = int(lastID)
+		err = RecordAudit(tx, r, AuditCreate, "scores", score.ID, nil, score)
+		if err != nil {
+			return score, http.StatusInternalServerError, err
+		}
+		return score, http.StatusOK, nil
+	} else if err != nil {
+		return score, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve score")
+	}
+
+	score.ID = existing.ID
+	score.Version = existing.Version + 1
+	_, err = tx.Exec("UPDATE scores SET score = ?, comment = ?, version = version + 1 WHERE id = ?", score.Score, score.Comment, score.ID)
+	if err != nil {
+		return score, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error updating score")
+	}
+	err = RecordAudit(tx, r, AuditUpdate, "scores", score.ID, existing, score)
+	if err != nil {
+		return score, http.StatusInternalServerError, err
+	}
+	return score, http.StatusOK, nil
+}
+
+// To enter the scores of many students for one assessment in a single request
+func SaveScoresHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assessment id", http.StatusBadRequest)
+		return
+	}
+
+	var newScores []models.Score
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&newScores)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var assessment models.Assessment
+	err = scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ? AND deleted_at IS NULL", id), &assessment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assessment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	savedScores := make([]models.Score, len(newScores))
+	for i, score := range newScores {
+		var status int
+		savedScores[i], status, err = SaveScore(tx, r, assessment, score)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Score `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(savedScores),
+		Data:   savedScores,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/storage"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const attachmentColumns = "id, owner_type, owner_id, file_name, content_type, size, storage_path, uploaded_by, created_at"
+
+// The most files one request may carry
+const maxFilesPerUpload = 5
+
+func scanAttachment(row interface{ Scan(...interface{}) error }, attachment *models.Attachment) error {
+	return row.Scan(
+		&attachment.ID,
+		&attachment.OwnerType,
+		&attachment.OwnerID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StoragePath,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+}
+
+// To read a multipart request whose files are sent in the "files" field.
+// The body is capped so a huge upload is cut off before it fills the disk.
+func parseUpload(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFilesPerUpload*storage.MaxSize()+1<<20)
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return storage.ErrTooLarge
+		}
+		return errors.New("❌ Expected a multipart/form-data body")
+	}
+	if len(r.MultipartForm.File["files"]) > maxFilesPerUpload {
+		return errors.New("❌ Too many files, send at most " + strconv.Itoa(maxFilesPerUpload) + " at a time")
+	}
+	return nil
+}
+
+// To store the files of a parsed upload and record them against their owner.
+// Files written before a failure are removed again.
+func saveAttachments(tx *sql.Tx, r *http.Request, ownerType string, ownerId int) ([]models.Attachment, int, error) {
+	attachments := make([]models.Attachment, 0)
+	if r.MultipartForm == nil {
+		return attachments, http.StatusOK, nil
+	}
+
+	fail := func(status int, err error) ([]models.Attachment, int, error) {
+		removeStoredFiles(attachments)
+		return nil, status, err
+	}
+
+	for _, header := range r.MultipartForm.File["files"] {
+		file, err := header.Open()
+		if err != nil {
+			return fail(http.StatusBadRequest, utils.ErrorHandler(err, "❌ Unable to read uploaded file"))
+		}
+		stored, err := storage.Save(ownerType, header.Filename, file)
+		file.Close()
+		if errors.Is(err, storage.ErrTooLarge) {
+			return fail(http.StatusRequestEntityTooLarge, err)
+		} else if errors.Is(err, storage.ErrUnsupportedType) {
+			return fail(http.StatusUnsupportedMediaType, err)
+		} else if err != nil {
+			return fail(http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to store uploaded file"))
+		}
+
+		attachment := models.Attachment{
+			OwnerType:   ownerType,
+			OwnerID:     ownerId,
+			FileName:    header.Filename,
+			ContentType: stored.ContentType,
+			Size:        stored.Size,
+			StoragePath: stored.Path,
+			UploadedBy:  ActorID(r),
+		}
+		attachments = append(attachments, attachment)
+
+		res, err := tx.Exec(
+			"INSERT INTO attachments (owner_type, owner_id, file_name, content_type, size, storage_path, uploaded_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			attachment.OwnerType, attachment.OwnerID, attachment.FileName, attachment.ContentType, attachment.Size, attachment.StoragePath, attachment.UploadedBy,
+		)
+		if err != nil {
+			return fail(http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error saving attachment"))
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return fail(http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error getting last insert ID"))
+		}
+		attachments[len(attachments)-1].ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "attachments", int(lastID), nil, attachments[len(attachments)-1])
+		if err != nil {
+			return fail(http.StatusInternalServerError, err)
+		}
+	}
+	return attachments, http.StatusOK, nil
+}
+
+// To delete the files of attachments whose rows were never committed
+func removeStoredFiles(attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		storage.Remove(attachment.StoragePath)
+	}
+}
+
+// To load the attachments of several owners of one type with one query
+func loadAttachments(db *sql.DB, ownerType string, ownerIds []int) (map[int][]models.Attachment, error) {
+	byOwner := map[int][]models.Attachment{}
+	if len(ownerIds) == 0 {
+		return byOwner, nil
+	}
+
+	placeholders := make([]string, len(ownerIds))
+	args := []interface{}{ownerType}
+	for i, id := range ownerIds {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	rows, err := db.Query("SELECT "+attachmentColumns+" FROM attachments WHERE owner_type = ? AND owner_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY id", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to retrieve attachments")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attachment models.Attachment
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve attachments")
+		}
+		byOwner[attachment.OwnerID] = append(byOwner[attachment.OwnerID], attachment)
+	}
+	return byOwner, nil
+}
+
+// To download an attachment
+func GetAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid attachment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var attachment models.Attachment
+	err = scanAttachment(db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id), &attachment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Attachment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	file, err := storage.Open(attachment.StoragePath)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Attachment file is missing")
+		http.Error(w, "❌ Attachment file is missing", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	fileName := strings.NewReplacer(`"`, "", "\r", "", "\n", "").Replace(attachment.FileName)
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, file)
+}
+
+// To remove an attachment and its file
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid attachment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var attachment models.Attachment
+	err = scanAttachment(db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id), &attachment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Attachment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM attachments WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete attachment")
+		http.Error(w, "❌ Unable delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "attachments", id, attachment, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// The file only goes once the row is gone for good
+	err = storage.Remove(attachment.StoragePath)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to remove attachment file")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Attachment successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
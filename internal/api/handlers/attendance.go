@@ -0,0 +1,853 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+var attendanceStatuses = map[string]bool{
+	AttendancePresent: true,
+	AttendanceAbsent:  true,
+	AttendanceLate:    true,
+	AttendanceExcused: true,
+}
+
+const attendanceColumns = "id, student_id, class_id, term_id, session_date, period, status, note, COALESCE(recorded_by, 0), version"
+
+var attendanceFilterFields = map[string]string{
+	"student_id": "student_id",
+	"class_id":   "class_id",
+	"term_id":    "term_id",
+	"date":       "session_date",
+	"period":     "period",
+	"status":     "status",
+}
+
+var attendanceSortFields = map[string]bool{
+	"session_date": true,
+	"period":       true,
+	"student_id":   true,
+	"class_id":     true,
+	"status":       true,
+}
+
+// The share of sessions a student may miss before being flagged, overridable with ABSENCE_THRESHOLD_RATE
+const defaultAbsenceThresholdRate = 0.1
+
+// Counts per status for the summaries; late arrivals count as attended in the rate
+const attendanceCounts = `COALESCE(SUM(status = 'present'), 0), COALESCE(SUM(status = 'absent'), 0), COALESCE(SUM(status = 'late'), 0), COALESCE(SUM(status = 'excused'), 0), COUNT(*)`
+
+func scanAttendance(row interface{ Scan(...interface{}) error }, record *models.AttendanceRecord) error {
+	return row.Scan(
+		&record.ID,
+		&record.StudentID,
+		&record.ClassID,
+		&record.TermID,
+		&record.Date,
+		&record.Period,
+		&record.Status,
+		&record.Note,
+		&record.RecordedBy,
+		&record.Version,
+	)
+}
+
+func scanAttendanceSummary(row interface{ Scan(...interface{}) error }, summary *models.AttendanceSummary, leading ...interface{}) error {
+	targets := append(leading, &summary.Present, &summary.Absent, &summary.Late, &summary.Excused, &summary.Total)
+	if err := row.Scan(targets...); err != nil {
+		return err
+	}
+	if summary.Total > 0 {
+		summary.AttendanceRate = float64(summary.Present+summary.Late) / float64(summary.Total)
+	}
+	return nil
+}
+
+// To check an attendance record and fill in its class and term.
+// It returns the HTTP status to answer with when invalid.
+func prepareAttendance(db Queryer, record *models.AttendanceRecord) (int, error) {
+	if record.StudentID == 0 || record.Date == "" {
+		return http.StatusBadRequest, errors.New("❌ student_id, date and status are required")
+	}
+	if !attendanceStatuses[record.Status] {
+		return http.StatusBadRequest, errors.New("❌ status must be present, absent, late or excused")
+	}
+	if _, err := time.Parse(DateLayout, record.Date); err != nil {
+		return http.StatusBadRequest, errors.New("❌ date must be a date like 2025-09-01")
+	}
+	if record.Period < 0 {
+		return http.StatusBadRequest, errors.New("❌ period cannot be negative")
+	}
+
+	// The student's current class is used when the session doesn't name one
+	var studentClassId int
+	err := db.QueryRow("SELECT COALESCE(class_id, 0) FROM students WHERE id = ? AND deleted_at IS NULL", record.StudentID).Scan(&studentClassId)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest, fmt.Errorf("❌ Student %d does not exist", record.StudentID)
+	} else if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve student")
+	}
+	if record.ClassID == 0 {
+		record.ClassID = studentClassId
+	}
+	if record.ClassID == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Student %d is not in a class", record.StudentID)
+	}
+
+	term, err := CurrentTerm(db, record.Date)
+	if err == ErrNoCurrentTerm {
+		return http.StatusBadRequest, fmt.Errorf("❌ No term covers %s", record.Date)
+	} else if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	record.TermID = term.ID
+
+	holiday, holidayName, err := IsHoliday(db, record.Date)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if holiday {
+		return http.StatusConflict, fmt.Errorf("❌ %s is a holiday (%s)", record.Date, holidayName)
+	}
+
+	return http.StatusOK, nil
+}
+
+// To write an attendance record, replacing the mark already given for the same session
+func saveAttendance(tx *sql.Tx, r *http.Request, record models.AttendanceRecord) (models.AttendanceRecord, error) {
+	record.RecordedBy = ActorID(r)
+
+	var existing models.AttendanceRecord
+	err := scanAttendance(tx.QueryRow(
+		"SELECT "+attendanceColumns+" FROM attendance_records WHERE student_id = ? AND class_id = ? AND session_date = ? AND period = ? FOR UPDATE",
+		record.StudentID, record.ClassID, record.Date, record.Period,
+	), &existing)
+
+	if err == sql.ErrNoRows {
+		record.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO attendance_records (student_id, class_id, term_id, session_date, period, status, note, recorded_by, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			record.StudentID, record.ClassID, record.TermID, record.Date, record.Period, record.Status, record.Note, nullableID(record.RecordedBy), record.Version,
+		)
+		if err != nil {
+			return record, utils.ErrorHandler(err, "❌ Error inserting data into database")
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return record, utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		record.ID = int(lastID)
+		return record, RecordAudit(tx, r, AuditCreate, "attendance_records", record.ID, nil, record)
+	} else if err != nil {
+		return record, utils.ErrorHandler(err, "❌ Unable to retrieve attendance")
+	}
+
+	record.ID = existing.ID
+	record.Version = existing.Version + 1
+	_, err = tx.Exec(
+		"UPDATE attendance_records SET status = ?, note = ?, recorded_by = ?, version = version + 1 WHERE id = ?",
+		record.Status, record.Note, nullableID(record.RecordedBy), record.ID,
+	)
+	if err != nil {
+		return record, utils.ErrorHandler(err, "❌ Error updating attendance")
+	}
+	return record, RecordAudit(tx, r, AuditUpdate, "attendance_records", record.ID, existing, record)
+}
+
+// To get attendance records, filtered by student, class, term, status and date range
+func GetAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + attendanceColumns + " FROM attendance_records WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFiltersFor(r, query, args, attendanceFilterFields)
+
+	// The date range is given as ?from=2025-09-01&to=2025-09-30
+	for param, operator := range map[string]string{"from": ">=", "to": "<="} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, value); err != nil {
+			http.Error(w, "❌ Invalid "+param+" date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		query += " AND session_date " + operator + " ?"
+		args = append(args, value)
+	}
+
+	if r.URL.Query().Get("sortby") == "" {
+		query += " ORDER BY session_date DESC, period, student_id"
+	} else {
+		query = utils.AddSortingFor(r, query, attendanceSortFields)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	recordList := make([]models.AttendanceRecord, 0)
+	for rows.Next() {
+		var record models.AttendanceRecord
+		err := scanAttendance(rows, &record)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		recordList = append(recordList, record)
+	}
+
+	response := struct {
+		Status string                    `json:"status"`
+		Count  int                       `json:"count"`
+		Data   []models.AttendanceRecord `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(recordList),
+		Data:   recordList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To mark attendance for individual students. Marking a session again replaces the earlier mark.
+func AddAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	var newRecords []models.AttendanceRecord
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newRecords)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newRecords {
+		status, err := prepareAttendance(db, &newRecords[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	savedRecords := make([]models.AttendanceRecord, len(newRecords))
+	for i, record := range newRecords {
+		savedRecords[i], err = saveAttendance(tx, r, record)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                    `json:"status"`
+		Count  int                       `json:"count"`
+		Data   []models.AttendanceRecord `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(savedRecords),
+		Data:   savedRecords,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+type classAttendanceRequest struct {
+	ClassID       int    `json:"class_id"`
+	Date          string `json:"date"`
+	Period        int    `json:"period"`
+	DefaultStatus string `json:"default_status"`
+	Records       []struct {
+		StudentID int    `json:"student_id"`
+		Status    string `json:"status"`
+		Note      string `json:"note"`
+	} `json:"records"`
+}
+
+// SubmitClassAttendanceHandler lets a teacher mark a whole class session in one go.
+// Students left out of the records get default_status, so a register where everyone
+// turned up can be sent as {"class_id": 3, "date": "2025-09-01", "default_status": "present"}.
+func SubmitClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	teacherId := r.PathValue("id")
+
+	var request classAttendanceRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if request.ClassID == 0 || request.Date == "" {
+		http.Error(w, "❌ class_id and date are required", http.StatusBadRequest)
+		return
+	}
+	if request.DefaultStatus != "" && !attendanceStatuses[request.DefaultStatus] {
+		http.Error(w, "❌ default_status must be present, absent, late or excused", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	// The register is the teacher's students who are in this class
+	classQuery, classArgs := teacherClassesQuery(r, teacherId)
+	rows, err := db.Query(
+		"SELECT id FROM students WHERE deleted_at IS NULL AND class_id = ? AND class_id IN ("+classQuery+") ORDER BY id",
+		append([]interface{}{request.ClassID}, classArgs...)...,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	var roster []int
+	inRoster := map[int]bool{}
+	for rows.Next() {
+		var studentId int
+		if err := rows.Scan(&studentId); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		roster = append(roster, studentId)
+		inRoster[studentId] = true
+	}
+	rows.Close()
+
+	if len(roster) == 0 {
+		http.Error(w, fmt.Sprintf("❌ Teacher %s does not teach any students in class %d", teacherId, request.ClassID), http.StatusForbidden)
+		return
+	}
+
+	marked := map[int]models.AttendanceRecord{}
+	for _, entry := range request.Records {
+		if !inRoster[entry.StudentID] {
+			http.Error(w, fmt.Sprintf("❌ Student %d is not in this class", entry.StudentID), http.StatusBadRequest)
+			return
+		}
+		marked[entry.StudentID] = models.AttendanceRecord{StudentID: entry.StudentID, Status: entry.Status, Note: entry.Note}
+	}
+
+	var records []models.AttendanceRecord
+	for _, studentId := range roster {
+		record, ok := marked[studentId]
+		if !ok {
+			if request.DefaultStatus == "" {
+				http.Error(w, fmt.Sprintf("❌ Student %d has no mark and no default_status was given", studentId), http.StatusBadRequest)
+				return
+			}
+			record = models.AttendanceRecord{StudentID: studentId, Status: request.DefaultStatus}
+		}
+		record.ClassID = request.ClassID
+		record.Date = request.Date
+		record.Period = request.Period
+
+		status, err := prepareAttendance(db, &record)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		records = append(records, record)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	for i, record := range records {
+		records[i], err = saveAttendance(tx, r, record)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                    `json:"status"`
+		Count  int                       `json:"count"`
+		Data   []models.AttendanceRecord `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(records),
+		Data:   records,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To correct the status or note of a single mark
+func EditAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid attendance id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	for k := range input {
+		if k != "status" && k != "note" {
+			http.Error(w, "❌ Only status and note can be changed, mark the session again to move it", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingRecord models.AttendanceRecord
+	err = scanAttendance(db.QueryRow("SELECT "+attendanceColumns+" FROM attendance_records WHERE id = ?", id), &existingRecord)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Attendance record not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingRecord.ID, existingRecord.Version) {
+		return
+	}
+
+	previousRecord := existingRecord
+	err = ApplyPatch(&existingRecord, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !attendanceStatuses[existingRecord.Status] {
+		http.Error(w, "❌ status must be present, absent, late or excused", http.StatusBadRequest)
+		return
+	}
+	existingRecord.RecordedBy = ActorID(r)
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE attendance_records SET status = ?, note = ?, recorded_by = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingRecord.Status, existingRecord.Note, nullableID(existingRecord.RecordedBy), existingRecord.ID, previousRecord.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating attendance")
+		http.Error(w, "❌ Error updating attendance", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingRecord.Version = previousRecord.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "attendance_records", existingRecord.ID, previousRecord, existingRecord)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingRecord.ID, existingRecord.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingRecord)
+}
+
+// Marks entered by mistake are removed outright; the audit log keeps what they said
+func DeleteAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid attendance id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedRecord models.AttendanceRecord
+	err = scanAttendance(db.QueryRow("SELECT "+attendanceColumns+" FROM attendance_records WHERE id = ?", id), &deletedRecord)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Attendance record not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM attendance_records WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete attendance record")
+		http.Error(w, "❌ Unable delete attendance record", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "attendance_records", id, deletedRecord, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Attendance record successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// To work out which sessions a summary covers: one day with ?date=, a range with ?from=&to=,
+// or a whole term with ?term_id= which defaults to the current term
+func attendancePeriod(db Queryer, r *http.Request) (string, []interface{}, models.AttendanceSummary, error) {
+	params := r.URL.Query()
+	var label models.AttendanceSummary
+
+	if date := params.Get("date"); date != "" {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			return "", nil, label, errors.New("❌ date must be a date like 2025-09-01")
+		}
+		label.Date = date
+		return " AND session_date = ?", []interface{}{date}, label, nil
+	}
+
+	from, to := params.Get("from"), params.Get("to")
+	if from != "" || to != "" {
+		if err := validateDateRange(from, to); err != nil {
+			return "", nil, label, errors.New("❌ from and to must both be dates like 2025-09-01")
+		}
+		return " AND session_date BETWEEN ? AND ?", []interface{}{from, to}, label, nil
+	}
+
+	if termId := params.Get("term_id"); termId != "" {
+		id, err := strconv.Atoi(termId)
+		if err != nil {
+			return "", nil, label, errors.New("❌ Invalid term_id")
+		}
+		label.TermID = id
+	} else {
+		term, err := CurrentTerm(db, "")
+		if err != nil {
+			return "", nil, label, err
+		}
+		label.TermID = term.ID
+	}
+	return " AND term_id = ?", []interface{}{label.TermID}, label, nil
+}
+
+// To get how often a student attended, for a day, a date range or a term
+func GetStudentAttendanceSummary(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	condition, args, summary, err := attendancePeriod(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary.StudentID = id
+
+	err = scanAttendanceSummary(db.QueryRow(
+		"SELECT "+attendanceCounts+" FROM attendance_records WHERE student_id = ?"+condition,
+		append([]interface{}{id}, args...)...,
+	), &summary)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// To get attendance for every student of a class, for a day, a date range or a term
+func GetClassAttendanceSummary(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	condition, args, label, err := attendancePeriod(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(
+		"SELECT student_id, "+attendanceCounts+" FROM attendance_records WHERE class_id = ?"+condition+" GROUP BY student_id ORDER BY student_id",
+		append([]interface{}{id}, args...)...,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	summaries := make([]models.AttendanceSummary, 0)
+	classTotal := label
+	classTotal.ClassID = id
+	for rows.Next() {
+		summary := label
+		summary.ClassID = id
+		err := scanAttendanceSummary(rows, &summary, &summary.StudentID)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		classTotal.Present += summary.Present
+		classTotal.Absent += summary.Absent
+		classTotal.Late += summary.Late
+		classTotal.Excused += summary.Excused
+		classTotal.Total += summary.Total
+		summaries = append(summaries, summary)
+	}
+	if classTotal.Total > 0 {
+		classTotal.AttendanceRate = float64(classTotal.Present+classTotal.Late) / float64(classTotal.Total)
+	}
+
+	response := struct {
+		Status string                     `json:"status"`
+		Class  models.AttendanceSummary   `json:"class"`
+		Count  int                        `json:"count"`
+		Data   []models.AttendanceSummary `json:"data"`
+	}{
+		Status: "success",
+		Class:  classTotal,
+		Count:  len(summaries),
+		Data:   summaries,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// GetAbsenceFlagsHandler lists students whose unexcused absences in a term pass a threshold,
+// either a count with ?max_absences=5 or a share of sessions with ?max_absence_rate=0.1
+func GetAbsenceFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	maxAbsences := -1
+	if value := params.Get("max_absences"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			http.Error(w, "❌ Invalid max_absences", http.StatusBadRequest)
+			return
+		}
+		maxAbsences = count
+	}
+
+	maxRate := defaultAbsenceThresholdRate
+	if envRate, err := strconv.ParseFloat(os.Getenv("ABSENCE_THRESHOLD_RATE"), 64); err == nil {
+		maxRate = envRate
+	}
+	if value := params.Get("max_absence_rate"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			http.Error(w, "❌ max_absence_rate must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		maxRate = rate
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	condition, args, label, err := attendancePeriod(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := "SELECT student_id, MAX(class_id), " + attendanceCounts + " FROM attendance_records WHERE student_id IN (SELECT id FROM students WHERE deleted_at IS NULL)" + condition
+	if classId := params.Get("class_id"); classId != "" {
+		query += " AND class_id = ?"
+		args = append(args, classId)
+	}
+	query += " GROUP BY student_id ORDER BY SUM(status = 'absent') DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	flagged := make([]models.AttendanceSummary, 0)
+	for rows.Next() {
+		summary := label
+		err := scanAttendanceSummary(rows, &summary, &summary.StudentID, &summary.ClassID)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+
+		overCount := maxAbsences >= 0 && summary.Absent > maxAbsences
+		overRate := summary.Total > 0 && float64(summary.Absent)/float64(summary.Total) > maxRate
+		if overCount || (maxAbsences < 0 && overRate) {
+			flagged = append(flagged, summary)
+		}
+	}
+
+	response := struct {
+		Status string                     `json:"status"`
+		Count  int                        `json:"count"`
+		Data   []models.AttendanceSummary `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(flagged),
+		Data:   flagged,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
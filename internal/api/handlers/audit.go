@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	mw "github.com/greatdaveo/Schoolly/internal/api/middlewares"
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx so audit entries can join the caller's transaction
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Fields that must never be copied into the audit trail
+var auditRedactedFields = []string{"password", "password_reset_token", "password_token_expires"}
+
+// To get the id of the logged in user from the JWT claims
+func ActorID(r *http.Request) int {
+	switch uid := r.Context().Value(mw.ContextKey("userId")).(type) {
+	case float64:
+		return int(uid)
+	case int:
+		return uid
+	case string:
+		id, _ := strconv.Atoi(uid)
+		return id
+	}
+	return 0
+}
+
+func requestID(r *http.Request) string {
+	requestId, _ := r.Context().Value(mw.ContextKey("requestId")).(string)
+	return requestId
+}
+
+// To convert a model into a map of its JSON fields without any sensitive values
+func auditSnapshot(model interface{}) map[string]interface{} {
+	if model == nil || (reflect.ValueOf(model).Kind() == reflect.Ptr && reflect.ValueOf(model).IsNil()) {
+		return nil
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+
+	snapshot := map[string]interface{}{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+
+	for _, field := range auditRedactedFields {
+		delete(snapshot, field)
+	}
+	return snapshot
+}
+
+// To work out which fields changed between the before and after snapshots
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	diff := map[string]interface{}{}
+
+	for k, newValue := range after {
+		oldValue, ok := before[k]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			diff[k] = map[string]interface{}{"from": oldValue, "to": newValue}
+		}
+	}
+
+	for k, oldValue := range before {
+		if _, ok := after[k]; !ok {
+			diff[k] = map[string]interface{}{"from": oldValue, "to": nil}
+		}
+	}
+	return diff
+}
+
+func marshalOrNull(value map[string]interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// RecordAudit appends an entry to the audit log. Pass nil for before on create and nil for after on delete.
+func RecordAudit(db Execer, r *http.Request, action, resource string, resourceId int, before, after interface{}) error {
+	beforeSnapshot := auditSnapshot(before)
+	afterSnapshot := auditSnapshot(after)
+	diff := auditDiff(beforeSnapshot, afterSnapshot)
+
+	var actor interface{}
+	if actorId := ActorID(r); actorId != 0 {
+		actor = actorId
+	}
+
+	_, err := db.Exec(
+		"INSERT INTO audit_logs (actor_id, action, resource, resource_id, before_data, after_data, diff, request_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		actor,
+		action,
+		resource,
+		resourceId,
+		marshalOrNull(beforeSnapshot),
+		marshalOrNull(afterSnapshot),
+		marshalOrNull(diff),
+		requestID(r),
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error recording audit log")
+	}
+	return nil
+}
+
+// To get the audit trail, filtered by actor, resource and time range. Only the roles in
+// AUDIT_ROLES may read it since the snapshots hold other users' personal data.
+func GetAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if !HasRole(r, rolesFromEnv("AUDIT_ROLES", "admin")) {
+		http.Error(w, "❌ Your role may not read the audit trail", http.StatusForbidden)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT id, COALESCE(actor_id, 0), action, resource, resource_id, COALESCE(before_data, 'null'), COALESCE(after_data, 'null'), COALESCE(diff, 'null'), request_id, created_at FROM audit_logs WHERE 1=1"
+	var args []interface{}
+
+	params := r.URL.Query()
+
+	if actor := params.Get("actor"); actor != "" {
+		actorId, err := strconv.Atoi(actor)
+		if err != nil {
+			http.Error(w, "❌ Invalid actor", http.StatusBadRequest)
+			return
+		}
+		query += " AND actor_id = ?"
+		args = append(args, actorId)
+	}
+
+	if resource := params.Get("resource"); resource != "" {
+		query += " AND resource = ?"
+		args = append(args, resource)
+	}
+
+	if resourceId := params.Get("resource_id"); resourceId != "" {
+		id, err := strconv.Atoi(resourceId)
+		if err != nil {
+			http.Error(w, "❌ Invalid resource_id", http.StatusBadRequest)
+			return
+		}
+		query += " AND resource_id = ?"
+		args = append(args, id)
+	}
+
+	if action := params.Get("action"); action != "" {
+		query += " AND action = ?"
+		args = append(args, action)
+	}
+
+	// The time range is given as RFC3339 timestamps e.g. ?from=2025-01-01T00:00:00Z
+	for param, operator := range map[string]string{"from": ">=", "to": "<="} {
+		value := params.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "❌ Invalid "+param+" time, use RFC3339 format", http.StatusBadRequest)
+			return
+		}
+		query += " AND created_at " + operator + " ?"
+		args = append(args, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	auditList := make([]models.AuditLog, 0)
+	for rows.Next() {
+		var entry models.AuditLog
+		var before, after, diff []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.Resource,
+			&entry.ResourceID,
+			&before,
+			&after,
+			&diff,
+			&entry.RequestID,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		entry.Before = json.RawMessage(before)
+		entry.After = json.RawMessage(after)
+		entry.Diff = json.RawMessage(diff)
+		auditList = append(auditList, entry)
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.AuditLog `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(auditList),
+		Data:   auditList,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const classColumns = "id, name, grade_level, academic_year, COALESCE(homeroom_teacher_id, 0), room, capacity, version"
+
+var classFilterFields = map[string]string{
+	"name":                "name",
+	"grade_level":         "grade_level",
+	"academic_year":       "academic_year",
+	"homeroom_teacher_id": "homeroom_teacher_id",
+	"room":                "room",
+}
+
+var classSortFields = map[string]bool{
+	"name":          true,
+	"grade_level":   true,
+	"academic_year": true,
+	"room":          true,
+	"capacity":      true,
+}
+
+func scanClass(row interface{ Scan(...interface{}) error }, class *models.Class) error {
+	return row.Scan(
+		&class.ID,
+		&class.Name,
+		&class.GradeLevel,
+		&class.AcademicYear,
+		&class.HomeroomTeacherID,
+		&class.Room,
+		&class.Capacity,
+		&class.Version,
+	)
+}
+
+// nullableID stores a zero id as NULL so optional foreign keys stay valid
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func validateClass(class models.Class) error {
+	if class.Name == "" || class.AcademicYear == "" {
+		return utils.ErrorHandler(fmt.Errorf("missing class fields"), "❌ name and academic_year are required")
+	}
+	if class.GradeLevel < 0 || class.Capacity < 0 {
+		return utils.ErrorHandler(fmt.Errorf("negative class fields"), "❌ grade_level and capacity cannot be negative")
+	}
+	return nil
+}
+
+// ResolveClass makes the class name and class id of a student or teacher agree.
+// An id wins over a name; a blank name and zero id means no class.
+func ResolveClass(db Queryer, classId int, className string) (int, string, error) {
+	if classId == 0 && className == "" {
+		return 0, "", nil
+	}
+
+	var err error
+	if classId != 0 {
+		err = db.QueryRow("SELECT id, name FROM classes WHERE id = ? AND deleted_at IS NULL", classId).Scan(&classId, &className)
+	} else {
+		err = db.QueryRow("SELECT id, name FROM classes WHERE name = ? AND deleted_at IS NULL ORDER BY academic_year DESC LIMIT 1", className).Scan(&classId, &className)
+	}
+
+	if err == sql.ErrNoRows {
+		return 0, "", utils.ErrorHandler(err, "❌ Class does not exist")
+	} else if err != nil {
+		return 0, "", utils.ErrorHandler(err, "❌ Unable to retrieve class")
+	}
+	return classId, className, nil
+}
+
+// To get multiple classes
+func GetClassesHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + classColumns + " FROM classes WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, classFilterFields)
+	query = utils.AddSortingFor(r, query, classSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	classList := make([]models.Class, 0)
+	for rows.Next() {
+		var class models.Class
+		err := scanClass(rows, &class)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		classList = append(classList, class)
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(classList),
+		Data:   classList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single class
+func GetOneClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var class models.Class
+	err = scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ? AND deleted_at IS NULL", id), &class)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Class not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(class.ID, class.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(class)
+}
+
+// To add classes to the DB
+func AddClassesHandler(w http.ResponseWriter, r *http.Request) {
+	var newClasses []models.Class
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newClasses)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for _, class := range newClasses {
+		err := validateClass(class)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedClasses := make([]models.Class, len(newClasses))
+	for i, newClass := range newClasses {
+		newClass.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO classes (name, grade_level, academic_year, homeroom_teacher_id, room, capacity, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			newClass.Name,
+			newClass.GradeLevel,
+			newClass.AcademicYear,
+			nullableID(newClass.HomeroomTeacherID),
+			newClass.Room,
+			newClass.Capacity,
+			newClass.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newClass.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "classes", newClass.ID, nil, newClass)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedClasses[i] = newClass
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedClasses),
+		Data:   addedClasses,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a class
+func EditClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingClass models.Class
+	err = scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ? AND deleted_at IS NULL", id), &existingClass)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Class not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingClass.ID, existingClass.Version) {
+		return
+	}
+
+	previousClass := existingClass
+	err = ApplyPatch(&existingClass, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = validateClass(existingClass)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE classes SET name = ?, grade_level = ?, academic_year = ?, homeroom_teacher_id = ?, room = ?, capacity = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingClass.Name,
+		existingClass.GradeLevel,
+		existingClass.AcademicYear,
+		nullableID(existingClass.HomeroomTeacherID),
+		existingClass.Room,
+		existingClass.Capacity,
+		existingClass.ID,
+		previousClass.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating class")
+		http.Error(w, "❌ Error updating class", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingClass.Version = previousClass.Version + 1
+
+	// Students and teachers keep a copy of the class name, so a rename has to follow them
+	if existingClass.Name != previousClass.Name {
+		for _, table := range []string{"students", "teachers"} {
+			_, err = tx.Exec("UPDATE "+table+" SET class = ? WHERE class_id = ?", existingClass.Name, existingClass.ID)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error renaming class")
+				http.Error(w, "❌ Error renaming class", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "classes", existingClass.ID, previousClass, existingClass)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingClass.ID, existingClass.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingClass)
+}
+
+func DeleteOneClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedClass models.Class
+	err = scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ? AND deleted_at IS NULL", id), &deletedClass)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Class not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedClass.ID, deletedClass.Version) {
+		return
+	}
+
+	var enrolled int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE class_id = ? AND deleted_at IS NULL", id).Scan(&enrolled)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to count students in class")
+		http.Error(w, "❌ Unable to count students in class", http.StatusInternalServerError)
+		return
+	}
+	if enrolled > 0 {
+		http.Error(w, fmt.Sprintf("❌ Class still has %d students, move them first", enrolled), http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE classes SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedClass.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete class")
+		http.Error(w, "❌ Unable delete class", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "classes", id, deletedClass, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Class successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreClassHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "classes", "Class")
+}
+
+// To get the list of students in a class
+func GetStudentsForAClass(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT id, first_name, last_name, email, class, class_id, version FROM students WHERE class_id = ? AND deleted_at IS NULL"
+	args := []interface{}{id}
+	query = utils.AddSorting(r, query)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	students := make([]models.Student, 0)
+	for rows.Next() {
+		var student models.Student
+		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class, &student.ClassID, &student.Version)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		students = append(students, student)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Student `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(students),
+		Data:   students,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the list of teachers of a class
+func GetTeachersForAClass(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT id, first_name, last_name, email, class, class_id, subject, COALESCE(subject_id, 0), version FROM teachers WHERE class_id = ? AND deleted_at IS NULL"
+	args := []interface{}{id}
+	query = utils.AddSorting(r, query)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	teachers := make([]models.Teacher, 0)
+	for rows.Next() {
+		var teacher models.Teacher
+		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.ClassID, &teacher.Subject, &teacher.SubjectID, &teacher.Version)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		teachers = append(teachers, teacher)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Teacher `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(teachers),
+		Data:   teachers,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// ResolvePatchedClass keeps class and class_id in step after a partial update changed either of them
+func ResolvePatchedClass(db Queryer, input map[string]interface{}, classId *int, className *string) error {
+	_, idChanged := input["class_id"]
+	_, nameChanged := input["class"]
+	if !idChanged && !nameChanged {
+		return nil
+	}
+
+	// A new name without a new id means the client is moving by name
+	if nameChanged && !idChanged {
+		*classId = 0
+	}
+
+	id, name, err := ResolveClass(db, *classId, *className)
+	if err != nil {
+		return err
+	}
+	*classId, *className = id, name
+	return nil
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const coverSelect = `SELECT ca.id, COALESCE(ca.leave_request_id, 0), ca.timetable_slot_id, ca.cover_date, ca.absent_teacher_id, ca.cover_teacher_id,
+	CONCAT(t.first_name, ' ', t.last_name), ca.note, ca.assigned_by, ca.assigned_at
+	FROM cover_assignments ca
+	JOIN teachers t ON t.id = ca.cover_teacher_id`
+
+var coverFilterFields = map[string]string{
+	"date":              "ca.cover_date",
+	"leave_request_id":  "ca.leave_request_id",
+	"absent_teacher_id": "ca.absent_teacher_id",
+	"cover_teacher_id":  "ca.cover_teacher_id",
+}
+
+// To keep teacher t to those free for a lesson: not on approved leave that day, not teaching
+// and not already covering during an overlapping period. The arguments are given by coverAvailabilityArgs.
+const coverAvailability = `
+	AND NOT EXISTS (SELECT 1 FROM leave_requests l WHERE l.teacher_id = t.id AND l.status = 'approved' AND ? BETWEEN l.start_date AND l.end_date)
+	AND NOT EXISTS (SELECT 1 FROM timetable_slots s
+		JOIN periods p ON p.id = s.period_id
+		JOIN periods np ON np.id = ?
+		WHERE s.teacher_id = t.id AND s.term_id = ? AND s.day_of_week = ?
+		AND p.start_time < np.end_time AND np.start_time < p.end_time)
+	AND NOT EXISTS (SELECT 1 FROM cover_assignments ca
+		JOIN timetable_slots s ON s.id = ca.timetable_slot_id
+		JOIN periods p ON p.id = s.period_id
+		JOIN periods np ON np.id = ?
+		WHERE ca.cover_teacher_id = t.id AND ca.cover_date = ?
+		AND p.start_time < np.end_time AND np.start_time < p.end_time)`
+
+func coverAvailabilityArgs(lesson models.TimetableSlot, date string) []interface{} {
+	return []interface{}{date, lesson.PeriodID, lesson.TermID, lesson.Day, lesson.PeriodID, date}
+}
+
+func scanCoverAssignment(row interface{ Scan(...interface{}) error }, cover *models.CoverAssignment) error {
+	return row.Scan(
+		&cover.ID,
+		&cover.LeaveRequestID,
+		&cover.TimetableSlotID,
+		&cover.Date,
+		&cover.AbsentTeacherID,
+		&cover.CoverTeacherID,
+		&cover.CoverTeacher,
+		&cover.Note,
+		&cover.AssignedBy,
+		&cover.AssignedAt,
+	)
+}
+
+// To number a date's weekday the way the timetable does, 1 for Monday through 7 for Sunday
+func timetableDay(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
+
+// To suggest who could cover a lesson on a date: free teachers of the same subject,
+// either by their main subject or a teaching assignment, with the fewest covers this term first
+func coverCandidates(db *sql.DB, lesson models.TimetableSlot, date string) ([]models.CoverCandidate, error) {
+	args := []interface{}{lesson.TermID, lesson.TeacherID, lesson.SubjectID, lesson.SubjectID}
+	args = append(args, coverAvailabilityArgs(lesson, date)...)
+	rows, err := db.Query(
+		`SELECT t.id, CONCAT(t.first_name, ' ', t.last_name),
+			(SELECT COUNT(*) FROM cover_assignments c JOIN timetable_slots cs ON cs.id = c.timetable_slot_id
+				WHERE c.cover_teacher_id = t.id AND cs.term_id = ?) AS covers
+		FROM teachers t
+		WHERE t.deleted_at IS NULL AND t.id <> ?
+		AND (t.subject_id = ? OR EXISTS (SELECT 1 FROM teaching_assignments ta WHERE ta.teacher_id = t.id AND ta.subject_id = ? AND ta.deleted_at IS NULL))`+
+			coverAvailability+`
+		ORDER BY covers, t.last_name, t.first_name`,
+		args...,
+	)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to find cover teachers")
+	}
+	defer rows.Close()
+
+	candidates := make([]models.CoverCandidate, 0)
+	for rows.Next() {
+		var candidate models.CoverCandidate
+		err := rows.Scan(&candidate.TeacherID, &candidate.Name, &candidate.CoversThisTerm)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to find cover teachers")
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// To list every lesson a leave request takes the teacher away from, day by day, skipping
+// holidays and days outside any term. Lessons without cover come with suggestions.
+func GetCoverPlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid leave request id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	leave, status, err := loadLeaveRequest(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if leave.Status != "pending" && leave.Status != "approved" {
+		http.Error(w, "❌ No cover is needed for "+leave.Status+" leave", http.StatusConflict)
+		return
+	}
+
+	rows, err := db.Query(coverSelect+" WHERE ca.absent_teacher_id = ? AND ca.cover_date BETWEEN ? AND ?", leave.TeacherID, leave.StartDate, leave.EndDate)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve cover")
+		http.Error(w, "❌ Unable to retrieve cover", http.StatusInternalServerError)
+		return
+	}
+	covers := map[string]models.CoverAssignment{}
+	for rows.Next() {
+		var cover models.CoverAssignment
+		if err := scanCoverAssignment(rows, &cover); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Unable to retrieve cover")
+			http.Error(w, "❌ Unable to retrieve cover", http.StatusInternalServerError)
+			return
+		}
+		covers[fmt.Sprintf("%d/%s", cover.TimetableSlotID, cover.Date)] = cover
+	}
+	rows.Close()
+
+	startDate, _ := time.Parse(DateLayout, leave.StartDate)
+	endDate, _ := time.Parse(DateLayout, leave.EndDate)
+
+	needs := make([]models.CoverNeed, 0)
+	uncovered := 0
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+
+		holiday, _, err := IsHoliday(db, date)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if holiday {
+			continue
+		}
+		term, err := CurrentTerm(db, date)
+		if err == ErrNoCurrentTerm {
+			continue
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lessons, err := loadTimetable(db, term.ID, " AND s.teacher_id = ? AND s.day_of_week = ?", leave.TeacherID, timetableDay(day))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, lesson := range lessons {
+			need := models.CoverNeed{Date: date, Lesson: lesson, Suggestions: []models.CoverCandidate{}}
+			if cover, ok := covers[fmt.Sprintf("%d/%s", lesson.ID, date)]; ok {
+				need.Cover = &cover
+			} else {
+				uncovered++
+				need.Suggestions, err = coverCandidates(db, lesson.TimetableSlot, date)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			needs = append(needs, need)
+		}
+	}
+
+	response := struct {
+		Status    string              `json:"status"`
+		Leave     models.LeaveRequest `json:"leave"`
+		Count     int                 `json:"count"`
+		Uncovered int                 `json:"uncovered"`
+		Data      []models.CoverNeed  `json:"data"`
+	}{
+		Status:    "success",
+		Leave:     leave,
+		Count:     len(needs),
+		Uncovered: uncovered,
+		Data:      needs,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To record who covers the lessons of approved leave, e.g.
+// [{"timetable_slot_id": 12, "date": "2025-09-03", "cover_teacher_id": 7}].
+// Any free teacher may be chosen, not only the suggested ones.
+func AssignCoverHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid leave request id", http.StatusBadRequest)
+		return
+	}
+
+	var newCovers []models.CoverAssignment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&newCovers)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	leave, status, err := loadLeaveRequest(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if leave.Status != "approved" {
+		http.Error(w, "❌ Cover can only be arranged for approved leave", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	assignedAt := time.Now().Format(DateTimeLayout)
+	addedCovers := make([]models.CoverAssignment, len(newCovers))
+	for i, newCover := range newCovers {
+		status, err := validateCover(tx, leave, &newCover)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newCover.LeaveRequestID = leave.ID
+		newCover.AbsentTeacherID = leave.TeacherID
+		newCover.AssignedBy = ActorID(r)
+		newCover.AssignedAt = assignedAt
+		res, err := tx.Exec(
+			"INSERT INTO cover_assignments (leave_request_id, timetable_slot_id, cover_date, absent_teacher_id, cover_teacher_id, note, assigned_by, assigned_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			newCover.LeaveRequestID,
+			newCover.TimetableSlotID,
+			newCover.Date,
+			newCover.AbsentTeacherID,
+			newCover.CoverTeacherID,
+			newCover.Note,
+			newCover.AssignedBy,
+			newCover.AssignedAt,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newCover.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "cover_assignments", newCover.ID, nil, newCover)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedCovers[i] = newCover
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.CoverAssignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedCovers),
+		Data:   addedCovers,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To check a substitution against the leave it covers: the lesson must be the absent
+// teacher's, fall within the leave, and the cover teacher must be free at the time.
+// It returns the HTTP status to answer with when invalid.
+func validateCover(db Queryer, leave models.LeaveRequest, cover *models.CoverAssignment) (int, error) {
+	cover.Note = strings.TrimSpace(cover.Note)
+	if cover.TimetableSlotID == 0 || cover.Date == "" || cover.CoverTeacherID == 0 {
+		return http.StatusBadRequest, errors.New("❌ timetable_slot_id, date and cover_teacher_id are required")
+	}
+	date, err := time.Parse(DateLayout, cover.Date)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("❌ date must look like 2025-09-01")
+	}
+	if cover.Date < leave.StartDate || cover.Date > leave.EndDate {
+		return http.StatusBadRequest, fmt.Errorf("❌ %s is outside the leave from %s to %s", cover.Date, leave.StartDate, leave.EndDate)
+	}
+
+	var lesson models.TimetableSlot
+	err = scanTimetableSlot(db.QueryRow("SELECT "+timetableSlotColumns+" FROM timetable_slots WHERE id = ?", cover.TimetableSlotID), &lesson)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest, fmt.Errorf("❌ Timetable slot %d does not exist", cover.TimetableSlotID)
+	} else if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve timetable slot")
+	}
+	if lesson.TeacherID != leave.TeacherID {
+		return http.StatusBadRequest, fmt.Errorf("❌ Timetable slot %d is not taught by teacher %d", lesson.ID, leave.TeacherID)
+	}
+	if lesson.Day != timetableDay(date) {
+		return http.StatusBadRequest, fmt.Errorf("❌ Timetable slot %d is on %s, not %s", lesson.ID, dayName(lesson.Day), date.Weekday())
+	}
+	term, err := CurrentTerm(db, cover.Date)
+	if err != nil && err != ErrNoCurrentTerm {
+		return http.StatusInternalServerError, err
+	}
+	if err == ErrNoCurrentTerm || term.ID != lesson.TermID {
+		return http.StatusBadRequest, fmt.Errorf("❌ Timetable slot %d is not taught on %s", lesson.ID, cover.Date)
+	}
+
+	var coveredBy string
+	err = db.QueryRow(
+		"SELECT CONCAT(t.first_name, ' ', t.last_name) FROM cover_assignments ca JOIN teachers t ON t.id = ca.cover_teacher_id WHERE ca.timetable_slot_id = ? AND ca.cover_date = ? FOR UPDATE",
+		lesson.ID, cover.Date,
+	).Scan(&coveredBy)
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("❌ %s is already covering timetable slot %d on %s", coveredBy, lesson.ID, cover.Date)
+	} else if err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check existing cover")
+	}
+
+	if cover.CoverTeacherID == leave.TeacherID {
+		return http.StatusBadRequest, errors.New("❌ A teacher can't cover their own lessons while on leave")
+	}
+	var free int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM teachers t WHERE t.id = ? AND t.deleted_at IS NULL"+coverAvailability,
+		append([]interface{}{cover.CoverTeacherID}, coverAvailabilityArgs(lesson, cover.Date)...)...,
+	).Scan(&free)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check the cover teacher")
+	}
+	if free == 0 {
+		return http.StatusConflict, fmt.Errorf("❌ Teacher %d is not free for timetable slot %d on %s", cover.CoverTeacherID, lesson.ID, cover.Date)
+	}
+	return http.StatusOK, nil
+}
+
+// To list substitutions matching a condition in timetable order
+func listCoverAssignments(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := coverSelect + " JOIN timetable_slots s ON s.id = ca.timetable_slot_id JOIN periods p ON p.id = s.period_id WHERE 1=1" + condition
+	args := conditionArgs
+	for _, bound := range []struct{ param, clause string }{{"from", " AND ca.cover_date >= ?"}, {"to", " AND ca.cover_date <= ?"}} {
+		if value := r.URL.Query().Get(bound.param); value != "" {
+			if _, err := time.Parse(DateLayout, value); err != nil {
+				http.Error(w, "❌ "+bound.param+" must be a date like 2025-09-01", http.StatusBadRequest)
+				return
+			}
+			query += bound.clause
+			args = append(args, value)
+		}
+	}
+	query, args = utils.AddFiltersFor(r, query, args, coverFilterFields)
+	query += " ORDER BY ca.cover_date DESC, p.start_time, ca.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	coverList := make([]models.CoverAssignment, 0)
+	for rows.Next() {
+		var cover models.CoverAssignment
+		err := scanCoverAssignment(rows, &cover)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		coverList = append(coverList, cover)
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.CoverAssignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(coverList),
+		Data:   coverList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get substitutions, filtered by e.g. ?date=2025-09-03 for the day's cover sheet or ?from=&to=
+func GetCoverAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	listCoverAssignments(w, r, "")
+}
+
+// To get the lessons a teacher has covered for colleagues
+func GetCoverForATeacher(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	listCoverAssignments(w, r, " AND ca.cover_teacher_id = ?", teacherId)
+}
+
+// To remove a substitution, e.g. to hand the lesson to someone else
+func DeleteCoverAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid cover assignment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingCover models.CoverAssignment
+	err = scanCoverAssignment(db.QueryRow(coverSelect+" WHERE ca.id = ?", id), &existingCover)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Cover assignment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM cover_assignments WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete cover assignment")
+		http.Error(w, "❌ Unable delete cover assignment", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "cover_assignments", id, existingCover, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Cover assignment successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
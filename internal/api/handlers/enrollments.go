@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const enrollmentColumns = "id, student_id, class_id, term_id, created_at"
+
+// To record the class a student is in for a term, replacing any earlier class that term
+func enrollInTerm(db Execer, studentId, classId, termId int) error {
+	_, err := db.Exec(
+		"INSERT INTO enrollments (student_id, class_id, term_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE class_id = VALUES(class_id)",
+		studentId, classId, termId,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error saving enrollment")
+	}
+	return nil
+}
+
+// EnrollStudent records a student's class against the current term.
+// Nothing is recorded when the student has no class or no term is running.
+func EnrollStudent(db QueryExecer, studentId, classId int) error {
+	if classId == 0 {
+		return nil
+	}
+
+	term, err := CurrentTerm(db, "")
+	if err == ErrNoCurrentTerm {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return enrollInTerm(db, studentId, classId, term.ID)
+}
+
+// To list enrollments with a condition for the nested routes
+func listEnrollments(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + enrollmentColumns + " FROM enrollments WHERE " + condition
+	args := conditionArgs
+
+	// To narrow the list down e.g. ?class_id=3
+	if classId := r.URL.Query().Get("class_id"); classId != "" {
+		query += " AND class_id = ?"
+		args = append(args, classId)
+	}
+	query += " ORDER BY term_id, student_id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	enrollmentList := make([]models.Enrollment, 0)
+	for rows.Next() {
+		var enrollment models.Enrollment
+		err := rows.Scan(&enrollment.ID, &enrollment.StudentID, &enrollment.ClassID, &enrollment.TermID, &enrollment.CreatedAt)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		enrollmentList = append(enrollmentList, enrollment)
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollmentList),
+		Data:   enrollmentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the class history of a student, one entry per term
+func GetEnrollmentsForAStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	listEnrollments(w, r, "student_id = ?", id)
+}
+
+// To get who was enrolled in a term
+func GetEnrollmentsForATerm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+	listEnrollments(w, r, "term_id = ?", id)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// To build the ETag of a single row from its id and version
+func ETag(id, version int) string {
+	return fmt.Sprintf(`"%d-%d"`, id, version)
+}
+
+// To compare an ETag against an If-Match or If-None-Match header value
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch makes sure the client is editing the version it last read.
+// It writes 412 on a stale ETag, or 428 when the header is missing and REQUIRE_IF_MATCH=true, and returns false.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, id, version int) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+			http.Error(w, "❌ If-Match header is required", http.StatusPreconditionRequired)
+			return false
+		}
+		return true
+	}
+
+	if !etagMatches(ifMatch, ETag(id, version)) {
+		w.Header().Set("ETag", ETag(id, version))
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// CheckIfNoneMatch sets the ETag header and answers 304 when the client already has this version
+func CheckIfNoneMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// WriteJSONWithETag encodes the response, tags it with a hash of the body and honours If-None-Match
+func WriteJSONWithETag(w http.ResponseWriter, r *http.Request, response interface{}) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "❌ Error encoding response", http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+	if CheckIfNoneMatch(w, r, etag) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
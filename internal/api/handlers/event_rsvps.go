@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const rsvpSelect = `SELECT r.id, r.event_id, COALESCE(r.guardian_id, 0), COALESCE(r.teacher_id, 0),
+	COALESCE(CONCAT(g.first_name, ' ', g.last_name), CONCAT(t.first_name, ' ', t.last_name), ''),
+	r.response, r.guests, r.note, r.responded_at
+	FROM event_rsvps r
+	LEFT JOIN guardians g ON g.id = r.guardian_id
+	LEFT JOIN teachers t ON t.id = r.teacher_id`
+
+var rsvpResponses = map[string]bool{
+	"yes":   true,
+	"no":    true,
+	"maybe": true,
+}
+
+func scanRSVP(row interface{ Scan(...interface{}) error }, rsvp *models.RSVP) error {
+	return row.Scan(
+		&rsvp.ID,
+		&rsvp.EventID,
+		&rsvp.GuardianID,
+		&rsvp.TeacherID,
+		&rsvp.Name,
+		&rsvp.Response,
+		&rsvp.Guests,
+		&rsvp.Note,
+		&rsvp.RespondedAt,
+	)
+}
+
+// To check a guardian has a child in one of the classes an event is for. Everyone is invited to whole school events.
+func guardianInvited(db Queryer, guardianId int, event models.Event) (bool, error) {
+	if len(event.ClassIDs) == 0 {
+		return true, nil
+	}
+
+	placeholders := make([]string, len(event.ClassIDs))
+	args := []interface{}{guardianId}
+	for i, classId := range event.ClassIDs {
+		placeholders[i] = "?"
+		args = append(args, classId)
+	}
+
+	var children int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM student_guardians sg
+		JOIN enrollments en ON en.student_id = sg.student_id
+		WHERE sg.guardian_id = ? AND en.class_id IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...,
+	).Scan(&children)
+	if err != nil {
+		return false, utils.ErrorHandler(err, "❌ Unable to check the guardian's children")
+	}
+	return children > 0, nil
+}
+
+// To check an event can still be answered: one off events until they end, repeating ones always
+func eventOpen(event models.Event) bool {
+	if event.Recurrence != "" {
+		return true
+	}
+	endsAt, err := parseDateTime(event.EndsAt)
+	return err == nil && endsAt.After(time.Now())
+}
+
+// To get the answers to an event with a count of each, e.g. ?response=yes for who is coming
+func GetRSVPsHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if _, status, err := loadEvent(db, eventId); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	query := rsvpSelect + " WHERE r.event_id = ?"
+	args := []interface{}{eventId}
+	if response := r.URL.Query().Get("response"); response != "" {
+		if !rsvpResponses[response] {
+			http.Error(w, "❌ response must be yes, no or maybe", http.StatusBadRequest)
+			return
+		}
+		query += " AND r.response = ?"
+		args = append(args, response)
+	}
+	query += " ORDER BY r.responded_at, r.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var summary models.RSVPSummary
+	rsvpList := make([]models.RSVP, 0)
+	for rows.Next() {
+		var rsvp models.RSVP
+		err := scanRSVP(rows, &rsvp)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		switch rsvp.Response {
+		case "yes":
+			summary.Yes++
+			summary.Attending += 1 + rsvp.Guests
+		case "no":
+			summary.No++
+		case "maybe":
+			summary.Maybe++
+		}
+		rsvpList = append(rsvpList, rsvp)
+	}
+
+	response := struct {
+		Status  string             `json:"status"`
+		Count   int                `json:"count"`
+		Summary models.RSVPSummary `json:"summary"`
+		Data    []models.RSVP      `json:"data"`
+	}{
+		Status:  "success",
+		Count:   len(rsvpList),
+		Summary: summary,
+		Data:    rsvpList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To record a guardian's or teacher's answer to an event. Answering again replaces the earlier answer.
+// Guardians can only answer events for the whole school or for a class one of their children is in.
+func RespondToEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var rsvp models.RSVP
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&rsvp)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if (rsvp.GuardianID == 0) == (rsvp.TeacherID == 0) {
+		http.Error(w, "❌ Give either a guardian_id or a teacher_id", http.StatusBadRequest)
+		return
+	}
+	rsvp.Response = strings.ToLower(strings.TrimSpace(rsvp.Response))
+	if !rsvpResponses[rsvp.Response] {
+		http.Error(w, "❌ response must be yes, no or maybe", http.StatusBadRequest)
+		return
+	}
+	if rsvp.Guests < 0 || rsvp.Guests > 10 {
+		http.Error(w, "❌ guests must be between 0 and 10", http.StatusBadRequest)
+		return
+	}
+	if rsvp.Response == "no" {
+		rsvp.Guests = 0
+	}
+	rsvp.Note = strings.TrimSpace(rsvp.Note)
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	event, status, err := loadEvent(db, eventId)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if !eventOpen(event) {
+		http.Error(w, "❌ The event is over", http.StatusConflict)
+		return
+	}
+
+	responder, responderId, label := "teacher_id", rsvp.TeacherID, "Teacher"
+	table := "teachers"
+	if rsvp.GuardianID != 0 {
+		responder, responderId, label, table = "guardian_id", rsvp.GuardianID, "Guardian", "guardians"
+	}
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ? AND deleted_at IS NULL", responderId).Scan(&exists)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve "+strings.ToLower(label))
+		http.Error(w, "❌ Unable to retrieve "+strings.ToLower(label), http.StatusInternalServerError)
+		return
+	}
+	if exists == 0 {
+		http.Error(w, "❌ "+label+" "+strconv.Itoa(responderId)+" does not exist", http.StatusBadRequest)
+		return
+	}
+
+	if rsvp.GuardianID != 0 {
+		invited, err := guardianInvited(db, rsvp.GuardianID, event)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !invited {
+			http.Error(w, "❌ The guardian has no child in the classes this event is for", http.StatusForbidden)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var previousRSVP *models.RSVP
+	var existing models.RSVP
+	err = scanRSVP(tx.QueryRow(rsvpSelect+" WHERE r.event_id = ? AND r."+responder+" = ? FOR UPDATE", eventId, responderId), &existing)
+	if err == nil {
+		previousRSVP = &existing
+	} else if err != sql.ErrNoRows {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve RSVP")
+		http.Error(w, "❌ Unable to retrieve RSVP", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO event_rsvps (event_id, guardian_id, teacher_id, response, guests, note) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE response = VALUES(response), guests = VALUES(guests), note = VALUES(note)",
+		eventId, nullableID(rsvp.GuardianID), nullableID(rsvp.TeacherID), rsvp.Response, rsvp.Guests, rsvp.Note,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error saving RSVP")
+		http.Error(w, "❌ Error saving RSVP", http.StatusInternalServerError)
+		return
+	}
+
+	err = scanRSVP(tx.QueryRow(rsvpSelect+" WHERE r.event_id = ? AND r."+responder+" = ?", eventId, responderId), &rsvp)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve RSVP")
+		http.Error(w, "❌ Unable to retrieve RSVP", http.StatusInternalServerError)
+		return
+	}
+
+	action := AuditCreate
+	if previousRSVP != nil {
+		action = AuditUpdate
+	}
+	err = RecordAudit(tx, r, action, "event_rsvps", rsvp.ID, previousRSVP, rsvp)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if previousRSVP == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(rsvp)
+}
+
+// To withdraw an answer to an event
+func DeleteRSVPHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+	rsvpId, err := strconv.Atoi(r.PathValue("rsvpId"))
+	if err != nil {
+		http.Error(w, "❌ Invalid RSVP id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedRSVP models.RSVP
+	err = scanRSVP(db.QueryRow(rsvpSelect+" WHERE r.id = ? AND r.event_id = ?", rsvpId, eventId), &deletedRSVP)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "❌ RSVP not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM event_rsvps WHERE id = ?", rsvpId)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete RSVP")
+		http.Error(w, "❌ Unable delete RSVP", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "event_rsvps", rsvpId, deletedRSVP, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "RSVP successfully deleted",
+		ID:     rsvpId,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,603 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const eventSlotSelect = `SELECT s.id, s.event_id, s.teacher_id, CONCAT(t.first_name, ' ', t.last_name), s.starts_at, s.ends_at,
+	COALESCE(s.guardian_id, 0), COALESCE(s.student_id, 0), COALESCE(s.booked_at, '')
+	FROM event_slots s
+	JOIN teachers t ON t.id = s.teacher_id`
+
+func scanEventSlot(row interface{ Scan(...interface{}) error }, slot *models.EventSlot) error {
+	return row.Scan(
+		&slot.ID,
+		&slot.EventID,
+		&slot.TeacherID,
+		&slot.Teacher,
+		&slot.StartsAt,
+		&slot.EndsAt,
+		&slot.GuardianID,
+		&slot.StudentID,
+		&slot.BookedAt,
+	)
+}
+
+// To load a parent evening, refusing other kinds of event
+func loadParentEvening(db *sql.DB, id int) (models.Event, int, error) {
+	event, status, err := loadEvent(db, id)
+	if err != nil {
+		return event, status, err
+	}
+	if event.Category != "parent_evening" {
+		return event, http.StatusBadRequest, errors.New("❌ Appointment slots are only for parent evenings")
+	}
+	return event, http.StatusOK, nil
+}
+
+// To list the appointment slots matching a condition in time order
+func listEventSlots(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := eventSlotSelect + " WHERE 1=1" + condition
+	args := conditionArgs
+	if r.URL.Query().Get("available") == "true" {
+		query += " AND s.guardian_id IS NULL AND s.starts_at > NOW()"
+	}
+	query, args = utils.AddFiltersFor(r, query, args, map[string]string{"teacher_id": "s.teacher_id"})
+	query += " ORDER BY s.starts_at, t.last_name, s.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	slotList := make([]models.EventSlot, 0)
+	for rows.Next() {
+		var slot models.EventSlot
+		err := scanEventSlot(rows, &slot)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		slotList = append(slotList, slot)
+	}
+
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []models.EventSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(slotList),
+		Data:   slotList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the appointment slots of a parent evening, e.g. ?teacher_id=3&available=true for the ones still free
+func GetEventSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+	listEventSlots(w, r, " AND s.event_id = ?", eventId)
+}
+
+// To get the appointments a guardian has booked
+func GetEventSlotsForAGuardian(w http.ResponseWriter, r *http.Request) {
+	guardianId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+	listEventSlots(w, r, " AND s.guardian_id = ?", guardianId)
+}
+
+// To split a parent evening into appointment slots for each teacher, e.g.
+// {"teacher_ids": [3, 4], "slot_minutes": 10, "gap_minutes": 2}. A teacher can't have slots
+// at the same time at two parent evenings.
+func GenerateEventSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		TeacherIDs  []int `json:"teacher_ids"`
+		SlotMinutes int   `json:"slot_minutes"`
+		GapMinutes  int   `json:"gap_minutes"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&request)
+	if err != nil || len(request.TeacherIDs) == 0 {
+		http.Error(w, "❌ Invalid request body, teacher_ids is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if request.SlotMinutes == 0 {
+		request.SlotMinutes = 10
+	}
+	if request.SlotMinutes < 1 || request.GapMinutes < 0 {
+		http.Error(w, "❌ slot_minutes must be positive and gap_minutes can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	event, status, err := loadParentEvening(db, eventId)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	startsAt, _ := parseDateTime(event.StartsAt)
+	endsAt, _ := parseDateTime(event.EndsAt)
+
+	slotLength := time.Duration(request.SlotMinutes) * time.Minute
+	step := slotLength + time.Duration(request.GapMinutes)*time.Minute
+	var starts []time.Time
+	for start := startsAt; !start.Add(slotLength).After(endsAt); start = start.Add(step) {
+		starts = append(starts, start)
+	}
+	if len(starts) == 0 {
+		http.Error(w, "❌ The evening is too short for one slot", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	added := make([]models.EventSlot, 0, len(starts)*len(request.TeacherIDs))
+	seen := map[int]bool{}
+	for _, teacherId := range request.TeacherIDs {
+		if seen[teacherId] {
+			continue
+		}
+		seen[teacherId] = true
+
+		var teacherName string
+		err := tx.QueryRow("SELECT CONCAT(first_name, ' ', last_name) FROM teachers WHERE id = ? AND deleted_at IS NULL", teacherId).Scan(&teacherName)
+		if err == sql.ErrNoRows {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("❌ Teacher %d does not exist", teacherId), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to retrieve teacher")
+			http.Error(w, "❌ Unable to retrieve teacher", http.StatusInternalServerError)
+			return
+		}
+
+		// Any slot of the teacher's that overlaps the evening, at this or another event, is a clash
+		var clashes int
+		err = tx.QueryRow(
+			"SELECT COUNT(*) FROM event_slots WHERE teacher_id = ? AND starts_at < ? AND ends_at > ?",
+			teacherId, endsAt.Format(DateTimeLayout), startsAt.Format(DateTimeLayout),
+		).Scan(&clashes)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to check the teacher's slots")
+			http.Error(w, "❌ Unable to check the teacher's slots", http.StatusInternalServerError)
+			return
+		}
+		if clashes > 0 {
+			tx.Rollback()
+			http.Error(w, "❌ "+teacherName+" already has appointment slots at that time", http.StatusConflict)
+			return
+		}
+
+		for _, start := range starts {
+			slot := models.EventSlot{
+				EventID:   eventId,
+				TeacherID: teacherId,
+				Teacher:   teacherName,
+				StartsAt:  start.Format(DateTimeLayout),
+				EndsAt:    start.Add(slotLength).Format(DateTimeLayout),
+			}
+			res, err := tx.Exec(
+				"INSERT INTO event_slots (event_id, teacher_id, starts_at, ends_at) VALUES (?, ?, ?, ?)",
+				slot.EventID, slot.TeacherID, slot.StartsAt, slot.EndsAt,
+			)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error inserting data into database")
+				http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+				return
+			}
+			lastID, err := res.LastInsertId()
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error getting last insert ID")
+				http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+				return
+			}
+			slot.ID = int(lastID)
+			added = append(added, slot)
+		}
+
+		err = RecordAudit(tx, r, AuditCreate, "event_slots", eventId, nil, map[string]interface{}{"teacher_id": teacherId, "slots": len(starts)})
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []models.EventSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(added),
+		Data:   added,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To book an appointment slot for a guardian to see a teacher about their child.
+// The slot is only taken if it is still free, so two guardians can't book the same one,
+// and a guardian can't be in two appointments at once.
+func BookEventSlotHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+	slotId, err := strconv.Atoi(r.PathValue("slotId"))
+	if err != nil {
+		http.Error(w, "❌ Invalid slot id", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		GuardianID int `json:"guardian_id"`
+		StudentID  int `json:"student_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&request)
+	if err != nil || request.GuardianID == 0 || request.StudentID == 0 {
+		http.Error(w, "❌ Invalid request body, guardian_id and student_id are required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	event, status, err := loadParentEvening(db, eventId)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var slot models.EventSlot
+	err = scanEventSlot(db.QueryRow(eventSlotSelect+" WHERE s.id = ? AND s.event_id = ?", slotId, eventId), &slot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Slot not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve slot")
+		http.Error(w, "❌ Unable to retrieve slot", http.StatusInternalServerError)
+		return
+	}
+	if startsAt, _ := parseDateTime(slot.StartsAt); startsAt.Before(time.Now()) {
+		http.Error(w, "❌ The slot has already started", http.StatusConflict)
+		return
+	}
+
+	var linked int
+	err = db.QueryRow("SELECT COUNT(*) FROM student_guardians WHERE guardian_id = ? AND student_id = ?", request.GuardianID, request.StudentID).Scan(&linked)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check the guardian's children")
+		http.Error(w, "❌ Unable to check the guardian's children", http.StatusInternalServerError)
+		return
+	}
+	if linked == 0 {
+		http.Error(w, "❌ The student is not linked to this guardian", http.StatusBadRequest)
+		return
+	}
+
+	if len(event.ClassIDs) > 0 {
+		placeholders := make([]string, len(event.ClassIDs))
+		args := []interface{}{request.StudentID}
+		for i, classId := range event.ClassIDs {
+			placeholders[i] = "?"
+			args = append(args, classId)
+		}
+		var enrolled int
+		err = db.QueryRow("SELECT COUNT(*) FROM enrollments WHERE student_id = ? AND class_id IN ("+strings.Join(placeholders, ", ")+")", args...).Scan(&enrolled)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to check enrollment")
+			http.Error(w, "❌ Unable to check enrollment", http.StatusInternalServerError)
+			return
+		}
+		if enrolled == 0 {
+			http.Error(w, "❌ The student is not in a class this parent evening is for", http.StatusForbidden)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Locking the guardian's bookings stops them booking two overlapping slots at once
+	var clashes, sameTeacher int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM event_slots WHERE guardian_id = ? AND id <> ? AND starts_at < ? AND ends_at > ? FOR UPDATE",
+		request.GuardianID, slotId, slot.EndsAt, slot.StartsAt,
+	).Scan(&clashes)
+	if err == nil {
+		err = tx.QueryRow(
+			"SELECT COUNT(*) FROM event_slots WHERE event_id = ? AND teacher_id = ? AND student_id = ? AND id <> ?",
+			eventId, slot.TeacherID, request.StudentID, slotId,
+		).Scan(&sameTeacher)
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to check bookings")
+		http.Error(w, "❌ Unable to check bookings", http.StatusInternalServerError)
+		return
+	}
+	if clashes > 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The guardian already has an appointment at that time", http.StatusConflict)
+		return
+	}
+	if sameTeacher > 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The student already has an appointment with "+slot.Teacher+" this evening", http.StatusConflict)
+		return
+	}
+
+	bookedAt := time.Now().Format(DateTimeLayout)
+	result, err := tx.Exec(
+		"UPDATE event_slots SET guardian_id = ?, student_id = ?, booked_at = ? WHERE id = ? AND guardian_id IS NULL",
+		request.GuardianID, request.StudentID, bookedAt, slotId,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error booking slot")
+		http.Error(w, "❌ Error booking slot", http.StatusInternalServerError)
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The slot has already been booked", http.StatusConflict)
+		return
+	}
+
+	previousSlot := slot
+	slot.GuardianID = request.GuardianID
+	slot.StudentID = request.StudentID
+	slot.BookedAt = bookedAt
+
+	err = RecordAudit(tx, r, AuditUpdate, "event_slots", slot.ID, previousSlot, slot)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// To cancel the booking of an appointment slot, freeing it for someone else
+func CancelEventSlotBookingHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+	slotId, err := strconv.Atoi(r.PathValue("slotId"))
+	if err != nil {
+		http.Error(w, "❌ Invalid slot id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var slot models.EventSlot
+	err = scanEventSlot(db.QueryRow(eventSlotSelect+" WHERE s.id = ? AND s.event_id = ?", slotId, eventId), &slot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Slot not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve slot")
+		http.Error(w, "❌ Unable to retrieve slot", http.StatusInternalServerError)
+		return
+	}
+	if slot.GuardianID == 0 {
+		http.Error(w, "❌ The slot is not booked", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE event_slots SET guardian_id = NULL, student_id = NULL, booked_at = NULL WHERE id = ? AND guardian_id = ?", slotId, slot.GuardianID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to cancel booking")
+		http.Error(w, "❌ Unable to cancel booking", http.StatusInternalServerError)
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	previousSlot := slot
+	slot.GuardianID, slot.StudentID, slot.BookedAt = 0, 0, ""
+	err = RecordAudit(tx, r, AuditUpdate, "event_slots", slot.ID, previousSlot, slot)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// To remove an appointment slot nobody has booked, e.g. when a teacher leaves early
+func DeleteEventSlotHandler(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+	slotId, err := strconv.Atoi(r.PathValue("slotId"))
+	if err != nil {
+		http.Error(w, "❌ Invalid slot id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var slot models.EventSlot
+	err = scanEventSlot(db.QueryRow(eventSlotSelect+" WHERE s.id = ? AND s.event_id = ?", slotId, eventId), &slot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Slot not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve slot")
+		http.Error(w, "❌ Unable to retrieve slot", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("DELETE FROM event_slots WHERE id = ? AND guardian_id IS NULL", slotId)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete slot")
+		http.Error(w, "❌ Unable delete slot", http.StatusInternalServerError)
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The slot is booked, cancel the booking first", http.StatusConflict)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "event_slots", slotId, slot, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Slot successfully deleted",
+		ID:     slotId,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,885 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/ical"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const eventColumns = "e.id, e.title, e.description, e.category, e.location, e.starts_at, e.ends_at, e.all_day, e.recurrence, e.created_by, e.version"
+
+// The feed name signed into school calendar subscription tokens
+const schoolEventsFeed = "school-events"
+
+var eventCategories = map[string]bool{
+	"general":        true,
+	"open_day":       true,
+	"sports":         true,
+	"parent_evening": true,
+	"performance":    true,
+	"trip":           true,
+	"meeting":        true,
+}
+
+var eventFilterFields = map[string]string{
+	"category":   "e.category",
+	"created_by": "e.created_by",
+}
+
+var eventSortFields = map[string]bool{
+	"title":     true,
+	"starts_at": true,
+	"category":  true,
+}
+
+func scanEvent(row interface{ Scan(...interface{}) error }, event *models.Event) error {
+	return row.Scan(
+		&event.ID,
+		&event.Title,
+		&event.Description,
+		&event.Category,
+		&event.Location,
+		&event.StartsAt,
+		&event.EndsAt,
+		&event.AllDay,
+		&event.Recurrence,
+		&event.CreatedBy,
+		&event.Version,
+	)
+}
+
+// To match events a class sees: those for the whole school and those aimed at the class
+func eventForClassCondition(alias string) string {
+	return " AND (NOT EXISTS (SELECT 1 FROM event_classes ec WHERE ec.event_id = " + alias + "id)" +
+		" OR EXISTS (SELECT 1 FROM event_classes ec WHERE ec.event_id = " + alias + "id AND ec.class_id = ?))"
+}
+
+// To check an event before it is written and put its times in the stored form.
+// It returns the HTTP status to answer with when invalid.
+func validateEvent(db Queryer, event *models.Event) (int, error) {
+	event.Title = strings.TrimSpace(event.Title)
+	event.Description = strings.TrimSpace(event.Description)
+	event.Location = strings.TrimSpace(event.Location)
+	if event.Title == "" || event.StartsAt == "" {
+		return http.StatusBadRequest, errors.New("❌ title and starts_at are required")
+	}
+
+	if event.Category == "" {
+		event.Category = "general"
+	}
+	if !eventCategories[event.Category] {
+		return http.StatusBadRequest, errors.New("❌ category must be general, open_day, sports, parent_evening, performance, trip or meeting")
+	}
+
+	startsAt, err := parseDateTime(event.StartsAt)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	var endsAt time.Time
+	if event.EndsAt != "" {
+		endsAt, err = parseDateTime(event.EndsAt)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	// All day events run from the start of their first day to the end of their last
+	if event.AllDay {
+		startsAt = time.Date(startsAt.Year(), startsAt.Month(), startsAt.Day(), 0, 0, 0, 0, time.Local)
+		if endsAt.IsZero() {
+			endsAt = startsAt
+		}
+		endsAt = time.Date(endsAt.Year(), endsAt.Month(), endsAt.Day(), 23, 59, 59, 0, time.Local)
+	} else if endsAt.IsZero() {
+		endsAt = startsAt.Add(time.Hour)
+	}
+	if !endsAt.After(startsAt) {
+		return http.StatusBadRequest, errors.New("❌ ends_at must be after starts_at")
+	}
+	event.StartsAt = startsAt.Format(DateTimeLayout)
+	event.EndsAt = endsAt.Format(DateTimeLayout)
+
+	if event.Recurrence != "" {
+		if event.Category == "parent_evening" {
+			return http.StatusBadRequest, errors.New("❌ Parent evenings can't repeat, add each evening on its own")
+		}
+		recurrence, err := ical.ParseRRule(event.Recurrence, time.Local)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		event.Recurrence = recurrence.String()
+	}
+
+	seen := map[int]bool{}
+	classIds := make([]int, 0, len(event.ClassIDs))
+	for _, classId := range event.ClassIDs {
+		if seen[classId] {
+			continue
+		}
+		seen[classId] = true
+
+		var exists int
+		err := db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ? AND deleted_at IS NULL", classId).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Class %d does not exist", classId)
+		}
+		classIds = append(classIds, classId)
+	}
+	event.ClassIDs = classIds
+
+	return http.StatusOK, nil
+}
+
+// To replace the classes an event is aimed at
+func saveEventClasses(tx *sql.Tx, event models.Event) error {
+	_, err := tx.Exec("DELETE FROM event_classes WHERE event_id = ?", event.ID)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error updating event classes")
+	}
+	for _, classId := range event.ClassIDs {
+		_, err := tx.Exec("INSERT INTO event_classes (event_id, class_id) VALUES (?, ?)", event.ID, classId)
+		if err != nil {
+			return utils.ErrorHandler(err, "❌ Error updating event classes")
+		}
+	}
+	return nil
+}
+
+// To fill in the classes of each event
+func loadEventClasses(db *sql.DB, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	index := map[int]int{}
+	placeholders := make([]string, len(events))
+	args := make([]interface{}, len(events))
+	for i := range events {
+		events[i].ClassIDs = []int{}
+		index[events[i].ID] = i
+		placeholders[i] = "?"
+		args[i] = events[i].ID
+	}
+
+	rows, err := db.Query("SELECT event_id, class_id FROM event_classes WHERE event_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY class_id", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to retrieve event classes")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventId, classId int
+		if err := rows.Scan(&eventId, &classId); err != nil {
+			return utils.ErrorHandler(err, "❌ Unable to retrieve event classes")
+		}
+		events[index[eventId]].ClassIDs = append(events[index[eventId]].ClassIDs, classId)
+	}
+	return nil
+}
+
+// To load one event with its classes
+func loadEvent(db *sql.DB, id int) (models.Event, int, error) {
+	var event models.Event
+	err := scanEvent(db.QueryRow("SELECT "+eventColumns+" FROM events e WHERE e.id = ? AND e.deleted_at IS NULL", id), &event)
+	if err == sql.ErrNoRows {
+		return event, http.StatusNotFound, errors.New("❌ Event not found")
+	} else if err != nil {
+		return event, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+
+	events := []models.Event{event}
+	if err := loadEventClasses(db, events); err != nil {
+		return event, http.StatusInternalServerError, err
+	}
+	return events[0], http.StatusOK, nil
+}
+
+// To work out when an event happens between from and to, expanding its recurrence
+func eventOccurrences(event models.Event, from, to time.Time) []models.EventOccurrence {
+	startsAt, err := parseDateTime(event.StartsAt)
+	if err != nil {
+		return nil
+	}
+	endsAt, err := parseDateTime(event.EndsAt)
+	if err != nil {
+		return nil
+	}
+	length := endsAt.Sub(startsAt)
+
+	starts := []time.Time{startsAt}
+	if event.Recurrence != "" {
+		recurrence, err := ical.ParseRRule(event.Recurrence, time.Local)
+		if err != nil {
+			return nil
+		}
+		starts = recurrence.Occurrences(startsAt, length, from, to)
+	} else if !startsAt.Before(to) || !endsAt.After(from) {
+		return nil
+	}
+
+	occurrences := make([]models.EventOccurrence, len(starts))
+	for i, start := range starts {
+		occurrences[i] = models.EventOccurrence{
+			EventID:  event.ID,
+			Title:    event.Title,
+			Category: event.Category,
+			Location: event.Location,
+			StartsAt: start.Format(DateTimeLayout),
+			EndsAt:   start.Add(length).Format(DateTimeLayout),
+			AllDay:   event.AllDay,
+		}
+	}
+	return occurrences
+}
+
+// To read the class a listing is for from ?class_id=, 0 meaning the whole school
+func classFromRequest(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("class_id")
+	if value == "" {
+		return 0, nil
+	}
+	classId, err := strconv.Atoi(value)
+	if err != nil || classId < 1 {
+		return 0, errors.New("❌ Invalid class_id")
+	}
+	return classId, nil
+}
+
+// To get event definitions, filtered by e.g. ?category=sports or ?class_id=4 for what one class sees
+func GetEventsHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := classFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + eventColumns + " FROM events e WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND e.deleted_at IS NULL"
+	}
+	if classId != 0 {
+		query += eventForClassCondition("e.")
+		args = append(args, classId)
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, eventFilterFields)
+	if sorted := utils.AddSortingFor(r, query, eventSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY e.starts_at, e.id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	eventList := make([]models.Event, 0)
+	for rows.Next() {
+		var event models.Event
+		err := scanEvent(rows, &event)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		eventList = append(eventList, event)
+	}
+
+	if err := loadEventClasses(db, eventList); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Event `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(eventList),
+		Data:   eventList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get what is on between ?from= and ?to= (default the next 31 days, at most a year),
+// with repeating events expanded into each date. ?class_id= narrows it to what one class sees.
+func GetEventCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := classFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parseDateTime(value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 31)
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parseDateTime(value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(value) == len(DateLayout) {
+			to = to.AddDate(0, 0, 1) // A date alone includes the whole day
+		}
+	}
+	if !to.After(from) || to.After(from.AddDate(1, 0, 1)) {
+		http.Error(w, "❌ to must be after from and at most a year later", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + eventColumns + " FROM events e WHERE e.deleted_at IS NULL AND e.starts_at < ? AND (e.recurrence <> '' OR e.ends_at > ?)"
+	args := []interface{}{to.Format(DateTimeLayout), from.Format(DateTimeLayout)}
+	if classId != 0 {
+		query += eventForClassCondition("e.")
+		args = append(args, classId)
+	}
+	query, args = utils.AddFiltersFor(r, query, args, eventFilterFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	occurrences := make([]models.EventOccurrence, 0)
+	for rows.Next() {
+		var event models.Event
+		err := scanEvent(rows, &event)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		occurrences = append(occurrences, eventOccurrences(event, from, to)...)
+	}
+
+	// The stored time format sorts as text
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].StartsAt < occurrences[j].StartsAt })
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.EventOccurrence `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(occurrences),
+		Data:   occurrences,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+func GetOneEventHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	event, status, err := loadEvent(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(event.ID, event.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// To add events to the calendar
+func AddEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var newEvents []models.Event
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newEvents)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newEvents {
+		status, err := validateEvent(db, &newEvents[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedEvents := make([]models.Event, len(newEvents))
+	for i, newEvent := range newEvents {
+		newEvent.Version = 1
+		newEvent.CreatedBy = ActorID(r)
+		res, err := tx.Exec(
+			"INSERT INTO events (title, description, category, location, starts_at, ends_at, all_day, recurrence, created_by, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			newEvent.Title,
+			newEvent.Description,
+			newEvent.Category,
+			newEvent.Location,
+			newEvent.StartsAt,
+			newEvent.EndsAt,
+			newEvent.AllDay,
+			newEvent.Recurrence,
+			newEvent.CreatedBy,
+			newEvent.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newEvent.ID = int(lastID)
+
+		err = saveEventClasses(tx, newEvent)
+		if err == nil {
+			err = RecordAudit(tx, r, AuditCreate, "events", newEvent.ID, nil, newEvent)
+		}
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedEvents[i] = newEvent
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Event `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedEvents),
+		Data:   addedEvents,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update an event. A parent evening with appointment slots keeps its times and category
+// until its slots are removed.
+func EditEventHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingEvent, status, err := loadEvent(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingEvent.ID, existingEvent.Version) {
+		return
+	}
+
+	previousEvent := existingEvent
+
+	// class_ids is a list so it is taken out of the patch and set directly
+	if classIds, ok := input["class_ids"]; ok {
+		delete(input, "class_ids")
+		data, _ := json.Marshal(classIds)
+		existingEvent.ClassIDs = nil
+		if err := json.Unmarshal(data, &existingEvent.ClassIDs); err != nil {
+			http.Error(w, "❌ class_ids must be a list of class ids", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = ApplyPatch(&existingEvent, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingEvent.CreatedBy = previousEvent.CreatedBy
+
+	status, err = validateEvent(db, &existingEvent)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if existingEvent.StartsAt != previousEvent.StartsAt || existingEvent.EndsAt != previousEvent.EndsAt || existingEvent.Category != previousEvent.Category {
+		var slots int
+		err := db.QueryRow("SELECT COUNT(*) FROM event_slots WHERE event_id = ?", id).Scan(&slots)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to check appointment slots")
+			http.Error(w, "❌ Unable to check appointment slots", http.StatusInternalServerError)
+			return
+		}
+		if slots > 0 {
+			http.Error(w, "❌ The event has appointment slots, remove them before changing its times or category", http.StatusConflict)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE events SET title = ?, description = ?, category = ?, location = ?, starts_at = ?, ends_at = ?, all_day = ?, recurrence = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingEvent.Title,
+		existingEvent.Description,
+		existingEvent.Category,
+		existingEvent.Location,
+		existingEvent.StartsAt,
+		existingEvent.EndsAt,
+		existingEvent.AllDay,
+		existingEvent.Recurrence,
+		existingEvent.ID,
+		previousEvent.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating event")
+		http.Error(w, "❌ Error updating event", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingEvent.Version = previousEvent.Version + 1
+
+	err = saveEventClasses(tx, existingEvent)
+	if err == nil {
+		err = RecordAudit(tx, r, AuditUpdate, "events", existingEvent.ID, previousEvent, existingEvent)
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingEvent.ID, existingEvent.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingEvent)
+}
+
+func DeleteOneEventHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	deletedEvent, status, err := loadEvent(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedEvent.ID, deletedEvent.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE events SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedEvent.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete event")
+		http.Error(w, "❌ Unable delete event", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "events", id, deletedEvent, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Event successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreEventHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "events", "Event")
+}
+
+// To build the calendar of school events a class sees, or every event when classId is 0.
+// Repeating events keep their RRULE so calendar apps expand them.
+func schoolEventsCalendar(db *sql.DB, classId int) (ical.Calendar, error) {
+	calendar := ical.Calendar{Name: SchoolName() + " events"}
+
+	query := "SELECT " + eventColumns + " FROM events e WHERE e.deleted_at IS NULL"
+	var args []interface{}
+	if classId != 0 {
+		var className string
+		err := db.QueryRow("SELECT name FROM classes WHERE id = ? AND deleted_at IS NULL", classId).Scan(&className)
+		if err == sql.ErrNoRows {
+			return calendar, fmt.Errorf("❌ Class %d does not exist", classId)
+		} else if err != nil {
+			return calendar, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		}
+		calendar.Name += " - " + className
+
+		query += eventForClassCondition("e.")
+		args = append(args, classId)
+	}
+	query += " ORDER BY e.starts_at, e.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.Event
+		if err := scanEvent(rows, &event); err != nil {
+			return calendar, utils.ErrorHandler(err, "❌ Error scanning Database results")
+		}
+
+		start, _ := parseDateTime(event.StartsAt)
+		end, _ := parseDateTime(event.EndsAt)
+		if event.AllDay {
+			// DTEND of an all day event is the day after the last
+			end = time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, time.Local)
+		}
+
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("event-%d@schoolly", event.ID),
+			Summary:     event.Title,
+			Description: event.Description,
+			Location:    event.Location,
+			Start:       start,
+			End:         end,
+			AllDay:      event.AllDay,
+			RRule:       event.Recurrence,
+		})
+	}
+	return calendar, nil
+}
+
+// To answer with the school events calendar as an iCalendar file
+func writeSchoolEventsICS(w http.ResponseWriter, classId int) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	calendar, err := schoolEventsCalendar(db, classId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filename := "school-events.ics"
+	if classId != 0 {
+		filename = fmt.Sprintf("class-%d-events.ics", classId)
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	calendar.WriteTo(w)
+}
+
+// To download the events calendar as an .ics file, ?class_id= for what one class sees
+func GetEventsICSHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := classFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeSchoolEventsICS(w, classId)
+}
+
+// To get the link calendar apps can subscribe to for the school's events, or one class's with ?class_id=
+func GetEventsFeedURLHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := classFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url := "/feeds/events.ics?token=" + utils.FeedToken(schoolEventsFeed, classId)
+	if classId != 0 {
+		url = fmt.Sprintf("/feeds/events.ics?class_id=%d&token=%s", classId, utils.FeedToken(schoolEventsFeed, classId))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		URL    string `json:"url"`
+	}{
+		Status: "success",
+		URL:    url,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To serve the subscription feed of school events, authenticated by its token instead of a login
+func SchoolEventsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := classFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !utils.VerifyFeedToken(schoolEventsFeed, classId, r.URL.Query().Get("token")) {
+		http.Error(w, "❌ Invalid feed token", http.StatusUnauthorized)
+		return
+	}
+	writeSchoolEventsICS(w, classId)
+}
@@ -0,0 +1,539 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/pdf"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const examSeatSelect = `SELECT s.exam_id, e.name, s.student_id, st.first_name, st.last_name, c.name, s.room_id, rm.name, s.seat_number
+	FROM exam_seats s
+	JOIN exams e ON e.id = s.exam_id
+	JOIN students st ON st.id = s.student_id
+	JOIN classes c ON c.id = e.class_id
+	JOIN rooms rm ON rm.id = s.room_id`
+
+func scanExamSeat(row interface{ Scan(...interface{}) error }, seat *models.ExamSeat) error {
+	return row.Scan(
+		&seat.ExamID,
+		&seat.Exam,
+		&seat.StudentID,
+		&seat.FirstName,
+		&seat.LastName,
+		&seat.Class,
+		&seat.RoomID,
+		&seat.Room,
+		&seat.SeatNumber,
+	)
+}
+
+// RoomPlan is how full one room of a seating plan is and which classes sit in it
+type RoomPlan struct {
+	RoomID   int            `json:"room_id"`
+	Room     string         `json:"room"`
+	Capacity int            `json:"capacity"`
+	Seated   int            `json:"seated"`
+	ByClass  map[string]int `json:"by_class"`
+	quota    float64
+	nextSeat int
+}
+
+// To seat candidates in rooms. The lists of each exam are interleaved so neighbouring seats
+// go to different classes, then each candidate goes to the room that is least full for its
+// size, which spreads every class over the rooms in proportion to their capacity.
+func planSeating(candidates [][]models.ExamSeat, rooms []models.Room) ([]models.ExamSeat, []*RoomPlan, error) {
+	total := 0
+	for _, list := range candidates {
+		total += len(list)
+	}
+
+	capacity := 0
+	for _, room := range rooms {
+		capacity += room.Capacity
+	}
+	if capacity < total {
+		return nil, nil, fmt.Errorf("❌ %d candidates need seats but the rooms only hold %d", total, capacity)
+	}
+
+	plans := make([]*RoomPlan, len(rooms))
+	for i, room := range rooms {
+		plans[i] = &RoomPlan{
+			RoomID:   room.ID,
+			Room:     room.Name,
+			Capacity: room.Capacity,
+			ByClass:  map[string]int{},
+			quota:    float64(total) * float64(room.Capacity) / float64(capacity),
+		}
+	}
+
+	// Largest exams first so the smaller ones are spread between them
+	order := make([][]models.ExamSeat, len(candidates))
+	copy(order, candidates)
+	sort.SliceStable(order, func(i, j int) bool { return len(order[i]) > len(order[j]) })
+
+	seats := make([]models.ExamSeat, 0, total)
+	for i := 0; len(seats) < total; i++ {
+		for _, list := range order {
+			if i >= len(list) {
+				continue
+			}
+
+			var best *RoomPlan
+			for _, plan := range plans {
+				if plan.Seated >= plan.Capacity || plan.quota == 0 {
+					continue
+				}
+				if best == nil || float64(plan.Seated)/plan.quota < float64(best.Seated)/best.quota {
+					best = plan
+				}
+			}
+			if best == nil {
+				return nil, nil, errors.New("❌ Ran out of seats while planning")
+			}
+
+			seat := list[i]
+			best.nextSeat++
+			best.Seated++
+			best.ByClass[seat.Class]++
+			seat.RoomID = best.RoomID
+			seat.Room = best.Room
+			seat.SeatNumber = best.nextSeat
+			seats = append(seats, seat)
+		}
+	}
+
+	sort.Slice(seats, func(i, j int) bool {
+		if seats[i].RoomID != seats[j].RoomID {
+			return seats[i].RoomID < seats[j].RoomID
+		}
+		return seats[i].SeatNumber < seats[j].SeatNumber
+	})
+	return seats, plans, nil
+}
+
+// To plan the seating of exams sat together on one day. Each exam's candidates are the students
+// enrolled in its class that term. Rooms are those in room_ids, else the exams' own rooms, else
+// as few of the free rooms as will hold everyone. With dry_run the plan is returned without being saved.
+func GenerateExamSeatingHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ExamIDs []int `json:"exam_ids"`
+		RoomIDs []int `json:"room_ids"`
+		DryRun  bool  `json:"dry_run"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil || len(request.ExamIDs) == 0 {
+		http.Error(w, "❌ Invalid request body, exam_ids is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	// The exams and the window they are sat in
+	exams := make([]models.Exam, 0, len(request.ExamIDs))
+	inPlan := map[int]bool{}
+	for _, id := range request.ExamIDs {
+		if inPlan[id] {
+			continue
+		}
+		exam, status, err := loadExam(db, id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s: %d", err.Error(), id), status)
+			return
+		}
+		if len(exams) > 0 && exam.ExamDate != exams[0].ExamDate {
+			http.Error(w, "❌ Exams planned together must be on the same day", http.StatusBadRequest)
+			return
+		}
+		inPlan[id] = true
+		exams = append(exams, exam)
+	}
+
+	windowStart, windowEnd := examStart(exams[0]), examEnd(exams[0])
+	for _, exam := range exams[1:] {
+		if examStart(exam).Before(windowStart) {
+			windowStart = examStart(exam)
+		}
+		if examEnd(exam).After(windowEnd) {
+			windowEnd = examEnd(exam)
+		}
+	}
+
+	// The candidates of each exam, refusing a plan where anyone would sit two exams at once
+	candidates := make([][]models.ExamSeat, len(exams))
+	sitting := map[int]models.Exam{}
+	for i, exam := range exams {
+		rows, err := db.Query(`SELECT st.id, st.first_name, st.last_name, c.name FROM enrollments en
+			JOIN students st ON st.id = en.student_id AND st.deleted_at IS NULL
+			JOIN classes c ON c.id = en.class_id
+			WHERE en.class_id = ? AND en.term_id = ?
+			ORDER BY st.last_name, st.first_name, st.id`, exam.ClassID, exam.TermID)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to retrieve candidates")
+			http.Error(w, "❌ Unable to retrieve candidates", http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			seat := models.ExamSeat{ExamID: exam.ID, Exam: exam.Name}
+			if err := rows.Scan(&seat.StudentID, &seat.FirstName, &seat.LastName, &seat.Class); err != nil {
+				rows.Close()
+				utils.ErrorHandler(err, "❌ Unable to retrieve candidates")
+				http.Error(w, "❌ Unable to retrieve candidates", http.StatusInternalServerError)
+				return
+			}
+			if other, ok := sitting[seat.StudentID]; ok && examsOverlap(other, exam) {
+				rows.Close()
+				http.Error(w, fmt.Sprintf("❌ %s %s would sit %s and %s at the same time", seat.FirstName, seat.LastName, other.Name, exam.Name), http.StatusConflict)
+				return
+			}
+			sitting[seat.StudentID] = exam
+			candidates[i] = append(candidates[i], seat)
+		}
+		rows.Close()
+	}
+
+	// Rooms already holding another plan at an overlapping time can't be used
+	busyArgs := []interface{}{exams[0].ExamDate, windowEnd.Format(TimeLayout), windowStart.Format(TimeLayout)}
+	busyQuery := "SELECT DISTINCT s.room_id FROM exam_seats s JOIN exams e ON e.id = s.exam_id WHERE e.deleted_at IS NULL" + examOverlapCondition
+	for id := range inPlan {
+		busyQuery += " AND e.id <> ?"
+		busyArgs = append(busyArgs, id)
+	}
+	rows, err := db.Query(busyQuery, busyArgs...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check room usage")
+		http.Error(w, "❌ Unable to check room usage", http.StatusInternalServerError)
+		return
+	}
+	busy := map[int]bool{}
+	for rows.Next() {
+		var roomId int
+		if err := rows.Scan(&roomId); err == nil {
+			busy[roomId] = true
+		}
+	}
+	rows.Close()
+
+	roomIds := request.RoomIDs
+	if len(roomIds) == 0 {
+		for _, exam := range exams {
+			if exam.RoomID != 0 {
+				roomIds = append(roomIds, exam.RoomID)
+			}
+		}
+	}
+
+	rooms := make([]models.Room, 0)
+	if len(roomIds) > 0 {
+		chosen := map[int]bool{}
+		for _, id := range roomIds {
+			if chosen[id] {
+				continue
+			}
+			chosen[id] = true
+			var room models.Room
+			err := scanRoom(db.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE id = ?", id), &room)
+			if err == sql.ErrNoRows {
+				http.Error(w, fmt.Sprintf("❌ Room %d does not exist", id), http.StatusBadRequest)
+				return
+			} else if err != nil {
+				utils.ErrorHandler(err, "❌ Unable to retrieve room")
+				http.Error(w, "❌ Unable to retrieve room", http.StatusInternalServerError)
+				return
+			}
+			if busy[room.ID] {
+				http.Error(w, "❌ "+room.Name+" is already used by another exam at that time", http.StatusConflict)
+				return
+			}
+			rooms = append(rooms, room)
+		}
+	} else {
+		rows, err := db.Query("SELECT " + roomColumns + " FROM rooms WHERE capacity > 0 ORDER BY capacity DESC, id")
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to retrieve rooms")
+			http.Error(w, "❌ Unable to retrieve rooms", http.StatusInternalServerError)
+			return
+		}
+		needed := len(sitting)
+		for rows.Next() && needed > 0 {
+			var room models.Room
+			if err := scanRoom(rows, &room); err != nil {
+				rows.Close()
+				utils.ErrorHandler(err, "❌ Unable to retrieve rooms")
+				http.Error(w, "❌ Unable to retrieve rooms", http.StatusInternalServerError)
+				return
+			}
+			if busy[room.ID] {
+				continue
+			}
+			rooms = append(rooms, room)
+			needed -= room.Capacity
+		}
+		rows.Close()
+	}
+
+	seats, plans, err := planSeating(candidates, rooms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if !request.DryRun {
+		tx, err := db.Begin()
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error starting transaction")
+			http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+			return
+		}
+
+		for _, exam := range exams {
+			_, err := tx.Exec("DELETE FROM exam_seats WHERE exam_id = ?", exam.ID)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error clearing exam seating")
+				http.Error(w, "❌ Error clearing exam seating", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		seatsPerExam := map[int]int{}
+		for _, seat := range seats {
+			_, err := tx.Exec(
+				"INSERT INTO exam_seats (exam_id, student_id, room_id, seat_number) VALUES (?, ?, ?, ?)",
+				seat.ExamID, seat.StudentID, seat.RoomID, seat.SeatNumber,
+			)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error saving exam seating")
+				http.Error(w, "❌ Error saving exam seating", http.StatusInternalServerError)
+				return
+			}
+			seatsPerExam[seat.ExamID]++
+		}
+
+		for _, exam := range exams {
+			err := RecordAudit(tx, r, AuditCreate, "exam_seats", exam.ID, nil, map[string]interface{}{"seats": seatsPerExam[exam.ID]})
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error committing transaction")
+			http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	status := "success"
+	if request.DryRun {
+		status = "dry run, nothing was saved"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !request.DryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Rooms  []*RoomPlan       `json:"rooms"`
+		Data   []models.ExamSeat `json:"data"`
+	}{
+		Status: status,
+		Count:  len(seats),
+		Rooms:  plans,
+		Data:   seats,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To get where the candidates of an exam sit
+func GetExamSeatingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if _, status, err := loadExam(db, id); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	rows, err := db.Query(examSeatSelect+" WHERE s.exam_id = ? ORDER BY rm.name, s.seat_number", id)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	seatList := make([]models.ExamSeat, 0)
+	for rows.Next() {
+		var seat models.ExamSeat
+		err := scanExamSeat(rows, &seat)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		seatList = append(seatList, seat)
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.ExamSeat `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(seatList),
+		Data:   seatList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To download printable seating charts for the rooms an exam is sat in, one page per room.
+// Each page lists everyone in the room at that time, whichever exam they are sitting.
+func GetExamSeatingChartHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	exam, status, err := loadExam(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	rows, err := db.Query(
+		examSeatSelect+` WHERE s.room_id IN (SELECT room_id FROM exam_seats WHERE exam_id = ?) AND e.deleted_at IS NULL`+
+			examOverlapCondition+` ORDER BY rm.name, s.seat_number`,
+		id, exam.ExamDate, exam.EndTime, exam.StartTime,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var roomOrder []int
+	byRoom := map[int][]models.ExamSeat{}
+	for rows.Next() {
+		var seat models.ExamSeat
+		if err := scanExamSeat(rows, &seat); err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := byRoom[seat.RoomID]; !ok {
+			roomOrder = append(roomOrder, seat.RoomID)
+		}
+		byRoom[seat.RoomID] = append(byRoom[seat.RoomID], seat)
+	}
+	if len(roomOrder) == 0 {
+		http.Error(w, "❌ No seating has been planned for this exam yet", http.StatusNotFound)
+		return
+	}
+
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		bottom = pdf.PageHeight - 60
+	)
+
+	examDay := exam.ExamDate
+	if day, err := time.Parse(DateLayout, exam.ExamDate); err == nil {
+		examDay = day.Format("Monday 2 January 2006")
+	}
+
+	doc := pdf.New("Seating chart - " + exam.Name + " - " + exam.ExamDate)
+	for page, roomId := range roomOrder {
+		seats := byRoom[roomId]
+		if page > 0 {
+			doc.AddPage()
+		}
+
+		header := func(continued bool) float64 {
+			doc.FillRect(0, 0, pdf.PageWidth, 90, 0.92)
+			title := seats[0].Room
+			if continued {
+				title += " (continued)"
+			}
+			doc.Text(left, 45, 22, true, title)
+			doc.Text(left, 70, 12, false, SchoolName()+" - exam seating")
+			doc.TextRight(right, 70, 10, false, examDay+", "+exam.StartTime[:5]+" to "+exam.EndTime[:5])
+
+			y := 120.0
+			doc.FillRect(left, y, right-left, 20, 0.85)
+			doc.Text(left+6, y+14, 10, true, "Seat")
+			doc.Text(left+60, y+14, 10, true, "Candidate")
+			doc.Text(left+260, y+14, 10, true, "Class")
+			doc.Text(left+340, y+14, 10, true, "Exam")
+			return y + 20
+		}
+
+		y := header(false)
+		for _, seat := range seats {
+			if y+20 > bottom {
+				doc.AddPage()
+				y = header(true)
+			}
+			y += 16
+			doc.Text(left+6, y, 11, true, strconv.Itoa(seat.SeatNumber))
+			doc.Text(left+60, y, 10, false, strings.TrimSpace(seat.LastName+", "+seat.FirstName))
+			doc.Text(left+260, y, 10, false, seat.Class)
+			doc.Text(left+340, y, 10, false, seat.Exam)
+			y += 4
+			doc.Line(left, y, right, y, 0.3)
+		}
+
+		doc.Text(left, pdf.PageHeight-30, 8, false, fmt.Sprintf("%d candidates. Generated on %s", len(seats), time.Now().Format("2 January 2006")))
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="seating-exam-%d.pdf"`, exam.ID))
+	w.Write(doc.Bytes())
+}
@@ -0,0 +1,722 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const examColumns = "e.id, e.term_id, e.subject_id, e.class_id, e.name, e.exam_date, e.start_time, e.duration_minutes, COALESCE(e.room_id, 0), e.version"
+
+// The longest an exam may run
+const maxExamMinutes = 600
+
+// SQL matching exams on a date whose times overlap a window given as start and end times of day.
+// Arguments: exam date, window end, window start.
+const examOverlapCondition = " AND e.exam_date = ? AND e.start_time < ? AND ADDTIME(e.start_time, SEC_TO_TIME(e.duration_minutes * 60)) > ?"
+
+var examFilterFields = map[string]string{
+	"term_id":    "e.term_id",
+	"class_id":   "e.class_id",
+	"subject_id": "e.subject_id",
+	"exam_date":  "e.exam_date",
+	"room_id":    "e.room_id",
+}
+
+var examSortFields = map[string]bool{
+	"name":       true,
+	"exam_date":  true,
+	"start_time": true,
+}
+
+func scanExam(row interface{ Scan(...interface{}) error }, exam *models.Exam, extra ...interface{}) error {
+	err := row.Scan(append([]interface{}{
+		&exam.ID,
+		&exam.TermID,
+		&exam.SubjectID,
+		&exam.ClassID,
+		&exam.Name,
+		&exam.ExamDate,
+		&exam.StartTime,
+		&exam.DurationMinutes,
+		&exam.RoomID,
+		&exam.Version,
+	}, extra...)...)
+	if err == nil {
+		exam.EndTime = examEnd(*exam).Format(TimeLayout)
+	}
+	return err
+}
+
+// To work out when an exam finishes on its day
+func examStart(exam models.Exam) time.Time {
+	start, _ := parseTimeOfDay(exam.StartTime)
+	return start
+}
+
+func examEnd(exam models.Exam) time.Time {
+	return examStart(exam).Add(time.Duration(exam.DurationMinutes) * time.Minute)
+}
+
+// To check whether two exams are sat at the same time
+func examsOverlap(a, b models.Exam) bool {
+	return a.ExamDate == b.ExamDate && examStart(a).Before(examEnd(b)) && examStart(b).Before(examEnd(a))
+}
+
+// To check an exam before it is written and fill in its term from the date when not given.
+// An exam may not overlap another exam of the same class. It returns the HTTP status to answer with when invalid.
+func validateExam(db Queryer, exam *models.Exam) (int, error) {
+	exam.Name = strings.TrimSpace(exam.Name)
+	if exam.Name == "" || exam.SubjectID == 0 || exam.ClassID == 0 || exam.ExamDate == "" || exam.StartTime == "" {
+		return http.StatusBadRequest, errors.New("❌ name, subject_id, class_id, exam_date, start_time and duration_minutes are required")
+	}
+	if exam.DurationMinutes <= 0 || exam.DurationMinutes > maxExamMinutes {
+		return http.StatusBadRequest, fmt.Errorf("❌ duration_minutes must be between 1 and %d", maxExamMinutes)
+	}
+	if _, err := time.Parse(DateLayout, exam.ExamDate); err != nil {
+		return http.StatusBadRequest, errors.New("❌ exam_date must be a date like 2025-12-01")
+	}
+
+	start, err := parseTimeOfDay(exam.StartTime)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	exam.StartTime = start.Format(TimeLayout)
+	end := examEnd(*exam)
+	if end.Day() != start.Day() {
+		return http.StatusBadRequest, errors.New("❌ An exam must finish on the day it starts")
+	}
+	exam.EndTime = end.Format(TimeLayout)
+
+	if exam.TermID == 0 {
+		term, err := CurrentTerm(db, exam.ExamDate)
+		if err == ErrNoCurrentTerm {
+			return http.StatusBadRequest, errors.New("❌ No term covers exam_date, give a term_id")
+		} else if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		exam.TermID = term.ID
+	} else {
+		var inTerm int
+		err := db.QueryRow("SELECT COUNT(*) FROM terms WHERE id = ? AND ? BETWEEN start_date AND end_date AND deleted_at IS NULL", exam.TermID, exam.ExamDate).Scan(&inTerm)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve term")
+		}
+		if inTerm == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ exam_date is not within term %d", exam.TermID)
+		}
+	}
+
+	checks := []struct {
+		query string
+		id    int
+		label string
+	}{
+		{"SELECT COUNT(*) FROM subjects WHERE id = ? AND deleted_at IS NULL", exam.SubjectID, "Subject"},
+		{"SELECT COUNT(*) FROM classes WHERE id = ? AND deleted_at IS NULL", exam.ClassID, "Class"},
+		{"SELECT COUNT(*) FROM rooms WHERE id = ?", exam.RoomID, "Room"},
+	}
+	for _, check := range checks {
+		if check.id == 0 {
+			continue
+		}
+		var exists int
+		err := db.QueryRow(check.query, check.id).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve "+strings.ToLower(check.label))
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ %s %d does not exist", check.label, check.id)
+		}
+	}
+
+	var clashId int
+	var clashName string
+	err = db.QueryRow(
+		"SELECT e.id, e.name FROM exams e WHERE e.class_id = ? AND e.id <> ? AND e.deleted_at IS NULL"+examOverlapCondition+" LIMIT 1",
+		exam.ClassID, exam.ID, exam.ExamDate, exam.EndTime, exam.StartTime,
+	).Scan(&clashId, &clashName)
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("❌ The class already sits %s (exam %d) at that time", clashName, clashId)
+	} else if err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check for clashing exams")
+	}
+
+	return http.StatusOK, nil
+}
+
+// To get exams, filtered by e.g. ?term_id=3&class_id=2 or ?exam_date=2025-12-01
+func GetExamsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + examColumns + " FROM exams e WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND e.deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, examFilterFields)
+	if sorted := utils.AddSortingFor(r, query, examSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY e.exam_date, e.start_time, e.id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	examList := make([]models.Exam, 0)
+	for rows.Next() {
+		var exam models.Exam
+		err := scanExam(rows, &exam)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		examList = append(examList, exam)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Exam `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(examList),
+		Data:   examList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To load one exam that hasn't been deleted
+func loadExam(db Queryer, id int) (models.Exam, int, error) {
+	var exam models.Exam
+	err := scanExam(db.QueryRow("SELECT "+examColumns+" FROM exams e WHERE e.id = ? AND e.deleted_at IS NULL", id), &exam)
+	if err == sql.ErrNoRows {
+		return exam, http.StatusNotFound, errors.New("❌ Exam not found")
+	} else if err != nil {
+		return exam, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	return exam, http.StatusOK, nil
+}
+
+func GetOneExamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	exam, status, err := loadExam(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(exam.ID, exam.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exam)
+}
+
+// To schedule exams
+func AddExamsHandler(w http.ResponseWriter, r *http.Request) {
+	var newExams []models.Exam
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newExams)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newExams {
+		newExams[i].ID = 0
+		status, err := validateExam(db, &newExams[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// Exams in the same request are checked against each other too
+		for j := 0; j < i; j++ {
+			if newExams[j].ClassID == newExams[i].ClassID && examsOverlap(newExams[j], newExams[i]) {
+				http.Error(w, fmt.Sprintf("❌ %s and %s are at the same time for the same class", newExams[j].Name, newExams[i].Name), http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedExams := make([]models.Exam, len(newExams))
+	for i, newExam := range newExams {
+		newExam.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO exams (term_id, subject_id, class_id, name, exam_date, start_time, duration_minutes, room_id, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			newExam.TermID,
+			newExam.SubjectID,
+			newExam.ClassID,
+			newExam.Name,
+			newExam.ExamDate,
+			newExam.StartTime,
+			newExam.DurationMinutes,
+			nullableID(newExam.RoomID),
+			newExam.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newExam.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "exams", newExam.ID, nil, newExam)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedExams[i] = newExam
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Exam `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedExams),
+		Data:   addedExams,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update an exam. Moving it to another class, day or time clears its seating so it gets planned again.
+func EditExamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingExam, status, err := loadExam(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingExam.ID, existingExam.Version) {
+		return
+	}
+
+	previousExam := existingExam
+
+	err = ApplyPatch(&existingExam, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err = validateExam(db, &existingExam)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE exams SET term_id = ?, subject_id = ?, class_id = ?, name = ?, exam_date = ?, start_time = ?, duration_minutes = ?, room_id = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingExam.TermID,
+		existingExam.SubjectID,
+		existingExam.ClassID,
+		existingExam.Name,
+		existingExam.ExamDate,
+		existingExam.StartTime,
+		existingExam.DurationMinutes,
+		nullableID(existingExam.RoomID),
+		existingExam.ID,
+		previousExam.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating exam")
+		http.Error(w, "❌ Error updating exam", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingExam.Version = previousExam.Version + 1
+
+	if existingExam.ClassID != previousExam.ClassID || existingExam.ExamDate != previousExam.ExamDate || existingExam.StartTime != previousExam.StartTime {
+		_, err = tx.Exec("DELETE FROM exam_seats WHERE exam_id = ?", existingExam.ID)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error clearing exam seating")
+			http.Error(w, "❌ Error clearing exam seating", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "exams", existingExam.ID, previousExam, existingExam)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingExam.ID, existingExam.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingExam)
+}
+
+func DeleteOneExamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	deletedExam, status, err := loadExam(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedExam.ID, deletedExam.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE exams SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedExam.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete exam")
+		http.Error(w, "❌ Unable delete exam", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "exams", id, deletedExam, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Exam successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreExamHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "exams", "Exam")
+}
+
+// To find students due to sit two exams at once in a term (?term_id=, the current term by default).
+// Candidates are the students enrolled in the exam's class plus anyone already seated for it.
+func GetExamClashesHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query("SELECT "+examColumns+" FROM exams e WHERE e.term_id = ? AND e.deleted_at IS NULL", termId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	exams := map[int]models.Exam{}
+	for rows.Next() {
+		var exam models.Exam
+		if err := scanExam(rows, &exam); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		exams[exam.ID] = exam
+	}
+	rows.Close()
+
+	rows, err = db.Query(`SELECT e.id, st.id, st.first_name, st.last_name FROM exams e
+		JOIN enrollments en ON en.class_id = e.class_id AND en.term_id = e.term_id
+		JOIN students st ON st.id = en.student_id AND st.deleted_at IS NULL
+		WHERE e.term_id = ? AND e.deleted_at IS NULL
+		UNION
+		SELECT e.id, st.id, st.first_name, st.last_name FROM exam_seats s
+		JOIN exams e ON e.id = s.exam_id
+		JOIN students st ON st.id = s.student_id AND st.deleted_at IS NULL
+		WHERE e.term_id = ? AND e.deleted_at IS NULL`, termId, termId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	studentExams := map[int][]models.Exam{}
+	students := map[int]models.ExamClash{}
+	for rows.Next() {
+		var examId int
+		var student models.ExamClash
+		if err := rows.Scan(&examId, &student.StudentID, &student.FirstName, &student.LastName); err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		students[student.StudentID] = student
+		studentExams[student.StudentID] = append(studentExams[student.StudentID], exams[examId])
+	}
+
+	clashes := make([]models.ExamClash, 0)
+	for studentId, sitting := range studentExams {
+		clashing := map[int]bool{}
+		for i := range sitting {
+			for j := i + 1; j < len(sitting); j++ {
+				if examsOverlap(sitting[i], sitting[j]) {
+					clashing[sitting[i].ID] = true
+					clashing[sitting[j].ID] = true
+				}
+			}
+		}
+		if len(clashing) == 0 {
+			continue
+		}
+
+		clash := students[studentId]
+		for _, exam := range sitting {
+			if clashing[exam.ID] {
+				clash.Exams = append(clash.Exams, exam)
+			}
+		}
+		sort.Slice(clash.Exams, func(i, j int) bool {
+			return clash.Exams[i].ExamDate+clash.Exams[i].StartTime < clash.Exams[j].ExamDate+clash.Exams[j].StartTime
+		})
+		clashes = append(clashes, clash)
+	}
+	sort.Slice(clashes, func(i, j int) bool {
+		if clashes[i].LastName != clashes[j].LastName {
+			return clashes[i].LastName < clashes[j].LastName
+		}
+		return clashes[i].StudentID < clashes[j].StudentID
+	})
+
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []models.ExamClash `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(clashes),
+		Data:   clashes,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get a student's exams in a term (?term_id=, the current term by default) with their seats once planned
+func GetExamsForAStudent(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`SELECT `+examColumns+`, COALESCE(s.room_id, 0), COALESCE(rm.name, ''), COALESCE(s.seat_number, 0)
+		FROM exams e
+		LEFT JOIN exam_seats s ON s.exam_id = e.id AND s.student_id = ?
+		LEFT JOIN rooms rm ON rm.id = s.room_id
+		WHERE e.term_id = ? AND e.deleted_at IS NULL
+		AND (s.id IS NOT NULL OR e.class_id = (SELECT class_id FROM enrollments WHERE student_id = ? AND term_id = e.term_id))
+		ORDER BY e.exam_date, e.start_time`,
+		studentId, termId, studentId,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	examList := make([]models.StudentExam, 0)
+	for rows.Next() {
+		var exam models.StudentExam
+		err := scanExam(rows, &exam.Exam, &exam.SeatRoomID, &exam.SeatRoom, &exam.SeatNumber)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		examList = append(examList, exam)
+	}
+
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.StudentExam `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(examList),
+		Data:   examList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
@@ -15,7 +15,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/go-mail/mail/v2"
 	"github.com/greatdaveo/Schoolly/internal/models"
 	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
 	"github.com/greatdaveo/Schoolly/pkg/utils"
@@ -34,6 +33,11 @@ func GetExecsHandler(w http.ResponseWriter, r *http.Request) {
 	query := "SELECT id, first_name, last_name, email FROM execs WHERE 1=1"
 	var args []interface{}
 
+	// To hide soft deleted execs unless an admin asks for them
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
 	// To Filter
 	query, args = utils.AddFilters(r, query, args)
 	// To Sort
@@ -76,9 +80,7 @@ func GetExecsHandler(w http.ResponseWriter, r *http.Request) {
 		Data:   execList,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
+	WriteJSONWithETag(w, r, response)
 }
 
 // To get single exec
@@ -105,7 +107,7 @@ func GetOneExecHandler(w http.ResponseWriter, r *http.Request) {
 
 	var exec models.Exec
 	err = db.QueryRow(
-		"SELECT id, first_name, last_name, email FROM execs WHERE id = ?", id,
+		"SELECT id, first_name, last_name, email, username, user_created_at, inactive_status, role, version FROM execs WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&exec.ID,
 		&exec.FirstName,
@@ -115,6 +117,7 @@ func GetOneExecHandler(w http.ResponseWriter, r *http.Request) {
 		&exec.UserCreatedAt,
 		&exec.InactiveStatus,
 		&exec.Role,
+		&exec.Version,
 	)
 	if err == sql.ErrNoRows {
 		// http.Error(w, "❌ Exec not found", http.StatusNotFound)
@@ -126,8 +129,11 @@ func GetOneExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if CheckIfNoneMatch(w, r, ETag(exec.ID, exec.Version)) {
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(exec)
 }
 
@@ -194,9 +200,17 @@ func AddExecsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("execs", models.Exec{}))
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateInsertQuery("execs", models.Exec{}))
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error in preparing SQL query", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error in preparing SQL query")
 		return
@@ -208,13 +222,16 @@ func AddExecsHandler(w http.ResponseWriter, r *http.Request) {
 		// FOR HASHING THE PASSWORD
 		newExec.Password, err = utils.HashPassword(newExec.Password)
 		if err != nil {
+			tx.Rollback()
 			utils.ErrorHandler(err, "❌ Error adding new exec into database")
 			return
 		}
 
+		newExec.Version = 1
 		values := utils.GetStructValues(newExec)
 		res, err := stmt.Exec(values...)
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error inserting data into database")
 			return
@@ -223,16 +240,32 @@ func AddExecsHandler(w http.ResponseWriter, r *http.Request) {
 		// To get the id of this entry
 		lastID, err := res.LastInsertId()
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error getting last insert ID")
 			return
 		}
 
 		newExec.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "execs", newExec.ID, nil, newExec)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		// To add to the exec list
 		addedExecs[i] = newExec
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	response := struct {
@@ -294,13 +327,14 @@ func EditMultipleExecsHandler(w http.ResponseWriter, r *http.Request) {
 
 		var exec models.Exec
 		err = db.QueryRow(
-			"SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id,
+			"SELECT id, first_name, last_name, email, username, version FROM execs WHERE id = ? AND deleted_at IS NULL", id,
 		).Scan(
 			&exec.ID,
 			&exec.FirstName,
 			&exec.LastName,
 			&exec.Email,
 			&exec.Username,
+			&exec.Version,
 		)
 
 		if err != nil {
@@ -316,6 +350,8 @@ func EditMultipleExecsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		existingExec := exec
+
 		// To update using reflect
 		execVal := reflect.ValueOf(&exec).Elem()
 		execType := execVal.Type()
@@ -345,12 +381,14 @@ func EditMultipleExecsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// To execute and update the values in the transaction
-		_, err = tx.Exec(
-			"UPDATE execs SET first_name = ?, last_name = ?, email = ? WHERE id = ?",
+		// A "version" in the input is checked against the row so stale edits are rejected
+		result, err := tx.Exec(
+			"UPDATE execs SET first_name = ?, last_name = ?, email = ?, version = version + 1 WHERE id = ? AND version = ?",
 			exec.FirstName,
 			exec.LastName,
 			exec.Email,
 			exec.ID,
+			exec.Version,
 		)
 
 		if err != nil {
@@ -360,6 +398,20 @@ func EditMultipleExecsHandler(w http.ResponseWriter, r *http.Request) {
 			utils.ErrorHandler(err, "❌ Error updating exec")
 			return
 		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err == nil && rowsAffected == 0 {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("❌ Exec %d has been modified by someone else, reload and try again", exec.ID), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = RecordAudit(tx, r, AuditUpdate, "execs", exec.ID, existingExec, exec)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 	// To commit the transaction
 	err = tx.Commit()
@@ -401,15 +453,30 @@ func EditExecSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	var existingExec models.Exec
-	db.QueryRow(
-		"SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id,
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, username, version FROM execs WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&existingExec.ID,
 		&existingExec.FirstName,
 		&existingExec.LastName,
 		&existingExec.Email,
 		&existingExec.Username,
+		&existingExec.Version,
 	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Exec not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingExec.ID, existingExec.Version) {
+		return
+	}
+
+	previousExec := existingExec
 
 	// To apply update using reflect package
 	execVal := reflect.ValueOf(&existingExec).Elem()
@@ -430,21 +497,53 @@ func EditExecSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_, err = db.Exec(
-		"UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ? WHERE id = ?",
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ?, version = version + 1 WHERE id = ? AND version = ?",
 		existingExec.FirstName,
 		existingExec.LastName,
 		existingExec.Email,
 		existingExec.Username,
 		existingExec.ID,
+		previousExec.Version,
 	)
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error updating exec", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error updating exec")
 		return
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingExec.Version = previousExec.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "execs", existingExec.ID, previousExec, existingExec)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingExec.ID, existingExec.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingExec)
 }
@@ -467,8 +566,39 @@ func DeleteOneExecHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	result, err := db.Exec("DELETE FROM execs WHERE id = ?", id)
+	var deletedExec models.Exec
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, username, version FROM execs WHERE id = ? AND deleted_at IS NULL", id,
+	).Scan(
+		&deletedExec.ID,
+		&deletedExec.FirstName,
+		&deletedExec.LastName,
+		&deletedExec.Email,
+		&deletedExec.Username,
+		&deletedExec.Version,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Exec not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedExec.ID, deletedExec.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE execs SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedExec.Version)
+	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable delete exec", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable delete exec")
 		return
@@ -476,17 +606,33 @@ func DeleteOneExecHandler(w http.ResponseWriter, r *http.Request) {
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable retrieve deleted exec", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable retrieve deleted exec")
 		return
 	}
 
 	if rowsAffected == 0 {
+		tx.Rollback()
 		// http.Error(w, "❌ Exec not found", http.StatusNotFound)
 		utils.ErrorHandler(err, "❌ Exec not found")
 		return
 	}
 
+	err = RecordAudit(tx, r, AuditDelete, "execs", id, deletedExec, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// w.WriteHeader(http.StatusNoContent)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -528,7 +674,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	err = db.QueryRow(
-		`SELECT id, first_name, last_name, email, username, password, inactive_status, role FROM execs WHERE username = ?`,
+		`SELECT id, first_name, last_name, email, username, password, inactive_status, role FROM execs WHERE username = ? AND deleted_at IS NULL`,
 		req.Username,
 	).Scan(
 		&user.ID,
@@ -638,7 +784,7 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	var userPassword string
 	var userRole string
 
-	err = db.QueryRow("SELECT username, password, role FROM execs WHERE id = ?", userId).Scan(&username, &userPassword, &userRole)
+	err = db.QueryRow("SELECT username, password, role FROM execs WHERE id = ? AND deleted_at IS NULL", userId).Scan(&username, &userPassword, &userRole)
 	if err != nil {
 		utils.ErrorHandler(err, "❌ user not found")
 		return
@@ -659,12 +805,39 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 
 	currentTime := time.Now().Format(time.RFC3339)
 
-	_, err = db.Exec("UPDATE execs SET password = ?, password_changed_at = ? WHERE id = ?", hashedPassword, currentTime, userId)
+	tx, err := db.Begin()
 	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE execs SET password = ?, password_changed_at = ?, version = version + 1 WHERE id = ?", hashedPassword, currentTime, userId)
+	if err != nil {
+		tx.Rollback()
 		utils.ErrorHandler(err, "❌ failed to update the password")
 		return
 	}
 
+	err = RecordAudit(tx, r, AuditUpdate, "execs", userId, nil, map[string]interface{}{"password_changed_at": currentTime})
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	err = Notify(db, userId, "Your password was changed", fmt.Sprintf("The password of your account %s was changed on %s. If you did not do this, reset your password straight away.", username, time.Now().Format("2 January 2006 at 15:04")))
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Password updated. Could not send notification")
+	}
+
 	// // To send a new token
 	// token, err := utils.SignToken(userId, username, userRole)
 	// if err != nil {
@@ -714,7 +887,7 @@ func ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	var exec models.Exec
-	err = db.QueryRow("SELECT id FROM execs WHERE email = ?", req.Email).Scan(&exec.ID)
+	err = db.QueryRow("SELECT id FROM execs WHERE email = ? AND deleted_at IS NULL", req.Email).Scan(&exec.ID)
 	if err != nil {
 		utils.ErrorHandler(err, "❌ User not found")
 		return
@@ -763,14 +936,7 @@ func ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	resetUrl := fmt.Sprintf("https://localhost:3000/execs/reset-password/reset/%s", token)
 	message := fmt.Sprintf("Forgot your password? Reset your password using the following link: \n%s\nIf you did'nt request a password reset, please ignore this email. This link is only valid for %d minutes.", resetUrl, int(mins))
 
-	m := mail.NewMessage()
-	m.SetHeader("From", "admin@schoolly.com")
-	m.SetHeader("To", req.Email)
-	m.SetHeader("Subject", "Your password reset link")
-	m.SetBody("text/plain", message)
-
-	dialer := mail.NewDialer("localhost", 1025, "", "")
-	err = dialer.DialAndSend(m)
+	err = utils.SendMail([]string{req.Email}, "Your password reset link", message)
 	if err != nil {
 		utils.ErrorHandler(err, "❌ Failed to send password reset email")
 		return
@@ -824,7 +990,7 @@ func ResetPassword(w http.ResponseWriter, r *http.Request) {
 
 	var user models.Exec
 
-	query := "SELECT id, email FROM execs WHERE password_reset_token = ? AND password_token_expires > ?"
+	query := "SELECT id, email FROM execs WHERE password_reset_token = ? AND password_token_expires > ? AND deleted_at IS NULL"
 	err = db.QueryRow(query, hashedTokenString, time.Now().Format(time.RFC3339)).Scan(
 		&user.ID, &user.Email,
 	)
@@ -840,13 +1006,37 @@ func ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updateQuery := "UPDATE execs SET password = ?, password_reset_token = NULL, password_token_expires = NULL, password_changed_at = ? WHERE id = ?"
-	_, err = db.Exec(updateQuery, hashedPassword, time.Now().Format(time.RFC3339), user.ID)
+	passwordChangedAt := time.Now().Format(time.RFC3339)
+
+	updateQuery := "UPDATE execs SET password = ?, password_reset_token = NULL, password_token_expires = NULL, password_changed_at = ?, version = version + 1 WHERE id = ?"
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(updateQuery, hashedPassword, passwordChangedAt, user.ID)
 	if err != nil {
+		tx.Rollback()
 		utils.ErrorHandler(err, "❌ Internal error")
 		return
 	}
 
+	err = RecordAudit(tx, r, AuditUpdate, "execs", user.ID, nil, map[string]interface{}{"password_changed_at": passwordChangedAt})
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	fmt.Fprintln(w, "Password reset successfully")
 
 }
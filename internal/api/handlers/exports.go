@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/pkg/export"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+var studentExportColumns = []string{"id", "first_name", "last_name", "email", "class", "class_id", "status"}
+
+var teacherExportColumns = []string{"id", "first_name", "last_name", "email", "class", "class_id", "subject", "subject_id"}
+
+// To read the export format a list was asked for with ?format= or the Accept header.
+// An empty format means the usual JSON response.
+func exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format, err := export.Negotiate(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return format, true
+}
+
+// To send the rows of a query as a download, one row at a time straight from the cursor.
+// Once the first row is out the status can't change, so a failure part way is only logged
+// and the file ends early.
+func exportRows(w http.ResponseWriter, rows *sql.Rows, format, name string, columns []string, scan func(*sql.Rows) ([]interface{}, error)) {
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format(DateLayout), format))
+
+	writer, err := export.NewWriter(w, format, columns)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting export")
+		return
+	}
+
+	for rows.Next() {
+		values, err := scan(rows)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			return
+		}
+		err = writer.Write(values)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error writing export")
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		utils.ErrorHandler(err, "❌ Error reading Database results")
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error finishing export")
+	}
+}
+
+// To turn a student row of id, first_name, last_name, email, class, class_id, status, version into export values
+func scanStudentExport(rows *sql.Rows) ([]interface{}, error) {
+	var id, classId, version int
+	var firstName, lastName, email, class, status string
+	err := rows.Scan(&id, &firstName, &lastName, &email, &class, &classId, &status, &version)
+	return []interface{}{id, firstName, lastName, email, class, classId, status}, err
+}
+
+// To turn a teacher row of id, first_name, last_name, email, class, class_id, subject, subject_id, version into export values
+func scanTeacherExport(rows *sql.Rows) ([]interface{}, error) {
+	var id, classId, subjectId, version int
+	var firstName, lastName, email, class, subject string
+	err := rows.Scan(&id, &firstName, &lastName, &email, &class, &classId, &subject, &subjectId, &version)
+	return []interface{}{id, firstName, lastName, email, class, classId, subject, subjectId}, err
+}
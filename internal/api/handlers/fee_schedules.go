@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const feeScheduleColumns = "id, grade_level, term_id, name, amount, currency, due_date, version"
+
+var feeScheduleFilterFields = map[string]string{
+	"grade_level": "grade_level",
+	"term_id":     "term_id",
+	"currency":    "currency",
+}
+
+var feeScheduleSortFields = map[string]bool{
+	"grade_level": true,
+	"name":        true,
+	"amount":      true,
+	"due_date":    true,
+}
+
+func scanFeeSchedule(row interface{ Scan(...interface{}) error }, schedule *models.FeeSchedule) error {
+	return row.Scan(
+		&schedule.ID,
+		&schedule.GradeLevel,
+		&schedule.TermID,
+		&schedule.Name,
+		&schedule.Amount,
+		&schedule.Currency,
+		&schedule.DueDate,
+		&schedule.Version,
+	)
+}
+
+// DefaultCurrency is the currency used when none is given, from SCHOOL_CURRENCY (default USD)
+func DefaultCurrency() string {
+	if currency := os.Getenv("SCHOOL_CURRENCY"); currency != "" {
+		return strings.ToUpper(currency)
+	}
+	return "USD"
+}
+
+// To check a currency code and put it in upper case, falling back to the school's currency
+func normalizeCurrency(currency string) (string, error) {
+	if currency == "" {
+		return DefaultCurrency(), nil
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !utils.ValidCurrency(currency) {
+		return "", errors.New("❌ currency must be a three letter ISO 4217 code such as USD")
+	}
+	return currency, nil
+}
+
+// To check a fee schedule before it is written. It returns the HTTP status to answer with when invalid.
+func validateFeeSchedule(db Queryer, schedule *models.FeeSchedule) (int, error) {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" || schedule.TermID == 0 || schedule.DueDate == "" {
+		return http.StatusBadRequest, errors.New("❌ grade_level, term_id, name, amount and due_date are required")
+	}
+	if schedule.GradeLevel < 0 {
+		return http.StatusBadRequest, errors.New("❌ grade_level cannot be negative")
+	}
+	if schedule.Amount <= 0 {
+		return http.StatusBadRequest, errors.New("❌ amount must be a positive number of minor units, e.g. 150000 for 1,500.00")
+	}
+	if _, err := time.Parse(DateLayout, schedule.DueDate); err != nil {
+		return http.StatusBadRequest, errors.New("❌ due_date must be a date like 2025-09-01")
+	}
+
+	currency, err := normalizeCurrency(schedule.Currency)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	schedule.Currency = currency
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM terms WHERE id = ? AND deleted_at IS NULL", schedule.TermID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve term")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Term %d does not exist", schedule.TermID)
+	}
+
+	return http.StatusOK, nil
+}
+
+// To get fee schedules, filtered by e.g. ?grade_level=10&term_id=3
+func GetFeeSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + feeScheduleColumns + " FROM fee_schedules WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, feeScheduleFilterFields)
+	query = utils.AddSortingFor(r, query, feeScheduleSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scheduleList := make([]models.FeeSchedule, 0)
+	for rows.Next() {
+		var schedule models.FeeSchedule
+		err := scanFeeSchedule(rows, &schedule)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		scheduleList = append(scheduleList, schedule)
+	}
+
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.FeeSchedule `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scheduleList),
+		Data:   scheduleList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To add fee schedules
+func AddFeeSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	var newSchedules []models.FeeSchedule
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newSchedules)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newSchedules {
+		status, err := validateFeeSchedule(db, &newSchedules[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedSchedules := make([]models.FeeSchedule, len(newSchedules))
+	for i, newSchedule := range newSchedules {
+		newSchedule.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO fee_schedules (grade_level, term_id, name, amount, currency, due_date, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			newSchedule.GradeLevel,
+			newSchedule.TermID,
+			newSchedule.Name,
+			newSchedule.Amount,
+			newSchedule.Currency,
+			newSchedule.DueDate,
+			newSchedule.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newSchedule.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "fee_schedules", newSchedule.ID, nil, newSchedule)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedSchedules[i] = newSchedule
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.FeeSchedule `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSchedules),
+		Data:   addedSchedules,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a fee schedule. Invoices already issued keep their amounts.
+func EditFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid fee schedule id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingSchedule models.FeeSchedule
+	err = scanFeeSchedule(db.QueryRow("SELECT "+feeScheduleColumns+" FROM fee_schedules WHERE id = ? AND deleted_at IS NULL", id), &existingSchedule)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Fee schedule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingSchedule.ID, existingSchedule.Version) {
+		return
+	}
+
+	previousSchedule := existingSchedule
+
+	err = ApplyPatch(&existingSchedule, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := validateFeeSchedule(db, &existingSchedule)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE fee_schedules SET grade_level = ?, term_id = ?, name = ?, amount = ?, currency = ?, due_date = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingSchedule.GradeLevel,
+		existingSchedule.TermID,
+		existingSchedule.Name,
+		existingSchedule.Amount,
+		existingSchedule.Currency,
+		existingSchedule.DueDate,
+		existingSchedule.ID,
+		previousSchedule.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating fee schedule")
+		http.Error(w, "❌ Error updating fee schedule", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingSchedule.Version = previousSchedule.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "fee_schedules", existingSchedule.ID, previousSchedule, existingSchedule)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingSchedule.ID, existingSchedule.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingSchedule)
+}
+
+func DeleteOneFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid fee schedule id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedSchedule models.FeeSchedule
+	err = scanFeeSchedule(db.QueryRow("SELECT "+feeScheduleColumns+" FROM fee_schedules WHERE id = ? AND deleted_at IS NULL", id), &deletedSchedule)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Fee schedule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedSchedule.ID, deletedSchedule.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE fee_schedules SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedSchedule.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete fee schedule")
+		http.Error(w, "❌ Unable delete fee schedule", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "fee_schedules", id, deletedSchedule, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Fee schedule successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "fee_schedules", "Fee schedule")
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// LoadGradingScale returns the grade bands from the highest minimum down
+func LoadGradingScale(db *sql.DB) ([]models.GradeBand, error) {
+	rows, err := db.Query("SELECT letter, min_percent FROM grading_scale ORDER BY min_percent DESC")
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to retrieve grading scale")
+	}
+	defer rows.Close()
+
+	scale := make([]models.GradeBand, 0)
+	for rows.Next() {
+		var band models.GradeBand
+		if err := rows.Scan(&band.Letter, &band.MinPercent); err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve grading scale")
+		}
+		scale = append(scale, band)
+	}
+	return scale, nil
+}
+
+// LetterGrade finds the band a percentage falls in. The scale must be sorted from the highest minimum.
+func LetterGrade(scale []models.GradeBand, percent float64) string {
+	for _, band := range scale {
+		if percent >= band.MinPercent {
+			return band.Letter
+		}
+	}
+	return ""
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// To get the grading scale used for letter grades
+func GetGradingScaleHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	scale, err := LoadGradingScale(db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []models.GradeBand `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scale),
+		Data:   scale,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To replace the whole grading scale e.g. [{"letter": "A", "min_percent": 90}, {"letter": "F", "min_percent": 0}]
+func UpdateGradingScaleHandler(w http.ResponseWriter, r *http.Request) {
+	var scale []models.GradeBand
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&scale)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	letters := map[string]bool{}
+	hasFloor := false
+	for _, band := range scale {
+		if band.Letter == "" || band.MinPercent < 0 || band.MinPercent > 100 {
+			http.Error(w, "❌ Every band needs a letter and a min_percent between 0 and 100", http.StatusBadRequest)
+			return
+		}
+		if letters[band.Letter] {
+			http.Error(w, "❌ Letter "+band.Letter+" appears twice", http.StatusBadRequest)
+			return
+		}
+		letters[band.Letter] = true
+		hasFloor = hasFloor || band.MinPercent == 0
+	}
+	if !hasFloor {
+		http.Error(w, "❌ The scale needs a band starting at 0 so every score gets a letter", http.StatusBadRequest)
+		return
+	}
+	sort.Slice(scale, func(i, j int) bool { return scale[i].MinPercent > scale[j].MinPercent })
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	previousScale, err := LoadGradingScale(db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM grading_scale")
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating grading scale")
+		http.Error(w, "❌ Error updating grading scale", http.StatusInternalServerError)
+		return
+	}
+	for _, band := range scale {
+		_, err = tx.Exec("INSERT INTO grading_scale (letter, min_percent) VALUES (?, ?)", band.Letter, band.MinPercent)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error updating grading scale")
+			http.Error(w, "❌ Error updating grading scale", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "grading_scale", 0, map[string]interface{}{"bands": previousScale}, map[string]interface{}{"bands": scale})
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []models.GradeBand `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scale),
+		Data:   scale,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ComputeGrades works out the running weighted average of every student and subject
+// in a term, over the assessments that have been scored so far. The condition narrows
+// the scores down e.g. to one student or one class.
+func ComputeGrades(db *sql.DB, termId int, condition string, args ...interface{}) ([]models.StudentGrades, error) {
+	scale, err := LoadGradingScale(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		`SELECT sc.student_id, a.subject_id, COALESCE(sub.name, ''), a.weight, a.max_score, sc.score
+		FROM scores sc
+		JOIN assessments a ON a.id = sc.assessment_id
+		LEFT JOIN subjects sub ON sub.id = a.subject_id
+		WHERE a.term_id = ? AND a.deleted_at IS NULL`+condition+`
+		ORDER BY sc.student_id, sub.name`,
+		append([]interface{}{termId}, args...)...,
+	)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to retrieve scores")
+	}
+	defer rows.Close()
+
+	type running struct {
+		grade          models.SubjectGrade
+		weighted, sums float64
+	}
+	var studentOrder []int
+	subjectsByStudent := map[int][]*running{}
+
+	for rows.Next() {
+		var studentId, subjectId int
+		var subjectName string
+		var weight, maxScore, score float64
+		if err := rows.Scan(&studentId, &subjectId, &subjectName, &weight, &maxScore, &score); err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve scores")
+		}
+
+		subjects, seen := subjectsByStudent[studentId]
+		if !seen {
+			studentOrder = append(studentOrder, studentId)
+		}
+
+		var current *running
+		for _, subject := range subjects {
+			if subject.grade.SubjectID == subjectId {
+				current = subject
+				break
+			}
+		}
+		if current == nil {
+			current = &running{grade: models.SubjectGrade{SubjectID: subjectId, Subject: subjectName}}
+			subjectsByStudent[studentId] = append(subjects, current)
+		}
+
+		current.grade.Assessments++
+		current.weighted += weight * score / maxScore
+		current.sums += weight
+	}
+
+	results := make([]models.StudentGrades, 0, len(studentOrder))
+	for _, studentId := range studentOrder {
+		grades := models.StudentGrades{StudentID: studentId, TermID: termId, Subjects: []models.SubjectGrade{}}
+		total := 0.0
+		for _, subject := range subjectsByStudent[studentId] {
+			if subject.sums > 0 {
+				subject.grade.Average = roundPercent(subject.weighted / subject.sums * 100)
+			}
+			subject.grade.Letter = LetterGrade(scale, subject.grade.Average)
+			grades.Subjects = append(grades.Subjects, subject.grade)
+			total += subject.grade.Average
+		}
+		if len(grades.Subjects) > 0 {
+			grades.Average = roundPercent(total / float64(len(grades.Subjects)))
+			grades.Letter = LetterGrade(scale, grades.Average)
+		}
+		results = append(results, grades)
+	}
+	return results, nil
+}
+
+// To read ?term_id=, falling back to the term running today
+func termFromRequest(db Queryer, r *http.Request) (int, error) {
+	if termId := r.URL.Query().Get("term_id"); termId != "" {
+		id, err := strconv.Atoi(termId)
+		if err != nil {
+			return 0, errors.New("❌ Invalid term_id")
+		}
+		return id, nil
+	}
+
+	term, err := CurrentTerm(db, "")
+	if err != nil {
+		return 0, err
+	}
+	return term.ID, nil
+}
+
+// To get a student's grade summary for a term
+func GetStudentGradesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := ComputeGrades(db, termId, " AND sc.student_id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	grades := models.StudentGrades{StudentID: id, TermID: termId, Subjects: []models.SubjectGrade{}}
+	if len(results) > 0 {
+		grades = results[0]
+	}
+
+	WriteJSONWithETag(w, r, grades)
+}
+
+// To get the running averages of every student in a class for a term, optionally for one ?subject_id=
+func GetClassGradesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	condition := " AND a.class_id = ?"
+	args := []interface{}{id}
+	if subjectId := r.URL.Query().Get("subject_id"); subjectId != "" {
+		condition += " AND a.subject_id = ?"
+		args = append(args, subjectId)
+	}
+
+	results, err := ComputeGrades(db, termId, condition, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.StudentGrades `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(results),
+		Data:   results,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
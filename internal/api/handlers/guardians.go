@@ -0,0 +1,766 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const guardianColumns = "id, first_name, last_name, phone, alt_phone, email, address, version"
+
+var guardianFilterFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"phone":      "phone",
+}
+
+var guardianSortFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+}
+
+func scanGuardian(row interface{ Scan(...interface{}) error }, guardian *models.Guardian) error {
+	return row.Scan(
+		&guardian.ID,
+		&guardian.FirstName,
+		&guardian.LastName,
+		&guardian.Phone,
+		&guardian.AltPhone,
+		&guardian.Email,
+		&guardian.Address,
+		&guardian.Version,
+	)
+}
+
+// To check the required fields and bring phone numbers and email into one format
+func validateGuardian(guardian *models.Guardian) error {
+	guardian.FirstName = strings.TrimSpace(guardian.FirstName)
+	guardian.LastName = strings.TrimSpace(guardian.LastName)
+	guardian.Email = strings.ToLower(strings.TrimSpace(guardian.Email))
+	guardian.Address = strings.TrimSpace(guardian.Address)
+
+	if guardian.FirstName == "" || guardian.LastName == "" || guardian.Phone == "" {
+		return errors.New("❌ first_name, last_name and phone are required")
+	}
+
+	phone, err := utils.NormalizePhone(guardian.Phone)
+	if err != nil {
+		return err
+	}
+	guardian.Phone = phone
+
+	if guardian.AltPhone != "" {
+		altPhone, err := utils.NormalizePhone(guardian.AltPhone)
+		if err != nil {
+			return err
+		}
+		guardian.AltPhone = altPhone
+	}
+
+	if guardian.Email != "" {
+		if err := utils.ValidateEmail(guardian.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// To get multiple guardians
+func GetGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + guardianColumns + " FROM guardians WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, guardianFilterFields)
+	query = utils.AddSortingFor(r, query, guardianSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	guardianList := make([]models.Guardian, 0)
+	for rows.Next() {
+		var guardian models.Guardian
+		err := scanGuardian(rows, &guardian)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		guardianList = append(guardianList, guardian)
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardianList),
+		Data:   guardianList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single guardian
+func GetOneGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var guardian models.Guardian
+	err = scanGuardian(db.QueryRow("SELECT "+guardianColumns+" FROM guardians WHERE id = ? AND deleted_at IS NULL", id), &guardian)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Guardian not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(guardian.ID, guardian.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guardian)
+}
+
+// To add guardians to the DB
+func AddGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	var newGuardians []models.Guardian
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newGuardians)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range newGuardians {
+		err := validateGuardian(&newGuardians[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedGuardians := make([]models.Guardian, len(newGuardians))
+	for i, newGuardian := range newGuardians {
+		newGuardian.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO guardians (first_name, last_name, phone, alt_phone, email, address, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			newGuardian.FirstName,
+			newGuardian.LastName,
+			newGuardian.Phone,
+			newGuardian.AltPhone,
+			newGuardian.Email,
+			newGuardian.Address,
+			newGuardian.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newGuardian.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "guardians", newGuardian.ID, nil, newGuardian)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedGuardians[i] = newGuardian
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedGuardians),
+		Data:   addedGuardians,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a guardian
+func EditGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingGuardian models.Guardian
+	err = scanGuardian(db.QueryRow("SELECT "+guardianColumns+" FROM guardians WHERE id = ? AND deleted_at IS NULL", id), &existingGuardian)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Guardian not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingGuardian.ID, existingGuardian.Version) {
+		return
+	}
+
+	previousGuardian := existingGuardian
+
+	err = ApplyPatch(&existingGuardian, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = validateGuardian(&existingGuardian)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE guardians SET first_name = ?, last_name = ?, phone = ?, alt_phone = ?, email = ?, address = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingGuardian.FirstName,
+		existingGuardian.LastName,
+		existingGuardian.Phone,
+		existingGuardian.AltPhone,
+		existingGuardian.Email,
+		existingGuardian.Address,
+		existingGuardian.ID,
+		previousGuardian.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating guardian")
+		http.Error(w, "❌ Error updating guardian", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingGuardian.Version = previousGuardian.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "guardians", existingGuardian.ID, previousGuardian, existingGuardian)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingGuardian.ID, existingGuardian.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingGuardian)
+}
+
+func DeleteOneGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedGuardian models.Guardian
+	err = scanGuardian(db.QueryRow("SELECT "+guardianColumns+" FROM guardians WHERE id = ? AND deleted_at IS NULL", id), &deletedGuardian)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Guardian not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedGuardian.ID, deletedGuardian.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE guardians SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedGuardian.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete guardian")
+		http.Error(w, "❌ Unable delete guardian", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "guardians", id, deletedGuardian, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Guardian successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "guardians", "Guardian")
+}
+
+// To get the guardians of a student, primary contacts first
+func GetGuardiansForAStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT g.id, g.first_name, g.last_name, g.phone, g.alt_phone, g.email, g.address, g.version,
+			sg.student_id, sg.guardian_id, sg.relationship, sg.pickup_authorized, sg.primary_contact
+		FROM student_guardians sg
+		JOIN guardians g ON g.id = sg.guardian_id
+		WHERE sg.student_id = ? AND g.deleted_at IS NULL
+		ORDER BY sg.primary_contact DESC, g.last_name, g.first_name`,
+		id,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	guardianList := make([]models.StudentGuardian, 0)
+	for rows.Next() {
+		var guardian models.StudentGuardian
+		err := rows.Scan(
+			&guardian.ID, &guardian.FirstName, &guardian.LastName, &guardian.Phone, &guardian.AltPhone, &guardian.Email, &guardian.Address, &guardian.Version,
+			&guardian.StudentID, &guardian.GuardianID, &guardian.Relationship, &guardian.PickupAuthorized, &guardian.PrimaryContact,
+		)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		guardianList = append(guardianList, guardian)
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.StudentGuardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardianList),
+		Data:   guardianList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the children a guardian is linked to
+func GetStudentsForAGuardian(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT s.id, s.first_name, s.last_name, s.email, s.class, COALESCE(s.class_id, 0), s.version,
+			sg.student_id, sg.guardian_id, sg.relationship, sg.pickup_authorized, sg.primary_contact
+		FROM student_guardians sg
+		JOIN students s ON s.id = sg.student_id
+		WHERE sg.guardian_id = ? AND s.deleted_at IS NULL
+		ORDER BY s.last_name, s.first_name`,
+		id,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	childList := make([]models.GuardianChild, 0)
+	for rows.Next() {
+		var child models.GuardianChild
+		err := rows.Scan(
+			&child.ID, &child.FirstName, &child.LastName, &child.Email, &child.Class, &child.ClassID, &child.Version,
+			&child.StudentID, &child.GuardianID, &child.Relationship, &child.PickupAuthorized, &child.PrimaryContact,
+		)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		childList = append(childList, child)
+	}
+
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.GuardianChild `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(childList),
+		Data:   childList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To link a guardian to a student, or change how they are linked,
+// e.g. {"relationship": "mother", "pickup_authorized": true, "primary_contact": true}.
+// A student has at most one primary contact, so marking a new one clears the previous.
+func LinkGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	guardianId, err := strconv.Atoi(r.PathValue("guardian_id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	var link models.GuardianLink
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&link)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	link.StudentID = studentId
+	link.GuardianID = guardianId
+	link.Relationship = strings.ToLower(strings.TrimSpace(link.Relationship))
+	if link.Relationship == "" {
+		http.Error(w, "❌ relationship is required", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL) + (SELECT COUNT(*) FROM guardians WHERE id = ? AND deleted_at IS NULL)",
+		studentId, guardianId,
+	).Scan(&exists)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	if exists != 2 {
+		http.Error(w, "❌ Student or guardian not found", http.StatusNotFound)
+		return
+	}
+
+	var previousLink *models.GuardianLink
+	var existing models.GuardianLink
+	err = db.QueryRow(
+		"SELECT student_id, guardian_id, relationship, pickup_authorized, primary_contact FROM student_guardians WHERE student_id = ? AND guardian_id = ?",
+		studentId, guardianId,
+	).Scan(&existing.StudentID, &existing.GuardianID, &existing.Relationship, &existing.PickupAuthorized, &existing.PrimaryContact)
+	if err == nil {
+		previousLink = &existing
+	} else if err != sql.ErrNoRows {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if link.PrimaryContact {
+		_, err = tx.Exec("UPDATE student_guardians SET primary_contact = FALSE WHERE student_id = ? AND guardian_id <> ?", studentId, guardianId)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error updating primary contact")
+			http.Error(w, "❌ Error updating primary contact", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO student_guardians (student_id, guardian_id, relationship, pickup_authorized, primary_contact) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE relationship = VALUES(relationship), pickup_authorized = VALUES(pickup_authorized), primary_contact = VALUES(primary_contact)`,
+		link.StudentID, link.GuardianID, link.Relationship, link.PickupAuthorized, link.PrimaryContact,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error linking guardian")
+		http.Error(w, "❌ Error linking guardian", http.StatusInternalServerError)
+		return
+	}
+
+	action := AuditCreate
+	var before interface{}
+	if previousLink != nil {
+		action = AuditUpdate
+		before = previousLink
+	}
+	err = RecordAudit(tx, r, action, "student_guardians", studentId, before, link)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if previousLink == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(link)
+}
+
+// To remove a guardian from a student without deleting the guardian
+func UnlinkGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	guardianId, err := strconv.Atoi(r.PathValue("guardian_id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid guardian id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var link models.GuardianLink
+	err = db.QueryRow(
+		"SELECT student_id, guardian_id, relationship, pickup_authorized, primary_contact FROM student_guardians WHERE student_id = ? AND guardian_id = ?",
+		studentId, guardianId,
+	).Scan(&link.StudentID, &link.GuardianID, &link.Relationship, &link.PickupAuthorized, &link.PrimaryContact)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Guardian is not linked to this student", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM student_guardians WHERE student_id = ? AND guardian_id = ?", studentId, guardianId)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to unlink guardian")
+		http.Error(w, "❌ Unable to unlink guardian", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "student_guardians", studentId, link, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Guardian successfully unlinked",
+		ID:     guardianId,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
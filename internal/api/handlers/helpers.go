@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/http"
+	"os"
 	"reflect"
 	"strings"
 
+	mw "github.com/greatdaveo/Schoolly/internal/api/middlewares"
 	"github.com/greatdaveo/Schoolly/pkg/utils"
 )
 
@@ -31,3 +36,83 @@ func GetFieldsName(model interface{}) []string {
 	}
 	return fields
 }
+
+// To get the role of the logged in user from the JWT claims
+func UserRole(r *http.Request) string {
+	role, _ := r.Context().Value(mw.ContextKey("role")).(string)
+	return role
+}
+
+// To read a comma separated list of roles from an environment variable such as
+// INCIDENT_ROLES=admin,pastoral, falling back to defaults when it is unset
+func rolesFromEnv(name string, defaults ...string) map[string]bool {
+	list := defaults
+	if value := os.Getenv(name); value != "" {
+		list = strings.Split(value, ",")
+	}
+	roles := map[string]bool{}
+	for _, role := range list {
+		if role = strings.TrimSpace(role); role != "" {
+			roles[role] = true
+		}
+	}
+	return roles
+}
+
+// To check whether the logged in user holds one of the roles
+func HasRole(r *http.Request, roles map[string]bool) bool {
+	return roles[UserRole(r)]
+}
+
+// Only admins may see soft deleted rows, and only when they ask with ?include_deleted=true
+func IncludeDeleted(r *http.Request) bool {
+	return r.URL.Query().Get("include_deleted") == "true" && UserRole(r) == "admin"
+}
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// QueryExecer is satisfied by both *sql.DB and *sql.Tx for helpers that read and write
+type QueryExecer interface {
+	Queryer
+	Execer
+}
+
+// To apply a partial update from a JSON object onto a model using its json tags
+func ApplyPatch(model interface{}, input map[string]interface{}) error {
+	modelVal := reflect.ValueOf(model).Elem()
+	modelType := modelVal.Type()
+
+	for k, v := range input {
+		if k == "id" || k == "version" {
+			continue // To skip fields the client may not change
+		}
+
+		found := false
+		for i := 0; i < modelVal.NumField(); i++ {
+			field := modelType.Field(i)
+			if strings.TrimSuffix(field.Tag.Get("json"), ",omitempty") != k {
+				continue
+			}
+			found = true
+
+			fieldVal := modelVal.Field(i)
+			if !fieldVal.CanSet() || v == nil {
+				break
+			}
+			val := reflect.ValueOf(v)
+			if !val.Type().ConvertibleTo(fieldVal.Type()) {
+				return utils.ErrorHandler(fmt.Errorf("cannot convert %v to %v", val.Type(), fieldVal.Type()), fmt.Sprintf("❌ Invalid value for %s", k))
+			}
+			fieldVal.Set(val.Convert(fieldVal.Type()))
+			break
+		}
+
+		if !found {
+			return utils.ErrorHandler(fmt.Errorf("unknown field %s", k), "❌ Unacceptable field found in request. Only use allowed fields")
+		}
+	}
+	return nil
+}
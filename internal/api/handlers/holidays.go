@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const holidayColumns = "id, academic_year_id, name, start_date, end_date"
+
+func scanHoliday(row interface{ Scan(...interface{}) error }, holiday *models.Holiday) error {
+	return row.Scan(
+		&holiday.ID,
+		&holiday.AcademicYearID,
+		&holiday.Name,
+		&holiday.StartDate,
+		&holiday.EndDate,
+	)
+}
+
+// IsHoliday reports whether a date falls on a school holiday and returns the holiday's name
+func IsHoliday(db Queryer, date string) (bool, string, error) {
+	var name string
+	err := db.QueryRow(
+		"SELECT h.name FROM holidays h JOIN academic_years y ON y.id = h.academic_year_id WHERE ? BETWEEN h.start_date AND h.end_date AND y.deleted_at IS NULL LIMIT 1",
+		date,
+	).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	} else if err != nil {
+		return false, "", utils.ErrorHandler(err, "❌ Unable to retrieve holidays")
+	}
+	return true, name, nil
+}
+
+// To get the holidays of an academic year
+func GetHolidaysForAnAcademicYear(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT "+holidayColumns+" FROM holidays WHERE academic_year_id = ? ORDER BY start_date", id)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	holidayList := make([]models.Holiday, 0)
+	for rows.Next() {
+		var holiday models.Holiday
+		err := scanHoliday(rows, &holiday)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		holidayList = append(holidayList, holiday)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Holiday `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(holidayList),
+		Data:   holidayList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To add holidays to an academic year
+func AddHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	yearId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	var newHolidays []models.Holiday
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&newHolidays)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var year models.AcademicYear
+	err = scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", yearId), &year)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Academic year not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, holiday := range newHolidays {
+		if holiday.Name == "" {
+			http.Error(w, "❌ name, start_date and end_date are required", http.StatusBadRequest)
+			return
+		}
+		if err := validateDateRange(holiday.StartDate, holiday.EndDate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if holiday.StartDate < year.StartDate || holiday.EndDate > year.EndDate {
+			http.Error(w, "❌ "+holiday.Name+" is outside the academic year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedHolidays := make([]models.Holiday, len(newHolidays))
+	for i, newHoliday := range newHolidays {
+		newHoliday.AcademicYearID = yearId
+		res, err := tx.Exec(
+			"INSERT INTO holidays (academic_year_id, name, start_date, end_date) VALUES (?, ?, ?, ?)",
+			newHoliday.AcademicYearID,
+			newHoliday.Name,
+			newHoliday.StartDate,
+			newHoliday.EndDate,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newHoliday.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "holidays", newHoliday.ID, nil, newHoliday)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedHolidays[i] = newHoliday
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Holiday `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedHolidays),
+		Data:   addedHolidays,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Holidays are plain calendar entries, so they are removed outright rather than soft deleted
+func DeleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid holiday id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedHoliday models.Holiday
+	err = scanHoliday(db.QueryRow("SELECT "+holidayColumns+" FROM holidays WHERE id = ?", id), &deletedHoliday)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Holiday not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM holidays WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete holiday")
+		http.Error(w, "❌ Unable delete holiday", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "holidays", id, deletedHoliday, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Holiday successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,663 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/storage"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// DateTimeLayout is how DATETIME columns come back from MySQL
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// The owner_type of homework attachments
+const homeworkAttachments = "homework"
+
+const homeworkSelect = `SELECT h.id, h.assessment_id, h.teacher_id, a.class_id, a.subject_id, a.term_id, h.title, h.description, h.due_at, a.max_score, a.weight, h.version
+	FROM homework h JOIN assessments a ON a.id = h.assessment_id`
+
+var homeworkFilterFields = map[string]string{
+	"class_id":   "a.class_id",
+	"subject_id": "a.subject_id",
+	"term_id":    "a.term_id",
+	"teacher_id": "h.teacher_id",
+}
+
+func scanHomework(row interface{ Scan(...interface{}) error }, homework *models.Homework) error {
+	return row.Scan(
+		&homework.ID,
+		&homework.AssessmentID,
+		&homework.TeacherID,
+		&homework.ClassID,
+		&homework.SubjectID,
+		&homework.TermID,
+		&homework.Title,
+		&homework.Description,
+		&homework.DueAt,
+		&homework.MaxScore,
+		&homework.Weight,
+		&homework.Version,
+	)
+}
+
+// To read a due date given with or without a time. A date alone is due at the end of that day.
+func parseDueAt(value string) (time.Time, error) {
+	for _, layout := range []string{DateTimeLayout, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if due, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return due, nil
+		}
+	}
+	if day, err := time.ParseInLocation(DateLayout, value, time.Local); err == nil {
+		return day.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, errors.New("❌ due_at must look like 2025-09-01 or 2025-09-01 17:00")
+}
+
+// To check a homework before it is written and fill in its defaults.
+// It returns the HTTP status to answer with when invalid.
+func validateHomework(db Queryer, r *http.Request, homework *models.Homework) (int, error) {
+	homework.Title = strings.TrimSpace(homework.Title)
+	if homework.Title == "" || homework.TeacherID == 0 || homework.ClassID == 0 || homework.SubjectID == 0 || homework.DueAt == "" {
+		return http.StatusBadRequest, errors.New("❌ title, teacher_id, class_id, subject_id and due_at are required")
+	}
+
+	due, err := parseDueAt(homework.DueAt)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	homework.DueAt = due.Format(DateTimeLayout)
+
+	if homework.MaxScore == 0 {
+		homework.MaxScore = 100
+	}
+
+	// Homework is only set by a teacher assigned to the class
+	classQuery, classArgs := teacherClassesQuery(r, strconv.Itoa(homework.TeacherID))
+	var teachesClass int
+	err = db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ? AND id IN ("+classQuery+")", append([]interface{}{homework.ClassID}, classArgs...)...).Scan(&teachesClass)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve teacher's classes")
+	}
+	if teachesClass == 0 {
+		return http.StatusForbidden, fmt.Errorf("❌ Teacher %d does not teach class %d", homework.TeacherID, homework.ClassID)
+	}
+
+	if homework.TermID == 0 {
+		term, err := CurrentTerm(db, due.Format(DateLayout))
+		if err != nil {
+			return http.StatusBadRequest, errors.New("❌ No term covers the due date, send term_id")
+		}
+		homework.TermID = term.ID
+	}
+
+	return http.StatusOK, nil
+}
+
+// To turn a homework into the assessment its scores are kept against
+func homeworkAssessment(homework models.Homework) models.Assessment {
+	return models.Assessment{
+		ID:        homework.AssessmentID,
+		SubjectID: homework.SubjectID,
+		ClassID:   homework.ClassID,
+		TermID:    homework.TermID,
+		Name:      homework.Title,
+		Type:      AssessmentHomework,
+		Weight:    homework.Weight,
+		MaxScore:  homework.MaxScore,
+		DueDate:   homework.DueAt[:len(DateLayout)],
+	}
+}
+
+// To load one homework that hasn't been deleted
+func loadHomework(db Queryer, id int) (models.Homework, error) {
+	var homework models.Homework
+	err := scanHomework(db.QueryRow(homeworkSelect+" WHERE h.id = ? AND h.deleted_at IS NULL", id), &homework)
+	return homework, err
+}
+
+// To fill in the attachments of the given homework
+func loadHomeworkAttachments(db *sql.DB, homeworkList []models.Homework) error {
+	ids := make([]int, len(homeworkList))
+	for i, homework := range homeworkList {
+		ids[i] = homework.ID
+	}
+	attachments, err := loadAttachments(db, homeworkAttachments, ids)
+	if err != nil {
+		return err
+	}
+	for i := range homeworkList {
+		homeworkList[i].Attachments = attachments[homeworkList[i].ID]
+	}
+	return nil
+}
+
+// To get homework, filtered by e.g. ?class_id=, ?teacher_id=, ?term_id=
+func GetHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := homeworkSelect + " WHERE h.deleted_at IS NULL"
+	var args []interface{}
+	query, args = utils.AddFiltersFor(r, query, args, homeworkFilterFields)
+
+	// To only list homework that is still open e.g. ?due=upcoming
+	switch r.URL.Query().Get("due") {
+	case "upcoming":
+		query += " AND h.due_at >= NOW()"
+	case "past":
+		query += " AND h.due_at < NOW()"
+	}
+	query += " ORDER BY h.due_at"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	homeworkList := make([]models.Homework, 0)
+	for rows.Next() {
+		var homework models.Homework
+		err := scanHomework(rows, &homework)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		homeworkList = append(homeworkList, homework)
+	}
+	rows.Close()
+
+	err = loadHomeworkAttachments(db, homeworkList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Homework `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(homeworkList),
+		Data:   homeworkList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single homework with its attachments
+func GetOneHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	homework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	homeworkList := []models.Homework{homework}
+	err = loadHomeworkAttachments(db, homeworkList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	homework = homeworkList[0]
+
+	if CheckIfNoneMatch(w, r, ETag(homework.ID, homework.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(homework)
+}
+
+// To set homework for classes. Each homework gets a homework assessment in the gradebook.
+// Files are attached afterwards with POST /homework/{id}/attachments.
+func AddHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	var newHomework []models.Homework
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newHomework)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedHomework := make([]models.Homework, len(newHomework))
+	for i, homework := range newHomework {
+		homework.Attachments = nil
+		if homework.Weight == 0 {
+			homework.Weight = 1
+		}
+
+		status, err := validateHomework(tx, r, &homework)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		assessment := homeworkAssessment(homework)
+		status, err = validateAssessment(tx, assessment)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		assessment, err = insertAssessment(tx, r, assessment)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		homework.AssessmentID = assessment.ID
+
+		homework.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO homework (assessment_id, teacher_id, title, description, due_at, version) VALUES (?, ?, ?, ?, ?, ?)",
+			homework.AssessmentID,
+			homework.TeacherID,
+			homework.Title,
+			homework.Description,
+			homework.DueAt,
+			homework.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		homework.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "homework", homework.ID, nil, homework)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedHomework[i] = homework
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Homework `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedHomework),
+		Data:   addedHomework,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update the title, description, due date or marking of a homework.
+// The class, subject, term and teacher stay fixed because scores may already exist.
+func EditHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	for _, key := range []string{"class_id", "subject_id", "term_id", "teacher_id", "assessment_id", "attachments"} {
+		if _, ok := input[key]; ok {
+			http.Error(w, "❌ "+key+" cannot be changed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingHomework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingHomework.ID, existingHomework.Version) {
+		return
+	}
+
+	previousHomework := existingHomework
+
+	err = ApplyPatch(&existingHomework, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existingHomework.Title = strings.TrimSpace(existingHomework.Title)
+	if existingHomework.Title == "" {
+		http.Error(w, "❌ title cannot be blank", http.StatusBadRequest)
+		return
+	}
+	due, err := parseDueAt(existingHomework.DueAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingHomework.DueAt = due.Format(DateTimeLayout)
+
+	assessment := homeworkAssessment(existingHomework)
+	status, err := validateAssessment(db, assessment)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var highestScore float64
+	err = db.QueryRow("SELECT COALESCE(MAX(score), 0) FROM scores WHERE assessment_id = ?", existingHomework.AssessmentID).Scan(&highestScore)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve scores")
+		http.Error(w, "❌ Unable to retrieve scores", http.StatusInternalServerError)
+		return
+	}
+	if highestScore > existingHomework.MaxScore {
+		http.Error(w, fmt.Sprintf("❌ max_score cannot be below the highest score already given (%g)", highestScore), http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE homework SET title = ?, description = ?, due_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingHomework.Title,
+		existingHomework.Description,
+		existingHomework.DueAt,
+		existingHomework.ID,
+		previousHomework.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating homework")
+		http.Error(w, "❌ Error updating homework", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingHomework.Version = previousHomework.Version + 1
+
+	_, err = tx.Exec(
+		"UPDATE assessments SET name = ?, weight = ?, max_score = ?, due_date = ?, version = version + 1 WHERE id = ?",
+		assessment.Name, assessment.Weight, assessment.MaxScore, assessment.DueDate, assessment.ID,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating homework assessment")
+		http.Error(w, "❌ Error updating homework assessment", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "homework", existingHomework.ID, previousHomework, existingHomework)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingHomework.ID, existingHomework.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingHomework)
+}
+
+// To delete a homework along with its assessment, so its scores stop counting
+func DeleteOneHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	deletedHomework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedHomework.ID, deletedHomework.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE homework SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedHomework.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete homework")
+		http.Error(w, "❌ Unable delete homework", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE assessments SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND deleted_at IS NULL", deletedHomework.AssessmentID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete homework assessment")
+		http.Error(w, "❌ Unable delete homework assessment", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "homework", id, deletedHomework, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Homework successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// To attach files to a homework, sent as multipart/form-data in the "files" field
+func AddHomeworkAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	err = parseUpload(w, r)
+	if errors.Is(err, storage.ErrTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	_, err = loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	attachments, status, err := saveAttachments(tx, r, homeworkAttachments, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		removeStoredFiles(attachments)
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Attachment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(attachments),
+		Data:   attachments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
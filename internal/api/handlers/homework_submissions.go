@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/storage"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// Submission statuses. Missing and pending are never stored: they describe students without
+// a submission, before and after the homework is due.
+const (
+	SubmissionPending   = "pending"
+	SubmissionMissing   = "missing"
+	SubmissionSubmitted = "submitted"
+	SubmissionLate      = "late"
+	SubmissionGraded    = "graded"
+)
+
+// The owner_type of submission attachments
+const submissionAttachments = "submission"
+
+// To give students without a submission their status
+func unsubmittedStatus(dueAt string, now time.Time) string {
+	due, err := time.ParseInLocation(DateTimeLayout, dueAt, time.Local)
+	if err == nil && now.After(due) {
+		return SubmissionMissing
+	}
+	return SubmissionPending
+}
+
+// To fill in the attachments of the given submissions
+func loadSubmissionAttachments(db *sql.DB, submissions []*models.Submission) error {
+	ids := make([]int, 0, len(submissions))
+	for _, submission := range submissions {
+		if submission.ID != 0 {
+			ids = append(ids, submission.ID)
+		}
+	}
+	attachments, err := loadAttachments(db, submissionAttachments, ids)
+	if err != nil {
+		return err
+	}
+	for _, submission := range submissions {
+		submission.Attachments = attachments[submission.ID]
+	}
+	return nil
+}
+
+// To get where every student of the class stands on a homework, filtered by ?status=
+func GetSubmissionsForHomework(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	homework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	// The class roster for the term, plus anyone who submitted before moving class
+	rows, err := db.Query(
+		`SELECT s.id, COALESCE(hs.id, 0), COALESCE(hs.status, ''), COALESCE(hs.text, ''), COALESCE(hs.submitted_at, ''), COALESCE(hs.version, 0), sc.score, COALESCE(sc.comment, '')
+		FROM students s
+		LEFT JOIN homework_submissions hs ON hs.homework_id = ? AND hs.student_id = s.id
+		LEFT JOIN scores sc ON sc.assessment_id = ? AND sc.student_id = s.id
+		WHERE s.deleted_at IS NULL AND (
+			s.class_id = ?
+			OR s.id IN (SELECT student_id FROM enrollments WHERE class_id = ? AND term_id = ?)
+			OR hs.id IS NOT NULL
+		)
+		ORDER BY s.last_name, s.first_name`,
+		homework.ID, homework.AssessmentID, homework.ClassID, homework.ClassID, homework.TermID,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	statusFilter := r.URL.Query().Get("status")
+	submissionList := make([]models.Submission, 0)
+	for rows.Next() {
+		submission := models.Submission{HomeworkID: homework.ID}
+		var score sql.NullFloat64
+		err := rows.Scan(&submission.StudentID, &submission.ID, &submission.Status, &submission.Text, &submission.SubmittedAt, &submission.Version, &score, &submission.Comment)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		if score.Valid {
+			submission.Score = &score.Float64
+		}
+		if submission.Status == "" {
+			submission.Status = unsubmittedStatus(homework.DueAt, now)
+		}
+		if statusFilter != "" && submission.Status != statusFilter {
+			continue
+		}
+		submissionList = append(submissionList, submission)
+	}
+	rows.Close()
+
+	pointers := make([]*models.Submission, len(submissionList))
+	for i := range submissionList {
+		pointers[i] = &submissionList[i]
+	}
+	err = loadSubmissionAttachments(db, pointers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Submission `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(submissionList),
+		Data:   submissionList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To hand in a homework for a student, either as JSON {"student_id": 1, "text": "..."} or as
+// multipart/form-data with student_id, text and files. Handing in again replaces the text and
+// adds the new files until the submission is graded. Anything after the due date is late.
+func SubmitHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+
+	var submission models.Submission
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = parseUpload(w, r)
+		if errors.Is(err, storage.ErrTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		submission.StudentID, err = strconv.Atoi(r.FormValue("student_id"))
+		if err != nil {
+			http.Error(w, "❌ Invalid student_id", http.StatusBadRequest)
+			return
+		}
+		submission.Text = r.FormValue("text")
+	} else {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&submission)
+		if err != nil {
+			http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	if submission.StudentID == 0 {
+		http.Error(w, "❌ student_id is required", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	homework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	var inClass int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL AND (class_id = ? OR id IN (SELECT student_id FROM enrollments WHERE class_id = ? AND term_id = ?))",
+		submission.StudentID, homework.ClassID, homework.ClassID, homework.TermID,
+	).Scan(&inClass)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve student")
+		http.Error(w, "❌ Unable to retrieve student", http.StatusInternalServerError)
+		return
+	}
+	if inClass == 0 {
+		http.Error(w, fmt.Sprintf("❌ Student %d is not in class %d", submission.StudentID, homework.ClassID), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	submission.HomeworkID = homework.ID
+	submission.SubmittedAt = now.Format(DateTimeLayout)
+	submission.Status = SubmissionSubmitted
+	if unsubmittedStatus(homework.DueAt, now) == SubmissionMissing {
+		submission.Status = SubmissionLate
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var existing models.Submission
+	err = tx.QueryRow(
+		"SELECT id, homework_id, student_id, status, text, COALESCE(submitted_at, ''), version FROM homework_submissions WHERE homework_id = ? AND student_id = ? FOR UPDATE",
+		homework.ID, submission.StudentID,
+	).Scan(&existing.ID, &existing.HomeworkID, &existing.StudentID, &existing.Status, &existing.Text, &existing.SubmittedAt, &existing.Version)
+
+	if err == sql.ErrNoRows {
+		submission.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO homework_submissions (homework_id, student_id, status, text, submitted_at, version) VALUES (?, ?, ?, ?, ?, ?)",
+			submission.HomeworkID, submission.StudentID, submission.Status, submission.Text, submission.SubmittedAt, submission.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		submission.ID = int(lastID)
+		err = RecordAudit(tx, r, AuditCreate, "homework_submissions", submission.ID, nil, submission)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve submission")
+		http.Error(w, "❌ Unable to retrieve submission", http.StatusInternalServerError)
+		return
+	} else {
+		if existing.Status == SubmissionGraded {
+			tx.Rollback()
+			http.Error(w, "❌ This submission has already been graded", http.StatusConflict)
+			return
+		}
+
+		submission.ID = existing.ID
+		submission.Version = existing.Version + 1
+		_, err = tx.Exec(
+			"UPDATE homework_submissions SET status = ?, text = ?, submitted_at = ?, version = version + 1 WHERE id = ?",
+			submission.Status, submission.Text, submission.SubmittedAt, submission.ID,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error updating submission")
+			http.Error(w, "❌ Error updating submission", http.StatusInternalServerError)
+			return
+		}
+		err = RecordAudit(tx, r, AuditUpdate, "homework_submissions", submission.ID, existing, submission)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	attachments, status, err := saveAttachments(tx, r, submissionAttachments, submission.ID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		removeStoredFiles(attachments)
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	err = loadSubmissionAttachments(db, []*models.Submission{&submission})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(submission)
+}
+
+// To grade a student's homework e.g. {"score": 18, "comment": "Good work"}. The score is saved
+// against the homework's assessment so it counts in the gradebook. Students who never handed
+// anything in can be graded too, e.g. with a zero.
+func GradeSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid homework id", http.StatusBadRequest)
+		return
+	}
+	studentId, err := strconv.Atoi(r.PathValue("student_id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	var grade struct {
+		Score   *float64 `json:"score"`
+		Comment string   `json:"comment"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&grade)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if grade.Score == nil {
+		http.Error(w, "❌ score is required", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	homework, err := loadHomework(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Homework not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	score, status, err := SaveScore(tx, r, homeworkAssessment(homework), models.Score{StudentID: studentId, Score: *grade.Score, Comment: grade.Comment})
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	submission := models.Submission{HomeworkID: homework.ID, StudentID: studentId, Status: SubmissionGraded}
+	var existing models.Submission
+	err = tx.QueryRow(
+		"SELECT id, homework_id, student_id, status, text, COALESCE(submitted_at, ''), version FROM homework_submissions WHERE homework_id = ? AND student_id = ? FOR UPDATE",
+		homework.ID, studentId,
+	).Scan(&existing.ID, &existing.HomeworkID, &existing.StudentID, &existing.Status, &existing.Text, &existing.SubmittedAt, &existing.Version)
+
+	if err == sql.ErrNoRows {
+		submission.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO homework_submissions (homework_id, student_id, status, text, submitted_at, version) VALUES (?, ?, ?, '', NULL, ?)",
+			submission.HomeworkID, submission.StudentID, submission.Status, submission.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		submission.ID = int(lastID)
+		err = RecordAudit(tx, r, AuditCreate, "homework_submissions", submission.ID, nil, submission)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve submission")
+		http.Error(w, "❌ Unable to retrieve submission", http.StatusInternalServerError)
+		return
+	} else {
+		submission.ID = existing.ID
+		submission.Text = existing.Text
+		submission.SubmittedAt = existing.SubmittedAt
+		submission.Version = existing.Version + 1
+		_, err = tx.Exec("UPDATE homework_submissions SET status = ?, version = version + 1 WHERE id = ?", submission.Status, submission.ID)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error updating submission")
+			http.Error(w, "❌ Error updating submission", http.StatusInternalServerError)
+			return
+		}
+		err = RecordAudit(tx, r, AuditUpdate, "homework_submissions", submission.ID, existing, submission)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	submission.Score = &score.Score
+	submission.Comment = score.Comment
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submission)
+}
+
+// To get the homework set for a student's classes with where each of their submissions stands
+func GetHomeworkForAStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := `SELECT h.id, h.assessment_id, h.teacher_id, a.class_id, a.subject_id, a.term_id, h.title, h.description, h.due_at, a.max_score, a.weight, h.version,
+			COALESCE(hs.id, 0), COALESCE(hs.status, ''), COALESCE(hs.text, ''), COALESCE(hs.submitted_at, ''), COALESCE(hs.version, 0), sc.score, COALESCE(sc.comment, '')
+		FROM homework h
+		JOIN assessments a ON a.id = h.assessment_id
+		LEFT JOIN homework_submissions hs ON hs.homework_id = h.id AND hs.student_id = ?
+		LEFT JOIN scores sc ON sc.assessment_id = h.assessment_id AND sc.student_id = ?
+		WHERE h.deleted_at IS NULL AND (
+			a.class_id = (SELECT class_id FROM students WHERE id = ?)
+			OR EXISTS (SELECT 1 FROM enrollments e WHERE e.student_id = ? AND e.class_id = a.class_id AND e.term_id = a.term_id)
+		)`
+	args := []interface{}{id, id, id, id}
+	if termId := r.URL.Query().Get("term_id"); termId != "" {
+		query += " AND a.term_id = ?"
+		args = append(args, termId)
+	}
+	query += " ORDER BY h.due_at"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	statusFilter := r.URL.Query().Get("status")
+	homeworkList := make([]models.StudentHomework, 0)
+	for rows.Next() {
+		var item models.StudentHomework
+		var score sql.NullFloat64
+		err := rows.Scan(
+			&item.ID, &item.AssessmentID, &item.TeacherID, &item.ClassID, &item.SubjectID, &item.TermID, &item.Title, &item.Description, &item.DueAt, &item.MaxScore, &item.Weight, &item.Version,
+			&item.Submission.ID, &item.Submission.Status, &item.Submission.Text, &item.Submission.SubmittedAt, &item.Submission.Version, &score, &item.Submission.Comment,
+		)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		item.Submission.HomeworkID = item.ID
+		item.Submission.StudentID = id
+		if score.Valid {
+			item.Submission.Score = &score.Float64
+		}
+		if item.Submission.Status == "" {
+			item.Submission.Status = unsubmittedStatus(item.DueAt, now)
+		}
+		if statusFilter != "" && item.Submission.Status != statusFilter {
+			continue
+		}
+		homeworkList = append(homeworkList, item)
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.StudentHomework `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(homeworkList),
+		Data:   homeworkList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
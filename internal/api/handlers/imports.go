@@ -0,0 +1,695 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// The largest CSV file that can be imported in one go
+const maxImportSize = 10 << 20
+
+const importColumns = "id, resource, file_name, encoding, dry_run, batch_size, total_rows, imported_rows, failed_rows, created_by, created_at"
+
+var importFilterFields = map[string]string{
+	"resource": "resource",
+}
+
+// importer describes how the rows of a CSV file become records of one resource
+type importer struct {
+	resource string
+	// The fields a file can fill, each with the other headings exports use for it
+	aliases map[string][]string
+	// The file needs a column for at least one field of each group
+	required [][]string
+	// To turn the values of a row into a record, returning the column at fault when it can't
+	prepare func(db Queryer, values map[string]string) (interface{}, string, error)
+	// To save a prepared record
+	insert func(tx *sql.Tx, r *http.Request, record interface{}, source string) error
+}
+
+// A row of the file that passed validation, waiting to be saved
+type importRow struct {
+	line   int
+	record interface{}
+}
+
+var studentImporter = importer{
+	resource: "students",
+	aliases: map[string][]string{
+		"first_name": {"firstname", "first", "given_name", "forename"},
+		"last_name":  {"lastname", "last", "surname", "family_name"},
+		"email":      {"e_mail", "email_address", "e_mail_address"},
+		"class":      {"class_name", "form", "homeroom"},
+		"class_id":   {},
+	},
+	required: [][]string{{"first_name"}, {"last_name"}, {"email"}, {"class", "class_id"}},
+	prepare:  prepareImportedStudent,
+	insert:   insertImportedStudent,
+}
+
+var teacherImporter = importer{
+	resource: "teachers",
+	aliases: map[string][]string{
+		"first_name": {"firstname", "first", "given_name", "forename"},
+		"last_name":  {"lastname", "last", "surname", "family_name"},
+		"email":      {"e_mail", "email_address", "e_mail_address"},
+		"class":      {"class_name", "form", "homeroom"},
+		"class_id":   {},
+		"subject":    {"subject_name"},
+		"subject_id": {},
+	},
+	required: [][]string{{"first_name"}, {"last_name"}, {"email"}, {"class", "class_id"}, {"subject", "subject_id"}},
+	prepare:  prepareImportedTeacher,
+	insert:   insertImportedTeacher,
+}
+
+// To read an optional id column of an imported row
+func importedID(values map[string]string, field string) (int, error) {
+	if values[field] == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(values[field])
+	if err != nil || id < 1 {
+		return 0, errors.New("❌ " + values[field] + " is not a valid " + field)
+	}
+	return id, nil
+}
+
+// To check the email of an imported row and that nobody in the table already has it
+func importedEmail(db Queryer, table, email string) (string, error) {
+	email = strings.ToLower(email)
+	if err := utils.ValidateEmail(email); err != nil {
+		return "", err
+	}
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE email = ? AND deleted_at IS NULL", email).Scan(&count)
+	if err != nil {
+		return "", utils.ErrorHandler(err, "❌ Database query error")
+	}
+	if count > 0 {
+		return "", errors.New("❌ " + email + " is already in use")
+	}
+	return email, nil
+}
+
+func prepareImportedStudent(db Queryer, values map[string]string) (interface{}, string, error) {
+	student := models.Student{
+		FirstName: values["first_name"],
+		LastName:  values["last_name"],
+		Class:     values["class"],
+		Status:    "active",
+	}
+	if student.FirstName == "" || student.LastName == "" {
+		return nil, "", errors.New("❌ first_name and last_name are required")
+	}
+
+	var err error
+	student.Email, err = importedEmail(db, "students", values["email"])
+	if err != nil {
+		return nil, "email", err
+	}
+
+	student.ClassID, err = importedID(values, "class_id")
+	if err != nil {
+		return nil, "class_id", err
+	}
+	student.ClassID, student.Class, err = ResolveClass(db, student.ClassID, student.Class)
+	if err != nil {
+		return nil, "class", err
+	}
+
+	if err := CheckBlankFields(student); err != nil {
+		return nil, "", err
+	}
+	return student, "", nil
+}
+
+func insertImportedStudent(tx *sql.Tx, r *http.Request, record interface{}, source string) error {
+	student := record.(models.Student)
+	student.Version = 1
+	res, err := tx.Exec(utils.GenerateInsertQuery("students", models.Student{}), utils.GetStructValues(student)...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error inserting data into database")
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error getting last insert ID")
+	}
+	student.ID = int(lastID)
+
+	err = RecordAudit(tx, r, AuditCreate, "students", student.ID, nil, student)
+	if err != nil {
+		return err
+	}
+	err = EnrollStudent(tx, student.ID, student.ClassID)
+	if err != nil {
+		return err
+	}
+	return recordEnrollmentEvent(tx, r, &models.EnrollmentEvent{
+		StudentID: student.ID,
+		Event:     "admitted",
+		ToStatus:  student.Status,
+		ToClassID: student.ClassID,
+		Reason:    "Imported from " + source,
+	})
+}
+
+func prepareImportedTeacher(db Queryer, values map[string]string) (interface{}, string, error) {
+	teacher := models.Teacher{
+		FirstName: values["first_name"],
+		LastName:  values["last_name"],
+		Class:     values["class"],
+		Subject:   values["subject"],
+	}
+	if teacher.FirstName == "" || teacher.LastName == "" {
+		return nil, "", errors.New("❌ first_name and last_name are required")
+	}
+
+	var err error
+	teacher.Email, err = importedEmail(db, "teachers", values["email"])
+	if err != nil {
+		return nil, "email", err
+	}
+
+	teacher.ClassID, err = importedID(values, "class_id")
+	if err != nil {
+		return nil, "class_id", err
+	}
+	teacher.ClassID, teacher.Class, err = ResolveClass(db, teacher.ClassID, teacher.Class)
+	if err != nil {
+		return nil, "class", err
+	}
+
+	teacher.SubjectID, err = importedID(values, "subject_id")
+	if err != nil {
+		return nil, "subject_id", err
+	}
+	teacher.SubjectID, teacher.Subject, err = ResolveSubject(db, teacher.SubjectID, teacher.Subject)
+	if err != nil {
+		return nil, "subject", err
+	}
+
+	if err := CheckBlankFields(teacher); err != nil {
+		return nil, "", err
+	}
+	return teacher, "", nil
+}
+
+func insertImportedTeacher(tx *sql.Tx, r *http.Request, record interface{}, source string) error {
+	teacher := record.(models.Teacher)
+	teacher.Version = 1
+	res, err := tx.Exec(utils.GenerateInsertQuery("teachers", models.Teacher{}), utils.GetStructValues(teacher)...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error inserting data into database")
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error getting last insert ID")
+	}
+	teacher.ID = int(lastID)
+
+	return RecordAudit(tx, r, AuditCreate, "teachers", teacher.ID, nil, teacher)
+}
+
+// To import students from a CSV file, see runImport
+func ImportStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	runImport(w, r, studentImporter)
+}
+
+// To import teachers from a CSV file, see runImport
+func ImportTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	runImport(w, r, teacherImporter)
+}
+
+// To read the uploaded file, either the "file" field of a multipart/form-data body or the
+// whole body, e.g. sent as text/csv. It returns the HTTP status to answer with on failure.
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, string, int, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	tooLarge := fmt.Errorf("❌ The file is too large, import at most %d MB at a time", maxImportSize>>20)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(maxImportSize)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, "", http.StatusRequestEntityTooLarge, tooLarge
+			}
+			return nil, "", http.StatusBadRequest, errors.New("❌ Expected a multipart/form-data body")
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", http.StatusBadRequest, errors.New("❌ Send the CSV file in the file field")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error reading file")
+		}
+		return data, header.Filename, http.StatusOK, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, "", http.StatusRequestEntityTooLarge, tooLarge
+		}
+		return nil, "", http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Error reading request body")
+	}
+	defer r.Body.Close()
+	return data, r.URL.Query().Get("file_name"), http.StatusOK, nil
+}
+
+// To find the field each column fills. Headings are matched to fields by name and common
+// aliases such as Surname, and ?map=Heading=field,... maps the ones that aren't recognised.
+func mapImportColumns(imp importer, headings []string, mapping string) ([]string, map[string]string, []string, error) {
+	fields := make(map[string]string)
+	for field, aliases := range imp.aliases {
+		fields[field] = field
+		for _, alias := range aliases {
+			fields[alias] = field
+		}
+	}
+
+	explicit := make(map[string]string)
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		heading, field, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if _, known := imp.aliases[field]; !ok || !known {
+			return nil, nil, nil, errors.New("❌ Invalid map " + pair + ", use Heading=field with a field of " + imp.resource)
+		}
+		explicit[utils.NormalizeHeader(heading)] = field
+	}
+
+	columnFields := make([]string, len(headings))
+	columns := make(map[string]string)
+	ignored := make([]string, 0)
+	filledBy := make(map[string]string)
+	for i, heading := range headings {
+		normalized := utils.NormalizeHeader(heading)
+		field, ok := explicit[normalized]
+		if !ok {
+			field = fields[normalized]
+		}
+		if field == "" {
+			ignored = append(ignored, heading)
+			continue
+		}
+		if other, taken := filledBy[field]; taken {
+			return nil, nil, nil, fmt.Errorf("❌ The columns %s and %s both fill %s, map one of them to another field", other, heading, field)
+		}
+		filledBy[field] = heading
+		columnFields[i] = field
+		columns[heading] = field
+	}
+
+	for _, group := range imp.required {
+		found := false
+		for _, field := range group {
+			if filledBy[field] != "" {
+				found = true
+			}
+		}
+		if !found {
+			return nil, nil, nil, errors.New("❌ The file has no column for " + strings.Join(group, " or "))
+		}
+	}
+	return columnFields, columns, ignored, nil
+}
+
+// To import the rows of a CSV file. The file can be UTF-8, UTF-16 or Windows-1252 and
+// separated by commas, semicolons or tabs, and its headings are mapped to fields.
+//
+// ?dry_run=true only checks the rows. Otherwise ?batch_size=0, the default, saves every row
+// in one transaction and nothing when any row is invalid, while ?batch_size=N saves the valid
+// rows N at a time so that a failed batch doesn't undo the others. Every run is recorded in
+// imports, with the rows that failed downloadable as CSV from /imports/{id}/errors.
+func runImport(w http.ResponseWriter, r *http.Request, imp importer) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "❌ dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	batchSize := 0
+	if v := r.URL.Query().Get("batch_size"); v != "" {
+		var err error
+		batchSize, err = strconv.Atoi(v)
+		if err != nil || batchSize < 0 {
+			http.Error(w, "❌ batch_size must be 0 or a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, fileName, status, err := readImportFile(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if fileName == "" {
+		fileName = imp.resource + ".csv"
+	}
+
+	text, encoding := utils.DecodeText(data)
+	firstLine, _, _ := strings.Cut(text, "\n")
+	if strings.TrimSpace(firstLine) == "" {
+		http.Error(w, "❌ The file is empty, the first line must hold the column headings", http.StatusBadRequest)
+		return
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = utils.DetectDelimiter(firstLine)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headings, err := reader.Read()
+	if err != nil {
+		http.Error(w, "❌ The column headings can't be read: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	columnFields, columns, ignored, err := mapImportColumns(imp, headings, r.URL.Query().Get("map"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	result := models.Import{
+		Resource:       imp.resource,
+		FileName:       fileName,
+		Encoding:       encoding,
+		DryRun:         dryRun,
+		BatchSize:      batchSize,
+		Columns:        columns,
+		IgnoredColumns: ignored,
+		Errors:         make([]models.ImportRowError, 0),
+	}
+	rawRows := make(map[int][]string)
+	var validRows []importRow
+	emailRows := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, "❌ The file can't be read: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string)
+		blank := true
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				blank = false
+			}
+			if i < len(columnFields) && columnFields[i] != "" {
+				values[columnFields[i]] = value
+			}
+		}
+		if blank {
+			continue
+		}
+		result.TotalRows++
+		rawRows[line] = record
+
+		email := strings.ToLower(values["email"])
+		if earlier, ok := emailRows[email]; ok && email != "" {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: line, Column: "email", Message: fmt.Sprintf("❌ %s is also on row %d", email, earlier)})
+			continue
+		}
+		emailRows[email] = line
+
+		prepared, column, err := imp.prepare(db, values)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: line, Column: column, Message: err.Error()})
+			continue
+		}
+		validRows = append(validRows, importRow{line: line, record: prepared})
+	}
+
+	if result.TotalRows == 0 {
+		http.Error(w, "❌ The file has no rows below the column headings", http.StatusBadRequest)
+		return
+	}
+
+	if !dryRun && (batchSize > 0 || len(result.Errors) == 0) {
+		size := batchSize
+		if size == 0 {
+			size = len(validRows)
+		}
+		for start := 0; start < len(validRows); start += size {
+			batch := validRows[start:min(start+size, len(validRows))]
+			failed, err := saveImportBatch(db, r, imp, batch, fileName)
+			if err != nil {
+				for _, row := range batch {
+					message := fmt.Sprintf("❌ Not imported, row %d of the same batch failed", failed.line)
+					if row.line == failed.line {
+						message = err.Error()
+					}
+					result.Errors = append(result.Errors, models.ImportRowError{Row: row.line, Message: message})
+				}
+				continue
+			}
+			result.ImportedRows += len(batch)
+		}
+	}
+
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+	result.FailedRows = result.TotalRows - len(validRows)
+	if !dryRun {
+		result.FailedRows = result.TotalRows - result.ImportedRows
+	}
+
+	report, err := importErrorReport(headings, rawRows, result.Errors)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result.CreatedBy = ActorID(r)
+	result.CreatedAt = time.Now().Format(DateTimeLayout)
+	res, err := db.Exec(
+		"INSERT INTO imports (resource, file_name, encoding, dry_run, batch_size, total_rows, imported_rows, failed_rows, error_report, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		result.Resource,
+		result.FileName,
+		result.Encoding,
+		result.DryRun,
+		result.BatchSize,
+		result.TotalRows,
+		result.ImportedRows,
+		result.FailedRows,
+		report,
+		result.CreatedBy,
+		result.CreatedAt,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error recording import")
+		http.Error(w, "❌ Error recording import", http.StatusInternalServerError)
+		return
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+		return
+	}
+	result.ID = int(lastID)
+	if len(result.Errors) > 0 {
+		result.ErrorReport = fmt.Sprintf("/imports/%d/errors", result.ID)
+	}
+
+	status = http.StatusOK
+	if result.ImportedRows > 0 {
+		status = http.StatusCreated
+	} else if !dryRun {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// To save one batch of rows in its own transaction. On failure it returns the row at fault.
+func saveImportBatch(db *sql.DB, r *http.Request, imp importer, batch []importRow, source string) (importRow, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return batch[0], utils.ErrorHandler(err, "❌ Error starting transaction")
+	}
+
+	for _, row := range batch {
+		err = imp.insert(tx, r, row.record, source)
+		if err != nil {
+			tx.Rollback()
+			return row, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return batch[len(batch)-1], utils.ErrorHandler(err, "❌ Error committing transaction")
+	}
+	return importRow{}, nil
+}
+
+// To write the rows that failed as CSV: the row number and what was wrong, followed by the
+// row as it was in the file so it can be fixed and imported again
+func importErrorReport(headings []string, rawRows map[int][]string, rowErrors []models.ImportRowError) (string, error) {
+	if len(rowErrors) == 0 {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(append([]string{"row", "error"}, headings...))
+
+	for i := 0; i < len(rowErrors); {
+		row := rowErrors[i].Row
+		var messages []string
+		for ; i < len(rowErrors) && rowErrors[i].Row == row; i++ {
+			messages = append(messages, strings.TrimSpace(strings.TrimPrefix(rowErrors[i].Message, "❌")))
+		}
+		writer.Write(append([]string{strconv.Itoa(row), strings.Join(messages, "; ")}, rawRows[row]...))
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", utils.ErrorHandler(err, "❌ Error writing error report")
+	}
+	return buf.String(), nil
+}
+
+// To get past imports, newest first, e.g. ?resource=students
+func GetImportsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + importColumns + ", error_report != '' FROM imports WHERE 1=1"
+	var args []interface{}
+	query, args = utils.AddFiltersFor(r, query, args, importFilterFields)
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	importList := make([]models.Import, 0)
+	for rows.Next() {
+		var imp models.Import
+		var hasReport bool
+		err := rows.Scan(
+			&imp.ID,
+			&imp.Resource,
+			&imp.FileName,
+			&imp.Encoding,
+			&imp.DryRun,
+			&imp.BatchSize,
+			&imp.TotalRows,
+			&imp.ImportedRows,
+			&imp.FailedRows,
+			&imp.CreatedBy,
+			&imp.CreatedAt,
+			&hasReport,
+		)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		if hasReport {
+			imp.ErrorReport = fmt.Sprintf("/imports/%d/errors", imp.ID)
+		}
+		importList = append(importList, imp)
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Data   []models.Import `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(importList),
+		Data:   importList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To download the rows of an import that failed as a CSV file
+func GetImportErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid import id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var resource, report string
+	err = db.QueryRow("SELECT resource, error_report FROM imports WHERE id = ?", id).Scan(&resource, &report)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Import not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	if report == "" {
+		http.Error(w, "❌ Every row of this import was valid", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-import-%d-errors.csv"`, resource, id))
+	io.WriteString(w, report)
+}
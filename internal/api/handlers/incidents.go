@@ -0,0 +1,706 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const incidentColumns = "id, student_id, COALESCE(term_id, 0), occurred_at, category, severity, description, COALESCE(reported_by, 0), actions_taken, COALESCE(follow_up_date, ''), guardian_notified, COALESCE(guardian_notified_at, ''), confidential, recorded_by, version"
+
+// Severities from least to most serious
+var incidentSeverities = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+var incidentFilterFields = map[string]string{
+	"student_id":  "student_id",
+	"term_id":     "term_id",
+	"category":    "category",
+	"severity":    "severity",
+	"reported_by": "reported_by",
+}
+
+var incidentSortFields = map[string]bool{
+	"occurred_at":    true,
+	"severity":       true,
+	"category":       true,
+	"follow_up_date": true,
+}
+
+func scanIncident(row interface{ Scan(...interface{}) error }, incident *models.Incident) error {
+	return row.Scan(
+		&incident.ID,
+		&incident.StudentID,
+		&incident.TermID,
+		&incident.OccurredAt,
+		&incident.Category,
+		&incident.Severity,
+		&incident.Description,
+		&incident.ReportedBy,
+		&incident.ActionsTaken,
+		&incident.FollowUpDate,
+		&incident.GuardianNotified,
+		&incident.GuardianNotifiedAt,
+		&incident.Confidential,
+		&incident.RecordedBy,
+		&incident.Version,
+	)
+}
+
+// To refuse users outside the pastoral roles in INCIDENT_ROLES. It answers the request itself when they are refused.
+func allowIncidents(w http.ResponseWriter, r *http.Request) bool {
+	if !HasRole(r, rolesFromEnv("INCIDENT_ROLES", "admin", "manager", "pastoral")) {
+		http.Error(w, "❌ Your role may not access incident records", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// To check whether the user may see confidential incidents, per INCIDENT_CONFIDENTIAL_ROLES
+func canSeeConfidentialIncidents(r *http.Request) bool {
+	return HasRole(r, rolesFromEnv("INCIDENT_CONFIDENTIAL_ROLES", "admin", "pastoral"))
+}
+
+// To hide confidential incidents from users who may not see them
+func incidentVisibility(r *http.Request, alias string) string {
+	if canSeeConfidentialIncidents(r) {
+		return ""
+	}
+	return " AND " + alias + "confidential = FALSE"
+}
+
+// To check an incident before it is written and fill in its term and defaults.
+// It returns the HTTP status to answer with when invalid.
+func validateIncident(db Queryer, incident *models.Incident) (int, error) {
+	incident.Category = strings.ToLower(strings.TrimSpace(incident.Category))
+	incident.Description = strings.TrimSpace(incident.Description)
+	incident.ActionsTaken = strings.TrimSpace(incident.ActionsTaken)
+	if incident.StudentID == 0 || incident.Category == "" || incident.Description == "" {
+		return http.StatusBadRequest, errors.New("❌ student_id, reported_by, category and description are required")
+	}
+	// The reporter of an existing incident can be gone once their teacher record was purged
+	if incident.ID == 0 && incident.ReportedBy == 0 {
+		return http.StatusBadRequest, errors.New("❌ student_id, reported_by, category and description are required")
+	}
+
+	if incident.Severity == "" {
+		incident.Severity = "low"
+	}
+	if _, ok := incidentSeverities[incident.Severity]; !ok {
+		return http.StatusBadRequest, errors.New("❌ severity must be low, medium, high or critical")
+	}
+
+	occurredAt := time.Now()
+	if incident.OccurredAt != "" {
+		var err error
+		occurredAt, err = parseDateTime(incident.OccurredAt)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if occurredAt.After(time.Now()) {
+		return http.StatusBadRequest, errors.New("❌ occurred_at cannot be in the future")
+	}
+	incident.OccurredAt = occurredAt.Format(DateTimeLayout)
+
+	if incident.FollowUpDate != "" {
+		if _, err := time.Parse(DateLayout, incident.FollowUpDate); err != nil {
+			return http.StatusBadRequest, errors.New("❌ follow_up_date must be a date like 2025-09-01")
+		}
+		if incident.FollowUpDate < occurredAt.Format(DateLayout) {
+			return http.StatusBadRequest, errors.New("❌ follow_up_date cannot be before the incident")
+		}
+	}
+
+	if !incident.GuardianNotified {
+		incident.GuardianNotifiedAt = ""
+	} else if incident.GuardianNotifiedAt == "" {
+		incident.GuardianNotifiedAt = time.Now().Format(DateTimeLayout)
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL", incident.StudentID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve student")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Student %d does not exist", incident.StudentID)
+	}
+
+	if incident.ReportedBy != 0 {
+		err = db.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deleted_at IS NULL", incident.ReportedBy).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve teacher")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Teacher %d does not exist", incident.ReportedBy)
+		}
+	}
+
+	// Incidents outside any term are kept, they just don't show in term summaries
+	term, err := CurrentTerm(db, occurredAt.Format(DateLayout))
+	if errors.Is(err, ErrNoCurrentTerm) {
+		incident.TermID = 0
+	} else if err != nil {
+		return http.StatusInternalServerError, err
+	} else {
+		incident.TermID = term.ID
+	}
+
+	return http.StatusOK, nil
+}
+
+// To list incidents matching a condition, most recent first unless sorted otherwise
+func listIncidents(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + incidentColumns + " FROM incidents WHERE 1=1" + condition + incidentVisibility(r, "")
+	args := conditionArgs
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+	if r.URL.Query().Get("follow_up") == "due" {
+		query += " AND follow_up_date <= CURDATE()"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, incidentFilterFields)
+	if sorted := utils.AddSortingFor(r, query, incidentSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY occurred_at DESC, id DESC"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	incidentList := make([]models.Incident, 0)
+	for rows.Next() {
+		var incident models.Incident
+		err := scanIncident(rows, &incident)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		incidentList = append(incidentList, incident)
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Incident `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(incidentList),
+		Data:   incidentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get incidents, filtered by e.g. ?severity=high&term_id=3, or ?follow_up=due for follow ups that have come round
+func GetIncidentsHandler(w http.ResponseWriter, r *http.Request) {
+	listIncidents(w, r, "")
+}
+
+// To get a student's incident history
+func GetIncidentsForAStudent(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	listIncidents(w, r, " AND student_id = ?", studentId)
+}
+
+// To load one incident the user may see
+func loadIncident(db Queryer, r *http.Request, id int) (models.Incident, int, error) {
+	var incident models.Incident
+	err := scanIncident(db.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id = ? AND deleted_at IS NULL"+incidentVisibility(r, ""), id), &incident)
+	if err == sql.ErrNoRows {
+		return incident, http.StatusNotFound, errors.New("❌ Incident not found")
+	} else if err != nil {
+		return incident, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	return incident, http.StatusOK, nil
+}
+
+func GetOneIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid incident id", http.StatusBadRequest)
+		return
+	}
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	incident, status, err := loadIncident(db, r, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(incident.ID, incident.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+// To log incidents
+func AddIncidentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	var newIncidents []models.Incident
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newIncidents)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newIncidents {
+		if newIncidents[i].Confidential && !canSeeConfidentialIncidents(r) {
+			http.Error(w, "❌ Your role may not record confidential incidents", http.StatusForbidden)
+			return
+		}
+		newIncidents[i].GuardianNotifiedAt = ""
+		status, err := validateIncident(db, &newIncidents[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedIncidents := make([]models.Incident, len(newIncidents))
+	for i, newIncident := range newIncidents {
+		newIncident.Version = 1
+		newIncident.RecordedBy = ActorID(r)
+		res, err := tx.Exec(
+			"INSERT INTO incidents (student_id, term_id, occurred_at, category, severity, description, reported_by, actions_taken, follow_up_date, guardian_notified, guardian_notified_at, confidential, recorded_by, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			newIncident.StudentID,
+			nullableID(newIncident.TermID),
+			newIncident.OccurredAt,
+			newIncident.Category,
+			newIncident.Severity,
+			newIncident.Description,
+			newIncident.ReportedBy,
+			newIncident.ActionsTaken,
+			nullableDate(newIncident.FollowUpDate),
+			newIncident.GuardianNotified,
+			nullableDate(newIncident.GuardianNotifiedAt),
+			newIncident.Confidential,
+			newIncident.RecordedBy,
+			newIncident.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newIncident.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "incidents", newIncident.ID, nil, newIncident)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedIncidents[i] = newIncident
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Incident `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedIncidents),
+		Data:   addedIncidents,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update an incident, e.g. to record actions taken or that the guardian was told
+func EditIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid incident id", http.StatusBadRequest)
+		return
+	}
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingIncident, status, err := loadIncident(db, r, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingIncident.ID, existingIncident.Version) {
+		return
+	}
+
+	previousIncident := existingIncident
+
+	err = ApplyPatch(&existingIncident, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingIncident.RecordedBy = previousIncident.RecordedBy
+	if existingIncident.GuardianNotified != previousIncident.GuardianNotified {
+		existingIncident.GuardianNotifiedAt = ""
+	}
+	if existingIncident.Confidential && !canSeeConfidentialIncidents(r) {
+		http.Error(w, "❌ Your role may not mark incidents confidential", http.StatusForbidden)
+		return
+	}
+
+	status, err = validateIncident(db, &existingIncident)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE incidents SET student_id = ?, term_id = ?, occurred_at = ?, category = ?, severity = ?, description = ?, reported_by = ?, actions_taken = ?, follow_up_date = ?, guardian_notified = ?, guardian_notified_at = ?, confidential = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingIncident.StudentID,
+		nullableID(existingIncident.TermID),
+		existingIncident.OccurredAt,
+		existingIncident.Category,
+		existingIncident.Severity,
+		existingIncident.Description,
+		nullableID(existingIncident.ReportedBy),
+		existingIncident.ActionsTaken,
+		nullableDate(existingIncident.FollowUpDate),
+		existingIncident.GuardianNotified,
+		nullableDate(existingIncident.GuardianNotifiedAt),
+		existingIncident.Confidential,
+		existingIncident.ID,
+		previousIncident.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating incident")
+		http.Error(w, "❌ Error updating incident", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingIncident.Version = previousIncident.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "incidents", existingIncident.ID, previousIncident, existingIncident)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingIncident.ID, existingIncident.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingIncident)
+}
+
+func DeleteOneIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid incident id", http.StatusBadRequest)
+		return
+	}
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	deletedIncident, status, err := loadIncident(db, r, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedIncident.ID, deletedIncident.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE incidents SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedIncident.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete incident")
+		http.Error(w, "❌ Unable delete incident", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "incidents", id, deletedIncident, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Incident successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowIncidents(w, r) {
+		return
+	}
+	restoreResource(w, r, "incidents", "Incident")
+}
+
+// To summarise the incidents of a class's students in a term (?term_id=, the current term by default)
+func GetClassIncidentSummary(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+	if !allowIncidents(w, r) {
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Students are counted in the class they were enrolled in that term
+	rows, err := db.Query(`SELECT i.student_id, s.first_name, s.last_name, i.category, i.severity, i.guardian_notified,
+		i.follow_up_date IS NOT NULL AND i.follow_up_date <= CURDATE()
+		FROM incidents i
+		JOIN enrollments e ON e.student_id = i.student_id AND e.term_id = i.term_id
+		JOIN students s ON s.id = i.student_id
+		WHERE e.class_id = ? AND i.term_id = ? AND i.deleted_at IS NULL`+incidentVisibility(r, "i."),
+		classId, termId,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	summary := models.IncidentSummary{
+		ClassID:    classId,
+		TermID:     termId,
+		ByCategory: map[string]int{},
+		BySeverity: map[string]int{},
+		Students:   []models.StudentIncidents{},
+	}
+	byStudent := map[int]*models.StudentIncidents{}
+	for rows.Next() {
+		var student models.StudentIncidents
+		var category, severity string
+		var guardianNotified, followUpDue bool
+		err := rows.Scan(&student.StudentID, &student.FirstName, &student.LastName, &category, &severity, &guardianNotified, &followUpDue)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+
+		summary.Total++
+		summary.ByCategory[category]++
+		summary.BySeverity[severity]++
+		if guardianNotified {
+			summary.GuardianNotices++
+		}
+		if followUpDue {
+			summary.FollowUpsDue++
+		}
+
+		line, ok := byStudent[student.StudentID]
+		if !ok {
+			line = &student
+			byStudent[student.StudentID] = line
+		}
+		line.Incidents++
+		if incidentSeverities[severity] > incidentSeverities[line.HighestSeverity] {
+			line.HighestSeverity = severity
+		}
+	}
+
+	for _, line := range byStudent {
+		summary.Students = append(summary.Students, *line)
+	}
+	// Students with the most incidents first
+	sort.Slice(summary.Students, func(i, j int) bool {
+		a, b := summary.Students[i], summary.Students[j]
+		if a.Incidents != b.Incidents {
+			return a.Incidents > b.Incidents
+		}
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		return a.StudentID < b.StudentID
+	})
+
+	response := struct {
+		Status string                 `json:"status"`
+		Data   models.IncidentSummary `json:"data"`
+	}{
+		Status: "success",
+		Data:   summary,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
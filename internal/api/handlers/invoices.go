@@ -0,0 +1,650 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const invoiceColumns = "id, invoice_number, student_id, COALESCE(term_id, 0), currency, total, amount_paid, due_date, issued_at, version"
+
+// The status of an invoice is worked out from its amounts and due date rather than stored
+const invoiceStatusSQL = `CASE WHEN voided_at IS NOT NULL THEN 'void'
+	WHEN amount_paid >= total THEN 'paid'
+	WHEN due_date < CURDATE() THEN 'overdue'
+	WHEN amount_paid > 0 THEN 'partially_paid'
+	ELSE 'open' END`
+
+const invoiceSelect = "SELECT " + invoiceColumns + ", " + invoiceStatusSQL + " FROM invoices"
+
+var invoiceStatuses = map[string]bool{
+	"open":           true,
+	"partially_paid": true,
+	"paid":           true,
+	"overdue":        true,
+	"void":           true,
+}
+
+var invoiceFilterFields = map[string]string{
+	"student_id": "student_id",
+	"term_id":    "term_id",
+	"currency":   "currency",
+}
+
+var invoiceSortFields = map[string]bool{
+	"invoice_number": true,
+	"student_id":     true,
+	"total":          true,
+	"due_date":       true,
+	"issued_at":      true,
+}
+
+const invoiceLineColumns = "id, invoice_id, COALESCE(fee_schedule_id, 0), description, amount"
+
+func scanInvoice(row interface{ Scan(...interface{}) error }, invoice *models.Invoice) error {
+	err := row.Scan(
+		&invoice.ID,
+		&invoice.InvoiceNumber,
+		&invoice.StudentID,
+		&invoice.TermID,
+		&invoice.Currency,
+		&invoice.Total,
+		&invoice.AmountPaid,
+		&invoice.DueDate,
+		&invoice.IssuedAt,
+		&invoice.Version,
+		&invoice.Status,
+	)
+	if err == nil && invoice.Status != "void" {
+		invoice.Balance = invoice.Total - invoice.AmountPaid
+	}
+	return err
+}
+
+// To take the next number of a gapless sequence. The row stays locked until the
+// transaction ends, so concurrent invoices or receipts queue up behind each other.
+func nextSequence(tx *sql.Tx, name string) (int64, error) {
+	var value int64
+	err := tx.QueryRow("SELECT value FROM sequences WHERE name = ? FOR UPDATE", name).Scan(&value)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "❌ Unable to number the "+name)
+	}
+	value++
+	_, err = tx.Exec("UPDATE sequences SET value = ? WHERE name = ?", value, name)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "❌ Unable to number the "+name)
+	}
+	return value, nil
+}
+
+// To check the due date, currency and lines of an invoice and work out its total, without looking
+// up the student. Fines for a returned book are billed even when the student has since left.
+func validateInvoiceLines(invoice *models.Invoice) (int, error) {
+	if invoice.StudentID == 0 || invoice.DueDate == "" || len(invoice.Lines) == 0 {
+		return http.StatusBadRequest, errors.New("❌ student_id, due_date and at least one line are required")
+	}
+	if _, err := time.Parse(DateLayout, invoice.DueDate); err != nil {
+		return http.StatusBadRequest, errors.New("❌ due_date must be a date like 2025-09-01")
+	}
+
+	currency, err := normalizeCurrency(invoice.Currency)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	invoice.Currency = currency
+
+	invoice.Total = 0
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		line.Description = strings.TrimSpace(line.Description)
+		if line.Description == "" {
+			return http.StatusBadRequest, errors.New("❌ Every invoice line needs a description")
+		}
+		if line.Amount <= 0 {
+			return http.StatusBadRequest, errors.New("❌ Invoice line amounts must be positive minor units")
+		}
+		invoice.Total += line.Amount
+	}
+	return http.StatusOK, nil
+}
+
+// To check an invoice and its lines before it is issued. The total is worked out from the lines.
+func validateInvoice(db Queryer, invoice *models.Invoice) (int, error) {
+	status, err := validateInvoiceLines(invoice)
+	if err != nil {
+		return status, err
+	}
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL", invoice.StudentID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve student")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Student %d does not exist", invoice.StudentID)
+	}
+
+	if invoice.TermID != 0 {
+		err = db.QueryRow("SELECT COUNT(*) FROM terms WHERE id = ? AND deleted_at IS NULL", invoice.TermID).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve term")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Term %d does not exist", invoice.TermID)
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// To issue a validated invoice with the next invoice number and record its lines
+func createInvoice(tx *sql.Tx, r *http.Request, invoice *models.Invoice) error {
+	number, err := nextSequence(tx, "invoice")
+	if err != nil {
+		return err
+	}
+	invoice.InvoiceNumber = number
+	invoice.AmountPaid = 0
+	invoice.Balance = invoice.Total
+	invoice.Status = "open"
+	invoice.Version = 1
+
+	res, err := tx.Exec(
+		"INSERT INTO invoices (invoice_number, student_id, term_id, currency, total, due_date, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		invoice.InvoiceNumber,
+		invoice.StudentID,
+		nullableID(invoice.TermID),
+		invoice.Currency,
+		invoice.Total,
+		invoice.DueDate,
+		invoice.Version,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error inserting invoice")
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error getting last insert ID")
+	}
+	invoice.ID = int(lastID)
+
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		line.InvoiceID = invoice.ID
+		res, err := tx.Exec(
+			"INSERT INTO invoice_lines (invoice_id, fee_schedule_id, description, amount) VALUES (?, ?, ?, ?)",
+			line.InvoiceID, nullableID(line.FeeScheduleID), line.Description, line.Amount,
+		)
+		if err != nil {
+			return utils.ErrorHandler(err, "❌ Error inserting invoice line")
+		}
+		lineID, err := res.LastInsertId()
+		if err != nil {
+			return utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		}
+		line.ID = int(lineID)
+	}
+
+	return RecordAudit(tx, r, AuditCreate, "invoices", invoice.ID, nil, invoice)
+}
+
+// To load an invoice with its lines and payments
+func loadInvoice(db *sql.DB, id int) (models.Invoice, int, error) {
+	var invoice models.Invoice
+	err := scanInvoice(db.QueryRow(invoiceSelect+" WHERE id = ?", id), &invoice)
+	if err == sql.ErrNoRows {
+		return invoice, http.StatusNotFound, errors.New("❌ Invoice not found")
+	} else if err != nil {
+		return invoice, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+
+	rows, err := db.Query("SELECT "+invoiceLineColumns+" FROM invoice_lines WHERE invoice_id = ? ORDER BY id", id)
+	if err != nil {
+		return invoice, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve invoice lines")
+	}
+	defer rows.Close()
+
+	invoice.Lines = make([]models.InvoiceLine, 0)
+	for rows.Next() {
+		var line models.InvoiceLine
+		err := rows.Scan(&line.ID, &line.InvoiceID, &line.FeeScheduleID, &line.Description, &line.Amount)
+		if err != nil {
+			return invoice, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve invoice lines")
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	invoice.Payments, err = listPayments(db, " AND invoice_id = ?", id)
+	if err != nil {
+		return invoice, http.StatusInternalServerError, err
+	}
+
+	return invoice, http.StatusOK, nil
+}
+
+// To get invoices, e.g. ?status=overdue or ?student_id=12&term_id=3
+func GetInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := invoiceSelect + " WHERE 1=1"
+	var args []interface{}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		if !invoiceStatuses[status] {
+			http.Error(w, "❌ status must be one of open, partially_paid, paid, overdue or void", http.StatusBadRequest)
+			return
+		}
+		query += " AND (" + invoiceStatusSQL + ") = ?"
+		args = append(args, status)
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, invoiceFilterFields)
+	query = utils.AddSortingFor(r, query, invoiceSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	invoiceList := make([]models.Invoice, 0)
+	for rows.Next() {
+		var invoice models.Invoice
+		err := scanInvoice(rows, &invoice)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		invoiceList = append(invoiceList, invoice)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Invoice `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(invoiceList),
+		Data:   invoiceList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get one invoice with its lines and payments
+func GetOneInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid invoice id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	invoice, status, err := loadInvoice(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(invoice.ID, invoice.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// To issue invoices by hand, e.g. for a uniform or a trip that isn't on a fee schedule
+func AddInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	var newInvoices []models.Invoice
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newInvoices)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	for i := range newInvoices {
+		status, err := validateInvoice(db, &newInvoices[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range newInvoices {
+		err := createInvoice(tx, r, &newInvoices[i])
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Invoice `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(newInvoices),
+		Data:   newInvoices,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To invoice every student enrolled in a term from the fee schedules of their grade level.
+// Students who already hold an invoice for the term are skipped, so it is safe to run again
+// after late enrollments. With dry_run the invoices are returned without being issued.
+func GenerateInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TermID     int  `json:"term_id"`
+		GradeLevel *int `json:"grade_level"`
+		ClassID    int  `json:"class_id"`
+		DryRun     bool `json:"dry_run"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil || request.TermID == 0 {
+		http.Error(w, "❌ Invalid request body, term_id is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var termExists int
+	err = db.QueryRow("SELECT COUNT(*) FROM terms WHERE id = ? AND deleted_at IS NULL", request.TermID).Scan(&termExists)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve term")
+		http.Error(w, "❌ Unable to retrieve term", http.StatusInternalServerError)
+		return
+	}
+	if termExists == 0 {
+		http.Error(w, "❌ Term not found", http.StatusNotFound)
+		return
+	}
+
+	// Fee schedules of the term by grade level
+	rows, err := db.Query("SELECT "+feeScheduleColumns+" FROM fee_schedules WHERE term_id = ? AND deleted_at IS NULL ORDER BY grade_level, id", request.TermID)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve fee schedules")
+		http.Error(w, "❌ Unable to retrieve fee schedules", http.StatusInternalServerError)
+		return
+	}
+	schedules := map[int][]models.FeeSchedule{}
+	for rows.Next() {
+		var schedule models.FeeSchedule
+		if err := scanFeeSchedule(rows, &schedule); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Unable to retrieve fee schedules")
+			http.Error(w, "❌ Unable to retrieve fee schedules", http.StatusInternalServerError)
+			return
+		}
+		schedules[schedule.GradeLevel] = append(schedules[schedule.GradeLevel], schedule)
+	}
+	rows.Close()
+
+	// Students enrolled in the term, with whether they were invoiced already
+	query := `SELECT e.student_id, c.grade_level,
+		EXISTS (SELECT 1 FROM invoices i WHERE i.student_id = e.student_id AND i.term_id = e.term_id AND i.voided_at IS NULL)
+		FROM enrollments e
+		JOIN classes c ON c.id = e.class_id
+		JOIN students s ON s.id = e.student_id AND s.deleted_at IS NULL
+		WHERE e.term_id = ?`
+	args := []interface{}{request.TermID}
+	if request.GradeLevel != nil {
+		query += " AND c.grade_level = ?"
+		args = append(args, *request.GradeLevel)
+	}
+	if request.ClassID != 0 {
+		query += " AND e.class_id = ?"
+		args = append(args, request.ClassID)
+	}
+	query += " ORDER BY e.student_id"
+
+	rows, err = db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve enrollments")
+		http.Error(w, "❌ Unable to retrieve enrollments", http.StatusInternalServerError)
+		return
+	}
+
+	invoices := make([]models.Invoice, 0)
+	skipped := 0
+	for rows.Next() {
+		var studentId, gradeLevel int
+		var invoiced bool
+		if err := rows.Scan(&studentId, &gradeLevel, &invoiced); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Unable to retrieve enrollments")
+			http.Error(w, "❌ Unable to retrieve enrollments", http.StatusInternalServerError)
+			return
+		}
+		if invoiced {
+			skipped++
+			continue
+		}
+
+		// One invoice per currency, due on the earliest due date of its lines
+		byCurrency := map[string]int{}
+		for _, schedule := range schedules[gradeLevel] {
+			index, ok := byCurrency[schedule.Currency]
+			if !ok {
+				index = len(invoices)
+				byCurrency[schedule.Currency] = index
+				invoices = append(invoices, models.Invoice{
+					StudentID: studentId,
+					TermID:    request.TermID,
+					Currency:  schedule.Currency,
+					DueDate:   schedule.DueDate,
+					Status:    "open",
+				})
+			}
+			invoice := &invoices[index]
+			invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+				FeeScheduleID: schedule.ID,
+				Description:   schedule.Name,
+				Amount:        schedule.Amount,
+			})
+			invoice.Total += schedule.Amount
+			invoice.Balance = invoice.Total
+			if schedule.DueDate < invoice.DueDate {
+				invoice.DueDate = schedule.DueDate
+			}
+		}
+	}
+	rows.Close()
+
+	if !request.DryRun && len(invoices) > 0 {
+		tx, err := db.Begin()
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error starting transaction")
+			http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+			return
+		}
+
+		for i := range invoices {
+			err := createInvoice(tx, r, &invoices[i])
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error committing transaction")
+			http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	status := "success"
+	if request.DryRun {
+		status = "dry run, nothing was issued"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !request.DryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	response := struct {
+		Status  string           `json:"status"`
+		Count   int              `json:"count"`
+		Skipped int              `json:"skipped"`
+		Data    []models.Invoice `json:"data"`
+	}{
+		Status:  status,
+		Count:   len(invoices),
+		Skipped: skipped,
+		Data:    invoices,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To void an invoice issued in error. Invoices with money still paid against them
+// must have their payments refunded first so the books stay balanced.
+func VoidInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid invoice id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingInvoice models.Invoice
+	err = scanInvoice(db.QueryRow(invoiceSelect+" WHERE id = ?", id), &existingInvoice)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Invoice not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingInvoice.ID, existingInvoice.Version) {
+		return
+	}
+
+	if existingInvoice.Status == "void" {
+		http.Error(w, "❌ Invoice is already void", http.StatusConflict)
+		return
+	}
+	if existingInvoice.AmountPaid > 0 {
+		http.Error(w, "❌ Invoice has payments against it, refund them before voiding", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE invoices SET voided_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND amount_paid = 0 AND voided_at IS NULL", id, existingInvoice.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to void invoice")
+		http.Error(w, "❌ Unable to void invoice", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	voidedInvoice := existingInvoice
+	voidedInvoice.Status = "void"
+	voidedInvoice.Balance = 0
+	voidedInvoice.Version++
+
+	err = RecordAudit(tx, r, AuditUpdate, "invoices", id, existingInvoice, voidedInvoice)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(voidedInvoice.ID, voidedInvoice.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voidedInvoice)
+}
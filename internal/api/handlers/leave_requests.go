@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const leaveColumns = "id, teacher_id, leave_type, start_date, end_date, reason, status, requested_by, requested_at, COALESCE(reviewed_by, 0), COALESCE(reviewed_at, ''), review_note, version"
+
+var leaveTypes = map[string]bool{
+	"sick":          true,
+	"personal":      true,
+	"professional":  true,
+	"compassionate": true,
+	"other":         true,
+}
+
+var leaveFilterFields = map[string]string{
+	"teacher_id": "teacher_id",
+	"status":     "status",
+	"leave_type": "leave_type",
+}
+
+var leaveSortFields = map[string]bool{
+	"start_date":   true,
+	"end_date":     true,
+	"requested_at": true,
+	"status":       true,
+}
+
+func scanLeaveRequest(row interface{ Scan(...interface{}) error }, leave *models.LeaveRequest) error {
+	return row.Scan(
+		&leave.ID,
+		&leave.TeacherID,
+		&leave.LeaveType,
+		&leave.StartDate,
+		&leave.EndDate,
+		&leave.Reason,
+		&leave.Status,
+		&leave.RequestedBy,
+		&leave.RequestedAt,
+		&leave.ReviewedBy,
+		&leave.ReviewedAt,
+		&leave.ReviewNote,
+		&leave.Version,
+	)
+}
+
+// To check whether the user may approve or reject leave, per LEAVE_APPROVER_ROLES
+func canReviewLeave(r *http.Request) bool {
+	return HasRole(r, rolesFromEnv("LEAVE_APPROVER_ROLES", "admin", "manager"))
+}
+
+// To check a leave request before it is written. A teacher can't ask for leave
+// overlapping leave they already have pending or approved.
+// It returns the HTTP status to answer with when invalid.
+func validateLeaveRequest(db Queryer, leave *models.LeaveRequest) (int, error) {
+	leave.LeaveType = strings.ToLower(strings.TrimSpace(leave.LeaveType))
+	leave.Reason = strings.TrimSpace(leave.Reason)
+	if leave.TeacherID == 0 || leave.StartDate == "" {
+		return http.StatusBadRequest, errors.New("❌ teacher_id and start_date are required")
+	}
+	if leave.LeaveType == "" {
+		leave.LeaveType = "other"
+	}
+	if !leaveTypes[leave.LeaveType] {
+		return http.StatusBadRequest, errors.New("❌ leave_type must be sick, personal, professional, compassionate or other")
+	}
+
+	if leave.EndDate == "" {
+		leave.EndDate = leave.StartDate
+	}
+	startDate, err := time.Parse(DateLayout, leave.StartDate)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("❌ start_date must look like 2025-09-01")
+	}
+	endDate, err := time.Parse(DateLayout, leave.EndDate)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("❌ end_date must look like 2025-09-01")
+	}
+	if endDate.Before(startDate) {
+		return http.StatusBadRequest, errors.New("❌ end_date can't be before start_date")
+	}
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deleted_at IS NULL", leave.TeacherID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve teacher")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Teacher %d does not exist", leave.TeacherID)
+	}
+
+	var clashId int
+	err = db.QueryRow(
+		"SELECT id FROM leave_requests WHERE teacher_id = ? AND id <> ? AND status IN ('pending', 'approved') AND start_date <= ? AND end_date >= ? LIMIT 1",
+		leave.TeacherID, leave.ID, leave.EndDate, leave.StartDate,
+	).Scan(&clashId)
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("❌ Teacher %d already has leave request %d for those dates", leave.TeacherID, clashId)
+	} else if err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check existing leave")
+	}
+	return http.StatusOK, nil
+}
+
+// To load one leave request
+func loadLeaveRequest(db Queryer, id int) (models.LeaveRequest, int, error) {
+	var leave models.LeaveRequest
+	err := scanLeaveRequest(db.QueryRow("SELECT "+leaveColumns+" FROM leave_requests WHERE id = ?", id), &leave)
+	if err == sql.ErrNoRows {
+		return leave, http.StatusNotFound, errors.New("❌ Leave request not found")
+	} else if err != nil {
+		return leave, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	return leave, http.StatusOK, nil
+}
+
+// To list leave requests matching a condition, latest first unless sorted otherwise
+func listLeaveRequests(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + leaveColumns + " FROM leave_requests WHERE 1=1" + condition
+	args := conditionArgs
+	if on := r.URL.Query().Get("on"); on != "" {
+		if _, err := time.Parse(DateLayout, on); err != nil {
+			http.Error(w, "❌ on must be a date like 2025-09-01", http.StatusBadRequest)
+			return
+		}
+		query += " AND ? BETWEEN start_date AND end_date"
+		args = append(args, on)
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, leaveFilterFields)
+	if sorted := utils.AddSortingFor(r, query, leaveSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY start_date DESC, id DESC"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	leaveList := make([]models.LeaveRequest, 0)
+	for rows.Next() {
+		var leave models.LeaveRequest
+		err := scanLeaveRequest(rows, &leave)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		leaveList = append(leaveList, leave)
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.LeaveRequest `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(leaveList),
+		Data:   leaveList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get leave requests, filtered by e.g. ?status=pending, or ?on=2025-09-01&status=approved for who is away that day
+func GetLeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	listLeaveRequests(w, r, "")
+}
+
+// To get a teacher's leave history
+func GetLeaveRequestsForATeacher(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	listLeaveRequests(w, r, " AND teacher_id = ?", teacherId)
+}
+
+func GetOneLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid leave request id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	leave, status, err := loadLeaveRequest(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(leave.ID, leave.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
+
+// To submit leave requests. They wait as pending until an exec approves or rejects them.
+func AddLeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	var newLeaveRequests []models.LeaveRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newLeaveRequests)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	requestedAt := time.Now().Format(DateTimeLayout)
+	addedLeaveRequests := make([]models.LeaveRequest, len(newLeaveRequests))
+	for i, newLeave := range newLeaveRequests {
+		newLeave.ID = 0
+		// Validating inside the transaction lets requests in the same batch clash with each other
+		status, err := validateLeaveRequest(tx, &newLeave)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newLeave.Status = "pending"
+		newLeave.RequestedBy = ActorID(r)
+		newLeave.RequestedAt = requestedAt
+		newLeave.ReviewedBy, newLeave.ReviewedAt, newLeave.ReviewNote = 0, "", ""
+		newLeave.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO leave_requests (teacher_id, leave_type, start_date, end_date, reason, status, requested_by, requested_at, review_note, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			newLeave.TeacherID,
+			newLeave.LeaveType,
+			newLeave.StartDate,
+			newLeave.EndDate,
+			newLeave.Reason,
+			newLeave.Status,
+			newLeave.RequestedBy,
+			newLeave.RequestedAt,
+			newLeave.ReviewNote,
+			newLeave.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newLeave.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "leave_requests", newLeave.ID, nil, newLeave)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedLeaveRequests[i] = newLeave
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.LeaveRequest `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedLeaveRequests),
+		Data:   addedLeaveRequests,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To change the dates, type or reason of a leave request still waiting for a decision
+func EditLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid leave request id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	for field := range input {
+		switch field {
+		case "leave_type", "start_date", "end_date", "reason":
+		default:
+			http.Error(w, "❌ Only leave_type, start_date, end_date and reason can be changed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingLeave, status, err := loadLeaveRequest(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingLeave.ID, existingLeave.Version) {
+		return
+	}
+
+	if existingLeave.Status != "pending" {
+		http.Error(w, "❌ Only pending leave requests can be changed, cancel it and request again", http.StatusConflict)
+		return
+	}
+
+	updatedLeave := existingLeave
+	err = ApplyPatch(&updatedLeave, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err = validateLeaveRequest(db, &updatedLeave)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE leave_requests SET leave_type = ?, start_date = ?, end_date = ?, reason = ?, version = version + 1 WHERE id = ? AND version = ? AND status = 'pending'",
+		updatedLeave.LeaveType,
+		updatedLeave.StartDate,
+		updatedLeave.EndDate,
+		updatedLeave.Reason,
+		id,
+		existingLeave.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to update leave request")
+		http.Error(w, "❌ Unable to update leave request", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	updatedLeave.Version = existingLeave.Version + 1
+	err = RecordAudit(tx, r, AuditUpdate, "leave_requests", id, existingLeave, updatedLeave)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updatedLeave.ID, updatedLeave.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedLeave)
+}
+
+// To move a leave request on to a new status. from lists the statuses it may move from.
+// Cancelling also drops the cover arranged for days that haven't happened yet.
+func changeLeaveStatus(w http.ResponseWriter, r *http.Request, newStatus string, from ...string) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid leave request id", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			http.Error(w, "❌ Invalid request body, only a note can be given", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingLeave, status, err := loadLeaveRequest(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingLeave.ID, existingLeave.Version) {
+		return
+	}
+
+	allowed := false
+	for _, status := range from {
+		allowed = allowed || existingLeave.Status == status
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("❌ A %s leave request can't be %s", existingLeave.Status, newStatus), http.StatusConflict)
+		return
+	}
+
+	updatedLeave := existingLeave
+	updatedLeave.Status = newStatus
+	updatedLeave.Version++
+	if newStatus != "cancelled" {
+		updatedLeave.ReviewedBy = ActorID(r)
+		updatedLeave.ReviewedAt = time.Now().Format(DateTimeLayout)
+		updatedLeave.ReviewNote = strings.TrimSpace(request.Note)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE leave_requests SET status = ?, reviewed_by = ?, reviewed_at = ?, review_note = ?, version = version + 1 WHERE id = ? AND version = ?",
+		updatedLeave.Status,
+		nullableID(updatedLeave.ReviewedBy),
+		nullableDate(updatedLeave.ReviewedAt),
+		updatedLeave.ReviewNote,
+		id,
+		existingLeave.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to update leave request")
+		http.Error(w, "❌ Unable to update leave request", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	if newStatus == "cancelled" {
+		_, err = tx.Exec("DELETE FROM cover_assignments WHERE leave_request_id = ? AND cover_date >= CURDATE()", id)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to remove cover")
+			http.Error(w, "❌ Unable to remove cover", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "leave_requests", id, existingLeave, updatedLeave)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updatedLeave.ID, updatedLeave.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedLeave)
+}
+
+// To approve a pending leave request, optionally with {"note": "..."}
+func ApproveLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if !canReviewLeave(r) {
+		http.Error(w, "❌ Your role may not approve leave", http.StatusForbidden)
+		return
+	}
+	changeLeaveStatus(w, r, "approved", "pending")
+}
+
+// To reject a pending leave request, optionally with {"note": "..."} saying why
+func RejectLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if !canReviewLeave(r) {
+		http.Error(w, "❌ Your role may not reject leave", http.StatusForbidden)
+		return
+	}
+	changeLeaveStatus(w, r, "rejected", "pending")
+}
+
+// To withdraw a leave request that is no longer needed
+func CancelLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	changeLeaveStatus(w, r, "cancelled", "pending", "approved")
+}
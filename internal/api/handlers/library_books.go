@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const bookOutSQL = "(SELECT COUNT(*) FROM library_loans l WHERE l.book_id = b.id AND l.returned_at IS NULL)"
+
+const bookHoldsSQL = "(SELECT COUNT(*) FROM library_holds h WHERE h.book_id = b.id AND h.fulfilled_at IS NULL AND h.cancelled_at IS NULL)"
+
+const bookColumns = "b.id, b.isbn, b.title, b.author, b.copies, b.copies - " + bookOutSQL + ", " + bookHoldsSQL + ", b.version"
+
+var bookFilterFields = map[string]string{
+	"author": "b.author",
+}
+
+var bookSortFields = map[string]bool{
+	"title":  true,
+	"author": true,
+	"copies": true,
+}
+
+func scanBook(row interface{ Scan(...interface{}) error }, book *models.Book) error {
+	return row.Scan(
+		&book.ID,
+		&book.ISBN,
+		&book.Title,
+		&book.Author,
+		&book.Copies,
+		&book.Available,
+		&book.OnHold,
+		&book.Version,
+	)
+}
+
+// To check a book before it is written. It returns the HTTP status to answer with when invalid.
+func validateBook(db Queryer, book *models.Book) (int, error) {
+	book.Title = strings.TrimSpace(book.Title)
+	book.Author = strings.TrimSpace(book.Author)
+	if book.ISBN == "" || book.Title == "" || book.Author == "" {
+		return http.StatusBadRequest, errors.New("❌ isbn, title and author are required")
+	}
+
+	isbn, err := utils.NormalizeISBN(book.ISBN)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	book.ISBN = isbn
+
+	if book.Copies < 1 {
+		return http.StatusBadRequest, errors.New("❌ copies must be at least 1")
+	}
+
+	var existingId int
+	err = db.QueryRow("SELECT id FROM library_books WHERE isbn = ?", book.ISBN).Scan(&existingId)
+	if err != nil && err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check the catalog")
+	}
+	if err == nil && existingId != book.ID {
+		return http.StatusConflict, fmt.Errorf("❌ ISBN %s is already in the catalog as book %d", book.ISBN, existingId)
+	}
+
+	return http.StatusOK, nil
+}
+
+// To load one book in the catalog
+func loadBook(db Queryer, id int) (models.Book, int, error) {
+	var book models.Book
+	err := scanBook(db.QueryRow("SELECT "+bookColumns+" FROM library_books b WHERE b.id = ? AND b.deleted_at IS NULL", id), &book)
+	if err == sql.ErrNoRows {
+		return book, http.StatusNotFound, errors.New("❌ Book not found")
+	} else if err != nil {
+		return book, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Database query error")
+	}
+	return book, http.StatusOK, nil
+}
+
+// To search the catalog, e.g. ?q=dickens to match titles and authors or ?available=true for books on the shelf
+func GetBooksHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + bookColumns + " FROM library_books b WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND b.deleted_at IS NULL"
+	}
+	if search := strings.TrimSpace(r.URL.Query().Get("q")); search != "" {
+		query += " AND (b.title LIKE ? OR b.author LIKE ?)"
+		args = append(args, "%"+search+"%", "%"+search+"%")
+	}
+	if r.URL.Query().Get("available") == "true" {
+		query += " AND b.copies > " + bookOutSQL
+	}
+
+	if isbn := r.URL.Query().Get("isbn"); isbn != "" {
+		// Match however the ISBN was typed, with or without hyphens
+		if normalized, err := utils.NormalizeISBN(isbn); err == nil {
+			isbn = normalized
+		}
+		query += " AND b.isbn = ?"
+		args = append(args, isbn)
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, bookFilterFields)
+	if sorted := utils.AddSortingFor(r, query, bookSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY b.title, b.id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	bookList := make([]models.Book, 0)
+	for rows.Next() {
+		var book models.Book
+		err := scanBook(rows, &book)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		bookList = append(bookList, book)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Book `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(bookList),
+		Data:   bookList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+func GetOneBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid book id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	book, status, err := loadBook(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(book.ID, book.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+// To add books to the catalog
+func AddBooksHandler(w http.ResponseWriter, r *http.Request) {
+	var newBooks []models.Book
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newBooks)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	seen := map[string]bool{}
+	for i := range newBooks {
+		newBooks[i].ID = 0
+		status, err := validateBook(db, &newBooks[i])
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if seen[newBooks[i].ISBN] {
+			http.Error(w, "❌ ISBN "+newBooks[i].ISBN+" is listed more than once", http.StatusBadRequest)
+			return
+		}
+		seen[newBooks[i].ISBN] = true
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedBooks := make([]models.Book, len(newBooks))
+	for i, newBook := range newBooks {
+		newBook.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO library_books (isbn, title, author, copies, version) VALUES (?, ?, ?, ?, ?)",
+			newBook.ISBN,
+			newBook.Title,
+			newBook.Author,
+			newBook.Copies,
+			newBook.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newBook.ID = int(lastID)
+		newBook.Available = newBook.Copies
+		newBook.OnHold = 0
+
+		err = RecordAudit(tx, r, AuditCreate, "library_books", newBook.ID, nil, newBook)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedBooks[i] = newBook
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Book `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedBooks),
+		Data:   addedBooks,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update a book, e.g. when copies are bought or written off. Copies can't drop below those out on loan.
+func EditBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid book id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	existingBook, status, err := loadBook(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingBook.ID, existingBook.Version) {
+		return
+	}
+
+	previousBook := existingBook
+
+	err = ApplyPatch(&existingBook, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingBook.ID = previousBook.ID
+
+	status, err = validateBook(db, &existingBook)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	onLoan := previousBook.Copies - previousBook.Available
+	if existingBook.Copies < onLoan {
+		http.Error(w, fmt.Sprintf("❌ %d copies are out on loan, copies can't be fewer", onLoan), http.StatusConflict)
+		return
+	}
+	existingBook.Available = existingBook.Copies - onLoan
+	existingBook.OnHold = previousBook.OnHold
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE library_books SET isbn = ?, title = ?, author = ?, copies = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingBook.ISBN,
+		existingBook.Title,
+		existingBook.Author,
+		existingBook.Copies,
+		existingBook.ID,
+		previousBook.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating book")
+		http.Error(w, "❌ Error updating book", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingBook.Version = previousBook.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "library_books", existingBook.ID, previousBook, existingBook)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingBook.ID, existingBook.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingBook)
+}
+
+// To take a book out of the catalog. Books still out on loan have to come back first.
+func DeleteOneBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid book id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	deletedBook, status, err := loadBook(db, id)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedBook.ID, deletedBook.Version) {
+		return
+	}
+
+	if deletedBook.Available < deletedBook.Copies {
+		http.Error(w, "❌ Book still has copies out on loan", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE library_books SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedBook.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete book")
+		http.Error(w, "❌ Unable delete book", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "library_books", id, deletedBook, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Book successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreBookHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "library_books", "Book")
+}
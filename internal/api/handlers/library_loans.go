@@ -0,0 +1,813 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const loanColumns = "l.id, l.book_id, b.title, COALESCE(l.student_id, 0), COALESCE(l.teacher_id, 0), l.checked_out_at, l.due_date, COALESCE(l.returned_at, ''), l.fine, l.fine_waived, COALESCE(l.invoice_id, 0), l.checked_out_by"
+
+const loanSelect = "SELECT " + loanColumns + " FROM library_loans l JOIN library_books b ON b.id = l.book_id"
+
+const holdColumns = "h.id, h.book_id, b.title, COALESCE(h.student_id, 0), COALESCE(h.teacher_id, 0), h.placed_at, COALESCE(h.fulfilled_at, ''), COALESCE(h.loan_id, 0), COALESCE(h.cancelled_at, '')"
+
+const holdSelect = "SELECT " + holdColumns + " FROM library_holds h JOIN library_books b ON b.id = h.book_id"
+
+// Holds still waiting for a copy
+const pendingHold = " AND h.fulfilled_at IS NULL AND h.cancelled_at IS NULL"
+
+var loanStatuses = map[string]string{
+	"out":      " AND l.returned_at IS NULL",
+	"returned": " AND l.returned_at IS NOT NULL",
+	"overdue":  " AND l.returned_at IS NULL AND l.due_date < CURDATE()",
+}
+
+var loanFilterFields = map[string]string{
+	"book_id":    "l.book_id",
+	"student_id": "l.student_id",
+	"teacher_id": "l.teacher_id",
+}
+
+var loanSortFields = map[string]bool{
+	"due_date":       true,
+	"checked_out_at": true,
+	"returned_at":    true,
+}
+
+func scanLoan(row interface{ Scan(...interface{}) error }, loan *models.Loan) error {
+	err := row.Scan(
+		&loan.ID,
+		&loan.BookID,
+		&loan.Title,
+		&loan.StudentID,
+		&loan.TeacherID,
+		&loan.CheckedOutAt,
+		&loan.DueDate,
+		&loan.ReturnedAt,
+		&loan.Fine,
+		&loan.FineWaived,
+		&loan.InvoiceID,
+		&loan.CheckedOutBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Loans still out show the fine they have run up so far
+	returned := time.Now()
+	if loan.ReturnedAt != "" {
+		returned, _ = time.Parse(DateTimeLayout, loan.ReturnedAt)
+	}
+	loan.DaysOverdue = daysOverdue(loan.DueDate, returned)
+	if loan.ReturnedAt == "" {
+		loan.Fine = loanFine(*loan, loan.DaysOverdue)
+	}
+	return nil
+}
+
+func scanHold(row interface{ Scan(...interface{}) error }, hold *models.Hold) error {
+	return row.Scan(
+		&hold.ID,
+		&hold.BookID,
+		&hold.Title,
+		&hold.StudentID,
+		&hold.TeacherID,
+		&hold.PlacedAt,
+		&hold.FulfilledAt,
+		&hold.LoanID,
+		&hold.CancelledAt,
+	)
+}
+
+// To get a library setting counted in whole units, e.g. LIBRARY_LOAN_DAYS, falling back when unset or invalid
+func librarySetting(name string, fallback int64) int64 {
+	if value, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+// To count the whole days a book due on dueDate is late when returned at returned
+func daysOverdue(dueDate string, returned time.Time) int {
+	due, err := time.Parse(DateLayout, dueDate)
+	if err != nil {
+		return 0
+	}
+	// Both days are compared as UTC dates, where every day is 24 hours long whatever the clocks did
+	returnedDay := time.Date(returned.Year(), returned.Month(), returned.Day(), 0, 0, 0, 0, time.UTC)
+	dueDay := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(returnedDay.Sub(dueDay).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// To work out the fine for a late loan. Only students are fined, at LIBRARY_DAILY_FINE minor units
+// a day (default 10) up to LIBRARY_MAX_FINE when that is set.
+func loanFine(loan models.Loan, days int) int64 {
+	if loan.StudentID == 0 || days == 0 {
+		return 0
+	}
+	fine := int64(days) * librarySetting("LIBRARY_DAILY_FINE", 10)
+	if maxFine := librarySetting("LIBRARY_MAX_FINE", 0); maxFine > 0 && fine > maxFine {
+		fine = maxFine
+	}
+	return fine
+}
+
+// To check the borrower of a loan or hold is exactly one existing student or teacher
+func validateBorrower(db Queryer, studentId, teacherId int) (int, error) {
+	if (studentId == 0) == (teacherId == 0) {
+		return http.StatusBadRequest, errors.New("❌ Give either a student_id or a teacher_id")
+	}
+
+	table, id, label := "students", studentId, "Student"
+	if teacherId != 0 {
+		table, id, label = "teachers", teacherId, "Teacher"
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ? AND deleted_at IS NULL", id).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve borrower")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ %s %d does not exist", label, id)
+	}
+	return http.StatusOK, nil
+}
+
+// To match the rows of a borrower, whichever kind they are
+func borrowerCondition(alias string, studentId, teacherId int) (string, int) {
+	if studentId != 0 {
+		return " AND " + alias + "student_id = ?", studentId
+	}
+	return " AND " + alias + "teacher_id = ?", teacherId
+}
+
+// To list loans matching a condition, soonest due first unless sorted otherwise
+func listLoans(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := loanSelect + " WHERE 1=1" + condition
+	args := conditionArgs
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		statusCondition, ok := loanStatuses[status]
+		if !ok {
+			http.Error(w, "❌ status must be out, returned or overdue", http.StatusBadRequest)
+			return
+		}
+		query += statusCondition
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, loanFilterFields)
+	if sorted := utils.AddSortingFor(r, query, loanSortFields); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY l.due_date, l.id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	loanList := make([]models.Loan, 0)
+	for rows.Next() {
+		var loan models.Loan
+		err := scanLoan(rows, &loan)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		loanList = append(loanList, loan)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Loan `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(loanList),
+		Data:   loanList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get loans, e.g. ?status=out&book_id=4
+func GetLoansHandler(w http.ResponseWriter, r *http.Request) {
+	listLoans(w, r, "")
+}
+
+// To get the books that are late back, with the fines they have run up so far
+func GetOverdueLoansHandler(w http.ResponseWriter, r *http.Request) {
+	listLoans(w, r, loanStatuses["overdue"])
+}
+
+// To get a student's loans, e.g. ?status=out for the books they have now
+func GetLoansForAStudent(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	listLoans(w, r, " AND l.student_id = ?", studentId)
+}
+
+// To get a teacher's loans
+func GetLoansForATeacher(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	listLoans(w, r, " AND l.teacher_id = ?", teacherId)
+}
+
+// To check a book out to a student or teacher. Copies on the shelf are kept for the holds queue
+// in order, so someone further back can't take the copy the first in line is waiting for.
+// The due date defaults to LIBRARY_LOAN_DAYS (default 14) days from today.
+func CheckOutBookHandler(w http.ResponseWriter, r *http.Request) {
+	var loan models.Loan
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&loan)
+	if err != nil || loan.BookID == 0 {
+		http.Error(w, "❌ Invalid request body, book_id is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	now := time.Now()
+	if loan.DueDate == "" {
+		loan.DueDate = now.AddDate(0, 0, int(librarySetting("LIBRARY_LOAN_DAYS", 14))).Format(DateLayout)
+	}
+	if _, err := time.Parse(DateLayout, loan.DueDate); err != nil {
+		http.Error(w, "❌ due_date must be a date like 2025-09-01", http.StatusBadRequest)
+		return
+	}
+	if loan.DueDate < now.Format(DateLayout) {
+		http.Error(w, "❌ due_date cannot be in the past", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	status, err := validateBorrower(db, loan.StudentID, loan.TeacherID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Locking the book keeps two desks from lending its last copy at once
+	var copies int
+	err = tx.QueryRow("SELECT copies FROM library_books WHERE id = ? AND deleted_at IS NULL FOR UPDATE", loan.BookID).Scan(&copies)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Book not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve book")
+		http.Error(w, "❌ Unable to retrieve book", http.StatusInternalServerError)
+		return
+	}
+
+	borrower, borrowerId := borrowerCondition("", loan.StudentID, loan.TeacherID)
+
+	var alreadyOut, out int
+	err = tx.QueryRow("SELECT COUNT(*) FROM library_loans WHERE book_id = ? AND returned_at IS NULL"+borrower, loan.BookID, borrowerId).Scan(&alreadyOut)
+	if err == nil {
+		err = tx.QueryRow("SELECT COUNT(*) FROM library_loans WHERE book_id = ? AND returned_at IS NULL", loan.BookID).Scan(&out)
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to check loans")
+		http.Error(w, "❌ Unable to check loans", http.StatusInternalServerError)
+		return
+	}
+	if alreadyOut > 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The borrower already has this book out", http.StatusConflict)
+		return
+	}
+	available := copies - out
+	if available <= 0 {
+		tx.Rollback()
+		http.Error(w, "❌ No copies are on the shelf, place a hold instead", http.StatusConflict)
+		return
+	}
+
+	// Where the borrower stands in the holds queue, if they are in it
+	rows, err := tx.Query("SELECT h.id, COALESCE(h.student_id, 0), COALESCE(h.teacher_id, 0) FROM library_holds h WHERE h.book_id = ?"+pendingHold+" ORDER BY h.placed_at, h.id", loan.BookID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to check holds")
+		http.Error(w, "❌ Unable to check holds", http.StatusInternalServerError)
+		return
+	}
+	queued, position, holdId := 0, 0, 0
+	for rows.Next() {
+		var id, studentId, teacherId int
+		if err := rows.Scan(&id, &studentId, &teacherId); err != nil {
+			rows.Close()
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to check holds")
+			http.Error(w, "❌ Unable to check holds", http.StatusInternalServerError)
+			return
+		}
+		queued++
+		if position == 0 && ((loan.StudentID != 0 && studentId == loan.StudentID) || (loan.TeacherID != 0 && teacherId == loan.TeacherID)) {
+			position, holdId = queued, id
+		}
+	}
+	rows.Close()
+
+	if (position == 0 && available <= queued) || position > available {
+		tx.Rollback()
+		http.Error(w, "❌ The copies on the shelf are kept for borrowers ahead in the holds queue", http.StatusConflict)
+		return
+	}
+
+	loan.ID = 0
+	loan.CheckedOutAt = now.Format(DateTimeLayout)
+	loan.ReturnedAt = ""
+	loan.Fine = 0
+	loan.FineWaived = false
+	loan.InvoiceID = 0
+	loan.CheckedOutBy = ActorID(r)
+	res, err := tx.Exec(
+		"INSERT INTO library_loans (book_id, student_id, teacher_id, checked_out_at, due_date, checked_out_by) VALUES (?, ?, ?, ?, ?, ?)",
+		loan.BookID,
+		nullableID(loan.StudentID),
+		nullableID(loan.TeacherID),
+		loan.CheckedOutAt,
+		loan.DueDate,
+		loan.CheckedOutBy,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error inserting data into database")
+		http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+		return
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+		return
+	}
+	loan.ID = int(lastID)
+
+	if holdId != 0 {
+		_, err = tx.Exec("UPDATE library_holds SET fulfilled_at = ?, loan_id = ? WHERE id = ?", loan.CheckedOutAt, loan.ID, holdId)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error fulfilling hold")
+			http.Error(w, "❌ Error fulfilling hold", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditCreate, "library_loans", loan.ID, nil, loan)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.QueryRow("SELECT title FROM library_books WHERE id = ?", loan.BookID).Scan(&loan.Title)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve book")
+		http.Error(w, "❌ Unable to retrieve book", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(loan)
+}
+
+// To check a book back in. A student returning it late is fined, and the fine is billed to their
+// account on an invoice due in LIBRARY_FINE_DUE_DAYS (default 14) days. Roles in LIBRARY_WAIVE_ROLES
+// may waive the fine with {"waive_fine": true}.
+func CheckInBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid loan id", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		WaiveFine bool `json:"waive_fine"`
+	}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	if request.WaiveFine && !HasRole(r, rolesFromEnv("LIBRARY_WAIVE_ROLES", "admin", "manager")) {
+		http.Error(w, "❌ Your role may not waive library fines", http.StatusForbidden)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var loan models.Loan
+	err = scanLoan(tx.QueryRow(loanSelect+" WHERE l.id = ? FOR UPDATE", id), &loan)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Loan not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	if loan.ReturnedAt != "" {
+		tx.Rollback()
+		http.Error(w, "❌ This book has already been returned", http.StatusConflict)
+		return
+	}
+
+	previousLoan := loan
+	now := time.Now()
+	loan.ReturnedAt = now.Format(DateTimeLayout)
+	loan.DaysOverdue = daysOverdue(loan.DueDate, now)
+	loan.Fine = loanFine(loan, loan.DaysOverdue)
+	loan.FineWaived = loan.Fine > 0 && request.WaiveFine
+
+	if loan.Fine > 0 && !loan.FineWaived {
+		invoice := models.Invoice{
+			StudentID: loan.StudentID,
+			Currency:  DefaultCurrency(),
+			DueDate:   now.AddDate(0, 0, int(librarySetting("LIBRARY_FINE_DUE_DAYS", 14))).Format(DateLayout),
+			Lines: []models.InvoiceLine{{
+				Description: fmt.Sprintf("Library fine: %s returned %d days late", loan.Title, loan.DaysOverdue),
+				Amount:      loan.Fine,
+			}},
+		}
+		// The borrower is known from the loan and may have left since, so only the fine itself is checked
+		status, err := validateInvoiceLines(&invoice)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+		err = createInvoice(tx, r, &invoice)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "❌ Error billing library fine", http.StatusInternalServerError)
+			return
+		}
+		loan.InvoiceID = invoice.ID
+	}
+
+	_, err = tx.Exec(
+		"UPDATE library_loans SET returned_at = ?, fine = ?, fine_waived = ?, invoice_id = ? WHERE id = ?",
+		loan.ReturnedAt,
+		loan.Fine,
+		loan.FineWaived,
+		nullableID(loan.InvoiceID),
+		loan.ID,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating loan")
+		http.Error(w, "❌ Error updating loan", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "library_loans", loan.ID, previousLoan, loan)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
+
+// To get the holds queue of a book, first in line first
+func GetHoldsForABook(w http.ResponseWriter, r *http.Request) {
+	bookId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid book id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if _, status, err := loadBook(db, bookId); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	rows, err := db.Query(holdSelect+" WHERE h.book_id = ?"+pendingHold+" ORDER BY h.placed_at, h.id", bookId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	holdList := make([]models.Hold, 0)
+	for rows.Next() {
+		var hold models.Hold
+		err := scanHold(rows, &hold)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		hold.Position = len(holdList) + 1
+		holdList = append(holdList, hold)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Hold `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(holdList),
+		Data:   holdList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To join the holds queue for a book that has no copy on the shelf for the borrower
+func PlaceHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var hold models.Hold
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&hold)
+	if err != nil || hold.BookID == 0 {
+		http.Error(w, "❌ Invalid request body, book_id is required", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	status, err := validateBorrower(db, hold.StudentID, hold.TeacherID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var book models.Book
+	err = scanBook(tx.QueryRow("SELECT "+bookColumns+" FROM library_books b WHERE b.id = ? AND b.deleted_at IS NULL FOR UPDATE", hold.BookID), &book)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Book not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve book")
+		http.Error(w, "❌ Unable to retrieve book", http.StatusInternalServerError)
+		return
+	}
+
+	borrower, borrowerId := borrowerCondition("h.", hold.StudentID, hold.TeacherID)
+	var holding, borrowing int
+	err = tx.QueryRow("SELECT COUNT(*) FROM library_holds h WHERE h.book_id = ?"+pendingHold+borrower, hold.BookID, borrowerId).Scan(&holding)
+	if err == nil {
+		loanBorrower, _ := borrowerCondition("", hold.StudentID, hold.TeacherID)
+		err = tx.QueryRow("SELECT COUNT(*) FROM library_loans WHERE book_id = ? AND returned_at IS NULL"+loanBorrower, hold.BookID, borrowerId).Scan(&borrowing)
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to check holds")
+		http.Error(w, "❌ Unable to check holds", http.StatusInternalServerError)
+		return
+	}
+	if holding > 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The borrower is already in the holds queue for this book", http.StatusConflict)
+		return
+	}
+	if borrowing > 0 {
+		tx.Rollback()
+		http.Error(w, "❌ The borrower already has this book out", http.StatusConflict)
+		return
+	}
+	if book.Available > book.OnHold {
+		tx.Rollback()
+		http.Error(w, "❌ A copy is on the shelf, check it out instead", http.StatusConflict)
+		return
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO library_holds (book_id, student_id, teacher_id) VALUES (?, ?, ?)",
+		hold.BookID, nullableID(hold.StudentID), nullableID(hold.TeacherID),
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error inserting data into database")
+		http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+		return
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error getting last insert ID")
+		http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+		return
+	}
+
+	err = scanHold(tx.QueryRow(holdSelect+" WHERE h.id = ?", lastID), &hold)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve hold")
+		http.Error(w, "❌ Unable to retrieve hold", http.StatusInternalServerError)
+		return
+	}
+	hold.Position = book.OnHold + 1
+
+	err = RecordAudit(tx, r, AuditCreate, "library_holds", hold.ID, nil, hold)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// To take a borrower out of a holds queue
+func CancelHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid hold id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var hold models.Hold
+	err = scanHold(db.QueryRow(holdSelect+" WHERE h.id = ?"+pendingHold, id), &hold)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Hold not found or no longer waiting", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE library_holds SET cancelled_at = NOW() WHERE id = ? AND fulfilled_at IS NULL AND cancelled_at IS NULL", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to cancel hold")
+		http.Error(w, "❌ Unable to cancel hold", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Hold not found or no longer waiting", http.StatusNotFound)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "library_holds", id, hold, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Hold successfully cancelled",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// The encrypted columns of medical_records, each holding the JSON of its field
+var medicalFields = []string{"allergies", "conditions", "medications", "doctor_name", "doctor_phone", "immunizations", "notes"}
+
+// To point at the field of a record each encrypted column holds
+func medicalFieldValues(record *models.MedicalRecord) map[string]interface{} {
+	return map[string]interface{}{
+		"allergies":     &record.Allergies,
+		"conditions":    &record.Conditions,
+		"medications":   &record.Medications,
+		"doctor_name":   &record.DoctorName,
+		"doctor_phone":  &record.DoctorPhone,
+		"immunizations": &record.Immunizations,
+		"notes":         &record.Notes,
+	}
+}
+
+// The context each column is encrypted under, so ciphertext can't be moved to another student or column
+func medicalFieldContext(studentId int, field string) string {
+	return "medical_records:" + strconv.Itoa(studentId) + ":" + field
+}
+
+// To refuse users outside the roles in MEDICAL_ROLES (default nurse only). Refusals are logged
+// against the student too, and the request is answered when refused.
+func allowMedicalRecords(w http.ResponseWriter, r *http.Request, db Execer, studentId int) bool {
+	if HasRole(r, rolesFromEnv("MEDICAL_ROLES", "nurse")) {
+		return true
+	}
+	recordMedicalAccess(db, r, studentId, "denied")
+	http.Error(w, "❌ Your role may not access medical records", http.StatusForbidden)
+	return false
+}
+
+// To log who touched a student's health record
+func recordMedicalAccess(db Execer, r *http.Request, studentId int, action string) error {
+	_, err := db.Exec(
+		"INSERT INTO medical_record_access (student_id, user_id, role, action, request_id) VALUES (?, ?, ?, ?, ?)",
+		studentId, ActorID(r), UserRole(r), action, requestID(r),
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to log medical record access")
+	}
+	return nil
+}
+
+// To load and decrypt a student's health record. It returns sql.ErrNoRows when the student has none.
+func loadMedicalRecord(db Queryer, key []byte, studentId int) (models.MedicalRecord, error) {
+	record := models.MedicalRecord{StudentID: studentId}
+
+	encrypted := make([]string, len(medicalFields))
+	dest := []interface{}{&record.UpdatedAt, &record.UpdatedBy, &record.Version}
+	for i := range encrypted {
+		dest = append(dest, &encrypted[i])
+	}
+
+	err := db.QueryRow(
+		"SELECT updated_at, updated_by, version, "+strings.Join(medicalFields, ", ")+" FROM medical_records WHERE student_id = ?",
+		studentId,
+	).Scan(dest...)
+	if err != nil {
+		return record, err
+	}
+
+	values := medicalFieldValues(&record)
+	for i, field := range medicalFields {
+		plaintext, err := utils.DecryptField(key, encrypted[i], medicalFieldContext(studentId, field))
+		if err != nil {
+			return record, err
+		}
+		if err := json.Unmarshal([]byte(plaintext), values[field]); err != nil {
+			return record, utils.ErrorHandler(err, "❌ Medical record is corrupt")
+		}
+	}
+	return record, nil
+}
+
+// To tidy a record before it is written, dropping blank list entries. It returns the HTTP status to answer with when invalid.
+func validateMedicalRecord(record *models.MedicalRecord) (int, error) {
+	trimList := func(list []string) []string {
+		trimmed := make([]string, 0, len(list))
+		for _, item := range list {
+			if item = strings.TrimSpace(item); item != "" {
+				trimmed = append(trimmed, item)
+			}
+		}
+		return trimmed
+	}
+	record.Allergies = trimList(record.Allergies)
+	record.Conditions = trimList(record.Conditions)
+	record.DoctorName = strings.TrimSpace(record.DoctorName)
+	record.Notes = strings.TrimSpace(record.Notes)
+
+	if record.Medications == nil {
+		record.Medications = []models.Medication{}
+	}
+	for i := range record.Medications {
+		medication := &record.Medications[i]
+		medication.Name = strings.TrimSpace(medication.Name)
+		if medication.Name == "" {
+			return http.StatusBadRequest, errors.New("❌ Every medication needs a name")
+		}
+	}
+
+	if record.Immunizations == nil {
+		record.Immunizations = []models.Immunization{}
+	}
+	for i := range record.Immunizations {
+		immunization := &record.Immunizations[i]
+		immunization.Vaccine = strings.TrimSpace(immunization.Vaccine)
+		if immunization.Vaccine == "" {
+			return http.StatusBadRequest, errors.New("❌ Every immunization needs a vaccine")
+		}
+		if immunization.Date != "" {
+			if _, err := time.Parse(DateLayout, immunization.Date); err != nil {
+				return http.StatusBadRequest, errors.New("❌ Immunization dates must be like 2025-09-01")
+			}
+		}
+		if immunization.Dose < 0 {
+			return http.StatusBadRequest, errors.New("❌ Immunization dose cannot be negative")
+		}
+	}
+
+	if record.DoctorPhone != "" {
+		phone, err := utils.NormalizePhone(record.DoctorPhone)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		record.DoctorPhone = phone
+	}
+
+	return http.StatusOK, nil
+}
+
+// To find which health fields differ between two versions of a record, without saying what they hold
+func changedMedicalFields(before, after models.MedicalRecord) []string {
+	beforeValues, afterValues := medicalFieldValues(&before), medicalFieldValues(&after)
+	changed := make([]string, 0)
+	for _, field := range medicalFields {
+		if !reflect.DeepEqual(reflect.ValueOf(beforeValues[field]).Elem().Interface(), reflect.ValueOf(afterValues[field]).Elem().Interface()) {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// To get a student's health record. Every read is logged, and nothing is shown if the log can't be written.
+func GetMedicalRecordHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if !allowMedicalRecords(w, r, db, studentId) {
+		return
+	}
+
+	key, err := utils.FieldKey("MEDICAL_RECORDS_KEY")
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Medical records are unavailable")
+		http.Error(w, "❌ Medical records are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	record, err := loadMedicalRecord(db, key, studentId)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Medical record not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve medical record")
+		http.Error(w, "❌ Unable to retrieve medical record", http.StatusInternalServerError)
+		return
+	}
+
+	err = recordMedicalAccess(db, r, studentId, "read")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Health details must not be kept by browsers or proxies
+	w.Header().Set("Cache-Control", "no-store")
+	if CheckIfNoneMatch(w, r, ETag(studentId, record.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// To create or update a student's health record. PUT replaces the whole record, PATCH only the fields
+// sent. Updating an existing record honours If-Match like other edits.
+func SaveMedicalRecordHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if !allowMedicalRecords(w, r, db, studentId) {
+		return
+	}
+
+	key, err := utils.FieldKey("MEDICAL_RECORDS_KEY")
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Medical records are unavailable")
+		http.Error(w, "❌ Medical records are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL", studentId).Scan(&exists)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve student")
+		http.Error(w, "❌ Unable to retrieve student", http.StatusInternalServerError)
+		return
+	}
+	if exists == 0 {
+		http.Error(w, "❌ Student not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	previousRecord, err := loadMedicalRecord(tx, key, studentId)
+	creating := err == sql.ErrNoRows
+	if err != nil && !creating {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve medical record")
+		http.Error(w, "❌ Unable to retrieve medical record", http.StatusInternalServerError)
+		return
+	}
+	if !creating && !CheckIfMatch(w, r, studentId, previousRecord.Version) {
+		tx.Rollback()
+		return
+	}
+
+	record := models.MedicalRecord{}
+	if r.Method == http.MethodPatch {
+		if creating {
+			tx.Rollback()
+			http.Error(w, "❌ Medical record not found, create it with PUT", http.StatusNotFound)
+			return
+		}
+		record = previousRecord
+	}
+
+	// Decoding over the existing record only changes the fields that were sent
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&record); err != nil {
+		tx.Rollback()
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if record.StudentID != 0 && record.StudentID != studentId {
+		tx.Rollback()
+		http.Error(w, "❌ student_id does not match the URL", http.StatusBadRequest)
+		return
+	}
+	record.StudentID = studentId
+	record.UpdatedBy = ActorID(r)
+
+	status, err := validateMedicalRecord(&record)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	columns := make([]string, len(medicalFields))
+	args := make([]interface{}, 0, len(medicalFields)+3)
+	values := medicalFieldValues(&record)
+	for i, field := range medicalFields {
+		plaintext, err := json.Marshal(values[field])
+		if err == nil {
+			var ciphertext string
+			ciphertext, err = utils.EncryptField(key, string(plaintext), medicalFieldContext(studentId, field))
+			args = append(args, ciphertext)
+		}
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to encrypt medical record")
+			http.Error(w, "❌ Unable to encrypt medical record", http.StatusInternalServerError)
+			return
+		}
+		columns[i] = field + " = ?"
+	}
+
+	var result sql.Result
+	if creating {
+		record.Version = 1
+		args = append(args, record.UpdatedBy, record.Version, studentId)
+		result, err = tx.Exec(
+			"INSERT INTO medical_records SET "+strings.Join(columns, ", ")+", updated_by = ?, version = ?, student_id = ?",
+			args...,
+		)
+	} else {
+		record.Version = previousRecord.Version + 1
+		args = append(args, record.UpdatedBy, studentId, previousRecord.Version)
+		result, err = tx.Exec(
+			"UPDATE medical_records SET "+strings.Join(columns, ", ")+", updated_by = ?, version = version + 1 WHERE student_id = ? AND version = ?",
+			args...,
+		)
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error saving medical record")
+		http.Error(w, "❌ Error saving medical record", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	// The audit trail says which fields changed, never what they say
+	action, before := AuditUpdate, interface{}(map[string]interface{}{"version": previousRecord.Version})
+	if creating {
+		action, before = AuditCreate, nil
+	}
+	after := map[string]interface{}{
+		"version":        record.Version,
+		"changed_fields": changedMedicalFields(previousRecord, record),
+	}
+	err = RecordAudit(tx, r, action, "medical_records", studentId, before, after)
+	if err == nil {
+		err = recordMedicalAccess(tx, r, studentId, "write")
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	record.UpdatedAt = time.Now().Format(DateTimeLayout)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("ETag", ETag(studentId, record.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if creating {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(record)
+}
+
+// To get who has read, changed or been refused a student's health record, for the roles in MEDICAL_AUDIT_ROLES
+func GetMedicalRecordAccessHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	if !HasRole(r, rolesFromEnv("MEDICAL_AUDIT_ROLES", "admin")) {
+		http.Error(w, "❌ Your role may not see medical record access", http.StatusForbidden)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT id, student_id, user_id, role, action, request_id, accessed_at FROM medical_record_access WHERE student_id = ?"
+	args := []interface{}{studentId}
+	if action := r.URL.Query().Get("action"); action != "" {
+		query += " AND action = ?"
+		args = append(args, action)
+	}
+	query += " ORDER BY accessed_at DESC, id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	accessList := make([]models.MedicalRecordAccess, 0)
+	for rows.Next() {
+		var access models.MedicalRecordAccess
+		err := rows.Scan(&access.ID, &access.StudentID, &access.UserID, &access.Role, &access.Action, &access.RequestID, &access.AccessedAt)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		accessList = append(accessList, access)
+	}
+
+	response := struct {
+		Status string                       `json:"status"`
+		Count  int                          `json:"count"`
+		Data   []models.MedicalRecordAccess `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(accessList),
+		Data:   accessList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
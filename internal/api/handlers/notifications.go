@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const notificationColumns = "n.id, n.user_id, n.kind, n.title, n.body, COALESCE(n.announcement_id, 0), n.visible_from, COALESCE(n.read_at, '')"
+
+// Notifications a user can see now. Those of deleted announcements are hidden so a restore brings them back.
+const inboxSelect = "SELECT " + notificationColumns + ` FROM notifications n
+	LEFT JOIN announcements a ON a.id = n.announcement_id
+	WHERE n.user_id = ? AND n.visible_from <= NOW() AND (n.expires_at IS NULL OR n.expires_at > NOW())
+	AND (a.id IS NULL OR a.deleted_at IS NULL)`
+
+func scanNotification(row interface{ Scan(...interface{}) error }, notification *models.Notification) error {
+	err := row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Kind,
+		&notification.Title,
+		&notification.Body,
+		&notification.AnnouncementID,
+		&notification.CreatedAt,
+		&notification.ReadAt,
+	)
+	notification.Read = notification.ReadAt != ""
+	return err
+}
+
+// Notify puts a system event in a user's inbox and emails it to them. A failed email is
+// only logged, the event is still in the inbox.
+func Notify(db QueryExecer, userId int, title, body string) error {
+	_, err := db.Exec(
+		"INSERT INTO notifications (user_id, kind, title, body, visible_from) VALUES (?, 'system', ?, ?, NOW())",
+		userId, title, body,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to save notification")
+	}
+
+	var email string
+	err = db.QueryRow("SELECT email FROM execs WHERE id = ?", userId).Scan(&email)
+	if err == nil && email != "" {
+		err = utils.SendMail([]string{email}, title, body)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to email notification")
+		}
+	}
+	return nil
+}
+
+// To count the unread notifications of a user
+func unreadCount(db Queryer, userId int) (int, error) {
+	var unread int
+	err := db.QueryRow("SELECT COUNT(*) FROM ("+inboxSelect+" AND n.read_at IS NULL) inbox", userId).Scan(&unread)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "❌ Unable to count notifications")
+	}
+	return unread, nil
+}
+
+// To get the logged in user's inbox, newest first. ?unread=true leaves out what has been read.
+func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := ActorID(r)
+	if userId == 0 {
+		http.Error(w, "❌ Log in to see your notifications", http.StatusUnauthorized)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := inboxSelect
+	if r.URL.Query().Get("unread") == "true" {
+		query += " AND n.read_at IS NULL"
+	}
+	query += " ORDER BY n.visible_from DESC, n.id DESC"
+
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notificationList := make([]models.Notification, 0)
+	for rows.Next() {
+		var notification models.Notification
+		err := scanNotification(rows, &notification)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		notificationList = append(notificationList, notification)
+	}
+
+	unread, err := unreadCount(db, userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Unread int                   `json:"unread"`
+		Data   []models.Notification `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(notificationList),
+		Unread: unread,
+		Data:   notificationList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get how many unread notifications the logged in user has, e.g. for a badge
+func GetUnreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+	userId := ActorID(r)
+	if userId == 0 {
+		http.Error(w, "❌ Log in to see your notifications", http.StatusUnauthorized)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	unread, err := unreadCount(db, userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		Unread int    `json:"unread"`
+	}{
+		Status: "success",
+		Unread: unread,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To mark one of the logged in user's notifications as read
+func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid notification id", http.StatusBadRequest)
+		return
+	}
+
+	userId := ActorID(r)
+	if userId == 0 {
+		http.Error(w, "❌ Log in to see your notifications", http.StatusUnauthorized)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var notification models.Notification
+	err = scanNotification(db.QueryRow(inboxSelect+" AND n.id = ?", userId, id), &notification)
+	if err != nil {
+		http.Error(w, "❌ Notification not found", http.StatusNotFound)
+		return
+	}
+
+	if !notification.Read {
+		_, err = db.Exec("UPDATE notifications SET read_at = NOW() WHERE id = ? AND user_id = ? AND read_at IS NULL", id, userId)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to mark notification as read")
+			http.Error(w, "❌ Unable to mark notification as read", http.StatusInternalServerError)
+			return
+		}
+		notification.Read = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notification)
+}
+
+// To mark everything in the logged in user's inbox as read
+func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	userId := ActorID(r)
+	if userId == 0 {
+		http.Error(w, "❌ Log in to see your notifications", http.StatusUnauthorized)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	result, err := db.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL AND visible_from <= NOW()", userId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to mark notifications as read")
+		http.Error(w, "❌ Unable to mark notifications as read", http.StatusInternalServerError)
+		return
+	}
+	marked, _ := result.RowsAffected()
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		Marked int64  `json:"marked"`
+	}{
+		Status: "Notifications marked as read",
+		Marked: marked,
+	}
+	json.NewEncoder(w).Encode(response)
+}
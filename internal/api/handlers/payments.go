@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/pdf"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const paymentColumns = "id, receipt_number, invoice_id, student_id, kind, amount, currency, method, reference, COALESCE(refund_of, 0), received_at, recorded_by"
+
+var paymentFilterFields = map[string]string{
+	"student_id": "student_id",
+	"invoice_id": "invoice_id",
+	"kind":       "kind",
+	"method":     "method",
+}
+
+var paymentSortFields = map[string]bool{
+	"receipt_number": true,
+	"received_at":    true,
+	"amount":         true,
+}
+
+func scanPayment(row interface{ Scan(...interface{}) error }, payment *models.Payment) error {
+	return row.Scan(
+		&payment.ID,
+		&payment.ReceiptNumber,
+		&payment.InvoiceID,
+		&payment.StudentID,
+		&payment.Kind,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Method,
+		&payment.Reference,
+		&payment.RefundOf,
+		&payment.ReceivedAt,
+		&payment.RecordedBy,
+	)
+}
+
+// To list payments and refunds matching a condition, oldest receipt first
+func listPayments(db *sql.DB, condition string, args ...interface{}) ([]models.Payment, error) {
+	rows, err := db.Query("SELECT "+paymentColumns+" FROM payments WHERE 1=1"+condition+" ORDER BY receipt_number", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to retrieve payments")
+	}
+	defer rows.Close()
+
+	payments := make([]models.Payment, 0)
+	for rows.Next() {
+		var payment models.Payment
+		if err := scanPayment(rows, &payment); err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve payments")
+		}
+		payments = append(payments, payment)
+	}
+	return payments, nil
+}
+
+// To fill in when money changed hands, now if not given
+func parseReceivedAt(value string) (string, error) {
+	if value == "" {
+		return time.Now().Format(DateTimeLayout), nil
+	}
+	received, err := parseDateTime(value)
+	if err != nil {
+		return "", errors.New("❌ received_at must look like 2025-09-01 or 2025-09-01 10:30:00")
+	}
+	return received.Format(DateTimeLayout), nil
+}
+
+// To write a payment or refund with the next receipt number and move the invoice's paid amount
+func insertPayment(tx *sql.Tx, r *http.Request, payment *models.Payment) error {
+	number, err := nextSequence(tx, "receipt")
+	if err != nil {
+		return err
+	}
+	payment.ReceiptNumber = number
+	payment.RecordedBy = ActorID(r)
+
+	res, err := tx.Exec(
+		"INSERT INTO payments (receipt_number, invoice_id, student_id, kind, amount, currency, method, reference, refund_of, received_at, recorded_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		payment.ReceiptNumber,
+		payment.InvoiceID,
+		payment.StudentID,
+		payment.Kind,
+		payment.Amount,
+		payment.Currency,
+		payment.Method,
+		payment.Reference,
+		nullableID(payment.RefundOf),
+		payment.ReceivedAt,
+		payment.RecordedBy,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error recording payment")
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error getting last insert ID")
+	}
+	payment.ID = int(lastID)
+
+	change := payment.Amount
+	if payment.Kind == "refund" {
+		change = -change
+	}
+	_, err = tx.Exec("UPDATE invoices SET amount_paid = amount_paid + ?, version = version + 1 WHERE id = ?", change, payment.InvoiceID)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error updating invoice")
+	}
+
+	return RecordAudit(tx, r, AuditCreate, "payments", payment.ID, nil, payment)
+}
+
+// To get payments and refunds, filtered by e.g. ?student_id=12 or ?invoice_id=4
+func GetPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + paymentColumns + " FROM payments WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFiltersFor(r, query, args, paymentFilterFields)
+	query = utils.AddSortingFor(r, query, paymentSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	paymentList := make([]models.Payment, 0)
+	for rows.Next() {
+		var payment models.Payment
+		err := scanPayment(rows, &payment)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		paymentList = append(paymentList, payment)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Payment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(paymentList),
+		Data:   paymentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To record a payment against an invoice. Part payments are fine, paying more than is owed is not.
+func RecordPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid invoice id", http.StatusBadRequest)
+		return
+	}
+
+	var payment models.Payment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&payment)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payment.Amount <= 0 {
+		http.Error(w, "❌ amount must be a positive number of minor units", http.StatusBadRequest)
+		return
+	}
+	payment.ReceivedAt, err = parseReceivedAt(payment.ReceivedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	payment.Method = strings.TrimSpace(payment.Method)
+	payment.Reference = strings.TrimSpace(payment.Reference)
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var invoice models.Invoice
+	err = scanInvoice(tx.QueryRow(invoiceSelect+" WHERE id = ? FOR UPDATE", invoiceId), &invoice)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Invoice not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve invoice")
+		http.Error(w, "❌ Unable to retrieve invoice", http.StatusInternalServerError)
+		return
+	}
+
+	if invoice.Status == "void" {
+		tx.Rollback()
+		http.Error(w, "❌ Payments cannot be taken against a void invoice", http.StatusConflict)
+		return
+	}
+	if payment.Currency != "" && strings.ToUpper(payment.Currency) != invoice.Currency {
+		tx.Rollback()
+		http.Error(w, "❌ Invoice is in "+invoice.Currency+", record the payment in the same currency", http.StatusBadRequest)
+		return
+	}
+	if payment.Amount > invoice.Balance {
+		tx.Rollback()
+		http.Error(w, "❌ Payment of "+utils.FormatMoney(payment.Amount, invoice.Currency)+" is more than the "+utils.FormatMoney(invoice.Balance, invoice.Currency)+" owed", http.StatusConflict)
+		return
+	}
+
+	payment.ID = 0
+	payment.InvoiceID = invoice.ID
+	payment.StudentID = invoice.StudentID
+	payment.Kind = "payment"
+	payment.Currency = invoice.Currency
+	payment.RefundOf = 0
+
+	err = insertPayment(tx, r, &payment)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// To refund all or part of a payment. Without an amount whatever is left of the payment is refunded.
+func RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	paymentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid payment id", http.StatusBadRequest)
+		return
+	}
+
+	var refund models.Payment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&refund)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if refund.Amount < 0 {
+		http.Error(w, "❌ amount must be a positive number of minor units", http.StatusBadRequest)
+		return
+	}
+	refund.ReceivedAt, err = parseReceivedAt(refund.ReceivedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	refund.Method = strings.TrimSpace(refund.Method)
+	refund.Reference = strings.TrimSpace(refund.Reference)
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var original models.Payment
+	err = scanPayment(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id = ? FOR UPDATE", paymentId), &original)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Payment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve payment")
+		http.Error(w, "❌ Unable to retrieve payment", http.StatusInternalServerError)
+		return
+	}
+	if original.Kind != "payment" {
+		tx.Rollback()
+		http.Error(w, "❌ Only payments can be refunded", http.StatusBadRequest)
+		return
+	}
+
+	var refunded int64
+	err = tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE refund_of = ?", original.ID).Scan(&refunded)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve refunds")
+		http.Error(w, "❌ Unable to retrieve refunds", http.StatusInternalServerError)
+		return
+	}
+
+	refundable := original.Amount - refunded
+	if refund.Amount == 0 {
+		refund.Amount = refundable
+	}
+	if refundable <= 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Payment has already been refunded in full", http.StatusConflict)
+		return
+	}
+	if refund.Amount > refundable {
+		tx.Rollback()
+		http.Error(w, "❌ Only "+utils.FormatMoney(refundable, original.Currency)+" of this payment is left to refund", http.StatusConflict)
+		return
+	}
+
+	// To lock the invoice so its paid amount moves in step with other payments
+	var invoiceVersion int
+	err = tx.QueryRow("SELECT version FROM invoices WHERE id = ? FOR UPDATE", original.InvoiceID).Scan(&invoiceVersion)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve invoice")
+		http.Error(w, "❌ Unable to retrieve invoice", http.StatusInternalServerError)
+		return
+	}
+
+	refund.ID = 0
+	refund.InvoiceID = original.InvoiceID
+	refund.StudentID = original.StudentID
+	refund.Kind = "refund"
+	refund.Currency = original.Currency
+	refund.RefundOf = original.ID
+	if refund.Method == "" {
+		refund.Method = original.Method
+	}
+
+	err = insertPayment(tx, r, &refund)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+// To download the receipt of a payment or refund as a PDF
+func GetPaymentReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	paymentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid payment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var payment models.Payment
+	err = scanPayment(db.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id = ?", paymentId), &payment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Payment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	invoice, status, err := loadInvoice(db, payment.InvoiceID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var studentName string
+	err = db.QueryRow("SELECT CONCAT(first_name, ' ', last_name) FROM students WHERE id = ?", payment.StudentID).Scan(&studentName)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve student")
+		http.Error(w, "❌ Unable to retrieve student", http.StatusInternalServerError)
+		return
+	}
+
+	const (
+		left  = 50.0
+		right = pdf.PageWidth - 50
+	)
+
+	title := "Receipt"
+	if payment.Kind == "refund" {
+		title = "Refund receipt"
+	}
+
+	doc := pdf.New(fmt.Sprintf("%s %d", title, payment.ReceiptNumber))
+	doc.FillRect(0, 0, pdf.PageWidth, 90, 0.92)
+	doc.Text(left, 45, 22, true, SchoolName())
+	doc.Text(left, 70, 12, false, fmt.Sprintf("%s No. %06d", title, payment.ReceiptNumber))
+	doc.TextRight(right, 70, 10, false, payment.ReceivedAt)
+
+	y := 125.0
+	details := []struct {
+		label string
+		value string
+	}{
+		{"Student", studentName},
+		{"Student ID", strconv.Itoa(payment.StudentID)},
+		{"Invoice", fmt.Sprintf("No. %06d", invoice.InvoiceNumber)},
+		{"Method", payment.Method},
+		{"Reference", payment.Reference},
+	}
+	for _, detail := range details {
+		if detail.value == "" {
+			continue
+		}
+		doc.Text(left, y, 11, true, detail.label)
+		doc.Text(left+90, y, 11, false, detail.value)
+		y += 18
+	}
+
+	y += 20
+	doc.FillRect(left, y, right-left, 20, 0.85)
+	doc.Text(left+6, y+14, 10, true, "Invoice line")
+	doc.TextRight(right-6, y+14, 10, true, "Amount")
+	y += 20
+	for _, line := range invoice.Lines {
+		y += 16
+		doc.Text(left+6, y, 10, false, line.Description)
+		doc.TextRight(right-6, y, 10, false, utils.FormatMoney(line.Amount, invoice.Currency))
+		y += 4
+		doc.Line(left, y, right, y, 0.3)
+	}
+
+	totals := []struct {
+		label string
+		value int64
+	}{
+		{"Invoice total", invoice.Total},
+		{"Paid to date", invoice.AmountPaid},
+		{"Balance", invoice.Balance},
+	}
+	y += 10
+	for _, total := range totals {
+		y += 16
+		doc.Text(right-250, y, 10, false, total.label)
+		doc.TextRight(right-6, y, 10, false, utils.FormatMoney(total.value, invoice.Currency))
+	}
+
+	y += 36
+	label := "Amount received"
+	if payment.Kind == "refund" {
+		label = "Amount refunded"
+	}
+	doc.Text(left, y, 14, true, label)
+	doc.TextRight(right-6, y, 14, true, utils.FormatMoney(payment.Amount, payment.Currency))
+
+	doc.Text(left, pdf.PageHeight-30, 8, false, "Generated on "+time.Now().Format("2 January 2006"))
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%06d.pdf"`, payment.ReceiptNumber))
+	w.Write(doc.Bytes())
+}
+
+// To get what a student owes in each currency, ignoring void invoices
+func GetStudentBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL", studentId).Scan(&exists)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	if exists == 0 {
+		http.Error(w, "❌ Student not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := db.Query(`SELECT currency, SUM(total), SUM(amount_paid), SUM(total - amount_paid),
+		SUM(CASE WHEN due_date < CURDATE() THEN total - amount_paid ELSE 0 END)
+		FROM invoices WHERE student_id = ? AND voided_at IS NULL
+		GROUP BY currency ORDER BY currency`, studentId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	balances := make([]models.Balance, 0)
+	for rows.Next() {
+		var balance models.Balance
+		err := rows.Scan(&balance.Currency, &balance.Invoiced, &balance.Paid, &balance.Outstanding, &balance.Overdue)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		balances = append(balances, balance)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Balance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(balances),
+		Data:   balances,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// TimeLayout is how times of day are stored and returned
+const TimeLayout = "15:04:05"
+
+const periodColumns = "id, name, start_time, end_time, version"
+
+func scanPeriod(row interface{ Scan(...interface{}) error }, period *models.Period) error {
+	return row.Scan(
+		&period.ID,
+		&period.Name,
+		&period.StartTime,
+		&period.EndTime,
+		&period.Version,
+	)
+}
+
+// To read a time of day given as 08:00 or 08:00:00
+func parseTimeOfDay(value string) (time.Time, error) {
+	parsed, err := time.Parse(TimeLayout, value)
+	if err != nil {
+		parsed, err = time.Parse("15:04", value)
+	}
+	if err != nil {
+		return parsed, errors.New("❌ " + value + " is not a time of day, use HH:MM")
+	}
+	return parsed, nil
+}
+
+func validatePeriod(period *models.Period) error {
+	period.Name = strings.TrimSpace(period.Name)
+	if period.Name == "" || period.StartTime == "" || period.EndTime == "" {
+		return errors.New("❌ name, start_time and end_time are required")
+	}
+
+	start, err := parseTimeOfDay(period.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseTimeOfDay(period.EndTime)
+	if err != nil {
+		return err
+	}
+	if !start.Before(end) {
+		return errors.New("❌ start_time must be before end_time")
+	}
+
+	period.StartTime = start.Format(TimeLayout)
+	period.EndTime = end.Format(TimeLayout)
+	return nil
+}
+
+// To get the periods of the school day in order
+func GetPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT " + periodColumns + " FROM periods ORDER BY start_time")
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	periodList := make([]models.Period, 0)
+	for rows.Next() {
+		var period models.Period
+		err := scanPeriod(rows, &period)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		periodList = append(periodList, period)
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Data   []models.Period `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(periodList),
+		Data:   periodList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To add periods to the school day
+func AddPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	var newPeriods []models.Period
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newPeriods)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range newPeriods {
+		err := validatePeriod(&newPeriods[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedPeriods := make([]models.Period, len(newPeriods))
+	for i, newPeriod := range newPeriods {
+		newPeriod.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO periods (name, start_time, end_time, version) VALUES (?, ?, ?, ?)",
+			newPeriod.Name,
+			newPeriod.StartTime,
+			newPeriod.EndTime,
+			newPeriod.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database, period names must be unique", http.StatusConflict)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newPeriod.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "periods", newPeriod.ID, nil, newPeriod)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedPeriods[i] = newPeriod
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Data   []models.Period `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedPeriods),
+		Data:   addedPeriods,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To change the name or times of a period. Slots using it are checked for new overlaps.
+func EditPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid period id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingPeriod models.Period
+	err = scanPeriod(db.QueryRow("SELECT "+periodColumns+" FROM periods WHERE id = ?", id), &existingPeriod)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Period not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingPeriod.ID, existingPeriod.Version) {
+		return
+	}
+
+	previousPeriod := existingPeriod
+
+	err = ApplyPatch(&existingPeriod, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = validatePeriod(&existingPeriod)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE periods SET name = ?, start_time = ?, end_time = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingPeriod.Name,
+		existingPeriod.StartTime,
+		existingPeriod.EndTime,
+		existingPeriod.ID,
+		previousPeriod.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating period")
+		http.Error(w, "❌ Error updating period", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingPeriod.Version = previousPeriod.Version + 1
+
+	// Longer periods can start overlapping their neighbours
+	rows, err := tx.Query("SELECT id, term_id, day_of_week, period_id, class_id, teacher_id, subject_id, COALESCE(room_id, 0), version FROM timetable_slots WHERE period_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve timetable")
+		http.Error(w, "❌ Unable to retrieve timetable", http.StatusInternalServerError)
+		return
+	}
+	var slots []models.TimetableSlot
+	for rows.Next() {
+		var slot models.TimetableSlot
+		if err := scanTimetableSlot(rows, &slot); err != nil {
+			rows.Close()
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to retrieve timetable")
+			http.Error(w, "❌ Unable to retrieve timetable", http.StatusInternalServerError)
+			return
+		}
+		slots = append(slots, slot)
+	}
+	rows.Close()
+
+	for _, slot := range slots {
+		status, err := checkSlotConflicts(tx, slot)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "periods", existingPeriod.ID, previousPeriod, existingPeriod)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingPeriod.ID, existingPeriod.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingPeriod)
+}
+
+// To remove a period that no timetable slot uses
+func DeletePeriodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid period id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedPeriod models.Period
+	err = scanPeriod(db.QueryRow("SELECT "+periodColumns+" FROM periods WHERE id = ?", id), &deletedPeriod)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Period not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	var inUse int
+	err = db.QueryRow("SELECT COUNT(*) FROM timetable_slots WHERE period_id = ?", id).Scan(&inUse)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check period usage")
+		http.Error(w, "❌ Unable to check period usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "❌ Period is still used by the timetable", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM periods WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete period")
+		http.Error(w, "❌ Unable delete period", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "periods", id, deletedPeriod, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Period successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/pdf"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// Everything printed on one report card
+type reportCard struct {
+	Student      models.Student
+	ClassName    string
+	Term         models.Term
+	AcademicYear string
+	Grades       models.StudentGrades
+	Attendance   models.AttendanceSummary
+	Comments     []reportCardLine
+}
+
+type reportCardLine struct {
+	Teacher string
+	Subject string
+	Comment string
+}
+
+// To gather the grades, attendance and comments of a student for a term
+func loadReportCard(db *sql.DB, studentId, termId int) (reportCard, int, error) {
+	var card reportCard
+
+	err := db.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), version FROM students WHERE id = ? AND deleted_at IS NULL", studentId,
+	).Scan(&card.Student.ID, &card.Student.FirstName, &card.Student.LastName, &card.Student.Email, &card.Student.Class, &card.Student.ClassID, &card.Student.Version)
+	if err == sql.ErrNoRows {
+		return card, http.StatusNotFound, fmt.Errorf("❌ Student %d not found", studentId)
+	} else if err != nil {
+		return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve student")
+	}
+
+	err = db.QueryRow(
+		"SELECT t.id, t.academic_year_id, t.name, t.start_date, t.end_date, t.version, y.name FROM terms t JOIN academic_years y ON y.id = t.academic_year_id WHERE t.id = ? AND t.deleted_at IS NULL", termId,
+	).Scan(&card.Term.ID, &card.Term.AcademicYearID, &card.Term.Name, &card.Term.StartDate, &card.Term.EndDate, &card.Term.Version, &card.AcademicYear)
+	if err == sql.ErrNoRows {
+		return card, http.StatusNotFound, fmt.Errorf("❌ Term %d not found", termId)
+	} else if err != nil {
+		return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve term")
+	}
+
+	// The class the student was enrolled in that term, or their current class
+	err = db.QueryRow("SELECT c.name FROM enrollments e JOIN classes c ON c.id = e.class_id WHERE e.student_id = ? AND e.term_id = ?", studentId, termId).Scan(&card.ClassName)
+	if err == sql.ErrNoRows {
+		card.ClassName = card.Student.Class
+	} else if err != nil {
+		return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve enrollment")
+	}
+
+	results, err := ComputeGrades(db, termId, " AND sc.student_id = ?", studentId)
+	if err != nil {
+		return card, http.StatusInternalServerError, err
+	}
+	card.Grades = models.StudentGrades{StudentID: studentId, TermID: termId}
+	if len(results) > 0 {
+		card.Grades = results[0]
+	}
+
+	err = scanAttendanceSummary(db.QueryRow(
+		"SELECT "+attendanceCounts+" FROM attendance_records WHERE student_id = ? AND term_id = ?", studentId, termId,
+	), &card.Attendance)
+	if err != nil {
+		return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve attendance")
+	}
+
+	rows, err := db.Query(
+		`SELECT CONCAT(t.first_name, ' ', t.last_name), COALESCE(sub.name, ''), c.comment
+		FROM report_card_comments c
+		JOIN teachers t ON t.id = c.teacher_id
+		LEFT JOIN subjects sub ON sub.id = c.subject_id
+		WHERE c.student_id = ? AND c.term_id = ?
+		ORDER BY sub.name, t.last_name`,
+		studentId, termId,
+	)
+	if err != nil {
+		return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve comments")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line reportCardLine
+		if err := rows.Scan(&line.Teacher, &line.Subject, &line.Comment); err != nil {
+			return card, http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve comments")
+		}
+		card.Comments = append(card.Comments, line)
+	}
+
+	return card, http.StatusOK, nil
+}
+
+// To split text into lines that fit a width
+func wrapText(text string, width, size float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && pdf.TextWidth(candidate, size, false) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// SchoolName is printed at the top of generated documents, from SCHOOL_NAME (default Schoolly)
+func SchoolName() string {
+	if name := os.Getenv("SCHOOL_NAME"); name != "" {
+		return name
+	}
+	return "Schoolly"
+}
+
+// To draw a report card onto an A4 document
+func renderReportCard(card reportCard) []byte {
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		bottom = pdf.PageHeight - 60
+	)
+
+	studentName := card.Student.FirstName + " " + card.Student.LastName
+	doc := pdf.New("Report card - " + studentName + " - " + card.Term.Name)
+
+	// School header
+	doc.FillRect(0, 0, pdf.PageWidth, 90, 0.92)
+	doc.Text(left, 45, 22, true, SchoolName())
+	doc.Text(left, 70, 12, false, "Report card for "+card.Term.Name+", "+card.AcademicYear)
+	doc.TextRight(right, 70, 10, false, card.Term.StartDate+" to "+card.Term.EndDate)
+
+	y := 125.0
+	doc.Text(left, y, 11, true, "Student")
+	doc.Text(left+90, y, 11, false, studentName)
+	y += 18
+	doc.Text(left, y, 11, true, "Class")
+	doc.Text(left+90, y, 11, false, card.ClassName)
+	y += 18
+	doc.Text(left, y, 11, true, "Student ID")
+	doc.Text(left+90, y, 11, false, strconv.Itoa(card.Student.ID))
+
+	newPageIfNeeded := func(space float64) {
+		if y+space > bottom {
+			doc.AddPage()
+			y = 60
+		}
+	}
+
+	// Subject grades
+	y += 40
+	doc.Text(left, y, 14, true, "Subject grades")
+	y += 12
+	doc.FillRect(left, y, right-left, 20, 0.85)
+	doc.Text(left+6, y+14, 10, true, "Subject")
+	doc.TextRight(right-190, y+14, 10, true, "Assessments")
+	doc.TextRight(right-90, y+14, 10, true, "Average")
+	doc.TextRight(right-6, y+14, 10, true, "Grade")
+	y += 20
+
+	if len(card.Grades.Subjects) == 0 {
+		y += 16
+		doc.Text(left+6, y, 10, false, "No assessments have been scored this term.")
+		y += 8
+	}
+	for _, subject := range card.Grades.Subjects {
+		newPageIfNeeded(20)
+		y += 16
+		doc.Text(left+6, y, 10, false, subject.Subject)
+		doc.TextRight(right-190, y, 10, false, strconv.Itoa(subject.Assessments))
+		doc.TextRight(right-90, y, 10, false, fmt.Sprintf("%.1f%%", subject.Average))
+		doc.TextRight(right-6, y, 10, true, subject.Letter)
+		y += 4
+		doc.Line(left, y, right, y, 0.3)
+	}
+
+	if len(card.Grades.Subjects) > 0 {
+		y += 18
+		doc.Text(left+6, y, 11, true, "Overall")
+		doc.TextRight(right-90, y, 11, true, fmt.Sprintf("%.1f%%", card.Grades.Average))
+		doc.TextRight(right-6, y, 11, true, card.Grades.Letter)
+	}
+
+	// Attendance totals
+	newPageIfNeeded(90)
+	y += 40
+	doc.Text(left, y, 14, true, "Attendance")
+	y += 22
+	columns := []struct {
+		label string
+		value string
+	}{
+		{"Present", strconv.Itoa(card.Attendance.Present)},
+		{"Late", strconv.Itoa(card.Attendance.Late)},
+		{"Absent", strconv.Itoa(card.Attendance.Absent)},
+		{"Excused", strconv.Itoa(card.Attendance.Excused)},
+		{"Sessions", strconv.Itoa(card.Attendance.Total)},
+		{"Rate", fmt.Sprintf("%.1f%%", card.Attendance.AttendanceRate*100)},
+	}
+	columnWidth := (right - left) / float64(len(columns))
+	for i, column := range columns {
+		x := left + float64(i)*columnWidth
+		doc.Text(x, y, 9, false, column.label)
+		doc.Text(x, y+16, 13, true, column.value)
+	}
+	y += 16
+
+	// Teacher comments
+	if len(card.Comments) > 0 {
+		newPageIfNeeded(60)
+		y += 40
+		doc.Text(left, y, 14, true, "Teacher comments")
+		for _, comment := range card.Comments {
+			lines := wrapText(comment.Comment, right-left, 10)
+			newPageIfNeeded(30 + float64(len(lines))*14)
+			heading := comment.Teacher
+			if comment.Subject != "" {
+				heading += " (" + comment.Subject + ")"
+			}
+			y += 22
+			doc.Text(left, y, 10, true, heading)
+			for _, line := range lines {
+				y += 14
+				doc.Text(left, y, 10, false, line)
+			}
+		}
+	}
+
+	doc.Text(left, pdf.PageHeight-30, 8, false, "Generated on "+time.Now().Format("2 January 2006"))
+	return doc.Bytes()
+}
+
+// To name a report card file e.g. report-card-okafor-ada-12-term-1.pdf
+func reportCardFileName(card reportCard) string {
+	name := strings.ToLower(strings.Join([]string{"report-card", card.Student.LastName, card.Student.FirstName, strconv.Itoa(card.Student.ID), card.Term.Name}, "-"))
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		if r == ' ' {
+			return '-'
+		}
+		return -1
+	}, name)
+	return name + ".pdf"
+}
+
+// To download a student's report card for a term as a PDF
+func GetReportCardHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	termId, err := strconv.Atoi(r.PathValue("term"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	card, status, err := loadReportCard(db, studentId, termId)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+reportCardFileName(card)+`"`)
+	w.Write(renderReportCard(card))
+}
+
+// To download the report cards of a whole class for a term as a ZIP of PDFs
+func GetClassReportCardsHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+	termId, err := strconv.Atoi(r.PathValue("term"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	// The students enrolled in the class that term, or its current students when nobody was enrolled
+	rows, err := db.Query(
+		`SELECT e.student_id FROM enrollments e JOIN students s ON s.id = e.student_id WHERE e.class_id = ? AND e.term_id = ? AND s.deleted_at IS NULL
+		UNION
+		SELECT id FROM students WHERE class_id = ? AND deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM enrollments WHERE class_id = ? AND term_id = ?)`,
+		classId, termId, classId, classId, termId,
+	)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	var studentIds []int
+	for rows.Next() {
+		var studentId int
+		if err := rows.Scan(&studentId); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		studentIds = append(studentIds, studentId)
+	}
+	rows.Close()
+
+	if len(studentIds) == 0 {
+		http.Error(w, "❌ No students found for this class and term", http.StatusNotFound)
+		return
+	}
+
+	// Every card is loaded before anything is written so a failure can still be reported as an error
+	cards := make([]reportCard, 0, len(studentIds))
+	for _, studentId := range studentIds {
+		card, status, err := loadReportCard(db, studentId, termId)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		cards = append(cards, card)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-cards-class-%d-term-%d.zip"`, classId, termId))
+
+	archive := zip.NewWriter(w)
+	for _, card := range cards {
+		file, err := archive.Create(reportCardFileName(card))
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error writing report card archive")
+			return
+		}
+		file.Write(renderReportCard(card))
+	}
+	err = archive.Close()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error writing report card archive")
+	}
+}
+
+// To get the teacher comments written on a student's report card
+func GetReportCardCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	termId, err := strconv.Atoi(r.PathValue("term"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, student_id, term_id, teacher_id, subject_id, comment FROM report_card_comments WHERE student_id = ? AND term_id = ? ORDER BY id", studentId, termId)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	commentList := make([]models.ReportCardComment, 0)
+	for rows.Next() {
+		var comment models.ReportCardComment
+		err := rows.Scan(&comment.ID, &comment.StudentID, &comment.TermID, &comment.TeacherID, &comment.SubjectID, &comment.Comment)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		commentList = append(commentList, comment)
+	}
+
+	response := struct {
+		Status string                     `json:"status"`
+		Count  int                        `json:"count"`
+		Data   []models.ReportCardComment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(commentList),
+		Data:   commentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To write teacher comments for a report card. A teacher has one comment per subject, which is replaced when sent again.
+func SaveReportCardCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+	termId, err := strconv.Atoi(r.PathValue("term"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	var comments []models.ReportCardComment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&comments)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for _, comment := range comments {
+		if comment.TeacherID == 0 || strings.TrimSpace(comment.Comment) == "" {
+			http.Error(w, "❌ teacher_id and comment are required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range comments {
+		comments[i].StudentID = studentId
+		comments[i].TermID = termId
+		comment := comments[i]
+
+		_, err = tx.Exec(
+			"INSERT INTO report_card_comments (student_id, term_id, teacher_id, subject_id, comment) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE comment = VALUES(comment)",
+			comment.StudentID, comment.TermID, comment.TeacherID, comment.SubjectID, comment.Comment,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error saving comment")
+			http.Error(w, "❌ Error saving comment, check the teacher, term and student exist", http.StatusBadRequest)
+			return
+		}
+
+		err = tx.QueryRow(
+			"SELECT id FROM report_card_comments WHERE student_id = ? AND term_id = ? AND teacher_id = ? AND subject_id = ?",
+			comment.StudentID, comment.TermID, comment.TeacherID, comment.SubjectID,
+		).Scan(&comments[i].ID)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error saving comment")
+			http.Error(w, "❌ Error saving comment", http.StatusInternalServerError)
+			return
+		}
+
+		err = RecordAudit(tx, r, AuditUpdate, "report_card_comments", comments[i].ID, nil, comments[i])
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                     `json:"status"`
+		Count  int                        `json:"count"`
+		Data   []models.ReportCardComment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(comments),
+		Data:   comments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// The grade level students leave school after, unless the request says otherwise
+const defaultFinalGradeLevel = 12
+
+var leadingGradeLevel = regexp.MustCompile(`^[0-9]+`)
+
+type rolloverRequest struct {
+	ToAcademicYearID int  `json:"to_academic_year_id"`
+	FinalGradeLevel  int  `json:"final_grade_level"`
+	DryRun           bool `json:"dry_run"`
+}
+
+type promotion struct {
+	StudentID   int    `json:"student_id"`
+	FromClassID int    `json:"from_class_id"`
+	ToClassID   int    `json:"to_class_id"`
+	ToClass     string `json:"to_class"`
+}
+
+// To work out the name of next year's class e.g. "10A" becomes "11A"
+func nextClassName(name string, gradeLevel int) string {
+	if !leadingGradeLevel.MatchString(name) {
+		return name
+	}
+	return leadingGradeLevel.ReplaceAllString(name, strconv.Itoa(gradeLevel+1))
+}
+
+// To list the classes a rollover can't handle: classes with students but no grade level, and
+// classes below the final grade whose class for the next grade can't be found or worked out
+func rolloverBlockers(db Queryer, classes []models.Class, toYear models.AcademicYear, finalGradeLevel int) ([]string, error) {
+	blockers := make([]string, 0)
+	for _, class := range classes {
+		var students int
+		err := db.QueryRow("SELECT COUNT(*) FROM students WHERE class_id = ? AND deleted_at IS NULL", class.ID).Scan(&students)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to count students")
+		}
+		if students == 0 {
+			continue
+		}
+
+		if class.GradeLevel == 0 {
+			blockers = append(blockers, class.Name+" has no grade level")
+			continue
+		}
+		if class.GradeLevel >= finalGradeLevel {
+			continue
+		}
+
+		var nextGradeLevel int
+		err = db.QueryRow("SELECT grade_level FROM classes WHERE name = ? AND academic_year = ? AND deleted_at IS NULL", nextClassName(class.Name, class.GradeLevel), toYear.Name).Scan(&nextGradeLevel)
+		if err == sql.ErrNoRows {
+			// A missing class is only created when its name can be worked out from this one
+			if !leadingGradeLevel.MatchString(class.Name) {
+				blockers = append(blockers, fmt.Sprintf("%s has no grade %d class in %s", class.Name, class.GradeLevel+1, toYear.Name))
+			}
+		} else if err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+		} else if nextGradeLevel != class.GradeLevel+1 {
+			blockers = append(blockers, fmt.Sprintf("%s has no grade %d class in %s", class.Name, class.GradeLevel+1, toYear.Name))
+		}
+	}
+	return blockers, nil
+}
+
+// RolloverAcademicYearHandler promotes every student of an academic year into the
+// next grade level's class of the target year, creating those classes when missing.
+// Students in the final grade level graduate and leave their class, except those whose
+// status rules graduation out, e.g. suspended students, who are reported as held back.
+func RolloverAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid academic year id", http.StatusBadRequest)
+		return
+	}
+
+	var request rolloverRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&request)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if request.ToAcademicYearID == 0 || request.ToAcademicYearID == id {
+		http.Error(w, "❌ to_academic_year_id must name a different academic year", http.StatusBadRequest)
+		return
+	}
+	if request.FinalGradeLevel == 0 {
+		request.FinalGradeLevel = defaultFinalGradeLevel
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var fromYear, toYear models.AcademicYear
+	for _, year := range []struct {
+		id     int
+		target *models.AcademicYear
+	}{{id, &fromYear}, {request.ToAcademicYearID, &toYear}} {
+		err = scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", year.id), year.target)
+		if err == sql.ErrNoRows {
+			http.Error(w, "❌ Academic year not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			utils.ErrorHandler(err, "❌ Unable to retrieve data")
+			http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if toYear.StartDate <= fromYear.EndDate {
+		http.Error(w, "❌ Students can only be rolled over into a later academic year", http.StatusBadRequest)
+		return
+	}
+
+	// Promoted students are enrolled in the first term of the new year when it has one
+	var firstTermId int
+	err = db.QueryRow("SELECT id FROM terms WHERE academic_year_id = ? AND deleted_at IS NULL ORDER BY start_date LIMIT 1", toYear.ID).Scan(&firstTermId)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorHandler(err, "❌ Unable to retrieve terms")
+		http.Error(w, "❌ Unable to retrieve terms", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query("SELECT "+classColumns+" FROM classes WHERE academic_year = ? AND deleted_at IS NULL ORDER BY grade_level, name", fromYear.Name)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	var fromClasses []models.Class
+	for rows.Next() {
+		var class models.Class
+		if err := scanClass(rows, &class); err != nil {
+			rows.Close()
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		fromClasses = append(fromClasses, class)
+	}
+	rows.Close()
+
+	// Nothing is changed unless every class can be rolled over
+	blockers, err := rolloverBlockers(db, fromClasses, toYear, request.FinalGradeLevel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(blockers) > 0 {
+		http.Error(w, "❌ Unable to roll over, fix these classes first: "+strings.Join(blockers, "; "), http.StatusConflict)
+		return
+	}
+
+	// Everything happens in one transaction so a dry run can simply roll it back
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	promotions := make([]promotion, 0)
+	graduated := make([]int, 0)
+	heldBack := make([]int, 0)
+	createdClasses := make([]models.Class, 0)
+
+	for _, fromClass := range fromClasses {
+		studentRows, err := tx.Query("SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE class_id = ? AND deleted_at IS NULL FOR UPDATE", fromClass.ID)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Database query error")
+			http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+			return
+		}
+		var students []models.Student
+		for studentRows.Next() {
+			var student models.Student
+			err := studentRows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class, &student.ClassID, &student.Status, &student.Version)
+			if err != nil {
+				studentRows.Close()
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error scanning Database results")
+				http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+				return
+			}
+			students = append(students, student)
+		}
+		studentRows.Close()
+
+		if len(students) == 0 {
+			continue
+		}
+
+		if fromClass.GradeLevel >= request.FinalGradeLevel {
+			for _, student := range students {
+				eventName, allowed := studentStatusTransitions[student.Status]["graduated"]
+				if !allowed {
+					heldBack = append(heldBack, student.ID)
+					continue
+				}
+
+				graduate := student
+				graduate.Status, graduate.Class, graduate.ClassID, graduate.Version = "graduated", "", 0, student.Version+1
+
+				_, err = tx.Exec("UPDATE students SET status = ?, class = ?, class_id = NULL, version = version + 1 WHERE id = ?", graduate.Status, graduate.Class, graduate.ID)
+				if err != nil {
+					tx.Rollback()
+					utils.ErrorHandler(err, "❌ Error graduating student")
+					http.Error(w, "❌ Error graduating student", http.StatusInternalServerError)
+					return
+				}
+
+				err = recordEnrollmentEvent(tx, r, &models.EnrollmentEvent{
+					StudentID:   student.ID,
+					Event:       eventName,
+					FromStatus:  student.Status,
+					ToStatus:    graduate.Status,
+					FromClassID: student.ClassID,
+					Reason:      "Graduated at the end of " + fromYear.Name,
+				})
+				if err == nil {
+					err = RecordAudit(tx, r, AuditUpdate, "students", graduate.ID, student, graduate)
+				}
+				if err != nil {
+					tx.Rollback()
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				graduated = append(graduated, student.ID)
+			}
+			continue
+		}
+
+		// To find next year's class, creating it from this year's one when missing
+		toClass := models.Class{
+			Name:         nextClassName(fromClass.Name, fromClass.GradeLevel),
+			GradeLevel:   fromClass.GradeLevel + 1,
+			AcademicYear: toYear.Name,
+			Room:         fromClass.Room,
+			Capacity:     fromClass.Capacity,
+			Version:      1,
+		}
+		err = scanClass(tx.QueryRow("SELECT "+classColumns+" FROM classes WHERE name = ? AND academic_year = ? AND deleted_at IS NULL", toClass.Name, toYear.Name), &toClass)
+		if err == sql.ErrNoRows {
+			res, err := tx.Exec(
+				"INSERT INTO classes (name, grade_level, academic_year, room, capacity, version) VALUES (?, ?, ?, ?, ?, ?)",
+				toClass.Name, toClass.GradeLevel, toClass.AcademicYear, toClass.Room, toClass.Capacity, toClass.Version,
+			)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error creating class")
+				http.Error(w, "❌ Error creating class", http.StatusInternalServerError)
+				return
+			}
+			lastID, err := res.LastInsertId()
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error getting last insert ID")
+				http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+				return
+			}
+			toClass.ID = int(lastID)
+
+			err = RecordAudit(tx, r, AuditCreate, "classes", toClass.ID, nil, toClass)
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			createdClasses = append(createdClasses, toClass)
+		} else if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to retrieve class")
+			http.Error(w, "❌ Unable to retrieve class", http.StatusInternalServerError)
+			return
+		}
+
+		for _, student := range students {
+			promoted := student
+			promoted.Class, promoted.ClassID, promoted.Version = toClass.Name, toClass.ID, student.Version+1
+
+			_, err = tx.Exec("UPDATE students SET class = ?, class_id = ?, version = version + 1 WHERE id = ?", promoted.Class, promoted.ClassID, promoted.ID)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error promoting student")
+				http.Error(w, "❌ Error promoting student", http.StatusInternalServerError)
+				return
+			}
+
+			err = RecordAudit(tx, r, AuditUpdate, "students", promoted.ID, student, promoted)
+			if err == nil {
+				err = recordClassChange(tx, r, student, promoted, "Promoted for "+toYear.Name)
+			}
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if firstTermId != 0 {
+				err = enrollInTerm(tx, promoted.ID, promoted.ClassID, firstTermId)
+				if err != nil {
+					tx.Rollback()
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			promotions = append(promotions, promotion{
+				StudentID:   student.ID,
+				FromClassID: fromClass.ID,
+				ToClassID:   toClass.ID,
+				ToClass:     toClass.Name,
+			})
+		}
+	}
+
+	if request.DryRun {
+		err = tx.Rollback()
+	} else {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status         string         `json:"status"`
+		DryRun         bool           `json:"dry_run"`
+		Promoted       []promotion    `json:"promoted"`
+		Graduated      []int          `json:"graduated"`
+		HeldBack       []int          `json:"held_back"`
+		ClassesCreated []models.Class `json:"classes_created"`
+	}{
+		Status:         "success",
+		DryRun:         request.DryRun,
+		Promoted:       promotions,
+		Graduated:      graduated,
+		HeldBack:       heldBack,
+		ClassesCreated: createdClasses,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const roomColumns = "id, name, building, capacity, version"
+
+func scanRoom(row interface{ Scan(...interface{}) error }, room *models.Room) error {
+	return row.Scan(
+		&room.ID,
+		&room.Name,
+		&room.Building,
+		&room.Capacity,
+		&room.Version,
+	)
+}
+
+func validateRoom(room *models.Room) error {
+	room.Name = strings.TrimSpace(room.Name)
+	room.Building = strings.TrimSpace(room.Building)
+	if room.Name == "" {
+		return errors.New("❌ name is required")
+	}
+	if room.Capacity < 0 {
+		return errors.New("❌ capacity cannot be negative")
+	}
+	return nil
+}
+
+// To get the rooms lessons can be held in
+func GetRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT " + roomColumns + " FROM rooms ORDER BY name")
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	roomList := make([]models.Room, 0)
+	for rows.Next() {
+		var room models.Room
+		err := scanRoom(rows, &room)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		roomList = append(roomList, room)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Room `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(roomList),
+		Data:   roomList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To add rooms
+func AddRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	var newRooms []models.Room
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newRooms)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range newRooms {
+		err := validateRoom(&newRooms[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedRooms := make([]models.Room, len(newRooms))
+	for i, newRoom := range newRooms {
+		newRoom.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO rooms (name, building, capacity, version) VALUES (?, ?, ?, ?)",
+			newRoom.Name,
+			newRoom.Building,
+			newRoom.Capacity,
+			newRoom.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database, room names must be unique", http.StatusConflict)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newRoom.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "rooms", newRoom.ID, nil, newRoom)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedRooms[i] = newRoom
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Room `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedRooms),
+		Data:   addedRooms,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a room
+func EditRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingRoom models.Room
+	err = scanRoom(db.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE id = ?", id), &existingRoom)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingRoom.ID, existingRoom.Version) {
+		return
+	}
+
+	previousRoom := existingRoom
+
+	err = ApplyPatch(&existingRoom, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = validateRoom(&existingRoom)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE rooms SET name = ?, building = ?, capacity = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingRoom.Name,
+		existingRoom.Building,
+		existingRoom.Capacity,
+		existingRoom.ID,
+		previousRoom.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating room")
+		http.Error(w, "❌ Error updating room", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingRoom.Version = previousRoom.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "rooms", existingRoom.ID, previousRoom, existingRoom)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingRoom.ID, existingRoom.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingRoom)
+}
+
+// To remove a room that no timetable slot uses
+func DeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedRoom models.Room
+	err = scanRoom(db.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE id = ?", id), &deletedRoom)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	var inUse int
+	err = db.QueryRow("SELECT COUNT(*) FROM timetable_slots WHERE room_id = ?", id).Scan(&inUse)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check room usage")
+		http.Error(w, "❌ Unable to check room usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "❌ Room is still used by the timetable", http.StatusConflict)
+		return
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM exam_seats WHERE room_id = ?", id).Scan(&inUse)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check room usage")
+		http.Error(w, "❌ Unable to check room usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "❌ Room is still used by exam seating plans", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM rooms WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete room")
+		http.Error(w, "❌ Unable delete room", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "rooms", id, deletedRoom, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Room successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// To undo a soft delete on any table that has a deleted_at column. Like include_deleted, this is
+// for admins only.
+func restoreResource(w http.ResponseWriter, r *http.Request, table, resource string) {
+	if UserRole(r) != "admin" {
+		http.Error(w, "❌ Only admins may restore deleted records", http.StatusForbidden)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "❌ Invalid "+resource+" id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// The purge job anonymises students it has to keep, and there is nothing left to bring back
+	if table == "students" {
+		var anonymised bool
+		err = tx.QueryRow("SELECT anonymised_at IS NOT NULL FROM students WHERE id = ? FOR UPDATE", id).Scan(&anonymised)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Unable to retrieve data")
+			http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+			return
+		}
+		if anonymised {
+			tx.Rollback()
+			http.Error(w, "❌ This student has been anonymised and can't be restored", http.StatusConflict)
+			return
+		}
+	}
+
+	result, err := tx.Exec("UPDATE "+table+" SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to restore "+resource)
+		http.Error(w, "❌ Unable to restore "+resource, http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve restored "+resource)
+		http.Error(w, "❌ Unable to retrieve restored "+resource, http.StatusInternalServerError)
+		return
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ No deleted "+resource+" found with that id", http.StatusNotFound)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditRestore, table, id, map[string]interface{}{"deleted": true}, map[string]interface{}{"deleted": false})
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: resource + " successfully restored",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreStudentHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "students", "Student")
+}
+
+func RestoreTeacherHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "teachers", "Teacher")
+}
+
+func RestoreExecHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "execs", "Exec")
+}
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const enrollmentEventSelect = `SELECT h.id, h.student_id, h.event, COALESCE(h.from_status, ''), h.to_status,
+	COALESCE(h.from_class_id, 0), COALESCE(fc.name, ''), COALESCE(h.to_class_id, 0), COALESCE(tc.name, ''),
+	h.effective_date, h.reason, h.recorded_by, h.recorded_at
+	FROM enrollment_history h
+	LEFT JOIN classes fc ON fc.id = h.from_class_id
+	LEFT JOIN classes tc ON tc.id = h.to_class_id`
+
+var studentStatuses = map[string]bool{
+	"active":      true,
+	"suspended":   true,
+	"transferred": true,
+	"withdrawn":   true,
+	"graduated":   true,
+}
+
+// The statuses a student can move to from each status, with the event the move is recorded as.
+// Graduation is final, a student who left any other way can be readmitted.
+var studentStatusTransitions = map[string]map[string]string{
+	"active": {
+		"suspended":   "suspended",
+		"transferred": "transferred",
+		"withdrawn":   "withdrawn",
+		"graduated":   "graduated",
+	},
+	"suspended": {
+		"active":      "reinstated",
+		"transferred": "transferred",
+		"withdrawn":   "withdrawn",
+	},
+	"transferred": {
+		"active": "readmitted",
+	},
+	"withdrawn": {
+		"active": "readmitted",
+	},
+	"graduated": {},
+}
+
+// To check whether a status means the student has left the school
+func hasLeftSchool(status string) bool {
+	return status == "transferred" || status == "withdrawn" || status == "graduated"
+}
+
+func scanEnrollmentEvent(row interface{ Scan(...interface{}) error }, event *models.EnrollmentEvent) error {
+	return row.Scan(
+		&event.ID,
+		&event.StudentID,
+		&event.Event,
+		&event.FromStatus,
+		&event.ToStatus,
+		&event.FromClassID,
+		&event.FromClass,
+		&event.ToClassID,
+		&event.ToClass,
+		&event.EffectiveDate,
+		&event.Reason,
+		&event.RecordedBy,
+		&event.RecordedAt,
+	)
+}
+
+// To write a step of a student's enrollment history. A missing effective date means today.
+func recordEnrollmentEvent(db Execer, r *http.Request, event *models.EnrollmentEvent) error {
+	if event.EffectiveDate == "" {
+		event.EffectiveDate = time.Now().Format(DateLayout)
+	}
+	event.RecordedBy = ActorID(r)
+	event.RecordedAt = time.Now().Format(DateTimeLayout)
+
+	var fromStatus interface{}
+	if event.FromStatus != "" {
+		fromStatus = event.FromStatus
+	}
+	res, err := db.Exec(
+		"INSERT INTO enrollment_history (student_id, event, from_status, to_status, from_class_id, to_class_id, effective_date, reason, recorded_by, recorded_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		event.StudentID,
+		event.Event,
+		fromStatus,
+		event.ToStatus,
+		nullableID(event.FromClassID),
+		nullableID(event.ToClassID),
+		event.EffectiveDate,
+		event.Reason,
+		event.RecordedBy,
+		event.RecordedAt,
+	)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error saving enrollment history")
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error saving enrollment history")
+	}
+	event.ID = int(lastID)
+	return nil
+}
+
+// To refuse moving a student who has left the school into a class. They must be readmitted first.
+func checkClassChange(before, after models.Student) error {
+	if after.ClassID != before.ClassID && hasLeftSchool(before.Status) {
+		return fmt.Errorf("❌ Student %d is %s, readmit them to change their class", before.ID, before.Status)
+	}
+	return nil
+}
+
+// To add a class change to a student's enrollment history
+func recordClassChange(db Execer, r *http.Request, before, after models.Student, reason string) error {
+	return recordEnrollmentEvent(db, r, &models.EnrollmentEvent{
+		StudentID:   after.ID,
+		Event:       "class_changed",
+		FromStatus:  before.Status,
+		ToStatus:    before.Status,
+		FromClassID: before.ClassID,
+		FromClass:   before.Class,
+		ToClassID:   after.ClassID,
+		ToClass:     after.Class,
+		Reason:      reason,
+	})
+}
+
+// To add the status filter of GET /students, e.g. ?status=active or ?status=active,suspended
+func addStudentStatusFilter(r *http.Request, query string, args []interface{}) (string, []interface{}, error) {
+	value := r.URL.Query().Get("status")
+	if value == "" {
+		return query, args, nil
+	}
+
+	var placeholders []string
+	for _, status := range strings.Split(value, ",") {
+		status = strings.TrimSpace(status)
+		if !studentStatuses[status] {
+			return query, args, errors.New("❌ status must be active, suspended, transferred, withdrawn or graduated")
+		}
+		placeholders = append(placeholders, "?")
+		args = append(args, status)
+	}
+	return query + " AND status IN (" + strings.Join(placeholders, ", ") + ")", args, nil
+}
+
+// To get a student's enrollment history: admission, class changes and status changes, oldest first
+func GetStudentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := enrollmentEventSelect + " WHERE h.student_id = ?"
+	args := []interface{}{studentId}
+	query, args = utils.AddFiltersFor(r, query, args, map[string]string{"event": "h.event"})
+	query += " ORDER BY h.effective_date, h.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := make([]models.EnrollmentEvent, 0)
+	for rows.Next() {
+		var event models.EnrollmentEvent
+		err := scanEnrollmentEvent(rows, &event)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		history = append(history, event)
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.EnrollmentEvent `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(history),
+		Data:   history,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To move a student through their time at the school, e.g.
+// {"status": "withdrawn", "effective_date": "2025-10-01", "reason": "Family moved abroad"}.
+// Leaving clears the student's class, readmission needs a class_id to return to.
+func ChangeStudentStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status        string `json:"status"`
+		EffectiveDate string `json:"effective_date"`
+		Reason        string `json:"reason"`
+		ClassID       int    `json:"class_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&request)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if !studentStatuses[request.Status] {
+		http.Error(w, "❌ status must be active, suspended, transferred, withdrawn or graduated", http.StatusBadRequest)
+		return
+	}
+	if request.EffectiveDate == "" {
+		request.EffectiveDate = time.Now().Format(DateLayout)
+	}
+	effectiveDate, err := time.ParseInLocation(DateLayout, request.EffectiveDate, time.Local)
+	if err != nil {
+		http.Error(w, "❌ effective_date must look like 2025-09-01", http.StatusBadRequest)
+		return
+	}
+	if effectiveDate.After(time.Now()) {
+		http.Error(w, "❌ effective_date can't be in the future", http.StatusBadRequest)
+		return
+	}
+	if request.Reason == "" && (request.Status == "suspended" || request.Status == "transferred" || request.Status == "withdrawn") {
+		http.Error(w, "❌ A reason is required to record a student as "+request.Status, http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var existingStudent models.Student
+	err = tx.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id,
+	).Scan(
+		&existingStudent.ID,
+		&existingStudent.FirstName,
+		&existingStudent.LastName,
+		&existingStudent.Email,
+		&existingStudent.Class,
+		&existingStudent.ClassID,
+		&existingStudent.Status,
+		&existingStudent.Version,
+	)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingStudent.ID, existingStudent.Version) {
+		tx.Rollback()
+		return
+	}
+
+	eventName, allowed := studentStatusTransitions[existingStudent.Status][request.Status]
+	if !allowed {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("❌ A %s student can't become %s", existingStudent.Status, request.Status), http.StatusConflict)
+		return
+	}
+
+	// History is kept in order, so a change can't be dated before the last one
+	var lastDate string
+	err = tx.QueryRow("SELECT COALESCE(MAX(effective_date), '') FROM enrollment_history WHERE student_id = ?", id).Scan(&lastDate)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve enrollment history")
+		http.Error(w, "❌ Unable to retrieve enrollment history", http.StatusInternalServerError)
+		return
+	}
+	if request.EffectiveDate < lastDate {
+		tx.Rollback()
+		http.Error(w, "❌ effective_date can't be before the student's last recorded change on "+lastDate, http.StatusBadRequest)
+		return
+	}
+
+	updatedStudent := existingStudent
+	updatedStudent.Status = request.Status
+	switch {
+	case eventName == "readmitted":
+		if request.ClassID == 0 {
+			tx.Rollback()
+			http.Error(w, "❌ class_id is required to readmit a student", http.StatusBadRequest)
+			return
+		}
+		updatedStudent.ClassID, updatedStudent.Class, err = ResolveClass(tx, request.ClassID, "")
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case request.ClassID != 0:
+		tx.Rollback()
+		http.Error(w, "❌ class_id can only be given when readmitting, change the class on the student instead", http.StatusBadRequest)
+		return
+	case hasLeftSchool(request.Status):
+		updatedStudent.ClassID, updatedStudent.Class = 0, ""
+	}
+
+	result, err := tx.Exec(
+		"UPDATE students SET status = ?, class = ?, class_id = ?, version = version + 1 WHERE id = ? AND version = ?",
+		updatedStudent.Status,
+		updatedStudent.Class,
+		nullableID(updatedStudent.ClassID),
+		id,
+		existingStudent.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating student")
+		http.Error(w, "❌ Error updating student", http.StatusInternalServerError)
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	updatedStudent.Version = existingStudent.Version + 1
+
+	event := models.EnrollmentEvent{
+		StudentID:     id,
+		Event:         eventName,
+		FromStatus:    existingStudent.Status,
+		ToStatus:      updatedStudent.Status,
+		FromClassID:   existingStudent.ClassID,
+		FromClass:     existingStudent.Class,
+		ToClassID:     updatedStudent.ClassID,
+		ToClass:       updatedStudent.Class,
+		EffectiveDate: request.EffectiveDate,
+		Reason:        request.Reason,
+	}
+	err = recordEnrollmentEvent(tx, r, &event)
+	if err == nil {
+		err = RecordAudit(tx, r, AuditUpdate, "students", id, existingStudent, updatedStudent)
+	}
+	if err == nil && eventName == "readmitted" {
+		err = EnrollStudent(tx, id, updatedStudent.ClassID)
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Student models.Student         `json:"student"`
+		Event   models.EnrollmentEvent `json:"event"`
+	}{
+		Student: updatedStudent,
+		Event:   event,
+	}
+
+	w.Header().Set("ETag", ETag(updatedStudent.ID, updatedStudent.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
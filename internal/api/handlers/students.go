@@ -15,6 +15,15 @@ import (
 	"github.com/greatdaveo/Schoolly/pkg/utils"
 )
 
+// The query params the student lists can filter on, also used for the students of a teacher
+var studentFilterFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+	"class_id":   "class_id",
+}
+
 // To get multiple students
 func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	db, err := sqlconnect.ConnectDB()
@@ -25,14 +34,29 @@ func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	query := "SELECT id, first_name, last_name, email, class FROM students WHERE 1=1"
+	query := "SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE 1=1"
 	var args []interface{}
 
+	// To hide soft deleted students unless an admin asks for them
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
 	// To Filter
-	query, args = utils.AddFilters(r, query, args)
+	query, args = utils.AddFiltersFor(r, query, args, studentFilterFields)
+	query, args, err = addStudentStatusFilter(r, query, args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	// To Sort
 	query = utils.AddSorting(r, query)
 
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
 	rows, err := db.Query(query, args...)
 	if err != nil {
 		// http.Error(w, "❌ Database query error", http.StatusInternalServerError)
@@ -41,12 +65,18 @@ func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
+	// To stream the list as a file when ?format=csv, xlsx or ndjson asks for one
+	if format != "" {
+		exportRows(w, rows, format, "students", studentExportColumns, scanStudentExport)
+		return
+	}
+
 	// To change student to a slice
 	studentList := make([]models.Student, 0)
 	// To loop through any possible rows if it is more than one rows
 	for rows.Next() {
 		var student models.Student
-		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
+		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class, &student.ClassID, &student.Status, &student.Version)
 		if err != nil {
 			// http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error scanning Database results")
@@ -65,9 +95,7 @@ func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		Data:   studentList,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
+	WriteJSONWithETag(w, r, response)
 }
 
 // To get single student
@@ -94,13 +122,16 @@ func GetOneStudentsHandler(w http.ResponseWriter, r *http.Request) {
 
 	var student models.Student
 	err = db.QueryRow(
-		"SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id,
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&student.ID,
 		&student.FirstName,
 		&student.LastName,
 		&student.Email,
 		&student.Class,
+		&student.ClassID,
+		&student.Status,
+		&student.Version,
 	)
 	if err == sql.ErrNoRows {
 		// http.Error(w, "❌ Student not found", http.StatusNotFound)
@@ -112,8 +143,11 @@ func GetOneStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if CheckIfNoneMatch(w, r, ETag(student.ID, student.Version)) {
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(student)
 }
 
@@ -172,6 +206,23 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// To link each student to a class by id or by name
+	for i := range newStudents {
+		classId, className, err := ResolveClass(db, newStudents[i].ClassID, newStudents[i].Class)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newStudents[i].ClassID, newStudents[i].Class = classId, className
+
+		// New students are admitted as active, later changes go through their status
+		if newStudents[i].Status != "" && newStudents[i].Status != "active" {
+			http.Error(w, "❌ New students are always active, use /students/{id}/status to change it", http.StatusBadRequest)
+			return
+		}
+		newStudents[i].Status = "active"
+	}
+
 	for _, student := range newStudents {
 		err := CheckBlankFields(student)
 		if err != nil {
@@ -180,10 +231,19 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// To save the students together with their audit rows, so no student exists without one
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// stmt, err := db.Prepare("INSERT INTO students (first_name, last_name, email, class) VALUES (?,?,?,?,?)")
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("students", models.Student{}))
+	stmt, err := tx.Prepare(utils.GenerateInsertQuery("students", models.Student{}))
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error in preparing SQL query", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error in preparing SQL query")
 		return
@@ -193,9 +253,11 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 	addedStudents := make([]models.Student, len(newStudents))
 	for i, newStudent := range newStudents {
 		// res, err := stmt.Exec(newStudent.FirstName, newStudent.LastName, newStudent.Email, newStudent.Class)
+		newStudent.Version = 1
 		values := utils.GetStructValues(newStudent)
 		res, err := stmt.Exec(values...)
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error inserting data into database")
 			return
@@ -204,16 +266,51 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		// To get the id of this entry
 		lastID, err := res.LastInsertId()
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error getting last insert ID")
 			return
 		}
 
 		newStudent.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "students", newStudent.ID, nil, newStudent)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = EnrollStudent(tx, newStudent.ID, newStudent.ClassID)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = recordEnrollmentEvent(tx, r, &models.EnrollmentEvent{
+			StudentID: newStudent.ID,
+			Event:     "admitted",
+			ToStatus:  newStudent.Status,
+			ToClassID: newStudent.ClassID,
+		})
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		// To add to the student list
 		addedStudents[i] = newStudent
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	response := struct {
@@ -256,13 +353,16 @@ func EditStudentHandler(w http.ResponseWriter, r *http.Request) {
 
 	var existingStudent models.Student
 	err = db.QueryRow(
-		"SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id,
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&existingStudent.ID,
 		&existingStudent.FirstName,
 		&existingStudent.LastName,
 		&existingStudent.Email,
 		&existingStudent.Class,
+		&existingStudent.ClassID,
+		&existingStudent.Status,
+		&existingStudent.Version,
 	)
 
 	if err == sql.ErrNoRows {
@@ -275,22 +375,89 @@ func EditStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckIfMatch(w, r, existingStudent.ID, existingStudent.Version) {
+		return
+	}
+
+	if updatedStudent.Status != "" && updatedStudent.Status != existingStudent.Status {
+		http.Error(w, "❌ Use /students/{id}/status to change a student's status", http.StatusBadRequest)
+		return
+	}
+	updatedStudent.Status = existingStudent.Status
+
+	updatedStudent.ClassID, updatedStudent.Class, err = ResolveClass(db, updatedStudent.ClassID, updatedStudent.Class)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	updatedStudent.ID = existingStudent.ID
-	_, err = db.Exec(
-		"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?",
+	if err := checkClassChange(existingStudent, updatedStudent); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 		updatedStudent.FirstName,
 		updatedStudent.LastName,
 		updatedStudent.Email,
 		updatedStudent.Class,
+		nullableID(updatedStudent.ClassID),
 		updatedStudent.ID,
+		existingStudent.Version,
 	)
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error updating student", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error updating student")
 		return
 	}
 
+	// Someone else saved between our read and write
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	updatedStudent.Version = existingStudent.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "students", updatedStudent.ID, existingStudent, updatedStudent)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if updatedStudent.ClassID != existingStudent.ClassID {
+		err = EnrollStudent(tx, updatedStudent.ID, updatedStudent.ClassID)
+		if err == nil {
+			err = recordClassChange(tx, r, existingStudent, updatedStudent, "")
+		}
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updatedStudent.ID, updatedStudent.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedStudent)
 }
@@ -323,6 +490,12 @@ func EditMultipleStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, input := range inputs {
+		if _, ok := input["status"]; ok {
+			tx.Rollback()
+			http.Error(w, "❌ Use /students/{id}/status to change a student's status", http.StatusBadRequest)
+			return
+		}
+
 		idStr, ok := input["id"].(string)
 		if !ok {
 			tx.Rollback()
@@ -342,13 +515,16 @@ func EditMultipleStudentsHandler(w http.ResponseWriter, r *http.Request) {
 
 		var student models.Student
 		err = db.QueryRow(
-			"SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id,
+			"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
 		).Scan(
 			&student.ID,
 			&student.FirstName,
 			&student.LastName,
 			&student.Email,
 			&student.Class,
+			&student.ClassID,
+			&student.Status,
+			&student.Version,
 		)
 
 		if err != nil {
@@ -364,6 +540,8 @@ func EditMultipleStudentsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		existingStudent := student
+
 		// To update using reflect
 		studentVal := reflect.ValueOf(&student).Elem()
 		studentType := studentVal.Type()
@@ -392,14 +570,29 @@ func EditMultipleStudentsHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		err = ResolvePatchedClass(tx, input, &student.ClassID, &student.Class)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkClassChange(existingStudent, student); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
 		// To execute and update the values in the transaction
-		_, err = tx.Exec(
-			"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?",
+		// A "version" in the input is checked against the row so stale edits are rejected
+		result, err := tx.Exec(
+			"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 			student.FirstName,
 			student.LastName,
 			student.Email,
 			student.Class,
+			nullableID(student.ClassID),
 			student.ID,
+			student.Version,
 		)
 
 		if err != nil {
@@ -409,6 +602,32 @@ func EditMultipleStudentsHandler(w http.ResponseWriter, r *http.Request) {
 			utils.ErrorHandler(err, "❌ Error updating student")
 			return
 		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err == nil && rowsAffected == 0 {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("❌ Student %d has been modified by someone else, reload and try again", student.ID), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = RecordAudit(tx, r, AuditUpdate, "students", student.ID, existingStudent, student)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if student.ClassID != existingStudent.ClassID {
+			err = EnrollStudent(tx, student.ID, student.ClassID)
+			if err == nil {
+				err = recordClassChange(tx, r, existingStudent, student, "")
+			}
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
 	}
 	// To commit the transaction
 	err = tx.Commit()
@@ -450,15 +669,37 @@ func EditStudentSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	var existingStudent models.Student
-	db.QueryRow(
-		"SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id,
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&existingStudent.ID,
 		&existingStudent.FirstName,
 		&existingStudent.LastName,
 		&existingStudent.Email,
 		&existingStudent.Class,
+		&existingStudent.ClassID,
+		&existingStudent.Status,
+		&existingStudent.Version,
 	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingStudent.ID, existingStudent.Version) {
+		return
+	}
+
+	if _, ok := input["status"]; ok {
+		http.Error(w, "❌ Use /students/{id}/status to change a student's status", http.StatusBadRequest)
+		return
+	}
+
+	previousStudent := existingStudent
 
 	// To apply update using reflect package
 	studentVal := reflect.ValueOf(&existingStudent).Elem()
@@ -479,21 +720,76 @@ func EditStudentSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_, err = db.Exec(
-		"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?",
+	err = ResolvePatchedClass(db, input, &existingStudent.ClassID, &existingStudent.Class)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkClassChange(previousStudent, existingStudent); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 		existingStudent.FirstName,
 		existingStudent.LastName,
 		existingStudent.Email,
 		existingStudent.Class,
+		nullableID(existingStudent.ClassID),
 		existingStudent.ID,
+		previousStudent.Version,
 	)
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error updating student", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error updating student")
 		return
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingStudent.Version = previousStudent.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "students", existingStudent.ID, previousStudent, existingStudent)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if existingStudent.ClassID != previousStudent.ClassID {
+		err = EnrollStudent(tx, existingStudent.ID, existingStudent.ClassID)
+		if err == nil {
+			err = recordClassChange(tx, r, previousStudent, existingStudent, "")
+		}
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingStudent.ID, existingStudent.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingStudent)
 }
@@ -516,8 +812,41 @@ func DeleteOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	result, err := db.Exec("DELETE FROM students WHERE id = ?", id)
+	var deletedStudent models.Student
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
+	).Scan(
+		&deletedStudent.ID,
+		&deletedStudent.FirstName,
+		&deletedStudent.LastName,
+		&deletedStudent.Email,
+		&deletedStudent.Class,
+		&deletedStudent.ClassID,
+		&deletedStudent.Status,
+		&deletedStudent.Version,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedStudent.ID, deletedStudent.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE students SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedStudent.Version)
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable delete student", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable delete student")
 		return
@@ -525,17 +854,33 @@ func DeleteOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable retrieve deleted student", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable retrieve deleted student")
 		return
 	}
 
 	if rowsAffected == 0 {
+		tx.Rollback()
 		// http.Error(w, "❌ Student not found", http.StatusNotFound)
 		utils.ErrorHandler(err, "❌ Student not found")
 		return
 	}
 
+	err = RecordAudit(tx, r, AuditDelete, "students", id, deletedStudent, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// w.WriteHeader(http.StatusNoContent)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -574,7 +919,7 @@ func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM students WHERE id = ?")
+	stmt, err := tx.Prepare("UPDATE students SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		tx.Rollback()
 		// http.Error(w, "❌ Error preparing deleting statement", http.StatusInternalServerError)
@@ -586,6 +931,29 @@ func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	deletedIds := []int{}
 
 	for _, id := range ids {
+		var deletedStudent models.Student
+		err := tx.QueryRow(
+			"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE id = ? AND deleted_at IS NULL", id,
+		).Scan(
+			&deletedStudent.ID,
+			&deletedStudent.FirstName,
+			&deletedStudent.LastName,
+			&deletedStudent.Email,
+			&deletedStudent.Class,
+			&deletedStudent.ClassID,
+			&deletedStudent.Status,
+			&deletedStudent.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				http.Error(w, fmt.Sprintf("❌ ID %d does not exist", id), http.StatusNotFound)
+				return
+			}
+			utils.ErrorHandler(err, "❌ Error retrieving student")
+			return
+		}
+
 		result, err := stmt.Exec(id)
 		if err != nil {
 			tx.Rollback()
@@ -605,6 +973,13 @@ func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		// If student was delete, add the ID to the deletedIDs slice
 		if rowsAffected > 0 {
 			deletedIds = append(deletedIds, id)
+
+			err = RecordAudit(tx, r, AuditDelete, "students", id, deletedStudent, nil)
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
 		}
 		if rowsAffected < 1 {
 			tx.Rollback()
@@ -0,0 +1,771 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const subjectColumns = "id, code, name, department, credit_hours, version"
+
+var subjectFilterFields = map[string]string{
+	"code":       "code",
+	"name":       "name",
+	"department": "department",
+}
+
+var subjectSortFields = map[string]bool{
+	"code":         true,
+	"name":         true,
+	"department":   true,
+	"credit_hours": true,
+}
+
+func scanSubject(row interface{ Scan(...interface{}) error }, subject *models.Subject) error {
+	return row.Scan(
+		&subject.ID,
+		&subject.Code,
+		&subject.Name,
+		&subject.Department,
+		&subject.CreditHours,
+		&subject.Version,
+	)
+}
+
+// To fill in the grade levels and prerequisites of the given subjects with one query each
+func loadSubjectLinks(db *sql.DB, subjects []models.Subject) error {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	byId := make(map[int]*models.Subject, len(subjects))
+	placeholders := make([]string, len(subjects))
+	args := make([]interface{}, len(subjects))
+	for i := range subjects {
+		byId[subjects[i].ID] = &subjects[i]
+		placeholders[i] = "?"
+		args[i] = subjects[i].ID
+	}
+	in := "(" + strings.Join(placeholders, ", ") + ")"
+
+	rows, err := db.Query("SELECT subject_id, grade_level FROM subject_grade_levels WHERE subject_id IN "+in+" ORDER BY grade_level", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to retrieve subject grade levels")
+	}
+	for rows.Next() {
+		var subjectId, gradeLevel int
+		if err := rows.Scan(&subjectId, &gradeLevel); err != nil {
+			rows.Close()
+			return utils.ErrorHandler(err, "❌ Unable to retrieve subject grade levels")
+		}
+		byId[subjectId].GradeLevels = append(byId[subjectId].GradeLevels, gradeLevel)
+	}
+	rows.Close()
+
+	rows, err = db.Query("SELECT subject_id, prerequisite_id FROM subject_prerequisites WHERE subject_id IN "+in+" ORDER BY prerequisite_id", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Unable to retrieve subject prerequisites")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subjectId, prerequisiteId int
+		if err := rows.Scan(&subjectId, &prerequisiteId); err != nil {
+			return utils.ErrorHandler(err, "❌ Unable to retrieve subject prerequisites")
+		}
+		byId[subjectId].Prerequisites = append(byId[subjectId].Prerequisites, prerequisiteId)
+	}
+	return nil
+}
+
+func validateSubject(subject models.Subject) error {
+	if subject.Code == "" || subject.Name == "" {
+		return errors.New("❌ code and name are required")
+	}
+	if subject.CreditHours < 0 {
+		return errors.New("❌ credit_hours cannot be negative")
+	}
+	for _, gradeLevel := range subject.GradeLevels {
+		if gradeLevel < 0 {
+			return errors.New("❌ grade_levels cannot be negative")
+		}
+	}
+	return nil
+}
+
+// To make sure prerequisites exist and never loop back to the subject itself
+func checkPrerequisites(tx *sql.Tx, subjectId int, prerequisites []int) (int, error) {
+	edges := map[int][]int{}
+	rows, err := tx.Query("SELECT sp.subject_id, sp.prerequisite_id FROM subject_prerequisites sp JOIN subjects s ON s.id = sp.subject_id WHERE s.deleted_at IS NULL")
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve subject prerequisites")
+	}
+	for rows.Next() {
+		var from, to int
+		if err := rows.Scan(&from, &to); err != nil {
+			rows.Close()
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve subject prerequisites")
+		}
+		edges[from] = append(edges[from], to)
+	}
+	rows.Close()
+
+	for _, prerequisiteId := range prerequisites {
+		if prerequisiteId == subjectId {
+			return http.StatusBadRequest, errors.New("❌ A subject cannot be its own prerequisite")
+		}
+		var exists int
+		err := tx.QueryRow("SELECT COUNT(*) FROM subjects WHERE id = ? AND deleted_at IS NULL", prerequisiteId).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve subject")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Prerequisite subject %d does not exist", prerequisiteId)
+		}
+	}
+	edges[subjectId] = prerequisites
+
+	// Depth first search from the subject; reaching it again means a cycle
+	visited := map[int]bool{}
+	var reaches func(id int) bool
+	reaches = func(id int) bool {
+		for _, next := range edges[id] {
+			if next == subjectId {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if reaches(next) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if reaches(subjectId) {
+		return http.StatusBadRequest, errors.New("❌ Prerequisites would create a cycle")
+	}
+	return http.StatusOK, nil
+}
+
+// To replace the grade levels and prerequisites stored for a subject
+func saveSubjectLinks(tx *sql.Tx, subject models.Subject) error {
+	_, err := tx.Exec("DELETE FROM subject_grade_levels WHERE subject_id = ?", subject.ID)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error saving subject grade levels")
+	}
+	for _, gradeLevel := range subject.GradeLevels {
+		_, err = tx.Exec("INSERT IGNORE INTO subject_grade_levels (subject_id, grade_level) VALUES (?, ?)", subject.ID, gradeLevel)
+		if err != nil {
+			return utils.ErrorHandler(err, "❌ Error saving subject grade levels")
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM subject_prerequisites WHERE subject_id = ?", subject.ID)
+	if err != nil {
+		return utils.ErrorHandler(err, "❌ Error saving subject prerequisites")
+	}
+	for _, prerequisiteId := range subject.Prerequisites {
+		_, err = tx.Exec("INSERT IGNORE INTO subject_prerequisites (subject_id, prerequisite_id) VALUES (?, ?)", subject.ID, prerequisiteId)
+		if err != nil {
+			return utils.ErrorHandler(err, "❌ Error saving subject prerequisites")
+		}
+	}
+	return nil
+}
+
+// ResolveSubject makes the subject name and subject id of a record agree.
+// An id wins over a name, and a name may also be given as the subject code.
+func ResolveSubject(db Queryer, subjectId int, subjectName string) (int, string, error) {
+	if subjectId == 0 && subjectName == "" {
+		return 0, "", nil
+	}
+
+	var err error
+	if subjectId != 0 {
+		err = db.QueryRow("SELECT id, name FROM subjects WHERE id = ? AND deleted_at IS NULL", subjectId).Scan(&subjectId, &subjectName)
+	} else {
+		normalized := utils.NormalizeName(subjectName)
+		err = db.QueryRow(
+			"SELECT id, name FROM subjects WHERE (LOWER(name) = LOWER(?) OR LOWER(code) = LOWER(?)) AND deleted_at IS NULL LIMIT 1",
+			normalized, strings.TrimSpace(subjectName),
+		).Scan(&subjectId, &subjectName)
+	}
+
+	if err == sql.ErrNoRows {
+		return 0, "", utils.ErrorHandler(err, "❌ Subject does not exist")
+	} else if err != nil {
+		return 0, "", utils.ErrorHandler(err, "❌ Unable to retrieve subject")
+	}
+	return subjectId, subjectName, nil
+}
+
+// ResolvePatchedSubject keeps subject and subject_id in step after a partial update changed either of them
+func ResolvePatchedSubject(db Queryer, input map[string]interface{}, subjectId *int, subjectName *string) error {
+	_, idChanged := input["subject_id"]
+	_, nameChanged := input["subject"]
+	if !idChanged && !nameChanged {
+		return nil
+	}
+
+	if nameChanged && !idChanged {
+		*subjectId = 0
+	}
+
+	id, name, err := ResolveSubject(db, *subjectId, *subjectName)
+	if err != nil {
+		return err
+	}
+	*subjectId, *subjectName = id, name
+	return nil
+}
+
+// To get multiple subjects
+func GetSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + subjectColumns + " FROM subjects WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, subjectFilterFields)
+
+	// To only list subjects offered to a grade level e.g. ?grade_level=10
+	if gradeLevel := r.URL.Query().Get("grade_level"); gradeLevel != "" {
+		query += " AND id IN (SELECT subject_id FROM subject_grade_levels WHERE grade_level = ?)"
+		args = append(args, gradeLevel)
+	}
+
+	query = utils.AddSortingFor(r, query, subjectSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	subjectList := make([]models.Subject, 0)
+	for rows.Next() {
+		var subject models.Subject
+		err := scanSubject(rows, &subject)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		subjectList = append(subjectList, subject)
+	}
+	rows.Close()
+
+	err = loadSubjectLinks(db, subjectList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(subjectList),
+		Data:   subjectList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get single subject
+func GetOneSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid subject id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	subjects := make([]models.Subject, 1)
+	err = scanSubject(db.QueryRow("SELECT "+subjectColumns+" FROM subjects WHERE id = ? AND deleted_at IS NULL", id), &subjects[0])
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Subject not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	err = loadSubjectLinks(db, subjects)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	subject := subjects[0]
+
+	if CheckIfNoneMatch(w, r, ETag(subject.ID, subject.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subject)
+}
+
+// To add subjects to the DB
+func AddSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	var newSubjects []models.Subject
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newSubjects)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range newSubjects {
+		newSubjects[i].Name = utils.NormalizeName(newSubjects[i].Name)
+		newSubjects[i].Code = strings.ToUpper(strings.TrimSpace(newSubjects[i].Code))
+		err := validateSubject(newSubjects[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedSubjects := make([]models.Subject, len(newSubjects))
+	for i, newSubject := range newSubjects {
+		newSubject.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO subjects (code, name, department, credit_hours, version) VALUES (?, ?, ?, ?, ?)",
+			newSubject.Code,
+			newSubject.Name,
+			newSubject.Department,
+			newSubject.CreditHours,
+			newSubject.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newSubject.ID = int(lastID)
+
+		status, err := checkPrerequisites(tx, newSubject.ID, newSubject.Prerequisites)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		err = saveSubjectLinks(tx, newSubject)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = RecordAudit(tx, r, AuditCreate, "subjects", newSubject.ID, nil, newSubject)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedSubjects[i] = newSubject
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSubjects),
+		Data:   addedSubjects,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To read a list of ids out of a JSON patch value
+func intList(value interface{}) ([]int, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("❌ expected a list of numbers")
+	}
+
+	list := make([]int, 0, len(items))
+	for _, item := range items {
+		number, ok := item.(float64)
+		if !ok {
+			return nil, errors.New("❌ expected a list of numbers")
+		}
+		list = append(list, int(number))
+	}
+	return list, nil
+}
+
+// To update specific entries of a subject
+func EditSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid subject id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	subjects := make([]models.Subject, 1)
+	err = scanSubject(db.QueryRow("SELECT "+subjectColumns+" FROM subjects WHERE id = ? AND deleted_at IS NULL", id), &subjects[0])
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Subject not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	err = loadSubjectLinks(db, subjects)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existingSubject := subjects[0]
+
+	if !CheckIfMatch(w, r, existingSubject.ID, existingSubject.Version) {
+		return
+	}
+
+	previousSubject := existingSubject
+
+	// The list fields can't go through ApplyPatch because JSON numbers arrive as float64
+	for _, key := range []string{"grade_levels", "prerequisites"} {
+		value, ok := input[key]
+		if !ok {
+			continue
+		}
+		list, err := intList(value)
+		if err != nil {
+			http.Error(w, err.Error()+" in "+key, http.StatusBadRequest)
+			return
+		}
+		if key == "grade_levels" {
+			existingSubject.GradeLevels = list
+		} else {
+			existingSubject.Prerequisites = list
+		}
+		delete(input, key)
+	}
+
+	err = ApplyPatch(&existingSubject, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existingSubject.Name = utils.NormalizeName(existingSubject.Name)
+	existingSubject.Code = strings.ToUpper(strings.TrimSpace(existingSubject.Code))
+
+	err = validateSubject(existingSubject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE subjects SET code = ?, name = ?, department = ?, credit_hours = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingSubject.Code,
+		existingSubject.Name,
+		existingSubject.Department,
+		existingSubject.CreditHours,
+		existingSubject.ID,
+		previousSubject.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating subject")
+		http.Error(w, "❌ Error updating subject", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingSubject.Version = previousSubject.Version + 1
+
+	status, err := checkPrerequisites(tx, existingSubject.ID, existingSubject.Prerequisites)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = saveSubjectLinks(tx, existingSubject)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Teachers and assignments keep a copy of the subject name, so a rename has to follow them
+	if existingSubject.Name != previousSubject.Name {
+		for _, table := range []string{"teachers", "teaching_assignments"} {
+			_, err = tx.Exec("UPDATE "+table+" SET subject = ? WHERE subject_id = ?", existingSubject.Name, existingSubject.ID)
+			if err != nil {
+				tx.Rollback()
+				utils.ErrorHandler(err, "❌ Error renaming subject")
+				http.Error(w, "❌ Error renaming subject", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "subjects", existingSubject.ID, previousSubject, existingSubject)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingSubject.ID, existingSubject.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingSubject)
+}
+
+func DeleteOneSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid subject id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedSubject models.Subject
+	err = scanSubject(db.QueryRow("SELECT "+subjectColumns+" FROM subjects WHERE id = ? AND deleted_at IS NULL", id), &deletedSubject)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Subject not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedSubject.ID, deletedSubject.Version) {
+		return
+	}
+
+	var inUse int
+	err = db.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM teachers WHERE subject_id = ? AND deleted_at IS NULL) + (SELECT COUNT(*) FROM teaching_assignments WHERE subject_id = ? AND deleted_at IS NULL)",
+		id, id,
+	).Scan(&inUse)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check subject usage")
+		http.Error(w, "❌ Unable to check subject usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "❌ Subject is still taught, reassign its teachers first", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE subjects SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedSubject.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete subject")
+		http.Error(w, "❌ Unable delete subject", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "subjects", id, deletedSubject, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Subject successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "subjects", "Subject")
+}
+
+// To get the teachers of a subject, either as their main subject or through an assignment
+func GetTeachersForASubject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid subject id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := `SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers
+		WHERE deleted_at IS NULL AND (subject_id = ? OR id IN (SELECT teacher_id FROM teaching_assignments WHERE subject_id = ? AND deleted_at IS NULL))`
+	query = utils.AddSorting(r, query)
+
+	rows, err := db.Query(query, id, id)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	teachers := make([]models.Teacher, 0)
+	for rows.Next() {
+		var teacher models.Teacher
+		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.ClassID, &teacher.Subject, &teacher.SubjectID, &teacher.Version)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		teachers = append(teachers, teacher)
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Teacher `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(teachers),
+		Data:   teachers,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
@@ -15,6 +15,17 @@ import (
 	"github.com/greatdaveo/Schoolly/pkg/utils"
 )
 
+// The query params the teacher list can filter on
+var teacherFilterFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+	"class_id":   "class_id",
+	"subject":    "subject",
+	"subject_id": "subject_id",
+}
+
 // To get multiple teachers
 func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 	db, err := sqlconnect.ConnectDB()
@@ -25,14 +36,24 @@ func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
+	query := "SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE 1=1"
 	var args []interface{}
 
+	// To hide soft deleted teachers unless an admin asks for them
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
 	// To Filter
-	query, args = utils.AddFilters(r, query, args)
+	query, args = utils.AddFiltersFor(r, query, args, teacherFilterFields)
 	// To Sort
 	query = utils.AddSorting(r, query)
 
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
 	rows, err := db.Query(query, args...)
 	if err != nil {
 		// http.Error(w, "❌ Database query error", http.StatusInternalServerError)
@@ -41,12 +62,18 @@ func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
+	// To stream the list as a file when ?format=csv, xlsx or ndjson asks for one
+	if format != "" {
+		exportRows(w, rows, format, "teachers", teacherExportColumns, scanTeacherExport)
+		return
+	}
+
 	// To change teacher to a slice
 	teacherList := make([]models.Teacher, 0)
 	// To loop through any possible rows if it is more than one rows
 	for rows.Next() {
 		var teacher models.Teacher
-		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
+		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.ClassID, &teacher.Subject, &teacher.SubjectID, &teacher.Version)
 		if err != nil {
 			// http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error scanning Database results")
@@ -65,9 +92,7 @@ func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		Data:   teacherList,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
+	WriteJSONWithETag(w, r, response)
 }
 
 // To get single teacher
@@ -94,14 +119,17 @@ func GetOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 
 	var teacher models.Teacher
 	err = db.QueryRow(
-		"SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id,
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&teacher.ID,
 		&teacher.FirstName,
 		&teacher.LastName,
 		&teacher.Email,
 		&teacher.Class,
+		&teacher.ClassID,
 		&teacher.Subject,
+		&teacher.SubjectID,
+		&teacher.Version,
 	)
 	if err == sql.ErrNoRows {
 		// http.Error(w, "❌ Teacher not found", http.StatusNotFound)
@@ -113,8 +141,11 @@ func GetOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if CheckIfNoneMatch(w, r, ETag(teacher.ID, teacher.Version)) {
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(teacher)
 }
 
@@ -173,6 +204,23 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// To link each teacher to a class by id or by name
+	for i := range newTeachers {
+		classId, className, err := ResolveClass(db, newTeachers[i].ClassID, newTeachers[i].Class)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newTeachers[i].ClassID, newTeachers[i].Class = classId, className
+
+		subjectId, subjectName, err := ResolveSubject(db, newTeachers[i].SubjectID, newTeachers[i].Subject)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newTeachers[i].SubjectID, newTeachers[i].Subject = subjectId, subjectName
+	}
+
 	for _, teacher := range newTeachers {
 		err := CheckBlankFields(teacher)
 		if err != nil {
@@ -181,10 +229,18 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// stmt, err := db.Prepare("INSERT INTO teachers (first_name, last_name, email, class, subject) VALUES (?,?,?,?,?)")
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("teachers", models.Teacher{}))
+	stmt, err := tx.Prepare(utils.GenerateInsertQuery("teachers", models.Teacher{}))
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error in preparing SQL query", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error in preparing SQL query")
 		return
@@ -194,9 +250,11 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	addedTeachers := make([]models.Teacher, len(newTeachers))
 	for i, newTeacher := range newTeachers {
 		// res, err := stmt.Exec(newTeacher.FirstName, newTeacher.LastName, newTeacher.Email, newTeacher.Class, newTeacher.Subject)
+		newTeacher.Version = 1
 		values := utils.GetStructValues(newTeacher)
 		res, err := stmt.Exec(values...)
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error inserting data into database")
 			return
@@ -205,16 +263,32 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		// To get the id of this entry
 		lastID, err := res.LastInsertId()
 		if err != nil {
+			tx.Rollback()
 			// http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
 			utils.ErrorHandler(err, "❌ Error getting last insert ID")
 			return
 		}
 
 		newTeacher.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "teachers", newTeacher.ID, nil, newTeacher)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		// To add to the teacher list
 		addedTeachers[i] = newTeacher
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	response := struct {
@@ -257,14 +331,17 @@ func EditTeacherHandler(w http.ResponseWriter, r *http.Request) {
 
 	var existingTeacher models.Teacher
 	err = db.QueryRow(
-		"SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id,
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&existingTeacher.ID,
 		&existingTeacher.FirstName,
 		&existingTeacher.LastName,
 		&existingTeacher.Email,
 		&existingTeacher.Class,
+		&existingTeacher.ClassID,
 		&existingTeacher.Subject,
+		&existingTeacher.SubjectID,
+		&existingTeacher.Version,
 	)
 
 	if err == sql.ErrNoRows {
@@ -277,23 +354,74 @@ func EditTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckIfMatch(w, r, existingTeacher.ID, existingTeacher.Version) {
+		return
+	}
+
+	updatedTeacher.ClassID, updatedTeacher.Class, err = ResolveClass(db, updatedTeacher.ClassID, updatedTeacher.Class)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedTeacher.SubjectID, updatedTeacher.Subject, err = ResolveSubject(db, updatedTeacher.SubjectID, updatedTeacher.Subject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	updatedTeacher.ID = existingTeacher.ID
-	_, err = db.Exec(
-		"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?",
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, subject = ?, subject_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 		updatedTeacher.FirstName,
 		updatedTeacher.LastName,
 		updatedTeacher.Email,
 		updatedTeacher.Class,
+		nullableID(updatedTeacher.ClassID),
 		updatedTeacher.Subject,
+		nullableID(updatedTeacher.SubjectID),
 		updatedTeacher.ID,
+		existingTeacher.Version,
 	)
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error updating teacher", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error updating teacher")
 		return
 	}
 
+	// Someone else saved between our read and write
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	updatedTeacher.Version = existingTeacher.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "teachers", updatedTeacher.ID, existingTeacher, updatedTeacher)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(updatedTeacher.ID, updatedTeacher.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedTeacher)
 }
@@ -348,14 +476,17 @@ func EditMultipleTeachersHandler(w http.ResponseWriter, r *http.Request) {
 
 		var teacher models.Teacher
 		err = db.QueryRow(
-			"SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id,
+			"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
 		).Scan(
 			&teacher.ID,
 			&teacher.FirstName,
 			&teacher.LastName,
 			&teacher.Email,
 			&teacher.Class,
+			&teacher.ClassID,
 			&teacher.Subject,
+			&teacher.SubjectID,
+			&teacher.Version,
 		)
 
 		if err != nil {
@@ -371,6 +502,8 @@ func EditMultipleTeachersHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		existingTeacher := teacher
+
 		// To update using reflect
 		teacherVal := reflect.ValueOf(&teacher).Elem()
 		teacherType := teacherVal.Type()
@@ -399,15 +532,33 @@ func EditMultipleTeachersHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		err = ResolvePatchedClass(tx, input, &teacher.ClassID, &teacher.Class)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = ResolvePatchedSubject(tx, input, &teacher.SubjectID, &teacher.Subject)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// To execute and update the values in the transaction
-		_, err = tx.Exec(
-			"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?",
+		// A "version" in the input is checked against the row so stale edits are rejected
+		result, err := tx.Exec(
+			"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, subject = ?, subject_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 			teacher.FirstName,
 			teacher.LastName,
 			teacher.Email,
 			teacher.Class,
+			nullableID(teacher.ClassID),
 			teacher.Subject,
+			nullableID(teacher.SubjectID),
 			teacher.ID,
+			teacher.Version,
 		)
 
 		if err != nil {
@@ -417,6 +568,20 @@ func EditMultipleTeachersHandler(w http.ResponseWriter, r *http.Request) {
 			utils.ErrorHandler(err, "❌ Error updating teacher")
 			return
 		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err == nil && rowsAffected == 0 {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("❌ Teacher %d has been modified by someone else, reload and try again", teacher.ID), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = RecordAudit(tx, r, AuditUpdate, "teachers", teacher.ID, existingTeacher, teacher)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 	// To commit the transaction
 	err = tx.Commit()
@@ -458,16 +623,27 @@ func EditTeacherSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	var existingTeacher models.Teacher
-	db.QueryRow(
-		"SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id,
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
 	).Scan(
 		&existingTeacher.ID,
 		&existingTeacher.FirstName,
 		&existingTeacher.LastName,
 		&existingTeacher.Email,
 		&existingTeacher.Class,
+		&existingTeacher.ClassID,
 		&existingTeacher.Subject,
+		&existingTeacher.SubjectID,
+		&existingTeacher.Version,
 	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Teacher not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
 
 	// To update the teacher data
 	// for k, v := range input {
@@ -485,6 +661,12 @@ func EditTeacherSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 	// 	}
 	// }
 
+	if !CheckIfMatch(w, r, existingTeacher.ID, existingTeacher.Version) {
+		return
+	}
+
+	previousTeacher := existingTeacher
+
 	// To apply update using reflect package
 	teacherVal := reflect.ValueOf(&existingTeacher).Elem()
 	teacherType := teacherVal.Type()
@@ -503,23 +685,69 @@ func EditTeacherSingleDataHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_, err = db.Exec(
-		"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?",
+	err = ResolvePatchedClass(db, input, &existingTeacher.ClassID, &existingTeacher.Class)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ResolvePatchedSubject(db, input, &existingTeacher.SubjectID, &existingTeacher.Subject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, subject = ?, subject_id = ?, version = version + 1 WHERE id = ? AND version = ?",
 		existingTeacher.FirstName,
 		existingTeacher.LastName,
 		existingTeacher.Email,
 		existingTeacher.Class,
+		nullableID(existingTeacher.ClassID),
 		existingTeacher.Subject,
+		nullableID(existingTeacher.SubjectID),
 		existingTeacher.ID,
+		previousTeacher.Version,
 	)
 
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Error updating teacher", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Error updating teacher")
 
 		return
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingTeacher.Version = previousTeacher.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "teachers", existingTeacher.ID, previousTeacher, existingTeacher)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingTeacher.ID, existingTeacher.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingTeacher)
 }
@@ -542,8 +770,42 @@ func DeleteOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	result, err := db.Exec("DELETE FROM teachers WHERE id = ?", id)
+	var deletedTeacher models.Teacher
+	err = db.QueryRow(
+		"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
+	).Scan(
+		&deletedTeacher.ID,
+		&deletedTeacher.FirstName,
+		&deletedTeacher.LastName,
+		&deletedTeacher.Email,
+		&deletedTeacher.Class,
+		&deletedTeacher.ClassID,
+		&deletedTeacher.Subject,
+		&deletedTeacher.SubjectID,
+		&deletedTeacher.Version,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Teacher not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedTeacher.ID, deletedTeacher.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE teachers SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedTeacher.Version)
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable delete teacher", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable delete teacher")
 		return
@@ -551,17 +813,33 @@ func DeleteOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		// http.Error(w, "❌ Unable retrieve deleted teacher", http.StatusInternalServerError)
 		utils.ErrorHandler(err, "❌ Unable retrieve deleted teacher")
 		return
 	}
 
 	if rowsAffected == 0 {
+		tx.Rollback()
 		// http.Error(w, "❌ Teacher not found", http.StatusNotFound)
 		utils.ErrorHandler(err, "❌ Teacher not found")
 		return
 	}
 
+	err = RecordAudit(tx, r, AuditDelete, "teachers", id, deletedTeacher, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// w.WriteHeader(http.StatusNoContent)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -600,7 +878,7 @@ func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM teachers WHERE id = ?")
+	stmt, err := tx.Prepare("UPDATE teachers SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		tx.Rollback()
 		// http.Error(w, "❌ Error preparing deleting statement", http.StatusInternalServerError)
@@ -612,6 +890,30 @@ func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
 	deletedIds := []int{}
 
 	for _, id := range ids {
+		var deletedTeacher models.Teacher
+		err := tx.QueryRow(
+			"SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), subject, COALESCE(subject_id, 0), version FROM teachers WHERE id = ? AND deleted_at IS NULL", id,
+		).Scan(
+			&deletedTeacher.ID,
+			&deletedTeacher.FirstName,
+			&deletedTeacher.LastName,
+			&deletedTeacher.Email,
+			&deletedTeacher.Class,
+			&deletedTeacher.ClassID,
+			&deletedTeacher.Subject,
+			&deletedTeacher.SubjectID,
+			&deletedTeacher.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				http.Error(w, fmt.Sprintf("❌ ID %d does not exist", id), http.StatusNotFound)
+				return
+			}
+			utils.ErrorHandler(err, "❌ Error retrieving teacher")
+			return
+		}
+
 		result, err := stmt.Exec(id)
 		if err != nil {
 			tx.Rollback()
@@ -631,6 +933,13 @@ func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		// If teacher was delete, add the ID to the deletedIDs slice
 		if rowsAffected > 0 {
 			deletedIds = append(deletedIds, id)
+
+			err = RecordAudit(tx, r, AuditDelete, "teachers", id, deletedTeacher, nil)
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
 		}
 		if rowsAffected < 1 {
 			tx.Rollback()
@@ -679,13 +988,41 @@ func GetStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	query := `SELECT id, first_name, last_name, email, class FROM students WHERE class = (SELECT class from teachers WHERE id = ?)`
-	rows, err := db.Query(query, teacherId)
+	// A teacher teaches every class they hold an assignment in, optionally narrowed with ?term=
+	classQuery, classArgs := teacherClassesQuery(r, teacherId)
+	query := `SELECT id, first_name, last_name, email, class, COALESCE(class_id, 0), status, version FROM students WHERE deleted_at IS NULL AND class_id IN (` + classQuery + `)`
+	args := classArgs
+
+	// The same filters as GET /students, so an export holds exactly the students listed
+	query, args = utils.AddFiltersFor(r, query, args, studentFilterFields)
+	query, args, err = addStudentStatusFilter(r, query, args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query = utils.AddSorting(r, query)
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	defer db.Close()
+	defer rows.Close()
+
+	// To stream the list as a file when ?format=csv, xlsx or ndjson asks for one
+	if format != "" {
+		name := "teacher-students"
+		if id, err := strconv.Atoi(teacherId); err == nil {
+			name = fmt.Sprintf("teacher-%d-students", id)
+		}
+		exportRows(w, rows, format, name, studentExportColumns, scanStudentExport)
+		return
+	}
 
 	for rows.Next() {
 		var student models.Student
@@ -695,6 +1032,9 @@ func GetStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
 			&student.LastName,
 			&student.Email,
 			&student.Class,
+			&student.ClassID,
+			&student.Status,
+			&student.Version,
 		)
 
 		if err != nil {
@@ -721,9 +1061,7 @@ func GetStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
 		Data:   students,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
+	WriteJSONWithETag(w, r, response)
 }
 
 func CountStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
@@ -738,9 +1076,10 @@ func CountStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	query := `SELECT COUNT(*) FROM students WHERE class = (SELECT class FROM teachers WHERE id = ?)`
+	classQuery, classArgs := teacherClassesQuery(r, teacherId)
+	query := `SELECT COUNT(*) FROM students WHERE deleted_at IS NULL AND class_id IN (` + classQuery + `)`
 
-	err = db.QueryRow(query, teacherId).Scan(&studentCount)
+	err = db.QueryRow(query, classArgs...).Scan(&studentCount)
 	if err != nil {
 		// log.Println(err)
 		return
@@ -754,7 +1093,21 @@ func CountStudentsForATeacher(w http.ResponseWriter, r *http.Request) {
 		Count:  studentCount,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	WriteJSONWithETag(w, r, response)
+}
 
+// To build the subquery of class ids a teacher is assigned to
+func teacherClassesQuery(r *http.Request, teacherId string) (string, []interface{}) {
+	query := `SELECT ta.class_id FROM teaching_assignments ta JOIN teachers t ON t.id = ta.teacher_id WHERE ta.teacher_id = ? AND ta.deleted_at IS NULL AND t.deleted_at IS NULL`
+	args := []interface{}{teacherId}
+
+	if term := r.URL.Query().Get("term"); term != "" {
+		query += ` AND ta.term = ?`
+		args = append(args, term)
+	}
+	if termId := r.URL.Query().Get("term_id"); termId != "" {
+		query += ` AND ta.term_id = ?`
+		args = append(args, termId)
+	}
+	return query, args
 }
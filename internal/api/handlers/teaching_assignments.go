@@ -0,0 +1,524 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const assignmentColumns = "id, teacher_id, class_id, subject, COALESCE(subject_id, 0), term, COALESCE(term_id, 0), role, version"
+
+const (
+	AssignmentLead      = "lead"
+	AssignmentAssistant = "assistant"
+)
+
+var assignmentFilterFields = map[string]string{
+	"teacher_id": "teacher_id",
+	"class_id":   "class_id",
+	"subject":    "subject",
+	"subject_id": "subject_id",
+	"term":       "term",
+	"term_id":    "term_id",
+	"role":       "role",
+}
+
+var assignmentSortFields = map[string]bool{
+	"teacher_id": true,
+	"class_id":   true,
+	"subject":    true,
+	"term":       true,
+}
+
+func scanAssignment(row interface{ Scan(...interface{}) error }, assignment *models.TeachingAssignment) error {
+	return row.Scan(
+		&assignment.ID,
+		&assignment.TeacherID,
+		&assignment.ClassID,
+		&assignment.Subject,
+		&assignment.SubjectID,
+		&assignment.Term,
+		&assignment.TermID,
+		&assignment.Role,
+		&assignment.Version,
+	)
+}
+
+// To check an assignment before it is written and link it to its subject.
+// It returns the HTTP status to answer with when invalid.
+func validateAssignment(db Queryer, assignment *models.TeachingAssignment) (int, error) {
+	if assignment.TeacherID == 0 || assignment.ClassID == 0 || (assignment.Subject == "" && assignment.SubjectID == 0) || (assignment.Term == "" && assignment.TermID == 0) {
+		return http.StatusBadRequest, errors.New("❌ teacher_id, class_id, subject and term are required")
+	}
+
+	if assignment.Role != AssignmentLead && assignment.Role != AssignmentAssistant {
+		return http.StatusBadRequest, errors.New("❌ role must be lead or assistant")
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deleted_at IS NULL", assignment.TeacherID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve teacher")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Teacher %d does not exist", assignment.TeacherID)
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ? AND deleted_at IS NULL", assignment.ClassID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve class")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Class %d does not exist", assignment.ClassID)
+	}
+
+	assignment.SubjectID, assignment.Subject, err = ResolveSubject(db, assignment.SubjectID, assignment.Subject)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	assignment.TermID, assignment.Term, err = ResolveTerm(db, assignment.TermID, assignment.Term)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// A subject in a class can only have one lead teacher per term
+	if assignment.Role == AssignmentLead {
+		var leadTeacherId int
+		err = db.QueryRow(
+			"SELECT teacher_id FROM teaching_assignments WHERE class_id = ? AND subject_id = ? AND term_id = ? AND role = ? AND id <> ? AND deleted_at IS NULL LIMIT 1 FOR UPDATE",
+			assignment.ClassID, assignment.SubjectID, assignment.TermID, AssignmentLead, assignment.ID,
+		).Scan(&leadTeacherId)
+		if err == nil {
+			return http.StatusConflict, fmt.Errorf("❌ Teacher %d is already the lead for %s in class %d for %s", leadTeacherId, assignment.Subject, assignment.ClassID, assignment.Term)
+		} else if err != sql.ErrNoRows {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check for conflicting assignments")
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// To key a lead assignment by its class, subject and term. The unique index on lead_key
+// is what stops two concurrent requests both adding a lead, the check in
+// validateAssignment only gives the clearer message.
+func assignmentLeadKey(assignment models.TeachingAssignment) interface{} {
+	if assignment.Role != AssignmentLead {
+		return nil
+	}
+	return fmt.Sprintf("%d:%s:%s", assignment.ClassID, assignment.Subject, assignment.Term)
+}
+
+func leadTakenError(assignment models.TeachingAssignment) error {
+	return fmt.Errorf("❌ Another teacher is already the lead for %s in class %d for %s", assignment.Subject, assignment.ClassID, assignment.Term)
+}
+
+// To list assignments with an extra condition for the nested routes
+func listAssignments(w http.ResponseWriter, r *http.Request, condition string, conditionArgs ...interface{}) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + assignmentColumns + " FROM teaching_assignments WHERE deleted_at IS NULL" + condition
+	args := conditionArgs
+
+	query, args = utils.AddFiltersFor(r, query, args, assignmentFilterFields)
+	query = utils.AddSortingFor(r, query, assignmentSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	assignmentList := make([]models.TeachingAssignment, 0)
+	for rows.Next() {
+		var assignment models.TeachingAssignment
+		err := scanAssignment(rows, &assignment)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		assignmentList = append(assignmentList, assignment)
+	}
+
+	response := struct {
+		Status string                      `json:"status"`
+		Count  int                         `json:"count"`
+		Data   []models.TeachingAssignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assignmentList),
+		Data:   assignmentList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get multiple teaching assignments
+func GetAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	listAssignments(w, r, "")
+}
+
+// To get the assignments of a specific teacher
+func GetAssignmentsForATeacher(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	listAssignments(w, r, " AND teacher_id = ?", teacherId)
+}
+
+// To get single teaching assignment
+func GetOneAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assignment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var assignment models.TeachingAssignment
+	err = scanAssignment(db.QueryRow("SELECT "+assignmentColumns+" FROM teaching_assignments WHERE id = ? AND deleted_at IS NULL", id), &assignment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assignment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(assignment.ID, assignment.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// To add teaching assignments to the DB
+func AddAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	var newAssignments []models.TeachingAssignment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newAssignments)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedAssignments := make([]models.TeachingAssignment, len(newAssignments))
+	for i, newAssignment := range newAssignments {
+		newAssignment.ID = 0
+		if newAssignment.Role == "" {
+			newAssignment.Role = AssignmentLead
+		}
+
+		// Validating inside the transaction also catches two leads within the same request
+		status, err := validateAssignment(tx, &newAssignment)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newAssignment.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO teaching_assignments (teacher_id, class_id, subject, subject_id, term, term_id, role, lead_key, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			newAssignment.TeacherID,
+			newAssignment.ClassID,
+			newAssignment.Subject,
+			newAssignment.SubjectID,
+			newAssignment.Term,
+			newAssignment.TermID,
+			newAssignment.Role,
+			assignmentLeadKey(newAssignment),
+			newAssignment.Version,
+		)
+		if utils.IsDuplicateKey(err) {
+			tx.Rollback()
+			http.Error(w, leadTakenError(newAssignment).Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newAssignment.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "teaching_assignments", newAssignment.ID, nil, newAssignment)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedAssignments[i] = newAssignment
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                      `json:"status"`
+		Count  int                         `json:"count"`
+		Data   []models.TeachingAssignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedAssignments),
+		Data:   addedAssignments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a teaching assignment
+func EditAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assignment id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var existingAssignment models.TeachingAssignment
+	err = scanAssignment(tx.QueryRow("SELECT "+assignmentColumns+" FROM teaching_assignments WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id), &existingAssignment)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "❌ Assignment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingAssignment.ID, existingAssignment.Version) {
+		tx.Rollback()
+		return
+	}
+
+	previousAssignment := existingAssignment
+	err = ApplyPatch(&existingAssignment, input)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ResolvePatchedSubject(tx, input, &existingAssignment.SubjectID, &existingAssignment.Subject)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ResolvePatchedTerm(tx, input, &existingAssignment.TermID, &existingAssignment.Term)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := validateAssignment(tx, &existingAssignment)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE teaching_assignments SET teacher_id = ?, class_id = ?, subject = ?, subject_id = ?, term = ?, term_id = ?, role = ?, lead_key = ?, version = version + 1 WHERE id = ?",
+		existingAssignment.TeacherID,
+		existingAssignment.ClassID,
+		existingAssignment.Subject,
+		existingAssignment.SubjectID,
+		existingAssignment.Term,
+		existingAssignment.TermID,
+		existingAssignment.Role,
+		assignmentLeadKey(existingAssignment),
+		existingAssignment.ID,
+	)
+	if utils.IsDuplicateKey(err) {
+		tx.Rollback()
+		http.Error(w, leadTakenError(existingAssignment).Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating assignment")
+		http.Error(w, "❌ Error updating assignment", http.StatusInternalServerError)
+		return
+	}
+	existingAssignment.Version = previousAssignment.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "teaching_assignments", existingAssignment.ID, previousAssignment, existingAssignment)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingAssignment.ID, existingAssignment.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingAssignment)
+}
+
+func DeleteOneAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid assignment id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedAssignment models.TeachingAssignment
+	err = scanAssignment(db.QueryRow("SELECT "+assignmentColumns+" FROM teaching_assignments WHERE id = ? AND deleted_at IS NULL", id), &deletedAssignment)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Assignment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedAssignment.ID, deletedAssignment.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE teaching_assignments SET deleted_at = NOW(), lead_key = NULL, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedAssignment.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete assignment")
+		http.Error(w, "❌ Unable delete assignment", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "teaching_assignments", id, deletedAssignment, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Assignment successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
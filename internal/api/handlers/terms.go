@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const termColumns = "id, academic_year_id, name, start_date, end_date, version"
+
+// ErrNoCurrentTerm is returned when no term covers the date asked about
+var ErrNoCurrentTerm = errors.New("❌ No term covers this date")
+
+var termFilterFields = map[string]string{
+	"academic_year_id": "academic_year_id",
+	"name":             "name",
+}
+
+var termSortFields = map[string]bool{
+	"name":       true,
+	"start_date": true,
+	"end_date":   true,
+}
+
+func scanTerm(row interface{ Scan(...interface{}) error }, term *models.Term) error {
+	return row.Scan(
+		&term.ID,
+		&term.AcademicYearID,
+		&term.Name,
+		&term.StartDate,
+		&term.EndDate,
+		&term.Version,
+	)
+}
+
+// CurrentTerm finds the term that covers a date. A blank date means today.
+func CurrentTerm(db Queryer, date string) (models.Term, error) {
+	if date == "" {
+		date = time.Now().Format(DateLayout)
+	}
+
+	var term models.Term
+	err := scanTerm(db.QueryRow(
+		"SELECT t.id, t.academic_year_id, t.name, t.start_date, t.end_date, t.version FROM terms t JOIN academic_years y ON y.id = t.academic_year_id WHERE ? BETWEEN t.start_date AND t.end_date AND t.deleted_at IS NULL AND y.deleted_at IS NULL LIMIT 1",
+		date,
+	), &term)
+	if err == sql.ErrNoRows {
+		return term, ErrNoCurrentTerm
+	} else if err != nil {
+		return term, utils.ErrorHandler(err, "❌ Unable to retrieve current term")
+	}
+	return term, nil
+}
+
+// ResolveTerm makes the term name and term id of a record agree. An id wins over a name.
+func ResolveTerm(db Queryer, termId int, termName string) (int, string, error) {
+	if termId == 0 && termName == "" {
+		return 0, "", nil
+	}
+
+	var err error
+	if termId != 0 {
+		err = db.QueryRow("SELECT id, name FROM terms WHERE id = ? AND deleted_at IS NULL", termId).Scan(&termId, &termName)
+	} else {
+		err = db.QueryRow("SELECT id, name FROM terms WHERE name = ? AND deleted_at IS NULL ORDER BY start_date DESC LIMIT 1", termName).Scan(&termId, &termName)
+	}
+
+	if err == sql.ErrNoRows {
+		return 0, "", utils.ErrorHandler(err, "❌ Term does not exist")
+	} else if err != nil {
+		return 0, "", utils.ErrorHandler(err, "❌ Unable to retrieve term")
+	}
+	return termId, termName, nil
+}
+
+// ResolvePatchedTerm keeps term and term_id in step after a partial update changed either of them
+func ResolvePatchedTerm(db Queryer, input map[string]interface{}, termId *int, termName *string) error {
+	_, idChanged := input["term_id"]
+	_, nameChanged := input["term"]
+	if !idChanged && !nameChanged {
+		return nil
+	}
+
+	if nameChanged && !idChanged {
+		*termId = 0
+	}
+
+	id, name, err := ResolveTerm(db, *termId, *termName)
+	if err != nil {
+		return err
+	}
+	*termId, *termName = id, name
+	return nil
+}
+
+// To check a term before it is written. It returns the HTTP status to answer with when invalid.
+func validateTerm(db Queryer, term models.Term) (int, error) {
+	if term.AcademicYearID == 0 || term.Name == "" {
+		return http.StatusBadRequest, errors.New("❌ academic_year_id, name, start_date and end_date are required")
+	}
+	if err := validateDateRange(term.StartDate, term.EndDate); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	var year models.AcademicYear
+	err := scanAcademicYear(db.QueryRow("SELECT "+academicYearColumns+" FROM academic_years WHERE id = ? AND deleted_at IS NULL", term.AcademicYearID), &year)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest, fmt.Errorf("❌ Academic year %d does not exist", term.AcademicYearID)
+	} else if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve academic year")
+	}
+
+	// Dates in the fixed YYYY-MM-DD layout compare correctly as strings
+	if term.StartDate < year.StartDate || term.EndDate > year.EndDate {
+		return http.StatusBadRequest, fmt.Errorf("❌ Term must fall within academic year %s (%s to %s)", year.Name, year.StartDate, year.EndDate)
+	}
+
+	var overlapping string
+	err = db.QueryRow(
+		"SELECT name FROM terms WHERE academic_year_id = ? AND start_date <= ? AND end_date >= ? AND id <> ? AND deleted_at IS NULL LIMIT 1",
+		term.AcademicYearID, term.EndDate, term.StartDate, term.ID,
+	).Scan(&overlapping)
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("❌ Dates overlap with term %s", overlapping)
+	} else if err != sql.ErrNoRows {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check for overlapping terms")
+	}
+
+	return http.StatusOK, nil
+}
+
+// To get multiple terms
+func GetTermsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + termColumns + " FROM terms WHERE 1=1"
+	var args []interface{}
+
+	if !IncludeDeleted(r) {
+		query += " AND deleted_at IS NULL"
+	}
+
+	query, args = utils.AddFiltersFor(r, query, args, termFilterFields)
+	query = utils.AddSortingFor(r, query, termSortFields)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	termList := make([]models.Term, 0)
+	for rows.Next() {
+		var term models.Term
+		err := scanTerm(rows, &term)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		termList = append(termList, term)
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Term `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(termList),
+		Data:   termList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the term running today, or on ?date=YYYY-MM-DD
+func GetCurrentTermHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date != "" {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			http.Error(w, "❌ date must be a date like 2025-09-01", http.StatusBadRequest)
+			return
+		}
+	} else {
+		date = time.Now().Format(DateLayout)
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	term, err := CurrentTerm(db, date)
+	if err == ErrNoCurrentTerm {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	holiday, holidayName, err := IsHoliday(db, date)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Date        string      `json:"date"`
+		Term        models.Term `json:"term"`
+		Holiday     bool        `json:"holiday"`
+		HolidayName string      `json:"holiday_name,omitempty"`
+	}{
+		Date:        date,
+		Term:        term,
+		Holiday:     holiday,
+		HolidayName: holidayName,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// To get single term
+func GetOneTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var term models.Term
+	err = scanTerm(db.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = ? AND deleted_at IS NULL", id), &term)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Term not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+
+	if CheckIfNoneMatch(w, r, ETag(term.ID, term.Version)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+// To add terms to the DB
+func AddTermsHandler(w http.ResponseWriter, r *http.Request) {
+	var newTerms []models.Term
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newTerms)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedTerms := make([]models.Term, len(newTerms))
+	for i, newTerm := range newTerms {
+		// Validating inside the transaction also catches overlaps within the same request
+		status, err := validateTerm(tx, newTerm)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newTerm.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO terms (academic_year_id, name, start_date, end_date, version) VALUES (?, ?, ?, ?, ?)",
+			newTerm.AcademicYearID,
+			newTerm.Name,
+			newTerm.StartDate,
+			newTerm.EndDate,
+			newTerm.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newTerm.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "terms", newTerm.ID, nil, newTerm)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedTerms[i] = newTerm
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Term `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedTerms),
+		Data:   addedTerms,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To update specific entries of a term
+func EditTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingTerm models.Term
+	err = scanTerm(db.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = ? AND deleted_at IS NULL", id), &existingTerm)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Term not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingTerm.ID, existingTerm.Version) {
+		return
+	}
+
+	previousTerm := existingTerm
+	err = ApplyPatch(&existingTerm, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := validateTerm(db, existingTerm)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE terms SET academic_year_id = ?, name = ?, start_date = ?, end_date = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingTerm.AcademicYearID,
+		existingTerm.Name,
+		existingTerm.StartDate,
+		existingTerm.EndDate,
+		existingTerm.ID,
+		previousTerm.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating term")
+		http.Error(w, "❌ Error updating term", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingTerm.Version = previousTerm.Version + 1
+
+	// Assignments keep a copy of the term name, and leads are keyed by it, so a rename has to follow them
+	if existingTerm.Name != previousTerm.Name {
+		_, err = tx.Exec("UPDATE teaching_assignments SET term = ?, lead_key = IF(lead_key IS NULL, NULL, CONCAT(class_id, ':', subject, ':', term)) WHERE term_id = ?", existingTerm.Name, existingTerm.ID)
+		if utils.IsDuplicateKey(err) {
+			tx.Rollback()
+			http.Error(w, "❌ A class would have two lead teachers for a subject under the new term name", http.StatusConflict)
+			return
+		} else if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error renaming term")
+			http.Error(w, "❌ Error renaming term", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = RecordAudit(tx, r, AuditUpdate, "terms", existingTerm.ID, previousTerm, existingTerm)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingTerm.ID, existingTerm.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingTerm)
+}
+
+func DeleteOneTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid term id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedTerm models.Term
+	err = scanTerm(db.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = ? AND deleted_at IS NULL", id), &deletedTerm)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Term not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedTerm.ID, deletedTerm.Version) {
+		return
+	}
+
+	var inUse int
+	err = db.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM teaching_assignments WHERE term_id = ? AND deleted_at IS NULL) + (SELECT COUNT(*) FROM enrollments WHERE term_id = ?)",
+		id, id,
+	).Scan(&inUse)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to check term usage")
+		http.Error(w, "❌ Unable to check term usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "❌ Term still has assignments or enrollments", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec("UPDATE terms SET deleted_at = NOW(), version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, deletedTerm.Version)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete term")
+		http.Error(w, "❌ Unable delete term", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "terms", id, deletedTerm, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Term successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreTermHandler(w http.ResponseWriter, r *http.Request) {
+	restoreResource(w, r, "terms", "Term")
+}
@@ -0,0 +1,723 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models"
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/ical"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+const timetableSlotColumns = "id, term_id, day_of_week, period_id, class_id, teacher_id, subject_id, COALESCE(room_id, 0), version"
+
+// The feed name signed into timetable subscription tokens
+const teacherTimetableFeed = "teacher-timetable"
+
+var timetableFilterFields = map[string]string{
+	"term_id":    "term_id",
+	"day":        "day_of_week",
+	"period_id":  "period_id",
+	"class_id":   "class_id",
+	"teacher_id": "teacher_id",
+	"subject_id": "subject_id",
+	"room_id":    "room_id",
+}
+
+func scanTimetableSlot(row interface{ Scan(...interface{}) error }, slot *models.TimetableSlot) error {
+	return row.Scan(
+		&slot.ID,
+		&slot.TermID,
+		&slot.Day,
+		&slot.PeriodID,
+		&slot.ClassID,
+		&slot.TeacherID,
+		&slot.SubjectID,
+		&slot.RoomID,
+		&slot.Version,
+	)
+}
+
+// To name a day of the week stored as 1 for Monday through 7 for Sunday
+func dayName(day int) string {
+	return time.Weekday(day % 7).String()
+}
+
+// To check a slot before it is written. A missing term_id means the current term.
+// It returns the HTTP status to answer with when invalid.
+func validateTimetableSlot(db Queryer, slot *models.TimetableSlot) (int, error) {
+	if slot.PeriodID == 0 || slot.ClassID == 0 || slot.TeacherID == 0 || slot.SubjectID == 0 {
+		return http.StatusBadRequest, errors.New("❌ day, period_id, class_id, teacher_id and subject_id are required")
+	}
+	if slot.Day < 1 || slot.Day > 7 {
+		return http.StatusBadRequest, errors.New("❌ day must be between 1 (Monday) and 7 (Sunday)")
+	}
+
+	if slot.TermID == 0 {
+		term, err := CurrentTerm(db, "")
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		slot.TermID = term.ID
+	}
+
+	checks := []struct {
+		table string
+		id    int
+		label string
+	}{
+		{"terms", slot.TermID, "Term"},
+		{"classes", slot.ClassID, "Class"},
+		{"teachers", slot.TeacherID, "Teacher"},
+		{"subjects", slot.SubjectID, "Subject"},
+	}
+	for _, check := range checks {
+		var exists int
+		err := db.QueryRow("SELECT COUNT(*) FROM "+check.table+" WHERE id = ? AND deleted_at IS NULL", check.id).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve "+check.table)
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ %s %d does not exist", check.label, check.id)
+		}
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM periods WHERE id = ?", slot.PeriodID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve period")
+	}
+	if exists == 0 {
+		return http.StatusBadRequest, fmt.Errorf("❌ Period %d does not exist", slot.PeriodID)
+	}
+
+	if slot.RoomID != 0 {
+		err = db.QueryRow("SELECT COUNT(*) FROM rooms WHERE id = ?", slot.RoomID).Scan(&exists)
+		if err != nil {
+			return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to retrieve room")
+		}
+		if exists == 0 {
+			return http.StatusBadRequest, fmt.Errorf("❌ Room %d does not exist", slot.RoomID)
+		}
+	}
+
+	return checkSlotConflicts(db, *slot)
+}
+
+// To make sure nobody is in two places at once: the teacher, the class and the room must
+// all be free for the whole period. Periods that only overlap in time count as clashes too.
+func checkSlotConflicts(db Queryer, slot models.TimetableSlot) (int, error) {
+	var clashId, classId, teacherId, roomId int
+	var periodName string
+	err := db.QueryRow(
+		`SELECT s.id, s.class_id, s.teacher_id, COALESCE(s.room_id, 0), p.name
+		FROM timetable_slots s
+		JOIN periods p ON p.id = s.period_id
+		JOIN periods np ON np.id = ?
+		WHERE s.term_id = ? AND s.day_of_week = ? AND s.id <> ?
+		AND p.start_time < np.end_time AND np.start_time < p.end_time
+		AND (s.teacher_id = ? OR s.class_id = ? OR s.room_id = ?)
+		LIMIT 1 FOR UPDATE`,
+		slot.PeriodID, slot.TermID, slot.Day, slot.ID,
+		slot.TeacherID, slot.ClassID, nullableID(slot.RoomID),
+	).Scan(&clashId, &classId, &teacherId, &roomId, &periodName)
+	if err == sql.ErrNoRows {
+		return http.StatusOK, nil
+	} else if err != nil {
+		return http.StatusInternalServerError, utils.ErrorHandler(err, "❌ Unable to check for timetable conflicts")
+	}
+
+	when := fmt.Sprintf("on %s during %s (slot %d)", dayName(slot.Day), periodName, clashId)
+	switch {
+	case teacherId == slot.TeacherID:
+		return http.StatusConflict, fmt.Errorf("❌ Teacher %d is already teaching %s", slot.TeacherID, when)
+	case classId == slot.ClassID:
+		return http.StatusConflict, fmt.Errorf("❌ Class %d already has a lesson %s", slot.ClassID, when)
+	default:
+		return http.StatusConflict, fmt.Errorf("❌ Room %d is already booked %s", slot.RoomID, when)
+	}
+}
+
+// To get timetable slots, filtered by e.g. ?term_id=, ?day=, ?room_id=
+func GetTimetableSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	query := "SELECT " + timetableSlotColumns + " FROM timetable_slots WHERE 1=1"
+	var args []interface{}
+	query, args = utils.AddFiltersFor(r, query, args, timetableFilterFields)
+	query += " ORDER BY term_id, day_of_week, period_id, class_id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Database query error")
+		http.Error(w, "❌ Database query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	slotList := make([]models.TimetableSlot, 0)
+	for rows.Next() {
+		var slot models.TimetableSlot
+		err := scanTimetableSlot(rows, &slot)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Error scanning Database results")
+			http.Error(w, "❌ Error scanning Database results", http.StatusInternalServerError)
+			return
+		}
+		slotList = append(slotList, slot)
+	}
+
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.TimetableSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(slotList),
+		Data:   slotList,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To add timetable slots. The whole batch is rejected if any slot clashes, including with another slot in the batch.
+func AddTimetableSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	var newSlots []models.TimetableSlot
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&newSlots)
+	if err != nil {
+		http.Error(w, "❌ Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	addedSlots := make([]models.TimetableSlot, len(newSlots))
+	for i, newSlot := range newSlots {
+		newSlot.ID = 0
+		status, err := validateTimetableSlot(tx, &newSlot)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		newSlot.Version = 1
+		res, err := tx.Exec(
+			"INSERT INTO timetable_slots (term_id, day_of_week, period_id, class_id, teacher_id, subject_id, room_id, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			newSlot.TermID,
+			newSlot.Day,
+			newSlot.PeriodID,
+			newSlot.ClassID,
+			newSlot.TeacherID,
+			newSlot.SubjectID,
+			nullableID(newSlot.RoomID),
+			newSlot.Version,
+		)
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error inserting data into database")
+			http.Error(w, "❌ Error inserting data into database", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			utils.ErrorHandler(err, "❌ Error getting last insert ID")
+			http.Error(w, "❌ Error getting last insert ID", http.StatusInternalServerError)
+			return
+		}
+		newSlot.ID = int(lastID)
+
+		err = RecordAudit(tx, r, AuditCreate, "timetable_slots", newSlot.ID, nil, newSlot)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		addedSlots[i] = newSlot
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.TimetableSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSlots),
+		Data:   addedSlots,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To move a slot to another day, period or room, or change who teaches it
+func EditTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid timetable slot id", http.StatusBadRequest)
+		return
+	}
+
+	var input map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "❌ Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var existingSlot models.TimetableSlot
+	err = scanTimetableSlot(db.QueryRow("SELECT "+timetableSlotColumns+" FROM timetable_slots WHERE id = ?", id), &existingSlot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Timetable slot not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, existingSlot.ID, existingSlot.Version) {
+		return
+	}
+
+	previousSlot := existingSlot
+
+	err = ApplyPatch(&existingSlot, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	status, err := validateTimetableSlot(tx, &existingSlot)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	result, err := tx.Exec(
+		"UPDATE timetable_slots SET term_id = ?, day_of_week = ?, period_id = ?, class_id = ?, teacher_id = ?, subject_id = ?, room_id = ?, version = version + 1 WHERE id = ? AND version = ?",
+		existingSlot.TermID,
+		existingSlot.Day,
+		existingSlot.PeriodID,
+		existingSlot.ClassID,
+		existingSlot.TeacherID,
+		existingSlot.SubjectID,
+		nullableID(existingSlot.RoomID),
+		existingSlot.ID,
+		previousSlot.Version,
+	)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Error updating timetable slot")
+		http.Error(w, "❌ Error updating timetable slot", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		http.Error(w, "❌ Resource has been modified by someone else, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	existingSlot.Version = previousSlot.Version + 1
+
+	err = RecordAudit(tx, r, AuditUpdate, "timetable_slots", existingSlot.ID, previousSlot, existingSlot)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(existingSlot.ID, existingSlot.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingSlot)
+}
+
+func DeleteTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid timetable slot id", http.StatusBadRequest)
+		return
+	}
+
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to connect to database")
+		http.Error(w, "❌ Unable to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var deletedSlot models.TimetableSlot
+	err = scanTimetableSlot(db.QueryRow("SELECT "+timetableSlotColumns+" FROM timetable_slots WHERE id = ?", id), &deletedSlot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "❌ Timetable slot not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.ErrorHandler(err, "❌ Unable to retrieve data")
+		http.Error(w, "❌ Unable to retrieve data", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckIfMatch(w, r, deletedSlot.ID, deletedSlot.Version) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error starting transaction")
+		http.Error(w, "❌ Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM timetable_slots WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorHandler(err, "❌ Unable delete timetable slot")
+		http.Error(w, "❌ Unable delete timetable slot", http.StatusInternalServerError)
+		return
+	}
+
+	err = RecordAudit(tx, r, AuditDelete, "timetable_slots", id, deletedSlot, nil)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error committing transaction")
+		http.Error(w, "❌ Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Timetable slot successfully deleted",
+		ID:     id,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// To load the timetable of a term with the names of everything in it, in weekly order
+func loadTimetable(db *sql.DB, termId int, condition string, args ...interface{}) ([]models.TimetableEntry, error) {
+	rows, err := db.Query(
+		`SELECT s.id, s.term_id, s.day_of_week, s.period_id, s.class_id, s.teacher_id, s.subject_id, COALESCE(s.room_id, 0), s.version,
+			p.name, p.start_time, p.end_time, c.name, CONCAT(t.first_name, ' ', t.last_name), sub.name, COALESCE(rm.name, '')
+		FROM timetable_slots s
+		JOIN periods p ON p.id = s.period_id
+		JOIN classes c ON c.id = s.class_id
+		JOIN teachers t ON t.id = s.teacher_id
+		JOIN subjects sub ON sub.id = s.subject_id
+		LEFT JOIN rooms rm ON rm.id = s.room_id
+		WHERE s.term_id = ?`+condition+`
+		ORDER BY s.day_of_week, p.start_time, c.name`,
+		append([]interface{}{termId}, args...)...,
+	)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "❌ Unable to retrieve timetable")
+	}
+	defer rows.Close()
+
+	entries := make([]models.TimetableEntry, 0)
+	for rows.Next() {
+		var entry models.TimetableEntry
+		err := rows.Scan(
+			&entry.ID, &entry.TermID, &entry.Day, &entry.PeriodID, &entry.ClassID, &entry.TeacherID, &entry.SubjectID, &entry.RoomID, &entry.Version,
+			&entry.Period, &entry.StartTime, &entry.EndTime, &entry.Class, &entry.Teacher, &entry.Subject, &entry.Room,
+		)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "❌ Unable to retrieve timetable")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// To answer with the timetable of one class or teacher for ?term_id=, or the current term
+func writeTimetable(w http.ResponseWriter, r *http.Request, condition string, id int) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := loadTimetable(db, termId, condition, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                  `json:"status"`
+		TermID int                     `json:"term_id"`
+		Count  int                     `json:"count"`
+		Data   []models.TimetableEntry `json:"data"`
+	}{
+		Status: "success",
+		TermID: termId,
+		Count:  len(entries),
+		Data:   entries,
+	}
+
+	WriteJSONWithETag(w, r, response)
+}
+
+// To get the weekly timetable of a class
+func GetClassTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid class id", http.StatusBadRequest)
+		return
+	}
+	writeTimetable(w, r, " AND s.class_id = ?", id)
+}
+
+// To get the weekly timetable of a teacher
+func GetTeacherTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	writeTimetable(w, r, " AND s.teacher_id = ?", id)
+}
+
+// To build a calendar of a teacher's lessons. Each slot becomes a weekly event for the
+// length of the term, skipping the school holidays.
+func teacherTimetableCalendar(db *sql.DB, teacherId, termId int) (ical.Calendar, error) {
+	var calendar ical.Calendar
+
+	var term models.Term
+	err := scanTerm(db.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = ? AND deleted_at IS NULL", termId), &term)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Unable to retrieve term")
+	}
+	termStart, err := time.ParseInLocation(DateLayout, term.StartDate, time.Local)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Invalid term start date")
+	}
+	termEnd, err := time.ParseInLocation(DateLayout, term.EndDate, time.Local)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Invalid term end date")
+	}
+
+	var teacherName string
+	err = db.QueryRow("SELECT CONCAT(first_name, ' ', last_name) FROM teachers WHERE id = ?", teacherId).Scan(&teacherName)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Unable to retrieve teacher")
+	}
+	calendar.Name = teacherName + " - " + term.Name
+
+	holidays := map[string]bool{}
+	rows, err := db.Query("SELECT start_date, end_date FROM holidays WHERE academic_year_id = ?", term.AcademicYearID)
+	if err != nil {
+		return calendar, utils.ErrorHandler(err, "❌ Unable to retrieve holidays")
+	}
+	for rows.Next() {
+		var start, end string
+		if err := rows.Scan(&start, &end); err != nil {
+			rows.Close()
+			return calendar, utils.ErrorHandler(err, "❌ Unable to retrieve holidays")
+		}
+		from, _ := time.Parse(DateLayout, start)
+		to, _ := time.Parse(DateLayout, end)
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			holidays[day.Format(DateLayout)] = true
+		}
+	}
+	rows.Close()
+
+	entries, err := loadTimetable(db, termId, " AND s.teacher_id = ?", teacherId)
+	if err != nil {
+		return calendar, err
+	}
+
+	for _, entry := range entries {
+		startTime, err := time.Parse(TimeLayout, entry.StartTime)
+		if err != nil {
+			return calendar, utils.ErrorHandler(err, "❌ Invalid period start time")
+		}
+		endTime, err := time.Parse(TimeLayout, entry.EndTime)
+		if err != nil {
+			return calendar, utils.ErrorHandler(err, "❌ Invalid period end time")
+		}
+
+		// The first lesson is on the first matching weekday of the term
+		first := termStart
+		for int(first.Weekday()) != entry.Day%7 {
+			first = first.AddDate(0, 0, 1)
+		}
+		if first.After(termEnd) {
+			continue
+		}
+
+		at := func(day time.Time, clock time.Time) time.Time {
+			return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local)
+		}
+
+		event := ical.Event{
+			UID:         fmt.Sprintf("timetable-slot-%d@schoolly", entry.ID),
+			Summary:     entry.Subject + " - " + entry.Class,
+			Description: entry.Period,
+			Location:    entry.Room,
+			Start:       at(first, startTime),
+			End:         at(first, endTime),
+			RRule:       "FREQ=WEEKLY;UNTIL=" + ical.Until(termEnd),
+		}
+		for day := first; !day.After(termEnd); day = day.AddDate(0, 0, 7) {
+			if holidays[day.Format(DateLayout)] {
+				event.ExDates = append(event.ExDates, at(day, startTime))
+			}
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+
+	return calendar, nil
+}
+
+// To answer with a teacher's timetable as an iCalendar file
+func writeTeacherTimetableICS(w http.ResponseWriter, r *http.Request, teacherId int) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Error connecting to DB")
+		http.Error(w, "❌ Error connecting to DB", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	termId, err := termFromRequest(db, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := teacherTimetableCalendar(db, teacherId, termId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="teacher-%d-timetable.ics"`, teacherId))
+	calendar.WriteTo(w)
+}
+
+// To download a teacher's timetable for a term as an .ics file
+func GetTeacherTimetableICSHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+	writeTeacherTimetableICS(w, r, id)
+}
+
+// To get the link calendar apps can subscribe to. Calendar apps can't log in,
+// so the link carries a token that only opens this teacher's timetable.
+func GetTeacherTimetableFeedURLHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		URL    string `json:"url"`
+	}{
+		Status: "success",
+		URL:    fmt.Sprintf("/feeds/teachers/%d/timetable.ics?token=%s", id, utils.FeedToken(teacherTimetableFeed, id)),
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To serve the subscription feed of a teacher's timetable, authenticated by its token instead of a login
+func TeacherTimetableFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "❌ Invalid teacher id", http.StatusBadRequest)
+		return
+	}
+
+	if !utils.VerifyFeedToken(teacherTimetableFeed, id, r.URL.Query().Get("token")) {
+		http.Error(w, "❌ Invalid feed token", http.StatusUnauthorized)
+		return
+	}
+	writeTeacherTimetableICS(w, r, id)
+}
@@ -22,8 +22,8 @@ func Cors(next http.Handler) http.Handler {
 			return
 		}
 
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-COntrol-Expose-Headers", "Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match")
+		w.Header().Set("Access-COntrol-Expose-Headers", "Authorization, ETag")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
 		w.Header().Set("Access-COntrol-Allow-Credentials", "true")
 		w.Header().Set("Access-COntrol-Max-Age", "3600")
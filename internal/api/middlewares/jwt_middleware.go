@@ -52,6 +52,7 @@ func JWTMiddleware(next http.Handler) http.Handler {
 		} else {
 			http.Error(w, "❌ Invalid LoginToken", http.StatusUnauthorized)
 			log.Println("❌ Invalid JWT: ", token)
+			return
 		}
 
 		claims, ok := parsedToken.Claims.(jwt.MapClaims)
@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -39,16 +40,21 @@ func (rl *rateLimiter) resetVisitorCount() {
 // To check the number of times the APi was visited
 func (rl *rateLimiter) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rl.mu.Lock()
-		defer rl.mu.Unlock()
+		// To count a visitor by host, since every new connection comes from a new port
+		visitorIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			visitorIP = r.RemoteAddr
+		}
 
-		visitorIP := r.RemoteAddr
+		rl.mu.Lock()
 		rl.visitors[visitorIP]++
+		count := rl.visitors[visitorIP]
+		rl.mu.Unlock()
 
-		fmt.Printf("Visitor Count from %v is %v\n", visitorIP, rl.visitors[visitorIP])
+		fmt.Printf("Visitor Count from %v is %v\n", visitorIP, count)
 
 		// To check if we have exceeded the rate limit
-		if rl.visitors[visitorIP] > rl.limit {
+		if count > rl.limit {
 			http.Error(w, "❌ Too many requests", http.StatusTooManyRequests)
 			return
 		}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// To tag every request with an id so logs and audit entries can be traced back to it
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-ID")
+		if requestId == "" || len(requestId) > 64 {
+			idBytes := make([]byte, 16)
+			rand.Read(idBytes)
+			requestId = hex.EncodeToString(idBytes)
+		}
+
+		w.Header().Set("X-Request-ID", requestId)
+
+		ctx := context.WithValue(r.Context(), ContextKey("requestId"), requestId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func academicYearsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /academic-years", handlers.GetAcademicYearsHandler)
+	mux.HandleFunc("POST /academic-years", handlers.AddAcademicYearsHandler)
+
+	mux.HandleFunc("GET /academic-years/{id}", handlers.GetOneAcademicYearHandler)
+	mux.HandleFunc("PATCH /academic-years/{id}", handlers.EditAcademicYearHandler)
+	mux.HandleFunc("DELETE /academic-years/{id}", handlers.DeleteOneAcademicYearHandler)
+	mux.HandleFunc("POST /academic-years/{id}/restore", handlers.RestoreAcademicYearHandler)
+
+	mux.HandleFunc("GET /academic-years/{id}/holidays", handlers.GetHolidaysForAnAcademicYear)
+	mux.HandleFunc("POST /academic-years/{id}/holidays", handlers.AddHolidaysHandler)
+	mux.HandleFunc("DELETE /holidays/{id}", handlers.DeleteHolidayHandler)
+
+	mux.HandleFunc("POST /academic-years/{id}/rollover", handlers.RolloverAcademicYearHandler)
+
+	return mux
+}
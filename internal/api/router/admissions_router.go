@@ -0,0 +1,42 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+	mw "github.com/greatdaveo/Schoolly/internal/api/middlewares"
+)
+
+func admissionsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Applicants aren't logged in, so each address gets ADMISSIONS_RATE_LIMIT requests a minute
+	limit := 5
+	if v, err := strconv.Atoi(os.Getenv("ADMISSIONS_RATE_LIMIT")); err == nil && v > 0 {
+		limit = v
+	}
+	rl := mw.NewRateLimiter(limit, time.Minute)
+
+	mux.Handle("POST /admissions/apply", rl.RateLimiterMiddleware(http.HandlerFunc(handlers.SubmitApplicationHandler)))
+	mux.Handle("GET /admissions/apply/{id}", rl.RateLimiterMiddleware(http.HandlerFunc(handlers.GetApplicationReceiptHandler)))
+	mux.Handle("POST /admissions/apply/{id}/documents", rl.RateLimiterMiddleware(http.HandlerFunc(handlers.AddApplicantDocumentsHandler)))
+	mux.Handle("POST /admissions/apply/{id}/respond", rl.RateLimiterMiddleware(http.HandlerFunc(handlers.RespondToOfferHandler)))
+
+	mux.HandleFunc("GET /applications", handlers.GetApplicationsHandler)
+	mux.HandleFunc("GET /applications/{id}", handlers.GetOneApplicationHandler)
+	mux.HandleFunc("PATCH /applications/{id}", handlers.EditApplicationHandler)
+	mux.HandleFunc("DELETE /applications/{id}", handlers.DeleteOneApplicationHandler)
+	mux.HandleFunc("POST /applications/{id}/restore", handlers.RestoreApplicationHandler)
+	mux.HandleFunc("POST /applications/{id}/documents", handlers.AddApplicationDocumentsHandler)
+
+	mux.HandleFunc("POST /applications/{id}/review", handlers.ReviewApplicationHandler)
+	mux.HandleFunc("POST /applications/{id}/offer", handlers.OfferApplicationHandler)
+	mux.HandleFunc("POST /applications/{id}/reject", handlers.RejectApplicationHandler)
+	mux.HandleFunc("POST /applications/{id}/respond", handlers.RecordApplicationResponseHandler)
+	mux.HandleFunc("POST /applications/{id}/convert", handlers.ConvertApplicationHandler)
+
+	return mux
+}
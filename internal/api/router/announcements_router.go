@@ -0,0 +1,28 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func announcementsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /announcements", handlers.GetAnnouncementsHandler)
+	mux.HandleFunc("POST /announcements", handlers.AddAnnouncementsHandler)
+
+	mux.HandleFunc("GET /announcements/{id}", handlers.GetOneAnnouncementHandler)
+	mux.HandleFunc("PATCH /announcements/{id}", handlers.EditAnnouncementHandler)
+	mux.HandleFunc("DELETE /announcements/{id}", handlers.DeleteOneAnnouncementHandler)
+	mux.HandleFunc("POST /announcements/{id}/restore", handlers.RestoreAnnouncementHandler)
+
+	mux.HandleFunc("GET /classes/{id}/announcements", handlers.GetAnnouncementsForAClass)
+
+	mux.HandleFunc("GET /notifications", handlers.GetNotificationsHandler)
+	mux.HandleFunc("GET /notifications/unread-count", handlers.GetUnreadNotificationsCountHandler)
+	mux.HandleFunc("POST /notifications/read-all", handlers.MarkAllNotificationsReadHandler)
+	mux.HandleFunc("POST /notifications/{id}/read", handlers.MarkNotificationReadHandler)
+
+	return mux
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func assignmentsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /assignments", handlers.GetAssignmentsHandler)
+	mux.HandleFunc("POST /assignments", handlers.AddAssignmentsHandler)
+
+	mux.HandleFunc("GET /assignments/{id}", handlers.GetOneAssignmentHandler)
+	mux.HandleFunc("PATCH /assignments/{id}", handlers.EditAssignmentHandler)
+	mux.HandleFunc("DELETE /assignments/{id}", handlers.DeleteOneAssignmentHandler)
+
+	return mux
+}
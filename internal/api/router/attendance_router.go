@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func attendanceRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /attendance", handlers.GetAttendanceHandler)
+	mux.HandleFunc("POST /attendance", handlers.AddAttendanceHandler)
+	mux.HandleFunc("GET /attendance/flags", handlers.GetAbsenceFlagsHandler)
+
+	mux.HandleFunc("PATCH /attendance/{id}", handlers.EditAttendanceHandler)
+	mux.HandleFunc("DELETE /attendance/{id}", handlers.DeleteAttendanceHandler)
+
+	mux.HandleFunc("POST /teachers/{id}/attendance", handlers.SubmitClassAttendanceHandler)
+	mux.HandleFunc("GET /students/{id}/attendance/summary", handlers.GetStudentAttendanceSummary)
+	mux.HandleFunc("GET /classes/{id}/attendance/summary", handlers.GetClassAttendanceSummary)
+
+	return mux
+}
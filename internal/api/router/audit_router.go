@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func auditRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /audit", handlers.GetAuditLogsHandler)
+
+	return mux
+}
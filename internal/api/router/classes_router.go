@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func classesRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /classes", handlers.GetClassesHandler)
+	mux.HandleFunc("POST /classes", handlers.AddClassesHandler)
+
+	mux.HandleFunc("GET /classes/{id}", handlers.GetOneClassHandler)
+	mux.HandleFunc("PATCH /classes/{id}", handlers.EditClassHandler)
+	mux.HandleFunc("DELETE /classes/{id}", handlers.DeleteOneClassHandler)
+	mux.HandleFunc("POST /classes/{id}/restore", handlers.RestoreClassHandler)
+
+	mux.HandleFunc("GET /classes/{id}/students", handlers.GetStudentsForAClass)
+	mux.HandleFunc("GET /classes/{id}/teachers", handlers.GetTeachersForAClass)
+
+	return mux
+}
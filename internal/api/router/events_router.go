@@ -0,0 +1,38 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func eventsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /events", handlers.GetEventsHandler)
+	mux.HandleFunc("POST /events", handlers.AddEventsHandler)
+	mux.HandleFunc("GET /events/calendar", handlers.GetEventCalendarHandler)
+	mux.HandleFunc("GET /events/export.ics", handlers.GetEventsICSHandler)
+	mux.HandleFunc("GET /events/feed-url", handlers.GetEventsFeedURLHandler)
+
+	mux.HandleFunc("GET /events/{id}", handlers.GetOneEventHandler)
+	mux.HandleFunc("PATCH /events/{id}", handlers.EditEventHandler)
+	mux.HandleFunc("DELETE /events/{id}", handlers.DeleteOneEventHandler)
+	mux.HandleFunc("POST /events/{id}/restore", handlers.RestoreEventHandler)
+
+	mux.HandleFunc("GET /events/{id}/rsvps", handlers.GetRSVPsHandler)
+	mux.HandleFunc("PUT /events/{id}/rsvps", handlers.RespondToEventHandler)
+	mux.HandleFunc("DELETE /events/{id}/rsvps/{rsvpId}", handlers.DeleteRSVPHandler)
+
+	mux.HandleFunc("GET /events/{id}/slots", handlers.GetEventSlotsHandler)
+	mux.HandleFunc("POST /events/{id}/slots", handlers.GenerateEventSlotsHandler)
+	mux.HandleFunc("DELETE /events/{id}/slots/{slotId}", handlers.DeleteEventSlotHandler)
+	mux.HandleFunc("POST /events/{id}/slots/{slotId}/book", handlers.BookEventSlotHandler)
+	mux.HandleFunc("DELETE /events/{id}/slots/{slotId}/booking", handlers.CancelEventSlotBookingHandler)
+	mux.HandleFunc("GET /guardians/{id}/slots", handlers.GetEventSlotsForAGuardian)
+
+	// Excluded from the JWT middleware, the token in the URL is checked instead
+	mux.HandleFunc("GET /feeds/events.ics", handlers.SchoolEventsFeedHandler)
+
+	return mux
+}
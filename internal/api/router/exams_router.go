@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func examsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /exams", handlers.GetExamsHandler)
+	mux.HandleFunc("POST /exams", handlers.AddExamsHandler)
+	mux.HandleFunc("GET /exams/clashes", handlers.GetExamClashesHandler)
+	mux.HandleFunc("POST /exams/seating", handlers.GenerateExamSeatingHandler)
+
+	mux.HandleFunc("GET /exams/{id}", handlers.GetOneExamHandler)
+	mux.HandleFunc("PATCH /exams/{id}", handlers.EditExamHandler)
+	mux.HandleFunc("DELETE /exams/{id}", handlers.DeleteOneExamHandler)
+	mux.HandleFunc("POST /exams/{id}/restore", handlers.RestoreExamHandler)
+	mux.HandleFunc("GET /exams/{id}/seating", handlers.GetExamSeatingHandler)
+	mux.HandleFunc("GET /exams/{id}/seating-chart", handlers.GetExamSeatingChartHandler)
+
+	mux.HandleFunc("GET /students/{id}/exams", handlers.GetExamsForAStudent)
+
+	return mux
+}
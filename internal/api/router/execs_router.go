@@ -16,6 +16,7 @@ func execRouter() *http.ServeMux {
 	mux.HandleFunc("GET /execs/{id}", handlers.GetOneExecHandler)
 	mux.HandleFunc("PATCH /execs/{id}", handlers.EditExecSingleDataHandler)
 	mux.HandleFunc("DELETE /execs/{id}", handlers.DeleteOneExecHandler)
+	mux.HandleFunc("POST /execs/{id}/restore", handlers.RestoreExecHandler)
 	mux.HandleFunc("POST /execs/{id}/update-password", handlers.UpdatePassword)
 
 	mux.HandleFunc("POST /execs/login", handlers.LoginHandler)
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func feesRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /fee-schedules", handlers.GetFeeSchedulesHandler)
+	mux.HandleFunc("POST /fee-schedules", handlers.AddFeeSchedulesHandler)
+	mux.HandleFunc("PATCH /fee-schedules/{id}", handlers.EditFeeScheduleHandler)
+	mux.HandleFunc("DELETE /fee-schedules/{id}", handlers.DeleteOneFeeScheduleHandler)
+	mux.HandleFunc("POST /fee-schedules/{id}/restore", handlers.RestoreFeeScheduleHandler)
+
+	mux.HandleFunc("GET /invoices", handlers.GetInvoicesHandler)
+	mux.HandleFunc("POST /invoices", handlers.AddInvoicesHandler)
+	mux.HandleFunc("POST /invoices/generate", handlers.GenerateInvoicesHandler)
+	mux.HandleFunc("GET /invoices/{id}", handlers.GetOneInvoiceHandler)
+	mux.HandleFunc("POST /invoices/{id}/void", handlers.VoidInvoiceHandler)
+	mux.HandleFunc("POST /invoices/{id}/payments", handlers.RecordPaymentHandler)
+
+	mux.HandleFunc("GET /payments", handlers.GetPaymentsHandler)
+	mux.HandleFunc("POST /payments/{id}/refund", handlers.RefundPaymentHandler)
+	mux.HandleFunc("GET /payments/{id}/receipt", handlers.GetPaymentReceiptHandler)
+
+	mux.HandleFunc("GET /students/{id}/balance", handlers.GetStudentBalanceHandler)
+
+	return mux
+}
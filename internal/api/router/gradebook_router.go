@@ -0,0 +1,30 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func gradebookRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /assessments", handlers.GetAssessmentsHandler)
+	mux.HandleFunc("POST /assessments", handlers.AddAssessmentsHandler)
+
+	mux.HandleFunc("GET /assessments/{id}", handlers.GetOneAssessmentHandler)
+	mux.HandleFunc("PATCH /assessments/{id}", handlers.EditAssessmentHandler)
+	mux.HandleFunc("DELETE /assessments/{id}", handlers.DeleteOneAssessmentHandler)
+	mux.HandleFunc("POST /assessments/{id}/restore", handlers.RestoreAssessmentHandler)
+
+	mux.HandleFunc("GET /assessments/{id}/scores", handlers.GetScoresForAnAssessment)
+	mux.HandleFunc("PUT /assessments/{id}/scores", handlers.SaveScoresHandler)
+
+	mux.HandleFunc("GET /grading-scale", handlers.GetGradingScaleHandler)
+	mux.HandleFunc("PUT /grading-scale", handlers.UpdateGradingScaleHandler)
+
+	mux.HandleFunc("GET /students/{id}/grades", handlers.GetStudentGradesHandler)
+	mux.HandleFunc("GET /classes/{id}/grades", handlers.GetClassGradesHandler)
+
+	return mux
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func guardiansRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /guardians", handlers.GetGuardiansHandler)
+	mux.HandleFunc("POST /guardians", handlers.AddGuardiansHandler)
+
+	mux.HandleFunc("GET /guardians/{id}", handlers.GetOneGuardianHandler)
+	mux.HandleFunc("PATCH /guardians/{id}", handlers.EditGuardianHandler)
+	mux.HandleFunc("DELETE /guardians/{id}", handlers.DeleteOneGuardianHandler)
+	mux.HandleFunc("POST /guardians/{id}/restore", handlers.RestoreGuardianHandler)
+	mux.HandleFunc("GET /guardians/{id}/students", handlers.GetStudentsForAGuardian)
+
+	mux.HandleFunc("GET /students/{id}/guardians", handlers.GetGuardiansForAStudent)
+	mux.HandleFunc("PUT /students/{id}/guardians/{guardian_id}", handlers.LinkGuardianHandler)
+	mux.HandleFunc("DELETE /students/{id}/guardians/{guardian_id}", handlers.UnlinkGuardianHandler)
+
+	return mux
+}
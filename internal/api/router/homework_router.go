@@ -0,0 +1,30 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func homeworkRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /homework", handlers.GetHomeworkHandler)
+	mux.HandleFunc("POST /homework", handlers.AddHomeworkHandler)
+
+	mux.HandleFunc("GET /homework/{id}", handlers.GetOneHomeworkHandler)
+	mux.HandleFunc("PATCH /homework/{id}", handlers.EditHomeworkHandler)
+	mux.HandleFunc("DELETE /homework/{id}", handlers.DeleteOneHomeworkHandler)
+	mux.HandleFunc("POST /homework/{id}/attachments", handlers.AddHomeworkAttachmentsHandler)
+
+	mux.HandleFunc("GET /homework/{id}/submissions", handlers.GetSubmissionsForHomework)
+	mux.HandleFunc("POST /homework/{id}/submissions", handlers.SubmitHomeworkHandler)
+	mux.HandleFunc("PUT /homework/{id}/submissions/{student_id}", handlers.GradeSubmissionHandler)
+
+	mux.HandleFunc("GET /students/{id}/homework", handlers.GetHomeworkForAStudent)
+
+	mux.HandleFunc("GET /attachments/{id}", handlers.GetAttachmentHandler)
+	mux.HandleFunc("DELETE /attachments/{id}", handlers.DeleteAttachmentHandler)
+
+	return mux
+}
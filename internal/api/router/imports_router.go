@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func importsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /imports", handlers.GetImportsHandler)
+	mux.HandleFunc("GET /imports/{id}/errors", handlers.GetImportErrorsHandler)
+
+	return mux
+}
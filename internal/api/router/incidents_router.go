@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func incidentsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /incidents", handlers.GetIncidentsHandler)
+	mux.HandleFunc("POST /incidents", handlers.AddIncidentsHandler)
+
+	mux.HandleFunc("GET /incidents/{id}", handlers.GetOneIncidentHandler)
+	mux.HandleFunc("PATCH /incidents/{id}", handlers.EditIncidentHandler)
+	mux.HandleFunc("DELETE /incidents/{id}", handlers.DeleteOneIncidentHandler)
+	mux.HandleFunc("POST /incidents/{id}/restore", handlers.RestoreIncidentHandler)
+
+	mux.HandleFunc("GET /students/{id}/incidents", handlers.GetIncidentsForAStudent)
+	mux.HandleFunc("GET /classes/{id}/incidents/summary", handlers.GetClassIncidentSummary)
+
+	return mux
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func leaveRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /leave-requests", handlers.GetLeaveRequestsHandler)
+	mux.HandleFunc("POST /leave-requests", handlers.AddLeaveRequestsHandler)
+	mux.HandleFunc("GET /leave-requests/{id}", handlers.GetOneLeaveRequestHandler)
+	mux.HandleFunc("PATCH /leave-requests/{id}", handlers.EditLeaveRequestHandler)
+	mux.HandleFunc("POST /leave-requests/{id}/approve", handlers.ApproveLeaveRequestHandler)
+	mux.HandleFunc("POST /leave-requests/{id}/reject", handlers.RejectLeaveRequestHandler)
+	mux.HandleFunc("POST /leave-requests/{id}/cancel", handlers.CancelLeaveRequestHandler)
+
+	mux.HandleFunc("GET /leave-requests/{id}/cover", handlers.GetCoverPlanHandler)
+	mux.HandleFunc("POST /leave-requests/{id}/cover", handlers.AssignCoverHandler)
+	mux.HandleFunc("GET /cover-assignments", handlers.GetCoverAssignmentsHandler)
+	mux.HandleFunc("DELETE /cover-assignments/{id}", handlers.DeleteCoverAssignmentHandler)
+
+	mux.HandleFunc("GET /teachers/{id}/leave", handlers.GetLeaveRequestsForATeacher)
+	mux.HandleFunc("GET /teachers/{id}/cover", handlers.GetCoverForATeacher)
+
+	return mux
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func libraryRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /library/books", handlers.GetBooksHandler)
+	mux.HandleFunc("POST /library/books", handlers.AddBooksHandler)
+
+	mux.HandleFunc("GET /library/books/{id}", handlers.GetOneBookHandler)
+	mux.HandleFunc("PATCH /library/books/{id}", handlers.EditBookHandler)
+	mux.HandleFunc("DELETE /library/books/{id}", handlers.DeleteOneBookHandler)
+	mux.HandleFunc("POST /library/books/{id}/restore", handlers.RestoreBookHandler)
+	mux.HandleFunc("GET /library/books/{id}/holds", handlers.GetHoldsForABook)
+
+	mux.HandleFunc("GET /library/loans", handlers.GetLoansHandler)
+	mux.HandleFunc("POST /library/loans", handlers.CheckOutBookHandler)
+	mux.HandleFunc("GET /library/loans/overdue", handlers.GetOverdueLoansHandler)
+	mux.HandleFunc("POST /library/loans/{id}/return", handlers.CheckInBookHandler)
+
+	mux.HandleFunc("POST /library/holds", handlers.PlaceHoldHandler)
+	mux.HandleFunc("DELETE /library/holds/{id}", handlers.CancelHoldHandler)
+
+	mux.HandleFunc("GET /students/{id}/loans", handlers.GetLoansForAStudent)
+	mux.HandleFunc("GET /teachers/{id}/loans", handlers.GetLoansForATeacher)
+
+	return mux
+}
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func medicalRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /students/{id}/medical", handlers.GetMedicalRecordHandler)
+	mux.HandleFunc("PUT /students/{id}/medical", handlers.SaveMedicalRecordHandler)
+	mux.HandleFunc("PATCH /students/{id}/medical", handlers.SaveMedicalRecordHandler)
+	mux.HandleFunc("GET /students/{id}/medical/access", handlers.GetMedicalRecordAccessHandler)
+
+	return mux
+}
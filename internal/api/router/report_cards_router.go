@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func reportCardsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /students/{id}/report-cards/{term}", handlers.GetReportCardHandler)
+	mux.HandleFunc("GET /students/{id}/report-cards/{term}/comments", handlers.GetReportCardCommentsHandler)
+	mux.HandleFunc("PUT /students/{id}/report-cards/{term}/comments", handlers.SaveReportCardCommentsHandler)
+
+	mux.HandleFunc("GET /classes/{id}/report-cards/{term}", handlers.GetClassReportCardsHandler)
+
+	return mux
+}
@@ -8,7 +8,51 @@ func MainRouter() *http.ServeMux {
 	eRouter := execRouter()
 	tRouter := teachersRouter()
 	sRouter := studentsRouter()
+	aRouter := auditRouter()
+	cRouter := classesRouter()
+	taRouter := assignmentsRouter()
+	subRouter := subjectsRouter()
+	ayRouter := academicYearsRouter()
+	termRouter := termsRouter()
+	atRouter := attendanceRouter()
+	gbRouter := gradebookRouter()
+	rcRouter := reportCardsRouter()
+	gRouter := guardiansRouter()
+	ttRouter := timetableRouter()
+	hwRouter := homeworkRouter()
+	fRouter := feesRouter()
+	anRouter := announcementsRouter()
+	inRouter := incidentsRouter()
+	exRouter := examsRouter()
+	liRouter := libraryRouter()
+	mdRouter := medicalRouter()
+	evRouter := eventsRouter()
+	lvRouter := leaveRouter()
+	adRouter := admissionsRouter()
+	imRouter := importsRouter()
 
+	adRouter.Handle("/", imRouter)
+	lvRouter.Handle("/", adRouter)
+	evRouter.Handle("/", lvRouter)
+	mdRouter.Handle("/", evRouter)
+	liRouter.Handle("/", mdRouter)
+	exRouter.Handle("/", liRouter)
+	inRouter.Handle("/", exRouter)
+	anRouter.Handle("/", inRouter)
+	fRouter.Handle("/", anRouter)
+	hwRouter.Handle("/", fRouter)
+	ttRouter.Handle("/", hwRouter)
+	gRouter.Handle("/", ttRouter)
+	rcRouter.Handle("/", gRouter)
+	gbRouter.Handle("/", rcRouter)
+	atRouter.Handle("/", gbRouter)
+	termRouter.Handle("/", atRouter)
+	ayRouter.Handle("/", termRouter)
+	subRouter.Handle("/", ayRouter)
+	taRouter.Handle("/", subRouter)
+	cRouter.Handle("/", taRouter)
+	aRouter.Handle("/", cRouter)
+	eRouter.Handle("/", aRouter)
 	sRouter.Handle("/", eRouter)
 	tRouter.Handle("/", sRouter)
 	return tRouter
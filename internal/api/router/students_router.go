@@ -13,11 +13,17 @@ func studentsRouter() *http.ServeMux {
 	mux.HandleFunc("POST /students", handlers.AddStudentHandler)
 	mux.HandleFunc("PATCH /students", handlers.EditMultipleStudentsHandler)
 	mux.HandleFunc("DELETE /students", handlers.DeleteStudentsHandler)
+	mux.HandleFunc("POST /students/import", handlers.ImportStudentsHandler)
 
 	mux.HandleFunc("PUT /students/{id}", handlers.EditStudentHandler)
 	mux.HandleFunc("GET /students/{id}", handlers.GetOneStudentsHandler)
 	mux.HandleFunc("PATCH /students/{id}", handlers.EditStudentSingleDataHandler)
 	mux.HandleFunc("DELETE /students/{id}", handlers.DeleteOneStudentHandler)
+	mux.HandleFunc("POST /students/{id}/restore", handlers.RestoreStudentHandler)
+
+	mux.HandleFunc("GET /students/{id}/enrollments", handlers.GetEnrollmentsForAStudent)
+	mux.HandleFunc("GET /students/{id}/history", handlers.GetStudentHistoryHandler)
+	mux.HandleFunc("POST /students/{id}/status", handlers.ChangeStudentStatusHandler)
 
 	return mux
 }
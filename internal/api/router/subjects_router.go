@@ -0,0 +1,23 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func subjectsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /subjects", handlers.GetSubjectsHandler)
+	mux.HandleFunc("POST /subjects", handlers.AddSubjectsHandler)
+
+	mux.HandleFunc("GET /subjects/{id}", handlers.GetOneSubjectHandler)
+	mux.HandleFunc("PATCH /subjects/{id}", handlers.EditSubjectHandler)
+	mux.HandleFunc("DELETE /subjects/{id}", handlers.DeleteOneSubjectHandler)
+	mux.HandleFunc("POST /subjects/{id}/restore", handlers.RestoreSubjectHandler)
+
+	mux.HandleFunc("GET /subjects/{id}/teachers", handlers.GetTeachersForASubject)
+
+	return mux
+}
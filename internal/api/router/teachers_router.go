@@ -13,14 +13,17 @@ func teachersRouter() *http.ServeMux {
 	mux.HandleFunc("POST /teachers", handlers.AddTeacherHandler)
 	mux.HandleFunc("PATCH /teachers", handlers.EditMultipleTeachersHandler)
 	mux.HandleFunc("DELETE /teachers", handlers.DeleteTeachersHandler)
+	mux.HandleFunc("POST /teachers/import", handlers.ImportTeachersHandler)
 
 	mux.HandleFunc("PUT /teachers/{id}", handlers.EditTeacherHandler)
 	mux.HandleFunc("GET /teachers/{id}", handlers.GetOneTeacherHandler)
 	mux.HandleFunc("PATCH /teachers/{id}", handlers.EditTeacherSingleDataHandler)
 	mux.HandleFunc("DELETE /teachers/{id}", handlers.DeleteOneTeacherHandler)
+	mux.HandleFunc("POST /teachers/{id}/restore", handlers.RestoreTeacherHandler)
 
 	mux.HandleFunc("GET /teachers/{id}/students", handlers.GetStudentsForATeacher)
 	mux.HandleFunc("GET /teachers/{id}/studentcount", handlers.CountStudentsForATeacher)
+	mux.HandleFunc("GET /teachers/{id}/assignments", handlers.GetAssignmentsForATeacher)
 
 	return mux
 }
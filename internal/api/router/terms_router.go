@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func termsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /terms", handlers.GetTermsHandler)
+	mux.HandleFunc("POST /terms", handlers.AddTermsHandler)
+	mux.HandleFunc("GET /terms/current", handlers.GetCurrentTermHandler)
+
+	mux.HandleFunc("GET /terms/{id}", handlers.GetOneTermHandler)
+	mux.HandleFunc("PATCH /terms/{id}", handlers.EditTermHandler)
+	mux.HandleFunc("DELETE /terms/{id}", handlers.DeleteOneTermHandler)
+	mux.HandleFunc("POST /terms/{id}/restore", handlers.RestoreTermHandler)
+
+	mux.HandleFunc("GET /terms/{id}/enrollments", handlers.GetEnrollmentsForATerm)
+
+	return mux
+}
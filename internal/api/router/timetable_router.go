@@ -0,0 +1,36 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/greatdaveo/Schoolly/internal/api/handlers"
+)
+
+func timetableRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /periods", handlers.GetPeriodsHandler)
+	mux.HandleFunc("POST /periods", handlers.AddPeriodsHandler)
+	mux.HandleFunc("PATCH /periods/{id}", handlers.EditPeriodHandler)
+	mux.HandleFunc("DELETE /periods/{id}", handlers.DeletePeriodHandler)
+
+	mux.HandleFunc("GET /rooms", handlers.GetRoomsHandler)
+	mux.HandleFunc("POST /rooms", handlers.AddRoomsHandler)
+	mux.HandleFunc("PATCH /rooms/{id}", handlers.EditRoomHandler)
+	mux.HandleFunc("DELETE /rooms/{id}", handlers.DeleteRoomHandler)
+
+	mux.HandleFunc("GET /timetable-slots", handlers.GetTimetableSlotsHandler)
+	mux.HandleFunc("POST /timetable-slots", handlers.AddTimetableSlotsHandler)
+	mux.HandleFunc("PATCH /timetable-slots/{id}", handlers.EditTimetableSlotHandler)
+	mux.HandleFunc("DELETE /timetable-slots/{id}", handlers.DeleteTimetableSlotHandler)
+
+	mux.HandleFunc("GET /classes/{id}/timetable", handlers.GetClassTimetableHandler)
+	mux.HandleFunc("GET /teachers/{id}/timetable", handlers.GetTeacherTimetableHandler)
+	mux.HandleFunc("GET /teachers/{id}/timetable.ics", handlers.GetTeacherTimetableICSHandler)
+	mux.HandleFunc("GET /teachers/{id}/timetable/feed", handlers.GetTeacherTimetableFeedURLHandler)
+
+	// Excluded from the JWT middleware, the token in the URL is checked instead
+	mux.HandleFunc("GET /feeds/teachers/{id}/timetable.ics", handlers.TeacherTimetableFeedHandler)
+
+	return mux
+}
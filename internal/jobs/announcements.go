@@ -0,0 +1,235 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// Email addresses an announcement reaches, by audience. Class announcements go to the
+// teachers of the class and the guardians of its students.
+var announcementRecipients = map[string]string{
+	"school": `SELECT email FROM execs WHERE deleted_at IS NULL AND inactive_status = FALSE
+		UNION SELECT email FROM teachers WHERE deleted_at IS NULL
+		UNION SELECT g.email FROM guardians g
+			JOIN student_guardians sg ON sg.guardian_id = g.id
+			JOIN students s ON s.id = sg.student_id AND s.deleted_at IS NULL
+			WHERE g.deleted_at IS NULL`,
+	"class": `SELECT email FROM teachers WHERE deleted_at IS NULL
+			AND (class_id = ? OR id IN (SELECT teacher_id FROM teaching_assignments WHERE class_id = ?))
+		UNION SELECT g.email FROM guardians g
+			JOIN student_guardians sg ON sg.guardian_id = g.id
+			JOIN students s ON s.id = sg.student_id AND s.deleted_at IS NULL
+			WHERE g.deleted_at IS NULL AND s.class_id = ?`,
+	"role": `SELECT email FROM execs WHERE deleted_at IS NULL AND inactive_status = FALSE AND role = ?`,
+}
+
+type dueAnnouncement struct {
+	id       int
+	title    string
+	body     string
+	audience string
+	classId  int
+	role     string
+}
+
+type delivery struct {
+	id             int
+	email          string
+	announcementId int
+	title          string
+	body           string
+	attempts       int
+}
+
+// StartAnnouncementEmailJob emails announcements once their publish time has come, trying
+// each recipient up to maxAttempts times
+func StartAnnouncementEmailJob(interval time.Duration, maxAttempts int) {
+	go func() {
+		for {
+			SendAnnouncementEmails(maxAttempts)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// SendAnnouncementEmails queues a delivery for every recipient of the published announcements
+// that asked for email, then sends the deliveries that are pending or failed fewer than maxAttempts times
+func SendAnnouncementEmails(maxAttempts int) {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Announcement email job could not connect to DB")
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id, title, body, audience, COALESCE(class_id, 0), role FROM announcements
+		WHERE send_email = TRUE AND emailed_at IS NULL AND deleted_at IS NULL
+		AND publish_at <= NOW() AND (expires_at IS NULL OR expires_at > NOW())`)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Announcement email job failed")
+		return
+	}
+	var due []dueAnnouncement
+	for rows.Next() {
+		var announcement dueAnnouncement
+		err := rows.Scan(&announcement.id, &announcement.title, &announcement.body, &announcement.audience, &announcement.classId, &announcement.role)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Announcement email job failed")
+			continue
+		}
+		due = append(due, announcement)
+	}
+	rows.Close()
+
+	for _, announcement := range due {
+		err := queueDeliveries(db, announcement)
+		if err != nil {
+			utils.ErrorHandler(err, fmt.Sprintf("❌ Unable to queue emails of announcement %d, it will be retried", announcement.id))
+		}
+	}
+
+	sendDeliveries(db, maxAttempts)
+}
+
+// To claim an announcement and write a pending delivery for each of its recipients. Both happen
+// in one transaction, so an announcement is either fully queued or left to the next run, and a
+// second server running the job can't claim it too.
+func queueDeliveries(db *sql.DB, announcement dueAnnouncement) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE announcements SET emailed_at = NOW() WHERE id = ? AND emailed_at IS NULL", announcement.id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return tx.Rollback()
+	}
+
+	var args []interface{}
+	switch announcement.audience {
+	case "class":
+		args = []interface{}{announcement.classId, announcement.classId, announcement.classId}
+	case "role":
+		args = []interface{}{announcement.role}
+	}
+
+	recipients, err := emailAddresses(tx, announcement.audience, args)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, email := range recipients {
+		_, err = tx.Exec("INSERT IGNORE INTO announcement_deliveries (announcement_id, email) VALUES (?, ?)", announcement.id, email)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// To send the deliveries that are due, recording the outcome of each recipient
+func sendDeliveries(db *sql.DB, maxAttempts int) {
+	rows, err := db.Query(`SELECT d.id, d.email, d.announcement_id, a.title, a.body, d.attempts FROM announcement_deliveries d
+		JOIN announcements a ON a.id = d.announcement_id
+		WHERE a.deleted_at IS NULL AND (a.expires_at IS NULL OR a.expires_at > NOW())
+		AND (d.status = 'pending' OR (d.status = 'failed' AND d.attempts < ?))
+		ORDER BY d.announcement_id, d.id`, maxAttempts)
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Announcement email job failed")
+		return
+	}
+	var deliveries []delivery
+	for rows.Next() {
+		var d delivery
+		err := rows.Scan(&d.id, &d.email, &d.announcementId, &d.title, &d.body, &d.attempts)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Announcement email job failed")
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+
+	// To claim each delivery so a second server running the job doesn't send it too
+	claimed := make(map[int][]delivery)
+	var order []int
+	for _, d := range deliveries {
+		result, err := db.Exec("UPDATE announcement_deliveries SET status = 'sending', attempts = attempts + 1 WHERE id = ? AND attempts = ? AND status IN ('pending', 'failed')", d.id, d.attempts)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Announcement email job failed")
+			continue
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		d.attempts++
+		if _, ok := claimed[d.announcementId]; !ok {
+			order = append(order, d.announcementId)
+		}
+		claimed[d.announcementId] = append(claimed[d.announcementId], d)
+	}
+
+	for _, announcementId := range order {
+		batch := claimed[announcementId]
+		recipients := make([]string, len(batch))
+		for i, d := range batch {
+			recipients[i] = d.email
+		}
+
+		failed := utils.SendMailEach(recipients, batch[0].title, batch[0].body)
+		for _, d := range batch {
+			if sendErr, ok := failed[d.email]; ok {
+				_, err = db.Exec("UPDATE announcement_deliveries SET status = 'failed', last_error = ? WHERE id = ?", truncate(sendErr.Error(), 500), d.id)
+				if d.attempts >= maxAttempts {
+					utils.ErrorHandler(sendErr, fmt.Sprintf("❌ Giving up emailing announcement %d to %s after %d attempts", announcementId, d.email, d.attempts))
+				}
+			} else {
+				_, err = db.Exec("UPDATE announcement_deliveries SET status = 'sent', last_error = '', sent_at = NOW() WHERE id = ?", d.id)
+			}
+			if err != nil {
+				utils.ErrorHandler(err, fmt.Sprintf("❌ Unable to record the email of announcement %d to %s", announcementId, d.email))
+			}
+		}
+
+		fmt.Printf("📣 Emailed announcement %d to %d of %d recipients\n", announcementId, len(batch)-len(failed), len(batch))
+	}
+}
+
+// To cut an error message down to the size of its column
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return s
+}
+
+// To collect the distinct, non empty email addresses of an audience
+func emailAddresses(tx *sql.Tx, audience string, args []interface{}) ([]string, error) {
+	rows, err := tx.Query(announcementRecipients[audience], args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		if email != "" {
+			recipients = append(recipients, email)
+		}
+	}
+	return recipients, rows.Err()
+}
@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/greatdaveo/Schoolly/internal/models/repositories/sqlconnect"
+	"github.com/greatdaveo/Schoolly/pkg/utils"
+)
+
+// Tables whose soft deleted rows are permanently removed once they are older than the retention period
+var purgeTables = []string{"students", "teachers", "execs", "classes", "subjects", "assessments", "terms", "academic_years", "guardians", "fee_schedules", "announcements", "incidents", "exams", "library_books", "events", "applications", "teaching_assignments"}
+
+// StartPurgeJob runs the purge every interval in the background
+func StartPurgeJob(retention, interval time.Duration) {
+	go func() {
+		for {
+			PurgeSoftDeleted(retention)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// Extra conditions on the rows of a table that are due to be purged. Anonymised students
+// stay for good since their invoices and payments point at them.
+var purgeConditions = map[string]string{
+	"students": " AND anonymised_at IS NULL",
+}
+
+// Tables whose rows are anonymised instead of purged when they have a financial history
+var purgeKeepers = map[string]func(tx *sql.Tx, id int) (bool, error){
+	"students": anonymiseStudentWithFinances,
+}
+
+// PurgeResult counts what a purge did to one table. Skipped rows are still referenced
+// by records that must be kept, they are tried again on the next run.
+type PurgeResult struct {
+	Table      string
+	Purged     int
+	Anonymised int
+	Skipped    []int
+}
+
+// PurgeSoftDeleted hard deletes rows that were soft deleted more than retention ago.
+// Rows are deleted one at a time so a row that a foreign key still holds on to only
+// skips itself instead of blocking the rest of its table.
+func PurgeSoftDeleted(retention time.Duration) []PurgeResult {
+	db, err := sqlconnect.ConnectDB()
+	if err != nil {
+		utils.ErrorHandler(err, "❌ Purge job could not connect to DB")
+		return nil
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-retention).Format("2006-01-02 15:04:05")
+
+	var results []PurgeResult
+	for _, table := range purgeTables {
+		ids, err := expiredIDs(db, table, cutoff)
+		if err != nil {
+			utils.ErrorHandler(err, "❌ Purge job failed for "+table)
+			continue
+		}
+
+		result := PurgeResult{Table: table}
+		for _, id := range ids {
+			if keep, ok := purgeKeepers[table]; ok {
+				anonymised, err := keepRow(db, keep, id)
+				if err != nil {
+					utils.ErrorHandler(err, fmt.Sprintf("❌ Purge job failed for %s %d", table, id))
+					result.Skipped = append(result.Skipped, id)
+					continue
+				}
+				if anonymised {
+					result.Anonymised++
+					continue
+				}
+			}
+
+			_, err := db.Exec("DELETE FROM "+table+" WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", id, cutoff)
+			if err != nil {
+				if !utils.IsForeignKeyViolation(err) {
+					utils.ErrorHandler(err, fmt.Sprintf("❌ Purge job failed for %s %d", table, id))
+				}
+				result.Skipped = append(result.Skipped, id)
+				continue
+			}
+			result.Purged++
+		}
+
+		if result.Purged > 0 || result.Anonymised > 0 || len(result.Skipped) > 0 {
+			fmt.Printf("🧹 Purged %d soft deleted rows from %s, anonymised %d, skipped %d\n", result.Purged, table, result.Anonymised, len(result.Skipped))
+		}
+		if len(result.Skipped) > 0 {
+			fmt.Printf("🧹 Still referenced in %s: %v\n", table, result.Skipped)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// To list the ids of the rows of a table that are due to be purged
+func expiredIDs(db *sql.DB, table, cutoff string) ([]int, error) {
+	rows, err := db.Query("SELECT id FROM "+table+" WHERE deleted_at IS NOT NULL AND deleted_at < ?"+purgeConditions[table]+" ORDER BY id", cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// To run a keeper in its own transaction, returning whether it kept the row
+func keepRow(db *sql.DB, keep func(tx *sql.Tx, id int) (bool, error), id int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	kept, err := keep(tx, id)
+	if err != nil || !kept {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// To clear the personal details of a student who has invoices or payments. The row stays
+// so the financial records keep pointing at it, the guardians and medical records go.
+func anonymiseStudentWithFinances(tx *sql.Tx, id int) (bool, error) {
+	var hasFinances bool
+	err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM invoices WHERE student_id = ?) OR EXISTS (SELECT 1 FROM payments WHERE student_id = ?)",
+		id, id,
+	).Scan(&hasFinances)
+	if err != nil || !hasFinances {
+		return false, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE students SET first_name = 'Former', last_name = 'Student', email = CONCAT('student-', id, '@anonymised.invalid'), class = '', class_id = NULL, anonymised_at = NOW(), version = version + 1 WHERE id = ?",
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range []string{"student_guardians", "medical_records"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE student_id = ?", id); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,9 @@
+package models
+
+type AcademicYear struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty"`
+	StartDate string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+type Announcement struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Title     string `json:"title,omitempty" db:"title,omitempty"`
+	Body      string `json:"body,omitempty" db:"body,omitempty"`
+	Audience  string `json:"audience,omitempty" db:"audience,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Role      string `json:"role,omitempty" db:"role,omitempty"`
+	AuthorID  int    `json:"author_id,omitempty" db:"author_id,omitempty"`
+	PublishAt string `json:"publish_at,omitempty" db:"publish_at,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty" db:"expires_at,omitempty"`
+	SendEmail bool   `json:"send_email,omitempty" db:"send_email,omitempty"`
+	EmailedAt string `json:"emailed_at,omitempty" db:"emailed_at,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type Notification struct {
+	ID             int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID         int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Kind           string `json:"kind,omitempty" db:"kind,omitempty"`
+	Title          string `json:"title,omitempty" db:"title,omitempty"`
+	Body           string `json:"body,omitempty" db:"body,omitempty"`
+	AnnouncementID int    `json:"announcement_id,omitempty" db:"announcement_id,omitempty"`
+	CreatedAt      string `json:"created_at,omitempty" db:"visible_from,omitempty"`
+	Read           bool   `json:"read"`
+	ReadAt         string `json:"read_at,omitempty" db:"read_at,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// Application is a prospective student's request for a place, with the guardian who applied.
+// It moves from submitted to under_review to offered, and then accepted or declined.
+type Application struct {
+	ID                int          `json:"id,omitempty" db:"id,omitempty"`
+	FirstName         string       `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName          string       `json:"last_name,omitempty" db:"last_name,omitempty"`
+	DateOfBirth       string       `json:"date_of_birth,omitempty" db:"date_of_birth,omitempty"`
+	Email             string       `json:"email,omitempty" db:"email,omitempty"`
+	GradeLevel        int          `json:"grade_level,omitempty" db:"grade_level,omitempty"`
+	AcademicYear      string       `json:"academic_year,omitempty" db:"academic_year,omitempty"`
+	PreviousSchool    string       `json:"previous_school,omitempty" db:"previous_school,omitempty"`
+	Notes             string       `json:"notes,omitempty" db:"notes,omitempty"`
+	GuardianFirstName string       `json:"guardian_first_name,omitempty" db:"guardian_first_name,omitempty"`
+	GuardianLastName  string       `json:"guardian_last_name,omitempty" db:"guardian_last_name,omitempty"`
+	GuardianPhone     string       `json:"guardian_phone,omitempty" db:"guardian_phone,omitempty"`
+	GuardianEmail     string       `json:"guardian_email,omitempty" db:"guardian_email,omitempty"`
+	Relationship      string       `json:"relationship,omitempty" db:"relationship,omitempty"`
+	Status            string       `json:"status,omitempty" db:"status,omitempty"`
+	SubmittedAt       string       `json:"submitted_at,omitempty" db:"submitted_at,omitempty"`
+	ReviewedBy        int          `json:"reviewed_by,omitempty" db:"reviewed_by,omitempty"`
+	DecisionNote      string       `json:"decision_note,omitempty" db:"decision_note,omitempty"`
+	OfferedClassID    int          `json:"offered_class_id,omitempty" db:"offered_class_id,omitempty"`
+	OfferExpiresOn    string       `json:"offer_expires_on,omitempty" db:"offer_expires_on,omitempty"`
+	RespondedAt       string       `json:"responded_at,omitempty" db:"responded_at,omitempty"`
+	StudentID         int          `json:"student_id,omitempty" db:"student_id,omitempty"`
+	ConvertedAt       string       `json:"converted_at,omitempty" db:"converted_at,omitempty"`
+	Version           int          `json:"version,omitempty" db:"version,omitempty"`
+	Documents         []Attachment `json:"documents,omitempty"`
+}
+
+// ApplicationReceipt is what the applicant is given back: enough to follow the application
+// and respond to an offer, without anything the school wrote about it
+type ApplicationReceipt struct {
+	ID             int    `json:"id"`
+	Token          string `json:"token,omitempty"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Status         string `json:"status"`
+	SubmittedAt    string `json:"submitted_at"`
+	OfferedClass   string `json:"offered_class,omitempty"`
+	OfferExpiresOn string `json:"offer_expires_on,omitempty"`
+	Documents      int    `json:"documents"`
+}
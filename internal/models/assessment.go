@@ -0,0 +1,14 @@
+package models
+
+type Assessment struct {
+	ID        int     `json:"id,omitempty" db:"id,omitempty"`
+	SubjectID int     `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	ClassID   int     `json:"class_id,omitempty" db:"class_id,omitempty"`
+	TermID    int     `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Name      string  `json:"name,omitempty" db:"name,omitempty"`
+	Type      string  `json:"type,omitempty" db:"type,omitempty"`
+	Weight    float64 `json:"weight,omitempty" db:"weight,omitempty"`
+	MaxScore  float64 `json:"max_score,omitempty" db:"max_score,omitempty"`
+	DueDate   string  `json:"due_date,omitempty" db:"due_date,omitempty"`
+	Version   int     `json:"version,omitempty" db:"version,omitempty"`
+}
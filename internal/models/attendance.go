@@ -0,0 +1,27 @@
+package models
+
+type AttendanceRecord struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID  int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	ClassID    int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	TermID     int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Date       string `json:"date,omitempty" db:"session_date,omitempty"`
+	Period     int    `json:"period,omitempty" db:"period,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Note       string `json:"note,omitempty" db:"note,omitempty"`
+	RecordedBy int    `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	Version    int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type AttendanceSummary struct {
+	StudentID      int     `json:"student_id,omitempty"`
+	ClassID        int     `json:"class_id,omitempty"`
+	Date           string  `json:"date,omitempty"`
+	TermID         int     `json:"term_id,omitempty"`
+	Present        int     `json:"present"`
+	Absent         int     `json:"absent"`
+	Late           int     `json:"late"`
+	Excused        int     `json:"excused"`
+	Total          int     `json:"total"`
+	AttendanceRate float64 `json:"attendance_rate"`
+}
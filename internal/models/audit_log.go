@@ -0,0 +1,16 @@
+package models
+
+import "encoding/json"
+
+type AuditLog struct {
+	ID         int             `json:"id,omitempty"  db:"id,omitempty"`
+	ActorID    int             `json:"actor_id,omitempty"  db:"actor_id,omitempty"`
+	Action     string          `json:"action,omitempty"  db:"action,omitempty"`
+	Resource   string          `json:"resource,omitempty"  db:"resource,omitempty"`
+	ResourceID int             `json:"resource_id,omitempty"  db:"resource_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"  db:"before_data,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"  db:"after_data,omitempty"`
+	Diff       json.RawMessage `json:"diff,omitempty"  db:"diff,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"  db:"request_id,omitempty"`
+	CreatedAt  string          `json:"created_at,omitempty"  db:"created_at,omitempty"`
+}
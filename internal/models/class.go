@@ -0,0 +1,12 @@
+package models
+
+type Class struct {
+	ID                int    `json:"id,omitempty" db:"id,omitempty"`
+	Name              string `json:"name,omitempty" db:"name,omitempty"`
+	GradeLevel        int    `json:"grade_level,omitempty" db:"grade_level,omitempty"`
+	AcademicYear      string `json:"academic_year,omitempty" db:"academic_year,omitempty"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id,omitempty" db:"homeroom_teacher_id,omitempty"`
+	Room              string `json:"room,omitempty" db:"room,omitempty"`
+	Capacity          int    `json:"capacity,omitempty" db:"capacity,omitempty"`
+	Version           int    `json:"version,omitempty" db:"version,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+type Enrollment struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	TermID    int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	CreatedAt string `json:"created_at,omitempty" db:"created_at,omitempty"`
+}
+
+// EnrollmentEvent is one step of a student's time at the school: an admission, a class
+// change or a move between statuses such as withdrawal or graduation
+type EnrollmentEvent struct {
+	ID            int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID     int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Event         string `json:"event,omitempty" db:"event,omitempty"`
+	FromStatus    string `json:"from_status,omitempty" db:"from_status,omitempty"`
+	ToStatus      string `json:"to_status,omitempty" db:"to_status,omitempty"`
+	FromClassID   int    `json:"from_class_id,omitempty" db:"from_class_id,omitempty"`
+	FromClass     string `json:"from_class,omitempty"`
+	ToClassID     int    `json:"to_class_id,omitempty" db:"to_class_id,omitempty"`
+	ToClass       string `json:"to_class,omitempty"`
+	EffectiveDate string `json:"effective_date,omitempty" db:"effective_date,omitempty"`
+	Reason        string `json:"reason,omitempty" db:"reason,omitempty"`
+	RecordedBy    int    `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	RecordedAt    string `json:"recorded_at,omitempty" db:"recorded_at,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+// Event is on the school calendar. Without class_ids it is for the whole school.
+type Event struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	Title       string `json:"title,omitempty" db:"title,omitempty"`
+	Description string `json:"description,omitempty" db:"description,omitempty"`
+	Category    string `json:"category,omitempty" db:"category,omitempty"`
+	Location    string `json:"location,omitempty" db:"location,omitempty"`
+	ClassIDs    []int  `json:"class_ids"`
+	StartsAt    string `json:"starts_at,omitempty" db:"starts_at,omitempty"`
+	EndsAt      string `json:"ends_at,omitempty" db:"ends_at,omitempty"`
+	AllDay      bool   `json:"all_day" db:"all_day"`
+	Recurrence  string `json:"recurrence,omitempty" db:"recurrence,omitempty"`
+	CreatedBy   int    `json:"created_by,omitempty" db:"created_by,omitempty"`
+	Version     int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// EventOccurrence is one date of an event on the calendar
+type EventOccurrence struct {
+	EventID  int    `json:"event_id"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Location string `json:"location,omitempty"`
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+	AllDay   bool   `json:"all_day"`
+}
+
+type RSVP struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	EventID     int    `json:"event_id,omitempty" db:"event_id,omitempty"`
+	GuardianID  int    `json:"guardian_id,omitempty" db:"guardian_id,omitempty"`
+	TeacherID   int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Response    string `json:"response,omitempty" db:"response,omitempty"`
+	Guests      int    `json:"guests" db:"guests"`
+	Note        string `json:"note,omitempty" db:"note,omitempty"`
+	RespondedAt string `json:"responded_at,omitempty" db:"responded_at,omitempty"`
+}
+
+// RSVPSummary counts the answers to an event. Attending counts those saying yes and their guests.
+type RSVPSummary struct {
+	Yes       int `json:"yes"`
+	No        int `json:"no"`
+	Maybe     int `json:"maybe"`
+	Attending int `json:"attending"`
+}
+
+// EventSlot is a parent evening appointment with one teacher
+type EventSlot struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	EventID    int    `json:"event_id,omitempty" db:"event_id,omitempty"`
+	TeacherID  int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Teacher    string `json:"teacher,omitempty"`
+	StartsAt   string `json:"starts_at,omitempty" db:"starts_at,omitempty"`
+	EndsAt     string `json:"ends_at,omitempty" db:"ends_at,omitempty"`
+	GuardianID int    `json:"guardian_id,omitempty" db:"guardian_id,omitempty"`
+	StudentID  int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	BookedAt   string `json:"booked_at,omitempty" db:"booked_at,omitempty"`
+}
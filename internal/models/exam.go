@@ -0,0 +1,44 @@
+package models
+
+type Exam struct {
+	ID              int    `json:"id,omitempty" db:"id,omitempty"`
+	TermID          int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	SubjectID       int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	ClassID         int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Name            string `json:"name,omitempty" db:"name,omitempty"`
+	ExamDate        string `json:"exam_date,omitempty" db:"exam_date,omitempty"`
+	StartTime       string `json:"start_time,omitempty" db:"start_time,omitempty"`
+	DurationMinutes int    `json:"duration_minutes,omitempty" db:"duration_minutes,omitempty"`
+	EndTime         string `json:"end_time,omitempty"`
+	RoomID          int    `json:"room_id,omitempty" db:"room_id,omitempty"`
+	Version         int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// ExamSeat is one candidate's seat, with the names needed to print a seating chart
+type ExamSeat struct {
+	ExamID     int    `json:"exam_id"`
+	Exam       string `json:"exam,omitempty"`
+	StudentID  int    `json:"student_id"`
+	FirstName  string `json:"first_name,omitempty"`
+	LastName   string `json:"last_name,omitempty"`
+	Class      string `json:"class,omitempty"`
+	RoomID     int    `json:"room_id"`
+	Room       string `json:"room,omitempty"`
+	SeatNumber int    `json:"seat_number"`
+}
+
+// ExamClash is a student due to sit two exams whose times overlap
+type ExamClash struct {
+	StudentID int    `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Exams     []Exam `json:"exams"`
+}
+
+// StudentExam is an exam on a student's timetable with where they sit, once seating is planned
+type StudentExam struct {
+	Exam
+	SeatRoomID int    `json:"seat_room_id,omitempty"`
+	SeatRoom   string `json:"seat_room,omitempty"`
+	SeatNumber int    `json:"seat_number,omitempty"`
+}
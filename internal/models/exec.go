@@ -15,6 +15,7 @@ type Exec struct {
 	PasswordTokenExpires sql.NullString `json:"password_token_expires,omitempty"  db:"password_token_expires,omitempty"`
 	InactiveStatus       bool           `json:"inactive_status,omitempty"  db:"inactive_status,omitempty"`
 	Role                 string         `json:"role,omitempty"  db:"role,omitempty"`
+	Version              int            `json:"version,omitempty"  db:"version,omitempty"`
 }
 
 type UpdatePasswordRequest struct {
@@ -0,0 +1,63 @@
+package models
+
+// Money amounts are integer minor units of their currency, e.g. 150000 USD is $1,500.00
+
+type FeeSchedule struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	GradeLevel int    `json:"grade_level,omitempty" db:"grade_level,omitempty"`
+	TermID     int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Name       string `json:"name,omitempty" db:"name,omitempty"`
+	Amount     int64  `json:"amount,omitempty" db:"amount,omitempty"`
+	Currency   string `json:"currency,omitempty" db:"currency,omitempty"`
+	DueDate    string `json:"due_date,omitempty" db:"due_date,omitempty"`
+	Version    int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type Invoice struct {
+	ID            int           `json:"id,omitempty" db:"id,omitempty"`
+	InvoiceNumber int64         `json:"invoice_number,omitempty" db:"invoice_number,omitempty"`
+	StudentID     int           `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TermID        int           `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Currency      string        `json:"currency,omitempty" db:"currency,omitempty"`
+	Total         int64         `json:"total" db:"total"`
+	AmountPaid    int64         `json:"amount_paid" db:"amount_paid"`
+	Balance       int64         `json:"balance"`
+	Status        string        `json:"status,omitempty"`
+	DueDate       string        `json:"due_date,omitempty" db:"due_date,omitempty"`
+	IssuedAt      string        `json:"issued_at,omitempty" db:"issued_at,omitempty"`
+	Lines         []InvoiceLine `json:"lines,omitempty"`
+	Payments      []Payment     `json:"payments,omitempty"`
+	Version       int           `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type InvoiceLine struct {
+	ID            int    `json:"id,omitempty" db:"id,omitempty"`
+	InvoiceID     int    `json:"invoice_id,omitempty" db:"invoice_id,omitempty"`
+	FeeScheduleID int    `json:"fee_schedule_id,omitempty" db:"fee_schedule_id,omitempty"`
+	Description   string `json:"description,omitempty" db:"description,omitempty"`
+	Amount        int64  `json:"amount" db:"amount"`
+}
+
+type Payment struct {
+	ID            int    `json:"id,omitempty" db:"id,omitempty"`
+	ReceiptNumber int64  `json:"receipt_number,omitempty" db:"receipt_number,omitempty"`
+	InvoiceID     int    `json:"invoice_id,omitempty" db:"invoice_id,omitempty"`
+	StudentID     int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Kind          string `json:"kind,omitempty" db:"kind,omitempty"`
+	Amount        int64  `json:"amount,omitempty" db:"amount,omitempty"`
+	Currency      string `json:"currency,omitempty" db:"currency,omitempty"`
+	Method        string `json:"method,omitempty" db:"method,omitempty"`
+	Reference     string `json:"reference,omitempty" db:"reference,omitempty"`
+	RefundOf      int    `json:"refund_of,omitempty" db:"refund_of,omitempty"`
+	ReceivedAt    string `json:"received_at,omitempty" db:"received_at,omitempty"`
+	RecordedBy    int    `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+}
+
+// Balance is what a student owes in one currency
+type Balance struct {
+	Currency    string `json:"currency"`
+	Invoiced    int64  `json:"invoiced"`
+	Paid        int64  `json:"paid"`
+	Outstanding int64  `json:"outstanding"`
+	Overdue     int64  `json:"overdue"`
+}
@@ -0,0 +1,22 @@
+package models
+
+type GradeBand struct {
+	Letter     string  `json:"letter" db:"letter"`
+	MinPercent float64 `json:"min_percent" db:"min_percent"`
+}
+
+type SubjectGrade struct {
+	SubjectID   int     `json:"subject_id"`
+	Subject     string  `json:"subject"`
+	Assessments int     `json:"assessments"`
+	Average     float64 `json:"average"`
+	Letter      string  `json:"letter"`
+}
+
+type StudentGrades struct {
+	StudentID int            `json:"student_id"`
+	TermID    int            `json:"term_id"`
+	Subjects  []SubjectGrade `json:"subjects"`
+	Average   float64        `json:"average"`
+	Letter    string         `json:"letter"`
+}
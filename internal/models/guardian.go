@@ -0,0 +1,34 @@
+package models
+
+type Guardian struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	FirstName string `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty" db:"last_name,omitempty"`
+	Phone     string `json:"phone,omitempty" db:"phone,omitempty"`
+	AltPhone  string `json:"alt_phone,omitempty" db:"alt_phone,omitempty"`
+	Email     string `json:"email,omitempty" db:"email,omitempty"`
+	Address   string `json:"address,omitempty" db:"address,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// GuardianLink is how a guardian relates to one student. The same person can be a
+// primary contact for one child and only allowed to pick up another.
+type GuardianLink struct {
+	StudentID        int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	GuardianID       int    `json:"guardian_id,omitempty" db:"guardian_id,omitempty"`
+	Relationship     string `json:"relationship,omitempty" db:"relationship,omitempty"`
+	PickupAuthorized bool   `json:"pickup_authorized" db:"pickup_authorized"`
+	PrimaryContact   bool   `json:"primary_contact" db:"primary_contact"`
+}
+
+// StudentGuardian is a guardian as listed on a student
+type StudentGuardian struct {
+	Guardian
+	GuardianLink
+}
+
+// GuardianChild is a student as listed on a guardian
+type GuardianChild struct {
+	Student
+	GuardianLink
+}
@@ -0,0 +1,51 @@
+package models
+
+// Homework is set for a class and graded through its own assessment of type homework,
+// so its scores count towards the gradebook like any other assessment
+type Homework struct {
+	ID           int          `json:"id,omitempty" db:"id,omitempty"`
+	AssessmentID int          `json:"assessment_id,omitempty" db:"assessment_id,omitempty"`
+	TeacherID    int          `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	ClassID      int          `json:"class_id,omitempty"`
+	SubjectID    int          `json:"subject_id,omitempty"`
+	TermID       int          `json:"term_id,omitempty"`
+	Title        string       `json:"title,omitempty" db:"title,omitempty"`
+	Description  string       `json:"description,omitempty" db:"description,omitempty"`
+	DueAt        string       `json:"due_at,omitempty" db:"due_at,omitempty"`
+	MaxScore     float64      `json:"max_score,omitempty"`
+	Weight       float64      `json:"weight,omitempty"`
+	Attachments  []Attachment `json:"attachments,omitempty"`
+	Version      int          `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type Submission struct {
+	ID          int          `json:"id,omitempty" db:"id,omitempty"`
+	HomeworkID  int          `json:"homework_id,omitempty" db:"homework_id,omitempty"`
+	StudentID   int          `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Status      string       `json:"status,omitempty" db:"status,omitempty"`
+	Text        string       `json:"text,omitempty" db:"text,omitempty"`
+	SubmittedAt string       `json:"submitted_at,omitempty" db:"submitted_at,omitempty"`
+	Score       *float64     `json:"score,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Version     int          `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// Attachment is an uploaded file belonging to another record, e.g. a homework or a submission
+type Attachment struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	OwnerType   string `json:"owner_type,omitempty" db:"owner_type,omitempty"`
+	OwnerID     int    `json:"owner_id,omitempty" db:"owner_id,omitempty"`
+	FileName    string `json:"file_name,omitempty" db:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty" db:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty" db:"size,omitempty"`
+	StoragePath string `json:"-" db:"storage_path,omitempty"`
+	UploadedBy  int    `json:"uploaded_by,omitempty" db:"uploaded_by,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty" db:"created_at,omitempty"`
+}
+
+// StudentHomework is a homework as a student sees it, with where their submission stands
+type StudentHomework struct {
+	Homework
+	Submission Submission `json:"submission"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Import is one run of a CSV import. The columns, ignored columns and row errors are only
+// returned by the run itself, later the failed rows are downloaded as ErrorReport.
+type Import struct {
+	ID             int               `json:"id,omitempty" db:"id,omitempty"`
+	Resource       string            `json:"resource,omitempty" db:"resource,omitempty"`
+	FileName       string            `json:"file_name,omitempty" db:"file_name,omitempty"`
+	Encoding       string            `json:"encoding,omitempty" db:"encoding,omitempty"`
+	DryRun         bool              `json:"dry_run" db:"dry_run"`
+	BatchSize      int               `json:"batch_size,omitempty" db:"batch_size,omitempty"`
+	TotalRows      int               `json:"total_rows" db:"total_rows"`
+	ImportedRows   int               `json:"imported_rows" db:"imported_rows"`
+	FailedRows     int               `json:"failed_rows" db:"failed_rows"`
+	CreatedBy      int               `json:"created_by,omitempty" db:"created_by,omitempty"`
+	CreatedAt      string            `json:"created_at,omitempty" db:"created_at,omitempty"`
+	Columns        map[string]string `json:"columns,omitempty"`
+	IgnoredColumns []string          `json:"ignored_columns,omitempty"`
+	Errors         []ImportRowError  `json:"errors,omitempty"`
+	ErrorReport    string            `json:"error_report,omitempty"`
+}
+
+// ImportRowError is a problem with one row of an imported file, Row being its line in the file
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,40 @@
+package models
+
+type Incident struct {
+	ID                 int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID          int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TermID             int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	OccurredAt         string `json:"occurred_at,omitempty" db:"occurred_at,omitempty"`
+	Category           string `json:"category,omitempty" db:"category,omitempty"`
+	Severity           string `json:"severity,omitempty" db:"severity,omitempty"`
+	Description        string `json:"description,omitempty" db:"description,omitempty"`
+	ReportedBy         int    `json:"reported_by,omitempty" db:"reported_by,omitempty"`
+	ActionsTaken       string `json:"actions_taken,omitempty" db:"actions_taken,omitempty"`
+	FollowUpDate       string `json:"follow_up_date,omitempty" db:"follow_up_date,omitempty"`
+	GuardianNotified   bool   `json:"guardian_notified" db:"guardian_notified"`
+	GuardianNotifiedAt string `json:"guardian_notified_at,omitempty" db:"guardian_notified_at,omitempty"`
+	Confidential       bool   `json:"confidential,omitempty" db:"confidential,omitempty"`
+	RecordedBy         int    `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	Version            int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// StudentIncidents is one student's line in a class summary
+type StudentIncidents struct {
+	StudentID       int    `json:"student_id"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	Incidents       int    `json:"incidents"`
+	HighestSeverity string `json:"highest_severity"`
+}
+
+// IncidentSummary counts the incidents of a class in a term
+type IncidentSummary struct {
+	ClassID         int                `json:"class_id"`
+	TermID          int                `json:"term_id"`
+	Total           int                `json:"total"`
+	GuardianNotices int                `json:"guardian_notified"`
+	FollowUpsDue    int                `json:"follow_ups_due"`
+	ByCategory      map[string]int     `json:"by_category"`
+	BySeverity      map[string]int     `json:"by_severity"`
+	Students        []StudentIncidents `json:"students"`
+}
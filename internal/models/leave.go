@@ -0,0 +1,47 @@
+package models
+
+// LeaveRequest is a teacher's absence for whole days from StartDate to EndDate
+type LeaveRequest struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	TeacherID   int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	LeaveType   string `json:"leave_type,omitempty" db:"leave_type,omitempty"`
+	StartDate   string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate     string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Reason      string `json:"reason,omitempty" db:"reason,omitempty"`
+	Status      string `json:"status,omitempty" db:"status,omitempty"`
+	RequestedBy int    `json:"requested_by,omitempty" db:"requested_by,omitempty"`
+	RequestedAt string `json:"requested_at,omitempty" db:"requested_at,omitempty"`
+	ReviewedBy  int    `json:"reviewed_by,omitempty" db:"reviewed_by,omitempty"`
+	ReviewedAt  string `json:"reviewed_at,omitempty" db:"reviewed_at,omitempty"`
+	ReviewNote  string `json:"review_note,omitempty" db:"review_note,omitempty"`
+	Version     int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// CoverAssignment records a teacher standing in for an absent colleague for one lesson
+type CoverAssignment struct {
+	ID              int    `json:"id,omitempty" db:"id,omitempty"`
+	LeaveRequestID  int    `json:"leave_request_id,omitempty" db:"leave_request_id,omitempty"`
+	TimetableSlotID int    `json:"timetable_slot_id,omitempty" db:"timetable_slot_id,omitempty"`
+	Date            string `json:"date,omitempty" db:"cover_date,omitempty"`
+	AbsentTeacherID int    `json:"absent_teacher_id,omitempty" db:"absent_teacher_id,omitempty"`
+	CoverTeacherID  int    `json:"cover_teacher_id,omitempty" db:"cover_teacher_id,omitempty"`
+	CoverTeacher    string `json:"cover_teacher,omitempty"`
+	Note            string `json:"note,omitempty" db:"note,omitempty"`
+	AssignedBy      int    `json:"assigned_by,omitempty" db:"assigned_by,omitempty"`
+	AssignedAt      string `json:"assigned_at,omitempty" db:"assigned_at,omitempty"`
+}
+
+// CoverCandidate is a teacher free to cover a lesson. CoversThisTerm helps share the load.
+type CoverCandidate struct {
+	TeacherID      int    `json:"teacher_id"`
+	Name           string `json:"name"`
+	CoversThisTerm int    `json:"covers_this_term"`
+}
+
+// CoverNeed is one lesson missed through leave, with its cover if arranged and who could take it
+type CoverNeed struct {
+	Date        string           `json:"date"`
+	Lesson      TimetableEntry   `json:"lesson"`
+	Cover       *CoverAssignment `json:"cover"`
+	Suggestions []CoverCandidate `json:"suggestions"`
+}
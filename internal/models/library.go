@@ -0,0 +1,42 @@
+package models
+
+type Book struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	ISBN      string `json:"isbn,omitempty" db:"isbn,omitempty"`
+	Title     string `json:"title,omitempty" db:"title,omitempty"`
+	Author    string `json:"author,omitempty" db:"author,omitempty"`
+	Copies    int    `json:"copies,omitempty" db:"copies,omitempty"`
+	Available int    `json:"available"`
+	OnHold    int    `json:"on_hold"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// Loan is a book checked out to a student or a teacher. Fines are in minor units of the school currency.
+type Loan struct {
+	ID           int    `json:"id,omitempty" db:"id,omitempty"`
+	BookID       int    `json:"book_id,omitempty" db:"book_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	StudentID    int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TeacherID    int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	CheckedOutAt string `json:"checked_out_at,omitempty" db:"checked_out_at,omitempty"`
+	DueDate      string `json:"due_date,omitempty" db:"due_date,omitempty"`
+	ReturnedAt   string `json:"returned_at,omitempty" db:"returned_at,omitempty"`
+	DaysOverdue  int    `json:"days_overdue,omitempty"`
+	Fine         int64  `json:"fine,omitempty" db:"fine,omitempty"`
+	FineWaived   bool   `json:"fine_waived,omitempty" db:"fine_waived,omitempty"`
+	InvoiceID    int    `json:"invoice_id,omitempty" db:"invoice_id,omitempty"`
+	CheckedOutBy int    `json:"checked_out_by,omitempty" db:"checked_out_by,omitempty"`
+}
+
+type Hold struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	BookID      int    `json:"book_id,omitempty" db:"book_id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	StudentID   int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TeacherID   int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Position    int    `json:"position,omitempty"`
+	PlacedAt    string `json:"placed_at,omitempty" db:"placed_at,omitempty"`
+	FulfilledAt string `json:"fulfilled_at,omitempty" db:"fulfilled_at,omitempty"`
+	LoanID      int    `json:"loan_id,omitempty" db:"loan_id,omitempty"`
+	CancelledAt string `json:"cancelled_at,omitempty" db:"cancelled_at,omitempty"`
+}
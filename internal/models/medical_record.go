@@ -0,0 +1,39 @@
+package models
+
+// MedicalRecord is a student's health information. It is only ever held decrypted in memory.
+type MedicalRecord struct {
+	StudentID     int            `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Allergies     []string       `json:"allergies"`
+	Conditions    []string       `json:"conditions"`
+	Medications   []Medication   `json:"medications"`
+	DoctorName    string         `json:"doctor_name"`
+	DoctorPhone   string         `json:"doctor_phone"`
+	Immunizations []Immunization `json:"immunizations"`
+	Notes         string         `json:"notes"`
+	UpdatedAt     string         `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+	UpdatedBy     int            `json:"updated_by,omitempty" db:"updated_by,omitempty"`
+	Version       int            `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type Medication struct {
+	Name         string `json:"name"`
+	Dosage       string `json:"dosage,omitempty"`
+	Instructions string `json:"instructions,omitempty"`
+}
+
+type Immunization struct {
+	Vaccine string `json:"vaccine"`
+	Date    string `json:"date,omitempty"`
+	Dose    int    `json:"dose,omitempty"`
+}
+
+// MedicalRecordAccess is one entry in the log of who opened a health record
+type MedicalRecordAccess struct {
+	ID         int    `json:"id"`
+	StudentID  int    `json:"student_id"`
+	UserID     int    `json:"user_id"`
+	Role       string `json:"role"`
+	Action     string `json:"action"`
+	RequestID  string `json:"request_id,omitempty"`
+	AccessedAt string `json:"accessed_at"`
+}
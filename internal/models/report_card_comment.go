@@ -0,0 +1,10 @@
+package models
+
+type ReportCardComment struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TermID    int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	SubjectID int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	Comment   string `json:"comment,omitempty" db:"comment,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type Score struct {
+	ID           int     `json:"id,omitempty" db:"id,omitempty"`
+	AssessmentID int     `json:"assessment_id,omitempty" db:"assessment_id,omitempty"`
+	StudentID    int     `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Score        float64 `json:"score" db:"score"`
+	Comment      string  `json:"comment,omitempty" db:"comment,omitempty"`
+	Version      int     `json:"version,omitempty" db:"version,omitempty"`
+}
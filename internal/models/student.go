@@ -6,4 +6,7 @@ type Student struct {
 	LastName  string `json:"last_name,omitempty"  db:"last_name,omitempty"`
 	Email     string `json:"email,omitempty"  db:"email,omitempty"`
 	Class     string `json:"class,omitempty"  db:"class,omitempty"`
+	ClassID   int    `json:"class_id,omitempty"  db:"class_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Version   int    `json:"version,omitempty"  db:"version,omitempty"`
 }
@@ -0,0 +1,12 @@
+package models
+
+type Subject struct {
+	ID            int    `json:"id,omitempty" db:"id,omitempty"`
+	Code          string `json:"code,omitempty" db:"code,omitempty"`
+	Name          string `json:"name,omitempty" db:"name,omitempty"`
+	Department    string `json:"department,omitempty" db:"department,omitempty"`
+	CreditHours   int    `json:"credit_hours,omitempty" db:"credit_hours,omitempty"`
+	GradeLevels   []int  `json:"grade_levels,omitempty"`
+	Prerequisites []int  `json:"prerequisites,omitempty"`
+	Version       int    `json:"version,omitempty" db:"version,omitempty"`
+}
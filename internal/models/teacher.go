@@ -6,5 +6,8 @@ type Teacher struct {
 	LastName  string `json:"last_name,omitempty" db:"last_name,omitempty"`
 	Email     string `json:"email,omitempty" db:"email,omitempty"`
 	Class     string `json:"class,omitempty" db:"class,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
 	Subject   string `json:"subject,omitempty" db:"subject,omitempty"`
+	SubjectID int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
 }
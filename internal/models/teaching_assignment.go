@@ -0,0 +1,13 @@
+package models
+
+type TeachingAssignment struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Subject   string `json:"subject,omitempty" db:"subject,omitempty"`
+	SubjectID int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	Term      string `json:"term,omitempty" db:"term,omitempty"`
+	TermID    int    `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Role      string `json:"role,omitempty" db:"role,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type Term struct {
+	ID             int    `json:"id,omitempty" db:"id,omitempty"`
+	AcademicYearID int    `json:"academic_year_id,omitempty" db:"academic_year_id,omitempty"`
+	Name           string `json:"name,omitempty" db:"name,omitempty"`
+	StartDate      string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate        string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Version        int    `json:"version,omitempty" db:"version,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Period is a named slot of the school day e.g. Period 1 from 08:00 to 08:45
+type Period struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty"`
+	StartTime string `json:"start_time,omitempty" db:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty" db:"end_time,omitempty"`
+	Version   int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+type Room struct {
+	ID       int    `json:"id,omitempty" db:"id,omitempty"`
+	Name     string `json:"name,omitempty" db:"name,omitempty"`
+	Building string `json:"building,omitempty" db:"building,omitempty"`
+	Capacity int    `json:"capacity,omitempty" db:"capacity,omitempty"`
+	Version  int    `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// TimetableSlot places a teacher, class and subject in a room for a period on one weekday
+// of a term. Day is 1 for Monday through 7 for Sunday.
+type TimetableSlot struct {
+	ID        int `json:"id,omitempty" db:"id,omitempty"`
+	TermID    int `json:"term_id,omitempty" db:"term_id,omitempty"`
+	Day       int `json:"day,omitempty" db:"day_of_week,omitempty"`
+	PeriodID  int `json:"period_id,omitempty" db:"period_id,omitempty"`
+	ClassID   int `json:"class_id,omitempty" db:"class_id,omitempty"`
+	TeacherID int `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	SubjectID int `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	RoomID    int `json:"room_id,omitempty" db:"room_id,omitempty"`
+	Version   int `json:"version,omitempty" db:"version,omitempty"`
+}
+
+// TimetableEntry is a slot with the names needed to print a timetable
+type TimetableEntry struct {
+	TimetableSlot
+	Period    string `json:"period"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Class     string `json:"class"`
+	Teacher   string `json:"teacher"`
+	Subject   string `json:"subject"`
+	Room      string `json:"room,omitempty"`
+}
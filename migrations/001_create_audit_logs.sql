@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT NULL,
+    action VARCHAR(20) NOT NULL,
+    resource VARCHAR(50) NOT NULL,
+    resource_id INT NOT NULL,
+    before_data JSON NULL,
+    after_data JSON NULL,
+    diff JSON NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_audit_actor (actor_id),
+    INDEX idx_audit_resource (resource, resource_id),
+    INDEX idx_audit_created_at (created_at)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE students ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL, ADD INDEX idx_students_deleted_at (deleted_at);
+ALTER TABLE teachers ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL, ADD INDEX idx_teachers_deleted_at (deleted_at);
+ALTER TABLE execs ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL, ADD INDEX idx_execs_deleted_at (deleted_at);
@@ -0,0 +1,3 @@
+ALTER TABLE students ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE teachers ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE execs ADD COLUMN version INT NOT NULL DEFAULT 1;
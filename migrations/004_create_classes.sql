@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS classes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    grade_level INT NOT NULL DEFAULT 0,
+    academic_year VARCHAR(20) NOT NULL,
+    homeroom_teacher_id INT NULL,
+    room VARCHAR(50) NOT NULL DEFAULT '',
+    capacity INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_classes_name_year (name, academic_year),
+    INDEX idx_classes_deleted_at (deleted_at),
+    CONSTRAINT fk_classes_homeroom_teacher FOREIGN KEY (homeroom_teacher_id) REFERENCES teachers (id) ON DELETE SET NULL
+);
+
+-- To turn the free text class values into class rows of the academic year running now,
+-- taking years to start in September like the academic years migration does
+INSERT INTO classes (name, academic_year)
+SELECT DISTINCT class,
+       CONCAT(YEAR(CURDATE()) - (MONTH(CURDATE()) < 9), '-', YEAR(CURDATE()) - (MONTH(CURDATE()) < 9) + 1)
+FROM (
+    SELECT class FROM students WHERE class IS NOT NULL AND class <> ''
+    UNION
+    SELECT class FROM teachers WHERE class IS NOT NULL AND class <> ''
+) AS existing_classes;
+
+-- Grade level is the leading number of names like "10A"
+UPDATE classes SET grade_level = CAST(name AS UNSIGNED) WHERE name REGEXP '^[0-9]+';
+
+ALTER TABLE students ADD COLUMN class_id INT NULL AFTER class;
+ALTER TABLE teachers ADD COLUMN class_id INT NULL AFTER class;
+
+UPDATE students s JOIN classes c ON c.name = s.class SET s.class_id = c.id;
+UPDATE teachers t JOIN classes c ON c.name = t.class SET t.class_id = c.id;
+
+-- A purged class leaves its former students and teachers without a class
+ALTER TABLE students ADD CONSTRAINT fk_students_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE SET NULL;
+ALTER TABLE teachers ADD CONSTRAINT fk_teachers_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE SET NULL;
@@ -0,0 +1,30 @@
+-- A class has one lead teacher per subject and term. lead_key is set to class:subject:term on
+-- live lead assignments and NULL on everything else, so the unique index only covers leads.
+CREATE TABLE IF NOT EXISTS teaching_assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    teacher_id INT NOT NULL,
+    class_id INT NOT NULL,
+    subject VARCHAR(100) NOT NULL,
+    term VARCHAR(50) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'lead',
+    lead_key VARCHAR(200) NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_assignments_teacher (teacher_id, term),
+    INDEX idx_assignments_class (class_id, subject, term),
+    INDEX idx_assignments_deleted_at (deleted_at),
+    UNIQUE KEY uq_assignments_lead_key (lead_key),
+    CONSTRAINT fk_assignments_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_assignments_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE
+);
+
+-- To carry over the single class and subject each teacher had, for the academic year of
+-- the class. The lowest teacher id becomes the lead where several teachers shared a class and subject.
+INSERT INTO teaching_assignments (teacher_id, class_id, subject, term, role)
+SELECT t.id, t.class_id, t.subject, c.academic_year,
+       IF(t.id = (SELECT MIN(t2.id) FROM teachers t2 WHERE t2.class_id = t.class_id AND t2.subject = t.subject AND t2.deleted_at IS NULL), 'lead', 'assistant')
+FROM teachers t
+JOIN classes c ON c.id = t.class_id
+WHERE t.class_id IS NOT NULL AND t.subject <> '' AND t.deleted_at IS NULL;
+
+UPDATE teaching_assignments SET lead_key = CONCAT(class_id, ':', subject, ':', term) WHERE role = 'lead';
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS subjects (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    department VARCHAR(100) NOT NULL DEFAULT '',
+    credit_hours INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_subjects_code (code),
+    INDEX idx_subjects_name (name),
+    INDEX idx_subjects_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS subject_grade_levels (
+    subject_id INT NOT NULL,
+    grade_level INT NOT NULL,
+    PRIMARY KEY (subject_id, grade_level),
+    CONSTRAINT fk_subject_grade_levels_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subject_prerequisites (
+    subject_id INT NOT NULL,
+    prerequisite_id INT NOT NULL,
+    PRIMARY KEY (subject_id, prerequisite_id),
+    CONSTRAINT fk_subject_prerequisites_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_subject_prerequisites_prerequisite FOREIGN KEY (prerequisite_id) REFERENCES subjects (id) ON DELETE CASCADE
+);
+
+ALTER TABLE teachers ADD COLUMN subject_id INT NULL AFTER subject;
+ALTER TABLE teaching_assignments ADD COLUMN subject_id INT NULL AFTER subject;
+
+ALTER TABLE teachers ADD CONSTRAINT fk_teachers_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE SET NULL;
+ALTER TABLE teaching_assignments ADD CONSTRAINT fk_assignments_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE SET NULL;
+CREATE INDEX idx_assignments_subject ON teaching_assignments (class_id, subject_id, term);
+
+-- The existing free text subjects are turned into catalog rows by cmd/migrate_subjects,
+-- which also fixes spelling and casing differences between teachers.
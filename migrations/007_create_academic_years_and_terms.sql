@@ -0,0 +1,74 @@
+CREATE TABLE IF NOT EXISTS academic_years (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(20) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_academic_years_name (name),
+    INDEX idx_academic_years_dates (start_date, end_date),
+    INDEX idx_academic_years_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS terms (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    academic_year_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_terms_dates (start_date, end_date),
+    INDEX idx_terms_deleted_at (deleted_at),
+    CONSTRAINT fk_terms_academic_year FOREIGN KEY (academic_year_id) REFERENCES academic_years (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS holidays (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    academic_year_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    INDEX idx_holidays_dates (start_date, end_date),
+    CONSTRAINT fk_holidays_academic_year FOREIGN KEY (academic_year_id) REFERENCES academic_years (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    class_id INT NOT NULL,
+    term_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_enrollments_student_term (student_id, term_id),
+    INDEX idx_enrollments_term_class (term_id, class_id),
+    CONSTRAINT fk_enrollments_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE
+);
+
+-- To create a year for each academic year name classes already use. Names like
+-- "2024-2025" are assumed to run from September to the end of the following July.
+INSERT INTO academic_years (name, start_date, end_date)
+SELECT DISTINCT academic_year,
+       STR_TO_DATE(CONCAT(LEFT(academic_year, 4), '-09-01'), '%Y-%m-%d'),
+       STR_TO_DATE(CONCAT(LEFT(academic_year, 4) + 1, '-07-31'), '%Y-%m-%d')
+FROM classes
+WHERE academic_year REGEXP '^[0-9]{4}';
+
+-- Existing assignments were recorded against the whole year, so each year starts with a
+-- single term of the same name. It can be renamed and split into real terms afterwards.
+INSERT INTO terms (academic_year_id, name, start_date, end_date)
+SELECT id, name, start_date, end_date FROM academic_years;
+
+ALTER TABLE teaching_assignments ADD COLUMN term_id INT NULL AFTER term;
+UPDATE teaching_assignments ta JOIN terms t ON t.name = ta.term SET ta.term_id = t.id;
+ALTER TABLE teaching_assignments ADD CONSTRAINT fk_assignments_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE SET NULL;
+CREATE INDEX idx_assignments_term ON teaching_assignments (term_id);
+
+INSERT INTO enrollments (student_id, class_id, term_id)
+SELECT s.id, s.class_id, t.id
+FROM students s
+JOIN classes c ON c.id = s.class_id
+JOIN academic_years y ON y.name = c.academic_year
+JOIN terms t ON t.academic_year_id = y.id
+WHERE s.deleted_at IS NULL;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS attendance_records (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    class_id INT NOT NULL,
+    term_id INT NOT NULL,
+    session_date DATE NOT NULL,
+    period INT NOT NULL DEFAULT 0,
+    status ENUM('present', 'absent', 'late', 'excused') NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    recorded_by INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_attendance_session (student_id, class_id, session_date, period),
+    INDEX idx_attendance_class_date (class_id, session_date),
+    INDEX idx_attendance_term_student (term_id, student_id),
+    CONSTRAINT fk_attendance_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE
+);
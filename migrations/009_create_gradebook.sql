@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS assessments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subject_id INT NOT NULL,
+    class_id INT NOT NULL,
+    term_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type ENUM('quiz', 'exam', 'homework') NOT NULL,
+    weight DECIMAL(6, 2) NOT NULL DEFAULT 1,
+    max_score DECIMAL(8, 2) NOT NULL,
+    due_date DATE NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_assessments_class_term (class_id, term_id, subject_id),
+    INDEX idx_assessments_deleted_at (deleted_at),
+    CONSTRAINT fk_assessments_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_assessments_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_assessments_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    assessment_id INT NOT NULL,
+    student_id INT NOT NULL,
+    score DECIMAL(8, 2) NOT NULL,
+    comment VARCHAR(255) NOT NULL DEFAULT '',
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_scores_assessment_student (assessment_id, student_id),
+    INDEX idx_scores_student (student_id),
+    CONSTRAINT fk_scores_assessment FOREIGN KEY (assessment_id) REFERENCES assessments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_scores_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS grading_scale (
+    letter VARCHAR(5) PRIMARY KEY,
+    min_percent DECIMAL(5, 2) NOT NULL
+);
+
+INSERT INTO grading_scale (letter, min_percent) VALUES
+    ('A', 90), ('B', 80), ('C', 70), ('D', 60), ('F', 0);
@@ -0,0 +1,14 @@
+-- subject_id is 0 for a general comment, e.g. from the form teacher
+CREATE TABLE IF NOT EXISTS report_card_comments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    term_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    subject_id INT NOT NULL DEFAULT 0,
+    comment TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_report_card_comments (student_id, term_id, teacher_id, subject_id),
+    CONSTRAINT fk_report_card_comments_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_report_card_comments_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_report_card_comments_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+);
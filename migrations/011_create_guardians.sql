@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS guardians (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    alt_phone VARCHAR(20) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(500) NOT NULL DEFAULT '',
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_guardians_name (last_name, first_name),
+    INDEX idx_guardians_email (email),
+    INDEX idx_guardians_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS student_guardians (
+    student_id INT NOT NULL,
+    guardian_id INT NOT NULL,
+    relationship VARCHAR(50) NOT NULL,
+    pickup_authorized BOOLEAN NOT NULL DEFAULT FALSE,
+    primary_contact BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (student_id, guardian_id),
+    INDEX idx_student_guardians_guardian (guardian_id),
+    CONSTRAINT fk_student_guardians_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_student_guardians_guardian FOREIGN KEY (guardian_id) REFERENCES guardians (id) ON DELETE CASCADE
+);
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS periods (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_periods_name (name)
+);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    building VARCHAR(100) NOT NULL DEFAULT '',
+    capacity INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_rooms_name (name)
+);
+
+-- The unique keys back up the conflict checks in the handlers for slots in the same period.
+-- Overlapping periods with different ids are caught by the handlers only.
+CREATE TABLE IF NOT EXISTS timetable_slots (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    term_id INT NOT NULL,
+    day_of_week TINYINT NOT NULL,
+    period_id INT NOT NULL,
+    class_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    subject_id INT NOT NULL,
+    room_id INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_timetable_class (term_id, day_of_week, period_id, class_id),
+    UNIQUE KEY uq_timetable_teacher (term_id, day_of_week, period_id, teacher_id),
+    UNIQUE KEY uq_timetable_room (term_id, day_of_week, period_id, room_id),
+    CONSTRAINT fk_timetable_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_timetable_period FOREIGN KEY (period_id) REFERENCES periods (id),
+    CONSTRAINT fk_timetable_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_timetable_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_timetable_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_timetable_room FOREIGN KEY (room_id) REFERENCES rooms (id)
+);
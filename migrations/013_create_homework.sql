@@ -0,0 +1,45 @@
+-- The class, subject, term, weight and max score of a homework live on its assessment
+CREATE TABLE IF NOT EXISTS homework (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    assessment_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    due_at DATETIME NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_homework_assessment (assessment_id),
+    INDEX idx_homework_teacher (teacher_id),
+    INDEX idx_homework_deleted_at (deleted_at),
+    CONSTRAINT fk_homework_assessment FOREIGN KEY (assessment_id) REFERENCES assessments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_homework_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+);
+
+-- A student without a row hasn't submitted; they count as missing once the homework is due
+CREATE TABLE IF NOT EXISTS homework_submissions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    homework_id INT NOT NULL,
+    student_id INT NOT NULL,
+    status ENUM('submitted', 'late', 'graded') NOT NULL,
+    text TEXT NOT NULL,
+    submitted_at DATETIME NULL,
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_submissions_homework_student (homework_id, student_id),
+    INDEX idx_submissions_student (student_id),
+    CONSTRAINT fk_submissions_homework FOREIGN KEY (homework_id) REFERENCES homework (id) ON DELETE CASCADE,
+    CONSTRAINT fk_submissions_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+-- Files kept on disk under UPLOAD_DIR. Rows are removed with their owner by the handlers.
+CREATE TABLE IF NOT EXISTS attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    owner_type VARCHAR(30) NOT NULL,
+    owner_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(150) NOT NULL,
+    size BIGINT NOT NULL,
+    storage_path VARCHAR(255) NOT NULL,
+    uploaded_by INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_attachments_owner (owner_type, owner_id)
+);
@@ -0,0 +1,83 @@
+-- Money is kept as integer minor units (cents, kobo, pence) with an ISO 4217 currency code.
+-- Every foreign key of the fee tables is RESTRICT: financial records are never removed
+-- along with the student, term or schedule they belong to.
+
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    grade_level INT NOT NULL,
+    term_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    due_date DATE NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_fee_schedules_grade_term (grade_level, term_id),
+    INDEX idx_fee_schedules_deleted_at (deleted_at),
+    CONSTRAINT fk_fee_schedules_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE RESTRICT
+);
+
+-- Gapless numbering for invoices and receipts. The row is locked by the transaction
+-- taking the next number, so a rollback gives the number back.
+CREATE TABLE IF NOT EXISTS sequences (
+    name VARCHAR(30) PRIMARY KEY,
+    value BIGINT NOT NULL
+);
+
+INSERT INTO sequences (name, value) VALUES ('invoice', 0), ('receipt', 0);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_number BIGINT NOT NULL,
+    student_id INT NOT NULL,
+    term_id INT NULL,
+    currency CHAR(3) NOT NULL,
+    total BIGINT NOT NULL,
+    amount_paid BIGINT NOT NULL DEFAULT 0,
+    due_date DATE NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    voided_at TIMESTAMP NULL DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE KEY uq_invoices_number (invoice_number),
+    INDEX idx_invoices_student (student_id, term_id),
+    INDEX idx_invoices_due_date (due_date),
+    CONSTRAINT fk_invoices_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_invoices_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE RESTRICT
+);
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    fee_schedule_id INT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    CONSTRAINT fk_invoice_lines_invoice FOREIGN KEY (invoice_id) REFERENCES invoices (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_invoice_lines_schedule FOREIGN KEY (fee_schedule_id) REFERENCES fee_schedules (id) ON DELETE RESTRICT
+);
+
+-- Refunds are rows of their own pointing at the payment they return money from.
+-- Amounts are always positive; the kind says which way the money went.
+CREATE TABLE IF NOT EXISTS payments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    receipt_number BIGINT NOT NULL,
+    invoice_id INT NOT NULL,
+    student_id INT NOT NULL,
+    kind ENUM('payment', 'refund') NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    method VARCHAR(30) NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    refund_of INT NULL,
+    received_at DATETIME NOT NULL,
+    recorded_by INT NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_payments_receipt (receipt_number),
+    INDEX idx_payments_invoice (invoice_id),
+    INDEX idx_payments_student (student_id),
+    CONSTRAINT fk_payments_invoice FOREIGN KEY (invoice_id) REFERENCES invoices (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_payments_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_payments_refund_of FOREIGN KEY (refund_of) REFERENCES payments (id) ON DELETE RESTRICT
+);
+
+-- Students with invoices or payments are never purged, the purge job clears their personal
+-- details instead and records when it did so
+ALTER TABLE students ADD COLUMN anonymised_at DATETIME NULL;
@@ -0,0 +1,39 @@
+-- Announcements are aimed at the whole school, one class, or exec users with a given role.
+-- They show between publish_at and expires_at, and are emailed once when send_email is set.
+CREATE TABLE IF NOT EXISTS announcements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    audience ENUM('school', 'class', 'role') NOT NULL DEFAULT 'school',
+    class_id INT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT '',
+    author_id INT NOT NULL DEFAULT 0,
+    publish_at DATETIME NOT NULL,
+    expires_at DATETIME NULL,
+    send_email BOOLEAN NOT NULL DEFAULT FALSE,
+    emailed_at DATETIME NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_announcements_publish (publish_at, expires_at),
+    INDEX idx_announcements_class (class_id),
+    INDEX idx_announcements_deleted_at (deleted_at),
+    CONSTRAINT fk_announcements_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE
+);
+
+-- The inbox of each exec user. Announcements are copied in for every user they target,
+-- system events such as password changes are written straight here.
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    kind ENUM('announcement', 'system') NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    announcement_id INT NULL,
+    visible_from DATETIME NOT NULL,
+    expires_at DATETIME NULL,
+    read_at DATETIME NULL,
+    UNIQUE KEY uq_notifications_announcement_user (announcement_id, user_id),
+    INDEX idx_notifications_inbox (user_id, read_at, visible_from),
+    CONSTRAINT fk_notifications_user FOREIGN KEY (user_id) REFERENCES execs (id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_announcement FOREIGN KEY (announcement_id) REFERENCES announcements (id) ON DELETE CASCADE
+);
@@ -0,0 +1,28 @@
+-- Behaviour incidents logged by pastoral staff. Confidential incidents are only shown to
+-- the roles in INCIDENT_CONFIDENTIAL_ROLES. The reporter is cleared when their teacher
+-- record is purged so the incident itself is kept.
+CREATE TABLE IF NOT EXISTS incidents (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    term_id INT NULL,
+    occurred_at DATETIME NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    severity ENUM('low', 'medium', 'high', 'critical') NOT NULL DEFAULT 'low',
+    description TEXT NOT NULL,
+    reported_by INT NULL,
+    actions_taken TEXT NOT NULL,
+    follow_up_date DATE NULL,
+    guardian_notified BOOLEAN NOT NULL DEFAULT FALSE,
+    guardian_notified_at DATETIME NULL,
+    confidential BOOLEAN NOT NULL DEFAULT FALSE,
+    recorded_by INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_incidents_student (student_id, occurred_at),
+    INDEX idx_incidents_term (term_id),
+    INDEX idx_incidents_follow_up (follow_up_date),
+    INDEX idx_incidents_deleted_at (deleted_at),
+    CONSTRAINT fk_incidents_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_incidents_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE SET NULL,
+    CONSTRAINT fk_incidents_teacher FOREIGN KEY (reported_by) REFERENCES teachers (id) ON DELETE SET NULL
+);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS exams (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    term_id INT NOT NULL,
+    subject_id INT NOT NULL,
+    class_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    exam_date DATE NOT NULL,
+    start_time TIME NOT NULL,
+    duration_minutes INT NOT NULL,
+    room_id INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_exams_date (exam_date, start_time),
+    INDEX idx_exams_term_class (term_id, class_id),
+    INDEX idx_exams_deleted_at (deleted_at),
+    CONSTRAINT fk_exams_term FOREIGN KEY (term_id) REFERENCES terms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_exams_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_exams_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_exams_room FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE SET NULL
+);
+
+-- Where each candidate sits. Exams sitting at the same time share rooms, so seat numbers
+-- are unique per room across the exams of one seating plan rather than per exam.
+CREATE TABLE IF NOT EXISTS exam_seats (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    exam_id INT NOT NULL,
+    student_id INT NOT NULL,
+    room_id INT NOT NULL,
+    seat_number INT NOT NULL,
+    UNIQUE KEY uq_exam_seats_student (exam_id, student_id),
+    INDEX idx_exam_seats_room (room_id),
+    CONSTRAINT fk_exam_seats_exam FOREIGN KEY (exam_id) REFERENCES exams (id) ON DELETE CASCADE,
+    CONSTRAINT fk_exam_seats_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_exam_seats_room FOREIGN KEY (room_id) REFERENCES rooms (id)
+);
@@ -0,0 +1,58 @@
+-- The library catalog. copies is how many the library owns; how many are on the shelf is
+-- worked out from the loans still out.
+CREATE TABLE IF NOT EXISTS library_books (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    isbn VARCHAR(13) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    copies INT NOT NULL DEFAULT 1,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_library_books_isbn (isbn),
+    INDEX idx_library_books_title (title),
+    INDEX idx_library_books_deleted_at (deleted_at)
+);
+
+-- A loan is to exactly one of a student or a teacher. Fines are charged when an overdue
+-- book comes back; a student's fine is billed on an invoice.
+CREATE TABLE IF NOT EXISTS library_loans (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    book_id INT NOT NULL,
+    student_id INT NULL,
+    teacher_id INT NULL,
+    checked_out_at DATETIME NOT NULL,
+    due_date DATE NOT NULL,
+    returned_at DATETIME NULL,
+    fine BIGINT NOT NULL DEFAULT 0,
+    fine_waived BOOLEAN NOT NULL DEFAULT FALSE,
+    invoice_id INT NULL,
+    checked_out_by INT NOT NULL DEFAULT 0,
+    INDEX idx_library_loans_book (book_id, returned_at),
+    INDEX idx_library_loans_student (student_id),
+    INDEX idx_library_loans_teacher (teacher_id),
+    INDEX idx_library_loans_due_date (due_date, returned_at),
+    CONSTRAINT chk_library_loans_borrower CHECK ((student_id IS NULL) <> (teacher_id IS NULL)),
+    CONSTRAINT fk_library_loans_book FOREIGN KEY (book_id) REFERENCES library_books (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_loans_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_loans_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_loans_invoice FOREIGN KEY (invoice_id) REFERENCES invoices (id) ON DELETE SET NULL
+);
+
+-- Holds queue first come, first served per book. A hold is fulfilled by the loan that
+-- hands the book to its borrower.
+CREATE TABLE IF NOT EXISTS library_holds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    book_id INT NOT NULL,
+    student_id INT NULL,
+    teacher_id INT NULL,
+    placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    fulfilled_at DATETIME NULL,
+    loan_id INT NULL,
+    cancelled_at DATETIME NULL,
+    INDEX idx_library_holds_queue (book_id, fulfilled_at, cancelled_at, placed_at),
+    CONSTRAINT chk_library_holds_borrower CHECK ((student_id IS NULL) <> (teacher_id IS NULL)),
+    CONSTRAINT fk_library_holds_book FOREIGN KEY (book_id) REFERENCES library_books (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_holds_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_holds_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_library_holds_loan FOREIGN KEY (loan_id) REFERENCES library_loans (id) ON DELETE SET NULL
+);
@@ -0,0 +1,29 @@
+-- One health record per student. Every health field is encrypted by the application with
+-- MEDICAL_RECORDS_KEY before it is written, so the database only ever holds ciphertext.
+CREATE TABLE IF NOT EXISTS medical_records (
+    student_id INT PRIMARY KEY,
+    allergies TEXT NOT NULL,
+    conditions TEXT NOT NULL,
+    medications TEXT NOT NULL,
+    doctor_name TEXT NOT NULL,
+    doctor_phone TEXT NOT NULL,
+    immunizations TEXT NOT NULL,
+    notes TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    updated_by INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT fk_medical_records_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+-- Who looked at or changed a health record, including attempts that were refused
+CREATE TABLE IF NOT EXISTS medical_record_access (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    user_id INT NOT NULL DEFAULT 0,
+    role VARCHAR(50) NOT NULL DEFAULT '',
+    action ENUM('read', 'write', 'denied') NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    accessed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_medical_record_access_student (student_id, accessed_at),
+    INDEX idx_medical_record_access_user (user_id)
+);
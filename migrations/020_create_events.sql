@@ -0,0 +1,66 @@
+-- School calendar events. An event with no rows in event_classes is for the whole school.
+-- recurrence holds an RRULE such as FREQ=WEEKLY;COUNT=10, empty for a one off.
+CREATE TABLE IF NOT EXISTS events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(30) NOT NULL DEFAULT 'general',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    all_day BOOLEAN NOT NULL DEFAULT FALSE,
+    recurrence VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_events_starts_at (starts_at),
+    INDEX idx_events_category (category),
+    INDEX idx_events_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS event_classes (
+    event_id INT NOT NULL,
+    class_id INT NOT NULL,
+    PRIMARY KEY (event_id, class_id),
+    INDEX idx_event_classes_class (class_id),
+    CONSTRAINT fk_event_classes_event FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_classes_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE
+);
+
+-- One answer per guardian or teacher per event; answering again replaces it
+CREATE TABLE IF NOT EXISTS event_rsvps (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_id INT NOT NULL,
+    guardian_id INT NULL,
+    teacher_id INT NULL,
+    response ENUM('yes', 'no', 'maybe') NOT NULL,
+    guests INT NOT NULL DEFAULT 0,
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    responded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_event_rsvps_guardian (event_id, guardian_id),
+    UNIQUE KEY uq_event_rsvps_teacher (event_id, teacher_id),
+    CONSTRAINT chk_event_rsvps_responder CHECK ((guardian_id IS NULL) <> (teacher_id IS NULL)),
+    CONSTRAINT fk_event_rsvps_event FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_rsvps_guardian FOREIGN KEY (guardian_id) REFERENCES guardians (id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_rsvps_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+);
+
+-- Parent evening appointments. A teacher has one slot at any start time, and booking
+-- only succeeds on a slot nobody holds, so a slot can't be booked twice.
+CREATE TABLE IF NOT EXISTS event_slots (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    guardian_id INT NULL,
+    student_id INT NULL,
+    booked_at DATETIME NULL,
+    UNIQUE KEY uq_event_slots_teacher_start (teacher_id, starts_at),
+    INDEX idx_event_slots_event (event_id, teacher_id),
+    INDEX idx_event_slots_guardian (guardian_id),
+    CONSTRAINT fk_event_slots_event FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_slots_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_slots_guardian FOREIGN KEY (guardian_id) REFERENCES guardians (id) ON DELETE SET NULL,
+    CONSTRAINT fk_event_slots_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE SET NULL
+);
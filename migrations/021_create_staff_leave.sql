@@ -0,0 +1,41 @@
+-- Leave requested by teachers and decided by execs in LEAVE_APPROVER_ROLES.
+-- Cancelled requests are kept so a teacher's absence history stays complete.
+CREATE TABLE IF NOT EXISTS leave_requests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    teacher_id INT NOT NULL,
+    leave_type ENUM('sick', 'personal', 'professional', 'compassionate', 'other') NOT NULL DEFAULT 'other',
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT NOT NULL,
+    status ENUM('pending', 'approved', 'rejected', 'cancelled') NOT NULL DEFAULT 'pending',
+    requested_by INT NOT NULL DEFAULT 0,
+    requested_at DATETIME NOT NULL,
+    reviewed_by INT NULL,
+    reviewed_at DATETIME NULL,
+    review_note TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    INDEX idx_leave_requests_teacher (teacher_id, start_date),
+    INDEX idx_leave_requests_dates (status, start_date, end_date),
+    CONSTRAINT fk_leave_requests_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+);
+
+-- A substitution is one lesson of the timetable taught by another teacher on one date.
+-- The unique key stops a lesson being covered twice on the same day.
+CREATE TABLE IF NOT EXISTS cover_assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    leave_request_id INT NULL,
+    timetable_slot_id INT NOT NULL,
+    cover_date DATE NOT NULL,
+    absent_teacher_id INT NOT NULL,
+    cover_teacher_id INT NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    assigned_by INT NOT NULL DEFAULT 0,
+    assigned_at DATETIME NOT NULL,
+    UNIQUE KEY uq_cover_assignments_lesson (timetable_slot_id, cover_date),
+    INDEX idx_cover_assignments_cover_teacher (cover_teacher_id, cover_date),
+    INDEX idx_cover_assignments_absent_teacher (absent_teacher_id, cover_date),
+    CONSTRAINT fk_cover_assignments_leave FOREIGN KEY (leave_request_id) REFERENCES leave_requests (id) ON DELETE SET NULL,
+    CONSTRAINT fk_cover_assignments_slot FOREIGN KEY (timetable_slot_id) REFERENCES timetable_slots (id) ON DELETE CASCADE,
+    CONSTRAINT fk_cover_assignments_absent FOREIGN KEY (absent_teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_cover_assignments_cover FOREIGN KEY (cover_teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+);
@@ -0,0 +1,30 @@
+-- Where a student is in their time at the school. Leaving statuses clear the student's class,
+-- the class they left from is kept in enrollment_history.
+ALTER TABLE students ADD COLUMN status ENUM('active', 'suspended', 'transferred', 'withdrawn', 'graduated') NOT NULL DEFAULT 'active' AFTER class_id;
+CREATE INDEX idx_students_status ON students (status);
+
+CREATE TABLE IF NOT EXISTS enrollment_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    event ENUM('admitted', 'class_changed', 'suspended', 'reinstated', 'transferred', 'withdrawn', 'graduated', 'readmitted') NOT NULL,
+    from_status VARCHAR(20) NULL,
+    to_status VARCHAR(20) NOT NULL,
+    from_class_id INT NULL,
+    to_class_id INT NULL,
+    effective_date DATE NOT NULL,
+    reason TEXT NOT NULL,
+    recorded_by INT NOT NULL DEFAULT 0,
+    recorded_at DATETIME NOT NULL,
+    INDEX idx_enrollment_history_student (student_id, effective_date),
+    CONSTRAINT fk_enrollment_history_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollment_history_from_class FOREIGN KEY (from_class_id) REFERENCES classes (id) ON DELETE SET NULL,
+    CONSTRAINT fk_enrollment_history_to_class FOREIGN KEY (to_class_id) REFERENCES classes (id) ON DELETE SET NULL
+);
+
+-- To start every existing student's history with their admission, dated from their first
+-- enrolled term when there is one
+INSERT INTO enrollment_history (student_id, event, to_status, to_class_id, effective_date, reason, recorded_at)
+SELECT s.id, 'admitted', 'active', s.class_id,
+       COALESCE((SELECT MIN(t.start_date) FROM enrollments e JOIN terms t ON t.id = e.term_id WHERE e.student_id = s.id), CURDATE()),
+       'Recorded when enrollment history began', NOW()
+FROM students s;
@@ -0,0 +1,34 @@
+-- Applications sent in by prospective families through the public form. An accepted
+-- application becomes a student, student_id then points at the record it was turned into.
+CREATE TABLE IF NOT EXISTS applications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    date_of_birth DATE NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    grade_level INT NOT NULL,
+    academic_year VARCHAR(20) NOT NULL,
+    previous_school VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL,
+    guardian_first_name VARCHAR(255) NOT NULL,
+    guardian_last_name VARCHAR(255) NOT NULL,
+    guardian_phone VARCHAR(20) NOT NULL,
+    guardian_email VARCHAR(255) NOT NULL,
+    relationship VARCHAR(50) NOT NULL,
+    status ENUM('submitted', 'under_review', 'offered', 'accepted', 'declined', 'rejected') NOT NULL DEFAULT 'submitted',
+    submitted_at DATETIME NOT NULL,
+    reviewed_by INT NULL,
+    decision_note TEXT NOT NULL,
+    offered_class_id INT NULL,
+    offer_expires_on DATE NULL,
+    responded_at DATETIME NULL,
+    student_id INT NULL,
+    converted_at DATETIME NULL,
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_applications_status (status, submitted_at),
+    INDEX idx_applications_year (academic_year, grade_level),
+    INDEX idx_applications_deleted_at (deleted_at),
+    CONSTRAINT fk_applications_class FOREIGN KEY (offered_class_id) REFERENCES classes (id) ON DELETE SET NULL,
+    CONSTRAINT fk_applications_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE SET NULL
+);
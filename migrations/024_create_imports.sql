@@ -0,0 +1,17 @@
+-- One run of a CSV import of students or teachers, dry runs included. The rows that
+-- failed are kept as a CSV in error_report so registrars can fix and upload them again.
+CREATE TABLE IF NOT EXISTS imports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resource ENUM('students', 'teachers') NOT NULL,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    encoding VARCHAR(20) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    batch_size INT NOT NULL DEFAULT 0,
+    total_rows INT NOT NULL DEFAULT 0,
+    imported_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    error_report MEDIUMTEXT NOT NULL,
+    created_by INT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    INDEX idx_imports_resource (resource, created_at)
+);
@@ -0,0 +1,15 @@
+-- One row per recipient of an emailed announcement, so a failed address is retried on its
+-- own instead of everyone being emailed again. Rows are written when the announcement is
+-- claimed, which is when emailed_at is set.
+CREATE TABLE IF NOT EXISTS announcement_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    announcement_id INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    status ENUM('pending', 'sending', 'sent', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(500) NOT NULL DEFAULT '',
+    sent_at DATETIME NULL,
+    UNIQUE KEY uq_deliveries_announcement_email (announcement_id, email),
+    INDEX idx_deliveries_status (status, attempts),
+    CONSTRAINT fk_deliveries_announcement FOREIGN KEY (announcement_id) REFERENCES announcements (id) ON DELETE CASCADE
+);
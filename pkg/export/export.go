@@ -0,0 +1,153 @@
+// Package export writes tables of records as CSV, XLSX or NDJSON one row at a time, so a
+// list can be sent straight from a database cursor without holding it all in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// The formats a table can be exported in
+const (
+	CSV    = "csv"
+	XLSX   = "xlsx"
+	NDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	CSV:    "text/csv; charset=utf-8",
+	XLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	NDJSON: "application/x-ndjson",
+}
+
+// The media types of the Accept header that ask for each format
+var acceptedTypes = map[string]string{
+	"text/csv": CSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": XLSX,
+	"application/x-ndjson": NDJSON,
+	"application/ndjson":   NDJSON,
+}
+
+// Negotiate picks the export format from a ?format= value, falling back to the first
+// media type of the Accept header that names one. An empty result means plain JSON.
+func Negotiate(format, accept string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case CSV, XLSX, NDJSON:
+		return format, nil
+	case "", "json":
+	default:
+		return "", errors.New("❌ Unknown format " + format + ", use json, csv, xlsx or ndjson")
+	}
+	if format == "json" {
+		return "", nil
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" {
+			return "", nil
+		}
+		if found, ok := acceptedTypes[mediaType]; ok {
+			return found, nil
+		}
+	}
+	return "", nil
+}
+
+// ContentType is the Content-Type header of a format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Writer writes the rows of a table. Values can be strings, numbers or booleans.
+type Writer interface {
+	Write(values []interface{}) error
+	// Close finishes the file, nothing can be written afterwards
+	Close() error
+}
+
+// NewWriter starts a table with the given column names in one of the export formats
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case XLSX:
+		return newXLSXWriter(w, columns)
+	case NDJSON:
+		return &ndjsonWriter{w: w, columns: columns}, nil
+	}
+	return nil, errors.New("❌ Unknown format " + format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	return cw, cw.w.Write(columns)
+}
+
+// Spreadsheets run text starting with these as a formula, so it is written with a leading quote.
+// Tab and carriage return are included because some spreadsheets skip them before the formula.
+const formulaPrefixes = "=+-@\t\r"
+
+func (cw *csvWriter) Write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+				s = "'" + s
+			}
+			record[i] = s
+		} else {
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter writes each row as a JSON object on its own line, keeping the column order
+type ndjsonWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (nw *ndjsonWriter) Write(values []interface{}) error {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, value := range values {
+		key, _ := json.Marshal(nw.columns[i])
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(encoded)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(nw.w, b.String())
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
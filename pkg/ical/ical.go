@@ -0,0 +1,113 @@
+// Package ical writes iCalendar (RFC 5545) feeds so school schedules can be
+// subscribed to from calendar apps.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateTimeLayout = "20060102T150405"
+	dateLayout     = "20060102"
+)
+
+// Event is one VEVENT. Times are written as floating local times, which calendar apps
+// show in the subscriber's own zone, unless UTC is set.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	UTC         bool
+	// RRule is the recurrence rule without the RRULE: prefix e.g. FREQ=WEEKLY;UNTIL=20250718T235959
+	RRule   string
+	ExDates []time.Time
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// To escape the characters that have a meaning in TEXT values
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// To fold a content line at 75 octets as the spec requires, without splitting UTF-8 characters
+func writeLine(buf *bytes.Buffer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		buf.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	buf.WriteString(line + "\r\n")
+}
+
+func (e Event) formatTime(t time.Time) string {
+	if e.AllDay {
+		return ";VALUE=DATE:" + t.Format(dateLayout)
+	}
+	if e.UTC {
+		return ":" + t.UTC().Format(dateTimeLayout) + "Z"
+	}
+	return ":" + t.Format(dateTimeLayout)
+}
+
+// WriteTo renders the calendar into w
+func (c Calendar) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	stamp := time.Now().UTC().Format(dateTimeLayout) + "Z"
+
+	writeLine(&buf, "BEGIN:VCALENDAR")
+	writeLine(&buf, "VERSION:2.0")
+	writeLine(&buf, "PRODID:-//Schoolly//Schoolly//EN")
+	writeLine(&buf, "CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		writeLine(&buf, "X-WR-CALNAME:"+escape(c.Name))
+	}
+
+	for _, e := range c.Events {
+		writeLine(&buf, "BEGIN:VEVENT")
+		writeLine(&buf, "UID:"+e.UID)
+		writeLine(&buf, "DTSTAMP:"+stamp)
+		writeLine(&buf, "DTSTART"+e.formatTime(e.Start))
+		if !e.End.IsZero() {
+			writeLine(&buf, "DTEND"+e.formatTime(e.End))
+		}
+		writeLine(&buf, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			writeLine(&buf, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Location != "" {
+			writeLine(&buf, "LOCATION:"+escape(e.Location))
+		}
+		if e.RRule != "" {
+			writeLine(&buf, "RRULE:"+e.RRule)
+		}
+		for _, exDate := range e.ExDates {
+			writeLine(&buf, "EXDATE"+e.formatTime(exDate))
+		}
+		writeLine(&buf, "END:VEVENT")
+	}
+
+	writeLine(&buf, "END:VCALENDAR")
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// Until formats the end of a recurrence for an RRULE, including the whole last day
+func Until(day time.Time) string {
+	return fmt.Sprintf("%sT235959", day.Format(dateLayout))
+}
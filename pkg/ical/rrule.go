@@ -0,0 +1,133 @@
+package ical
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the subset of an RRULE schools need: a frequency, an interval and an end by count or date
+type Recurrence struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+}
+
+var frequencies = map[string]bool{
+	"DAILY":   true,
+	"WEEKLY":  true,
+	"MONTHLY": true,
+	"YEARLY":  true,
+}
+
+// ParseRRule reads a rule such as FREQ=WEEKLY;INTERVAL=2;UNTIL=20250718, with or without the RRULE: prefix.
+// UNTIL takes a date or a date and time and is read in loc.
+func ParseRRule(rule string, loc *time.Location) (Recurrence, error) {
+	recurrence := Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return recurrence, errors.New("❌ Recurrence rules look like FREQ=WEEKLY;COUNT=10")
+		}
+
+		switch name {
+		case "FREQ":
+			if !frequencies[value] {
+				return recurrence, errors.New("❌ FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+			recurrence.Freq = value
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return recurrence, errors.New("❌ INTERVAL must be a positive number")
+			}
+			recurrence.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return recurrence, errors.New("❌ COUNT must be a positive number")
+			}
+			recurrence.Count = count
+		case "UNTIL":
+			until, err := time.ParseInLocation(dateTimeLayout, strings.TrimSuffix(value, "Z"), loc)
+			if err != nil {
+				until, err = time.ParseInLocation(dateLayout, value, loc)
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			if err != nil {
+				return recurrence, errors.New("❌ UNTIL must be a date like 20250718")
+			}
+			recurrence.Until = until
+		default:
+			return recurrence, errors.New("❌ Only FREQ, INTERVAL, COUNT and UNTIL are supported in recurrence rules")
+		}
+	}
+
+	if recurrence.Freq == "" {
+		return recurrence, errors.New("❌ Recurrence rules need a FREQ")
+	}
+	if recurrence.Count != 0 && !recurrence.Until.IsZero() {
+		return recurrence, errors.New("❌ A recurrence rule can have COUNT or UNTIL, not both")
+	}
+	return recurrence, nil
+}
+
+// String writes the rule back in its canonical form, ready for an RRULE line
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(dateTimeLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// To move n steps of the rule on from start. Months and years are counted from the
+// first occurrence so an event on the 31st isn't pulled earlier by shorter months.
+func (r Recurrence) step(start time.Time, n int) time.Time {
+	switch r.Freq {
+	case "DAILY":
+		return start.AddDate(0, 0, n*r.Interval)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*n*r.Interval)
+	case "MONTHLY":
+		return start.AddDate(0, n*r.Interval, 0)
+	default:
+		return start.AddDate(n*r.Interval, 0, 0)
+	}
+}
+
+// Occurrences lists the start of every occurrence from start that begins before to and ends
+// after from, given how long each occurrence lasts. As the RFC says, dates that don't exist
+// such as 31 April are skipped and don't count towards COUNT.
+func (r Recurrence) Occurrences(start time.Time, length time.Duration, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	counted := 0
+	for n := 0; ; n++ {
+		moment := r.step(start, n)
+		if !moment.Before(to) || (!r.Until.IsZero() && moment.After(r.Until)) {
+			break
+		}
+		if (r.Freq == "MONTHLY" || r.Freq == "YEARLY") && moment.Day() != start.Day() {
+			continue
+		}
+
+		counted++
+		if r.Count > 0 && counted > r.Count {
+			break
+		}
+		if moment.Add(length).After(from) || !moment.Before(from) {
+			occurrences = append(occurrences, moment)
+		}
+	}
+	return occurrences
+}
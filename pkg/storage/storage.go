@@ -0,0 +1,148 @@
+// Package storage keeps uploaded files on the local disk under UPLOAD_DIR
+// (default ./uploads), checking their size and type before they are written.
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTooLarge        = errors.New("❌ File is larger than the upload limit")
+	ErrUnsupportedType = errors.New("❌ File type is not accepted")
+)
+
+// Types detected from the file contents that may be uploaded. Office documents are
+// zip archives underneath, so zip content is only accepted with one of their extensions.
+var allowedTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+	"text/csv":        true,
+}
+
+var officeExtensions = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+}
+
+// File is a stored upload
+type File struct {
+	Path        string
+	Size        int64
+	ContentType string
+}
+
+// Dir is where uploads are kept
+func Dir() string {
+	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads"
+}
+
+// MaxSize is the largest upload accepted in bytes, from MAX_UPLOAD_MB (default 10)
+func MaxSize() int64 {
+	if mb, err := strconv.ParseInt(os.Getenv("MAX_UPLOAD_MB"), 10, 64); err == nil && mb > 0 {
+		return mb << 20
+	}
+	return 10 << 20
+}
+
+// To work out what a file is from its first bytes rather than trusting the client
+func detectType(head []byte, name string) (string, error) {
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	if contentType == "application/zip" {
+		if officeType, ok := officeExtensions[strings.ToLower(filepath.Ext(name))]; ok {
+			return officeType, nil
+		}
+	}
+	if contentType == "text/plain" && strings.EqualFold(filepath.Ext(name), ".csv") {
+		contentType = "text/csv"
+	}
+	if !allowedTypes[contentType] {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	return contentType, nil
+}
+
+// Save writes an upload into a folder under Dir with a random name and returns where it went.
+// Nothing is kept when the file is too large or of a type that isn't accepted.
+func Save(folder, name string, r io.Reader) (File, error) {
+	var file File
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return file, err
+	}
+	head = head[:n]
+
+	file.ContentType, err = detectType(head, name)
+	if err != nil {
+		return file, err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return file, err
+	}
+	dir := filepath.Join(Dir(), folder)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return file, err
+	}
+	file.Path = filepath.Join(folder, hex.EncodeToString(random)+strings.ToLower(filepath.Ext(name)))
+
+	out, err := os.OpenFile(filepath.Join(Dir(), file.Path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return file, err
+	}
+
+	// One byte over the limit is read so an oversized file can be told apart from one exactly at it
+	limit := MaxSize()
+	file.Size, err = io.Copy(out, io.LimitReader(io.MultiReader(bytes.NewReader(head), r), limit+1))
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && file.Size > limit {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		os.Remove(filepath.Join(Dir(), file.Path))
+		return File{}, err
+	}
+	return file, nil
+}
+
+// Open opens a stored file for reading
+func Open(path string) (*os.File, error) {
+	return os.Open(filepath.Join(Dir(), filepath.Clean("/"+path)))
+}
+
+// Remove deletes a stored file, ignoring files that are already gone
+func Remove(path string) error {
+	err := os.Remove(filepath.Join(Dir(), filepath.Clean("/"+path)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// ValidateEmail checks the value is a bare address such as ada@example.com
+func ValidateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return errors.New("❌ " + email + " is not a valid email address")
+	}
+	return nil
+}
+
+// NormalizePhone strips the spaces, dots, dashes and brackets people type into phone numbers,
+// keeping a leading + for international numbers, and rejects anything that isn't 7 to 15 digits
+func NormalizePhone(phone string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", errors.New("❌ " + phone + " is not a valid phone number")
+		}
+	}
+
+	normalized := b.String()
+	digits := len(strings.TrimPrefix(normalized, "+"))
+	if digits < 7 || digits > 15 {
+		return "", errors.New("❌ " + phone + " is not a valid phone number")
+	}
+	return normalized, nil
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The characters Windows-1252 puts in 0x80 to 0x9F, where Latin-1 has control codes.
+// Bytes the code page leaves undefined keep their Latin-1 meaning.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// DecodeText turns an uploaded text file into UTF-8 and names the encoding it was in.
+// Spreadsheet programs save CSV as UTF-8 with or without a byte order mark, as UTF-16
+// with one, or, when it isn't valid UTF-8, in the Windows-1252 code page.
+func DecodeText(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), "utf-8-bom"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), "utf-16le"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), "utf-16be"
+	case utf8.Valid(data):
+		return string(data), "utf-8"
+	}
+
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String(), "windows-1252"
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// DetectDelimiter picks the separator of a CSV file from its header line. Spreadsheets
+// in locales that write decimals with a comma use semicolons, and some exports use tabs.
+func DetectDelimiter(header string) rune {
+	delimiter, most := ',', strings.Count(header, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if count := strings.Count(header, string(candidate)); count > most {
+			delimiter, most = candidate, count
+		}
+	}
+	return delimiter
+}
+
+// NormalizeHeader brings a column heading such as " First-Name " or "E-mail Address"
+// into the snake_case form used for field names, e.g. first_name and e_mail_address
+func NormalizeHeader(header string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(header)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
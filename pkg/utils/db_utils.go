@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 func GenerateInsertQuery(tableName string, model interface{}) string {
@@ -50,50 +53,56 @@ func isValidSortOrder(order string) bool {
 	return order == "asc" || order == "desc"
 }
 
-func isValidSortField(field string) bool {
-	validFIelds := map[string]bool{
-		"first_name": true,
-		"last_name":  true,
-		"email":      true,
-		"class":      true,
-		"subject":    true,
-	}
+var defaultSortFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"class":      true,
+	"subject":    true,
+}
 
-	return validFIelds[field]
+var defaultFilterFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+	"subject":    "subject",
 }
 
 func AddSorting(r *http.Request, query string) string {
+	return AddSortingFor(r, query, defaultSortFields)
+}
+
+// AddSortingFor works like AddSorting but with the sortable columns of a specific table
+func AddSortingFor(r *http.Request, query string, validFields map[string]bool) string {
 	// https: //localhost:3000/teachers/?subject=Mathematics&sortby=last_name:asc&sortby=subject:desc
 	sortParams := r.URL.Query()["sortby"]
 	if len(sortParams) > 0 {
-		query += " ORDER BY "
-		for i, param := range sortParams {
+		var orderBy []string
+		for _, param := range sortParams {
 			parts := strings.Split(param, ":")
 			if len(parts) != 2 {
 				continue
 			}
 			field, order := parts[0], parts[1]
-			if !isValidSortField(field) || !isValidSortOrder(order) {
+			if !validFields[field] || !isValidSortOrder(order) {
 				continue
 			}
-			if i > 0 {
-				query += ","
-			}
-			query += " " + field + " " + order
+			orderBy = append(orderBy, field+" "+order)
+		}
+		if len(orderBy) > 0 {
+			query += " ORDER BY " + strings.Join(orderBy, ", ")
 		}
 	}
 	return query
 }
 
 func AddFilters(r *http.Request, query string, args []interface{}) (string, []interface{}) {
-	params := map[string]string{
-		"first_name": "first_name",
-		"last_name":  "last_name",
-		"email":      "email",
-		"class":      "class",
-		"subject":    "subject",
-	}
+	return AddFiltersFor(r, query, args, defaultFilterFields)
+}
 
+// AddFiltersFor works like AddFilters but with the query param to column mapping of a specific table
+func AddFiltersFor(r *http.Request, query string, args []interface{}, params map[string]string) (string, []interface{}) {
 	for param, dbField := range params {
 		value := r.URL.Query().Get(param)
 		if value != "" {
@@ -103,3 +112,15 @@ func AddFilters(r *http.Request, query string, args []interface{}) (string, []in
 	}
 	return query, args
 }
+
+// IsForeignKeyViolation reports whether a delete failed because other rows still reference the row
+func IsForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1451
+}
+
+// IsDuplicateKey reports whether a write failed on a unique index
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// FeedToken signs a feed such as a teacher's timetable so calendar apps, which can't
+// log in, can subscribe with the token in the URL. It stays valid until JWT_SECRET changes.
+func FeedToken(feed string, id int) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	fmt.Fprintf(mac, "%s:%d", feed, id)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyFeedToken checks a token made by FeedToken
+func VerifyFeedToken(feed string, id int, token string) bool {
+	return hmac.Equal([]byte(FeedToken(feed, id)), []byte(token))
+}
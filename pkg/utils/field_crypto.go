@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Encrypted values are stored as "v1:" followed by the base64 of the nonce and the AES-256-GCM ciphertext
+const fieldCipherPrefix = "v1:"
+
+var ErrFieldKeyMissing = errors.New("❌ Field encryption key is not configured")
+
+// FieldKey reads a 32 byte AES-256 key from an environment variable, given as base64 or as 64 hex characters
+func FieldKey(name string) ([]byte, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, ErrFieldKeyMissing
+	}
+
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("❌ " + name + " must be 32 bytes, as base64 or hex")
+	}
+	return key, nil
+}
+
+// EncryptField seals a value with the key. The context, e.g. the table, row and column, is
+// authenticated with it so a ciphertext copied to another row or column won't decrypt.
+func EncryptField(key []byte, plaintext, context string) (string, error) {
+	gcm, err := fieldCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", ErrorHandler(err, "❌ Unable to encrypt field")
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return fieldCipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptField opens a value sealed by EncryptField with the same key and context
+func DecryptField(key []byte, ciphertext, context string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(ciphertext, fieldCipherPrefix) {
+		return "", errors.New("❌ Unknown field encryption format")
+	}
+
+	gcm, err := fieldCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, fieldCipherPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("❌ Encrypted field is corrupt")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(context))
+	if err != nil {
+		return "", errors.New("❌ Unable to decrypt field, the key may be wrong")
+	}
+	return string(plaintext), nil
+}
+
+func fieldCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrorHandler(err, "❌ Invalid field encryption key")
+	}
+	return cipher.NewGCM(block)
+}
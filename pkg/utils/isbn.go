@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeISBN strips the hyphens and spaces from an ISBN-10 or ISBN-13, upper cases an X
+// check digit and rejects numbers whose check digit doesn't match
+func NormalizeISBN(isbn string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(isbn) {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	normalized := strings.ToUpper(b.String())
+	invalid := errors.New("❌ " + isbn + " is not a valid ISBN")
+
+	switch len(normalized) {
+	case 10:
+		sum := 0
+		for i, r := range normalized {
+			digit := int(r - '0')
+			if r == 'X' && i == 9 {
+				digit = 10
+			} else if r < '0' || r > '9' {
+				return "", invalid
+			}
+			sum += digit * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", invalid
+		}
+	case 13:
+		sum := 0
+		for i, r := range normalized {
+			if r < '0' || r > '9' {
+				return "", invalid
+			}
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += int(r-'0') * weight
+		}
+		if sum%10 != 0 {
+			return "", invalid
+		}
+	default:
+		return "", invalid
+	}
+	return normalized, nil
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/go-mail/mail/v2"
+)
+
+// To connect to the SMTP server from SMTP_HOST and SMTP_PORT, a local catcher on port 1025 by default
+func mailDialer() *mail.Dialer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		port = 1025
+	}
+	return mail.NewDialer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+}
+
+// SendMail sends a plain text email to each recipient on its own, over one connection,
+// so recipients never see each other's addresses
+func SendMail(recipients []string, subject, body string) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	sender, err := mailDialer().Dial()
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	for _, recipient := range recipients {
+		err := mail.Send(sender, newMessage(recipient, subject, body))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendMailEach sends like SendMail but carries on past a recipient that fails, and returns
+// the error of every recipient it could not reach. A failed send can leave the connection
+// unusable, so the next recipient gets a fresh one.
+func SendMailEach(recipients []string, subject, body string) map[string]error {
+	failed := make(map[string]error)
+	var sender mail.SendCloser
+	for _, recipient := range recipients {
+		if sender == nil {
+			var err error
+			sender, err = mailDialer().Dial()
+			if err != nil {
+				failed[recipient] = err
+				continue
+			}
+		}
+
+		err := mail.Send(sender, newMessage(recipient, subject, body))
+		if err != nil {
+			failed[recipient] = err
+			sender.Close()
+			sender = nil
+		}
+	}
+	if sender != nil {
+		sender.Close()
+	}
+	return failed
+}
+
+// To build a plain text message to one recipient from MAIL_FROM
+func newMessage(recipient, subject, body string) *mail.Message {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "admin@schoolly.com"
+	}
+
+	m := mail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", recipient)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", body)
+	return m
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Currencies whose minor unit isn't a hundredth
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 code such as USD or NGN
+func ValidCurrency(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// FormatMoney turns minor units into a readable amount, e.g. 150050 USD becomes "USD 1,500.50"
+func FormatMoney(amount int64, currency string) string {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		exponent = 2
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	unit := int64(1)
+	for i := 0; i < exponent; i++ {
+		unit *= 10
+	}
+
+	whole := fmt.Sprint(amount / unit)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if exponent == 0 {
+		return fmt.Sprintf("%s %s%s", currency, sign, grouped.String())
+	}
+	return fmt.Sprintf("%s %s%s.%0*d", currency, sign, grouped.String(), exponent, amount%unit)
+}